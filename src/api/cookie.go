@@ -58,6 +58,12 @@ func getHeaders(website, userAgent string) map[string]string {
 	case utils.KEMONO_BACKUP :
 		referer = utils.BACKUP_KEMONO_URL
 		origin = utils.BACKUP_KEMONO_URL
+	case utils.COOMER :
+		referer = utils.COOMER_URL
+		origin = utils.COOMER_URL
+	case utils.COOMER_BACKUP :
+		referer = utils.BACKUP_COOMER_URL
+		origin = utils.BACKUP_COOMER_URL
 	default :
 		// Shouldn't happen but could happen during development
 		panic(
@@ -86,10 +92,14 @@ func VerifyCookie(cookie *http.Cookie, website, userAgent string) (bool, error)
 		websiteUrl = utils.PIXIV_FANBOX_URL + "/creators/supporting"
 	case utils.PIXIV:
 		websiteUrl = utils.PIXIV_URL + "/dashboard"
-	// case utils.KEMONO: Since kemono.party is no longer up and redirects to kemono.su
-	// 	websiteUrl = utils.KEMONO_URL + "/favorites"
+	case utils.KEMONO, utils.COOMER:
+		// Since kemono.party and coomer.party are no longer up and redirect to kemono.su and coomer.su,
+		// the cookie will be verified on the backup domain instead by VerifyAndGetCookie.
+		return false, nil
 	case utils.KEMONO_BACKUP:
 		websiteUrl = utils.BACKUP_KEMONO_URL + "/favorites"
+	case utils.COOMER_BACKUP:
+		websiteUrl = utils.BACKUP_COOMER_URL + "/favorites"
 	default:
 		// Shouldn't happen but could happen during development
 		panic(
@@ -158,6 +168,8 @@ func backupVerifyCookie(website, cookieValue, userAgent string) *http.Cookie {
 	switch website {
 	case utils.KEMONO:
 		backupWebsite = utils.KEMONO_BACKUP
+	case utils.COOMER:
+		backupWebsite = utils.COOMER_BACKUP
 	default:
 		// Shouldn't happen but could happen during development
 		color.Red(
@@ -196,7 +208,7 @@ func VerifyAndGetCookie(website, cookieValue, userAgent string) *http.Cookie {
 	processCookieVerification(website, err)
 
 	if !cookieIsValid {
-		if website != utils.KEMONO {
+		if website != utils.KEMONO && website != utils.COOMER {
 			color.Red(
 				fmt.Sprintf(
 					"error %d: %s cookie is invalid",
@@ -10,6 +10,7 @@ import (
 	"time"
 	"os"
 
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
@@ -345,6 +346,14 @@ func getCreatorPosts(creatorId, pageNum string, dlOptions *FantiaDlOptions) ([]s
 		return nil, err
 	}
 
+	// when syncing, stop at the first post that has been seen from the last sync
+	var syncState, newSyncState *history.SyncState
+	if dlOptions.Configs.Sync {
+		syncState = history.GetSyncState(utils.FANTIA, creatorId)
+		newSyncState = &history.SyncState{}
+		*newSyncState = *syncState
+	}
+
 	useHttp3 := utils.IsHttp3Supported(utils.FANTIA, false)
 	curPage := minPage
 	for {
@@ -383,14 +392,29 @@ func getCreatorPosts(creatorId, pageNum string, dlOptions *FantiaDlOptions) ([]s
 		if err != nil {
 			return nil, err
 		}
-		postIds = append(postIds, creatorPostIds...)
+
+		reachedSeenPost := false
+		for _, postId := range creatorPostIds {
+			if syncState != nil {
+				newSyncState.Update(postId, time.Time{})
+				if syncState.IsSeen(postId, time.Time{}) {
+					reachedSeenPost = true
+					continue
+				}
+			}
+			postIds = append(postIds, postId)
+		}
 
 		// if there are no more posts, break
-		if len(creatorPostIds) == 0 || (hasMax && curPage >= maxPage) {
+		if len(creatorPostIds) == 0 || reachedSeenPost || (hasMax && curPage >= maxPage) {
 			break
 		}
 		curPage++
 	}
+
+	if newSyncState != nil {
+		history.SetPendingSyncState(newSyncState)
+	}
 	return postIds, nil
 }
 
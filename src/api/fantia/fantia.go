@@ -1,6 +1,8 @@
 package fantia
 
 import (
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/render"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
@@ -15,10 +17,14 @@ func FantiaDownloadProcess(fantiaDl *FantiaDl, fantiaDlOptions *FantiaDlOptions)
 		fantiaDl.getCreatorsPosts(fantiaDlOptions)
 	}
 
+	if !fantiaDlOptions.Configs.IgnoreHistory {
+		fantiaDl.PostIds = history.FilterPosts(utils.FANTIA, fantiaDl.PostIds)
+	}
+
 	var gdriveLinks []*request.ToDownload
 	var downloadedPosts bool
 	if len(fantiaDl.PostIds) > 0 {
-		fantiaDl.dlFantiaPosts(fantiaDlOptions)
+		gdriveLinks = fantiaDl.dlFantiaPosts(fantiaDlOptions)
 		downloadedPosts = true
 	}
 
@@ -27,6 +33,16 @@ func FantiaDownloadProcess(fantiaDl *FantiaDl, fantiaDlOptions *FantiaDlOptions)
 		downloadedPosts = true
 	}
 
+	if fantiaDlOptions.Configs.DryRun {
+		request.PrintPlan(fantiaDlOptions.Configs)
+		return
+	}
+
+	// render the post bodies and save the processed posts and the sync states
+	// only after the files of the posts have been downloaded
+	render.RenderPending(fantiaDlOptions.Configs)
+	history.CommitPosts()
+	history.CommitSyncStates()
 	if downloadedPosts {
 		utils.AlertWithoutErr(utils.Title, "Downloaded all posts from Fantia!")
 	} else {
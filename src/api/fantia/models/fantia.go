@@ -1,6 +1,11 @@
 package models
 
+import "encoding/json"
+
 type FantiaContent struct {
+	Title    string `json:"title"`
+	Category string `json:"category"` // e.g. "blog", "photo_gallery", "file", "text"
+
 	// Any attachments such as pdfs that are on their dedicated section
 	AttachmentURI string `json:"attachment_uri"`
 
@@ -19,23 +24,66 @@ type FantiaContent struct {
 	// for attachments such as pdfs that are embedded in the post content
 	DownloadUri string `json:"download_uri"`
 	Filename    string `json:"filename"`
+
+	// The fan plan required to view the content, nil if the content is free
+	Plan *struct {
+		Name  string `json:"name"`
+		Price int    `json:"price"`
+	} `json:"plan"`
+}
+
+// FantiaBlogComment is the comment of a content with the "blog" category
+// which is formatted as a Quill Delta JSON string.
+type FantiaBlogComment struct {
+	Ops []struct {
+		// Insert is either a string or an object such as {"fantiaImage": {...}}
+		Insert     json.RawMessage `json:"insert"`
+		Attributes struct {
+			Bold   bool   `json:"bold"`
+			Link   string `json:"link"`
+			Header int    `json:"header"`
+		} `json:"attributes"`
+	} `json:"ops"`
+}
+
+type FantiaBlogImage struct {
+	FantiaImage struct {
+		OriginalUrl string `json:"original_url"`
+	} `json:"fantiaImage"`
 }
 
 type FantiaPost struct {
 	Post struct {
-		ID      int    `json:"id"`
-		Comment string `json:"comment"` // the main post content
-		Title   string `json:"title"`
-		Thumb   struct {
+		ID       int    `json:"id"`
+		Comment  string `json:"comment"` // the main post content
+		Title    string `json:"title"`
+		PostedAt string `json:"posted_at"`
+		Thumb    struct {
 			Original string `json:"original"`
 		} `json:"thumb"`
 		Fanclub struct {
+			ID   int `json:"id"`
 			User struct {
 				Name string `json:"name"`
 			} `json:"user"`
 		} `json:"fanclub"`
-		Status       string `json:"status"`
+		Tags []struct {
+			Name string `json:"name"`
+		} `json:"tags"`
+		Status       string          `json:"status"`
 		PostContents []FantiaContent `json:"post_contents"`
 	} `json:"post"`
 	Redirect string `json:"redirect"` // if get flagged by the system, it will redirect to this recaptcha url
+
+	// Raw is the raw JSON of the post for the metadata file
+	Raw json.RawMessage `json:"-"`
+}
+
+func (p *FantiaPost) UnmarshalJSON(data []byte) error {
+	type fantiaPost FantiaPost
+	if err := json.Unmarshal(data, (*fantiaPost)(p)); err != nil {
+		return err
+	}
+	p.Raw = append(json.RawMessage(nil), data...)
+	return nil
 }
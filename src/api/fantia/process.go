@@ -5,11 +5,15 @@ import (
 	"net/http"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/fantia/models"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/metadata"
+	"github.com/KJHJason/Cultured-Downloader-CLI/render"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
 )
@@ -66,6 +70,40 @@ func dlAttachmentsFromPost(content *models.FantiaContent, postFolderPath string)
 
 var errRecaptcha = fmt.Errorf("recaptcha detected for the current session")
 
+// getPostMetadata returns the metadata of the Fantia post
+// with the lowest fan plan price of its contents as the post's fee.
+func getPostMetadata(postJson *models.FantiaPost, postUrl string, pathVars *utils.PathVars) *metadata.Post {
+	post := postJson.Post
+	postMetadata := &metadata.Post{
+		Site:        utils.FANTIA,
+		Url:         postUrl,
+		PostId:      pathVars.PostId,
+		Title:       post.Title,
+		Body:        post.Comment,
+		CreatorId:   pathVars.CreatorId,
+		CreatorName: pathVars.CreatorName,
+		PublishedAt: metadata.ParseTime(time.RFC1123Z, post.PostedAt),
+		Raw:         postJson.Raw,
+	}
+	for _, tag := range post.Tags {
+		postMetadata.Tags = append(postMetadata.Tags, tag.Name)
+	}
+	for _, content := range post.PostContents {
+		plan := content.Plan
+		if plan == nil {
+			continue
+		}
+		if postMetadata.Fee == nil || plan.Price < postMetadata.Fee.Price {
+			postMetadata.Fee = &metadata.Fee{
+				Name:     plan.Name,
+				Price:    plan.Price,
+				Currency: "JPY",
+			}
+		}
+	}
+	return postMetadata
+}
+
 // Process the JSON response from Fantia's API and
 // returns a slice of urls and a slice of gdrive urls to download from
 func processFantiaPost(res *http.Response, downloadPath string, dlOptions *FantiaDlOptions) ([]*request.ToDownload, []*request.ToDownload, error) {
@@ -118,9 +156,6 @@ func processFantiaPost(res *http.Response, downloadPath string, dlOptions *Fanti
 	)
 
 	postContent := post.PostContents
-	if postContent == nil {
-		return urlsSlice, gdriveLinks, nil
-	}
 	for _, content := range postContent {
 		commentGdriveLinks := gdrive.ProcessPostText(
 			content.Comment,
@@ -138,6 +173,40 @@ func processFantiaPost(res *http.Response, downloadPath string, dlOptions *Fanti
 			urlsSlice = append(urlsSlice, dlAttachmentsFromPost(&content, postFolderPath)...)
 		}
 	}
+
+	historyPost := &history.Post{
+		Site:      utils.FANTIA,
+		CreatorId: strconv.Itoa(post.Fanclub.ID),
+		PostId:    postId,
+	}
+	for _, urlInfo := range urlsSlice {
+		urlInfo.Post = historyPost
+	}
+	for _, gdriveLink := range gdriveLinks {
+		gdriveLink.Post = historyPost
+	}
+	if len(urlsSlice) == 0 && dlOptions.DlThumbnails && dlOptions.DlImages && dlOptions.DlAttachments {
+		// record text-only and GDrive-only posts as there are no files for the download process to record
+		history.AddProcessedPost(historyPost)
+	}
+
+	postedAt, _ := time.Parse(time.RFC1123Z, post.PostedAt)
+	pathVars := &utils.PathVars{
+		Site:        utils.FANTIA,
+		CreatorName: creatorName,
+		CreatorId:   historyPost.CreatorId,
+		PostId:      postId,
+		PostTitle:   postTitle,
+		PublishedAt: postedAt,
+	}
+	request.SetPathVars(urlsSlice, pathVars, postFolderPath, utils.THUMBNAIL_KIND)
+	request.SetPathVars(gdriveLinks, pathVars, postFolderPath, utils.GDRIVE_FOLDER)
+
+	postUrl := fmt.Sprintf("%s/posts/%s", utils.FANTIA_URL, postId)
+	metadata.Save(getPostMetadata(&postJson, postUrl, pathVars), postFolderPath, pathVars, dlOptions.Configs)
+	if dlOptions.Configs.BodyFormat != "" {
+		render.AddPending(getPostDocument(&postJson, postUrl, postFolderPath, pathVars))
+	}
 	return urlsSlice, gdriveLinks, nil
 }
 
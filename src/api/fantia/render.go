@@ -0,0 +1,108 @@
+package fantia
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/fantia/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/render"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// getBlogBlocks converts the Quill Delta comment of a blog content into blocks.
+// If the comment is not in the expected format, the comment will be returned as plain text.
+func getBlogBlocks(comment string) []*render.Block {
+	var blogComment models.FantiaBlogComment
+	if err := json.Unmarshal([]byte(comment), &blogComment); err != nil || len(blogComment.Ops) == 0 {
+		return []*render.Block{render.TextBlock(comment)}
+	}
+
+	var blocks []*render.Block
+	var lineSpans []*render.Span
+	for _, op := range blogComment.Ops {
+		var text string
+		if err := json.Unmarshal(op.Insert, &text); err != nil {
+			var image models.FantiaBlogImage
+			if err := json.Unmarshal(op.Insert, &image); err == nil && image.FantiaImage.OriginalUrl != "" {
+				blocks = append(blocks, &render.Block{Type: render.PARAGRAPH_BLOCK, Spans: lineSpans})
+				lineSpans = nil
+				blocks = append(blocks, &render.Block{
+					Type: render.IMAGE_BLOCK,
+					Url:  utils.FANTIA_URL + image.FantiaImage.OriginalUrl,
+				})
+			}
+			continue
+		}
+
+		// In Quill Delta, the line formats such as headers
+		// are in the attributes of the new line character that ends the line.
+		lines := strings.Split(text, "\n")
+		for idx, line := range lines {
+			if line != "" {
+				lineSpans = append(lineSpans, &render.Span{
+					Text: line,
+					Bold: op.Attributes.Bold,
+					Link: op.Attributes.Link,
+				})
+			}
+			if idx == len(lines)-1 {
+				continue
+			}
+
+			blockType := render.PARAGRAPH_BLOCK
+			if op.Attributes.Header > 0 {
+				blockType = render.HEADER_BLOCK
+			}
+			blocks = append(blocks, &render.Block{Type: blockType, Spans: lineSpans})
+			lineSpans = nil
+		}
+	}
+	blocks = append(blocks, &render.Block{Type: render.PARAGRAPH_BLOCK, Spans: lineSpans})
+	return blocks
+}
+
+// getContentBlocks returns the blocks of a content in the post based on its category
+func getContentBlocks(content *models.FantiaContent) []*render.Block {
+	blocks := []*render.Block{
+		{Type: render.HEADER_BLOCK, Spans: []*render.Span{{Text: content.Title}}},
+	}
+	if content.Category == "blog" {
+		return append(blocks, getBlogBlocks(content.Comment)...)
+	}
+
+	blocks = append(blocks, render.TextBlock(content.Comment))
+	for _, image := range content.PostContentPhotos {
+		blocks = append(blocks, &render.Block{
+			Type: render.IMAGE_BLOCK,
+			Url:  image.URL.Original,
+		})
+	}
+	if content.AttachmentURI != "" {
+		blocks = append(blocks, &render.Block{
+			Type: render.FILE_BLOCK,
+			Url:  utils.FANTIA_URL + content.AttachmentURI,
+			Name: content.Filename,
+		})
+	} else if content.DownloadUri != "" {
+		blocks = append(blocks, &render.Block{
+			Type: render.FILE_BLOCK,
+			Url:  utils.FANTIA_URL + content.DownloadUri,
+			Name: content.Filename,
+		})
+	}
+	return blocks
+}
+
+// getPostDocument returns the post body to be rendered with the contents of the post in order.
+func getPostDocument(postJson *models.FantiaPost, postUrl, postFolderPath string, pathVars *utils.PathVars) *render.Document {
+	post := postJson.Post
+	doc := render.NewDocument(post.Title, postUrl, postFolderPath, pathVars)
+	if thumbnail := post.Thumb.Original; thumbnail != "" {
+		doc.AddBlocks(&render.Block{Type: render.IMAGE_BLOCK, Url: thumbnail})
+	}
+	doc.AddBlocks(render.TextBlock(post.Comment))
+	for idx := range post.PostContents {
+		doc.AddBlocks(getContentBlocks(&post.PostContents[idx])...)
+	}
+	return doc
+}
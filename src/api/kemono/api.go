@@ -6,14 +6,19 @@ import (
 	"net/http"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 	"github.com/PuerkitoBio/goquery"
 )
 
+// KEMONO_PUBLISHED_LAYOUT is the time layout of the published time of a post from Kemono Party's API
+const KEMONO_PUBLISHED_LAYOUT = "2006-01-02T15:04:05"
+
 type kemonoChanRes struct {
 	urlsToDownload []*request.ToDownload
 	gdriveLinks    []*request.ToDownload
@@ -24,57 +29,18 @@ func getKemonoPartyHeaders() map[string]string {
 	return map[string]string{}
 }
 
-func getKemonoUrl(tld string) string {
-	if tld == utils.KEMONO_TLD {
-		return utils.KEMONO_URL
-	}
-	return utils.BACKUP_KEMONO_URL
-}
-
-func getKemonoApiUrl(tld string) string {
-	if tld == utils.KEMONO_TLD {
-		return utils.KEMONO_API_URL
-	}
-	return utils.BACKUP_KEMONO_API_URL
-}
-
-func getKemonoUrlFromConditions(isBackup, isApi bool) string {
-	if isApi {
-		if isBackup {
-			return utils.BACKUP_KEMONO_API_URL
-		}
-		return utils.KEMONO_API_URL
-	}
-
-	if isBackup {
-		return utils.BACKUP_KEMONO_URL
-	}
-	return utils.KEMONO_URL
-}
-
 var errSessionCookieNotFound = errors.New("could not find session cookie")
-func getKemonoUrlFromCookie(cookie []*http.Cookie, isApi bool) (string, string, error) {
-	for _, c := range cookie {
-		if c.Name == utils.KEMONO_SESSION_COOKIE_NAME {
-			if c.Domain == utils.KEMONO_COOKIE_BACKUP_DOMAIN {
-				return getKemonoUrlFromConditions(true, isApi), utils.KEMONO_BACKUP_TLD, nil
-			} // else {
-				// return getKemonoUrlFromConditions(false, isApi), utils.KEMONO_TLD ,nil
-			// }
-		}
-	}
-	return "", "", errSessionCookieNotFound
-}
 
 // To obtain the creator's username
-func parseCreatorHtml(res *http.Response, url string) (string, error) {
+func parseCreatorHtml(res *http.Response, url, title string) (string, error) {
 	// parse the response
 	doc, err := goquery.NewDocumentFromReader(res.Body)
 	res.Body.Close()
 	if err != nil {
 		err = fmt.Errorf(
-			"kemono error %d, failed to parse response body when getting creator name from Kemono Party at %s\nmore info => %v",
+			"kemono error %d, failed to parse response body when getting creator name from %s at %s\nmore info => %v",
 			utils.HTML_ERROR,
+			title,
 			url,
 			err,
 		)
@@ -85,8 +51,9 @@ func parseCreatorHtml(res *http.Response, url string) (string, error) {
 	creatorName := doc.Find("span[itemprop=name]").Text()
 	if creatorName == "" {
 		return "", fmt.Errorf(
-			"kemono error %d, failed to get creator name from Kemono Party at %s\nplease report this issue",
+			"kemono error %d, failed to get creator name from %s at %s\nplease report this issue",
 			utils.HTML_ERROR,
+			title,
 			url,
 		)
 	}
@@ -104,12 +71,12 @@ func getCreatorName(service, userId string, dlOptions *KemonoDlOptions) (string,
 		return name, nil
 	}
 
-	apiUrl, _, err := getKemonoUrlFromCookie(dlOptions.SessionCookies, false)
+	apiUrl, _, err := dlOptions.Archive.getUrlFromCookie(dlOptions.SessionCookies, false)
 	if err != nil {
 		return userId, err
 	}
 
-	useHttp3 := utils.IsHttp3Supported(utils.KEMONO, true)
+	useHttp3 := utils.IsHttp3Supported(dlOptions.Archive.Site, true)
 	url := fmt.Sprintf(
 		"%s/%s/user/%s",
 		apiUrl,
@@ -132,7 +99,7 @@ func getCreatorName(service, userId string, dlOptions *KemonoDlOptions) (string,
 		return userId, err
 	}
 
-	creatorName, err := parseCreatorHtml(res, url)
+	creatorName, err := parseCreatorHtml(res, url, dlOptions.Archive.Title)
 	if err != nil {
 		return userId, err
 	}
@@ -142,12 +109,12 @@ func getCreatorName(service, userId string, dlOptions *KemonoDlOptions) (string,
 }
 
 func getPostDetails(post *models.KemonoPostToDl, downloadPath string, dlOptions *KemonoDlOptions) ([]*request.ToDownload, []*request.ToDownload, error) {
-	useHttp3 := utils.IsHttp3Supported(utils.KEMONO, true)
+	useHttp3 := utils.IsHttp3Supported(dlOptions.Archive.Site, true)
 	res, err := request.CallRequest(
 		&request.RequestArgs{
 			Url: fmt.Sprintf(
 				"%s/%s/user/%s/post/%s",
-				getKemonoApiUrl(post.Tld),
+				dlOptions.Archive.GetApiUrl(post.Tld),
 				post.Service,
 				post.CreatorId,
 				post.PostId,
@@ -187,7 +154,8 @@ func getMultiplePosts(posts []*models.KemonoPostToDl, downloadPath string, dlOpt
 	queue := make(chan struct{}, maxConcurrency)
 	resChan := make(chan *kemonoChanRes, postLen)
 
-	baseMsg := "Getting post details from Kemono Party [%d/" + fmt.Sprintf("%d]...", postLen)
+	title := dlOptions.Archive.Title
+	baseMsg := "Getting post details from " + title + " [%d/" + fmt.Sprintf("%d]...", postLen)
 	progress := spinner.New(
 		spinner.REQ_SPINNER,
 		"fgHiYellow",
@@ -196,12 +164,14 @@ func getMultiplePosts(posts []*models.KemonoPostToDl, downloadPath string, dlOpt
 			0,
 		),
 		fmt.Sprintf(
-			"Finished getting %d post details from Kemono Party!",
+			"Finished getting %d post details from %s!",
 			postLen,
+			title,
 		),
 		fmt.Sprintf(
-			"Something went wrong while getting %d post details from Kemono Party.\nPlease refer to the logs for more details.",
+			"Something went wrong while getting %d post details from %s.\nPlease refer to the logs for more details.",
 			postLen,
+			title,
 		),
 		postLen,
 	)
@@ -251,13 +221,21 @@ func getMultiplePosts(posts []*models.KemonoPostToDl, downloadPath string, dlOpt
 }
 
 func getCreatorPosts(creator *models.KemonoCreatorToDl, downloadPath string, dlOptions *KemonoDlOptions) ([]*request.ToDownload, []*request.ToDownload, error) {
-	useHttp3 := utils.IsHttp3Supported(utils.KEMONO, true)
+	useHttp3 := utils.IsHttp3Supported(dlOptions.Archive.Site, true)
 	minPage, maxPage, hasMax, err := utils.GetMinMaxFromStr(creator.PageNum)
 	if err != nil {
 		return nil, nil, err
 	}
 	minOffset, maxOffset := utils.ConvertPageNumToOffset(minPage, maxPage, utils.KEMONO_PER_PAGE)
 
+	// when syncing, stop at the first post that has been seen from the last sync
+	var syncState, newSyncState *history.SyncState
+	if dlOptions.Configs.Sync {
+		syncState = history.GetSyncState(dlOptions.Archive.Site, getHistoryPostId(creator.Service, creator.CreatorId))
+		newSyncState = &history.SyncState{}
+		*newSyncState = *syncState
+	}
+
 	var postsToDl, gdriveLinksToDl []*request.ToDownload
 	params := make(map[string]string)
 	curOffset := minOffset
@@ -267,7 +245,7 @@ func getCreatorPosts(creator *models.KemonoCreatorToDl, downloadPath string, dlO
 			&request.RequestArgs{
 				Url: fmt.Sprintf(
 					"%s/%s/user/%s",
-					getKemonoApiUrl(creator.Tld),
+					dlOptions.Archive.GetApiUrl(creator.Tld),
 					creator.Service,
 					creator.CreatorId,
 				),
@@ -294,15 +272,35 @@ func getCreatorPosts(creator *models.KemonoCreatorToDl, downloadPath string, dlO
 			break
 		}
 
+		reachedSeenPost := false
+		if syncState != nil {
+			var unseenPosts models.KemonoJson
+			for _, post := range resJson {
+				publishedAt, _ := time.Parse(KEMONO_PUBLISHED_LAYOUT, post.Published)
+				newSyncState.Update(post.Id, publishedAt)
+				if syncState.IsSeen(post.Id, publishedAt) {
+					reachedSeenPost = true
+					continue
+				}
+				unseenPosts = append(unseenPosts, post)
+			}
+			resJson = unseenPosts
+		}
+
 		posts, gdriveLinks := processMultipleJson(resJson, creator.Tld, downloadPath, dlOptions)
 		postsToDl = append(postsToDl, posts...)
 		gdriveLinksToDl = append(gdriveLinksToDl, gdriveLinks...)
 
-		if (hasMax && curOffset >= maxOffset) {
+		if reachedSeenPost || (hasMax && curOffset >= maxOffset) {
 			break
 		}
 		curOffset += utils.KEMONO_PER_PAGE
 	}
+
+	if newSyncState != nil {
+		history.SetPendingSyncState(newSyncState)
+	}
+	postsToDl = append(postsToDl, getCreatorExtras(creator, downloadPath, dlOptions)...)
 	return postsToDl, gdriveLinksToDl, nil
 }
 
@@ -310,7 +308,8 @@ func getMultipleCreators(creators []*models.KemonoCreatorToDl, downloadPath stri
 	var errSlice []error
 	var urlsToDownload, gdriveLinks []*request.ToDownload
 	creatorLen := len(creators)
-	baseMsg := "Getting creator's posts from Kemono Party [%d/" + fmt.Sprintf("%d]...", creatorLen)
+	title := dlOptions.Archive.Title
+	baseMsg := "Getting creator's posts from " + title + " [%d/" + fmt.Sprintf("%d]...", creatorLen)
 	progress := spinner.New(
 		spinner.REQ_SPINNER,
 		"fgHiYellow",
@@ -319,12 +318,14 @@ func getMultipleCreators(creators []*models.KemonoCreatorToDl, downloadPath stri
 			0,
 		),
 		fmt.Sprintf(
-			"Finished getting %d creator's posts from Kemono Party!",
+			"Finished getting %d creator's posts from %s!",
 			creatorLen,
+			title,
 		),
 		fmt.Sprintf(
-			"Something went wrong while getting %d creator's posts from Kemono Party.\nPlease refer to the logs for more details.",
+			"Something went wrong while getting %d creator's posts from %s.\nPlease refer to the logs for more details.",
 			creatorLen,
+			title,
 		),
 		creatorLen,
 	)
@@ -363,55 +364,60 @@ func processFavCreator(resJson models.KemonoFavCreatorJson, tld string) []*model
 	return creators
 }
 
-func getFavourites(downloadPath string, dlOptions *KemonoDlOptions) ([]*request.ToDownload, []*request.ToDownload, error) {
-	apiUrl, tld, err := getKemonoUrlFromCookie(dlOptions.SessionCookies, true)
+func getFavourites(downloadPath string, dlFavCreators, dlFavPosts bool, dlOptions *KemonoDlOptions) ([]*request.ToDownload, []*request.ToDownload, error) {
+	apiUrl, tld, err := dlOptions.Archive.getUrlFromCookie(dlOptions.SessionCookies, true)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	useHttp3 := utils.IsHttp3Supported(utils.KEMONO, true)
-	params := map[string]string{
-		"type": "artist",
-	}
+	useHttp3 := utils.IsHttp3Supported(dlOptions.Archive.Site, true)
 	reqArgs := &request.RequestArgs{
-		Url:         fmt.Sprintf("%s/v1/account/favorites", apiUrl),
+		Url:         fmt.Sprintf("%s/account/favorites", apiUrl),
 		Method:      "GET",
 		Cookies:     dlOptions.SessionCookies,
-		Params:      params,
 		Headers:     getKemonoPartyHeaders(),
 		UserAgent:   dlOptions.Configs.UserAgent,
 		Http2:       !useHttp3,
 		Http3:       useHttp3,
 		CheckStatus: true,
 	}
-	res, err := request.CallRequest(reqArgs)
-	if err != nil {
-		return nil, nil, err
-	}
 
-	var creatorResJson models.KemonoFavCreatorJson
-	if err := utils.LoadJsonFromResponse(res, &creatorResJson); err != nil {
-		return nil, nil, err
-	}
-	artistToDl := processFavCreator(creatorResJson, tld)
+	var urlsToDownload, gdriveLinks []*request.ToDownload
+	if dlFavPosts {
+		reqArgs.Params = map[string]string{
+			"type": "post",
+		}
+		res, err := request.CallRequest(reqArgs)
+		if err != nil {
+			return nil, nil, err
+		}
 
-	reqArgs.Params = map[string]string{
-		"type": "post",
-	}
-	res, err = request.CallRequest(reqArgs)
-	if err != nil {
-		return nil, nil, err
+		var postResJson models.KemonoJson
+		if err := utils.LoadJsonFromResponse(res, &postResJson); err != nil {
+			return nil, nil, err
+		}
+		urlsToDownload, gdriveLinks = processMultipleJson(postResJson, tld, downloadPath, dlOptions)
 	}
 
-	var postResJson models.KemonoJson
-	if err := utils.LoadJsonFromResponse(res, &postResJson); err != nil {
-		return nil, nil, err
-	}
-	urlsToDownload, gdriveLinks := processMultipleJson(postResJson, tld, downloadPath, dlOptions)
+	if dlFavCreators {
+		reqArgs.Params = map[string]string{
+			"type": "artist",
+		}
+		res, err := request.CallRequest(reqArgs)
+		if err != nil {
+			return urlsToDownload, gdriveLinks, err
+		}
 
-	creatorsPost, creatorsGdrive := getMultipleCreators(artistToDl, downloadPath, dlOptions)
-	urlsToDownload = append(urlsToDownload, creatorsPost...)
-	gdriveLinks = append(gdriveLinks, creatorsGdrive...)
+		var creatorResJson models.KemonoFavCreatorJson
+		if err := utils.LoadJsonFromResponse(res, &creatorResJson); err != nil {
+			return urlsToDownload, gdriveLinks, err
+		}
+		artistToDl := processFavCreator(creatorResJson, tld)
+
+		creatorsPost, creatorsGdrive := getMultipleCreators(artistToDl, downloadPath, dlOptions)
+		urlsToDownload = append(urlsToDownload, creatorsPost...)
+		gdriveLinks = append(gdriveLinks, creatorsGdrive...)
+	}
 
 	return urlsToDownload, gdriveLinks, nil
 }
@@ -0,0 +1,165 @@
+package kemono
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// HEALTH_CHECK_PATH is the API path that the hosts of an archive are health-checked with
+const HEALTH_CHECK_PATH = "/api/v1/app_version"
+
+// Archive is a Kemono-compatible archive site that runs the same API as
+// Kemono Party but archives the posts from a different list of services.
+type Archive struct {
+	// Site is the site of the archive, e.g. utils.KEMONO, and
+	// BackupSite is the site of its backup domain, e.g. utils.KEMONO_BACKUP
+	Site       string
+	BackupSite string
+
+	Title      string
+	FolderName string
+	Services   []string
+
+	// Domain is the domain name of the archive without its top-level domain, e.g. "kemono".
+	Domain string
+
+	BaseRegexStr    string
+	PostUrlRegex    *regexp.Regexp
+	CreatorUrlRegex *regexp.Regexp
+
+	// DiscordUrlRegex is nil if the archive does not archive Discord servers
+	DiscordUrlRegex *regexp.Regexp
+}
+
+// NewArchive returns a Kemono-compatible archive with the regexes for its post and creator URLs
+// and for its Discord server URLs if the archive also archives Discord servers.
+func NewArchive(site, backupSite, title, folderName, domain string, services []string, hasDiscord bool) *Archive {
+	baseRegexStr := fmt.Sprintf(
+		`https://%s\.(?P<%s>party|su)/(?P<%s>%s)/user/(?P<%s>[\w-]+)`,
+		regexp.QuoteMeta(domain),
+		TLD_GROUP_NAME,
+		SERVICE_GROUP_NAME,
+		strings.Join(services, "|"),
+		CREATOR_ID_GROUP_NAME,
+	)
+	var discordUrlRegex *regexp.Regexp
+	if hasDiscord {
+		discordUrlRegex = regexp.MustCompile(
+			fmt.Sprintf(
+				`^https://%s\.(?P<%s>party|su)/discord/server/(?P<%s>\d+)(?:[/#](?P<%s>\d+))?$`,
+				regexp.QuoteMeta(domain),
+				TLD_GROUP_NAME,
+				SERVER_ID_GROUP_NAME,
+				CHANNEL_ID_GROUP_NAME,
+			),
+		)
+	}
+	return &Archive{
+		Site:            site,
+		BackupSite:      backupSite,
+		Title:           title,
+		FolderName:      folderName,
+		Services:        services,
+		Domain:          domain,
+		BaseRegexStr:    baseRegexStr,
+		PostUrlRegex:    regexp.MustCompile(fmt.Sprintf(`^%s%s$`, baseRegexStr, BASE_POST_SUFFIX_REGEX_STR)),
+		CreatorUrlRegex: regexp.MustCompile(fmt.Sprintf(`^%s$`, baseRegexStr)),
+		DiscordUrlRegex: discordUrlRegex,
+	}
+}
+
+var (
+	KEMONO_ARCHIVE = NewArchive(
+		utils.KEMONO,
+		utils.KEMONO_BACKUP,
+		utils.KEMONO_TITLE,
+		"Kemono-Party",
+		"kemono",
+		[]string{"patreon", "fanbox", "gumroad", "subscribestar", "dlsite", "fantia", "boosty"},
+		true,
+	)
+	COOMER_ARCHIVE = NewArchive(
+		utils.COOMER,
+		utils.COOMER_BACKUP,
+		utils.COOMER_TITLE,
+		"Coomer-Party",
+		"coomer",
+		[]string{"onlyfans", "fansly", "candfans"},
+		false,
+	)
+)
+
+// The requests to the archive fail over between its equivalent hosts which can be overridden with the --mirror flag.
+//
+// The data servers, e.g. "n1.kemono.su", are not a mirror group as each of them only stores some of the files.
+// Instead, the request package follows the redirects to them itself and remembers the ones that are down.
+func init() {
+	for _, archive := range []*Archive{KEMONO_ARCHIVE, COOMER_ARCHIVE} {
+		request.AddMirrorGroup(archive.getHosts(), HEALTH_CHECK_PATH)
+	}
+}
+
+// getHosts returns the hosts of the archive on each top-level domain, e.g. "kemono.su" and "kemono.party"
+func (a *Archive) getHosts() []string {
+	return []string{
+		fmt.Sprintf("%s.%s", a.Domain, utils.KEMONO_BACKUP_TLD),
+		fmt.Sprintf("%s.%s", a.Domain, utils.KEMONO_TLD),
+	}
+}
+
+// GetUrl returns the URL of the archive for the top-level domain
+func (a *Archive) GetUrl(tld string) string {
+	if tld == utils.KEMONO_TLD {
+		return fmt.Sprintf("https://%s.%s", a.Domain, utils.KEMONO_TLD)
+	}
+	return fmt.Sprintf("https://%s.%s", a.Domain, utils.KEMONO_BACKUP_TLD)
+}
+
+// GetApiUrl returns the API URL of the archive for the top-level domain
+func (a *Archive) GetApiUrl(tld string) string {
+	return a.GetUrl(tld) + "/api/v1"
+}
+
+// GetDataUrl returns the URL of the file at the path on the archive.
+//
+// The archive redirects the "/data/..." paths to the data server that stores the file
+// which the request package then tracks like a mirror so that the downloads fail fast when it is down.
+func (a *Archive) GetDataUrl(tld, path string) string {
+	return a.GetUrl(tld) + path
+}
+
+// GetCreatorUrl returns the URL of the creator's page on the archive for the top-level domain
+func (a *Archive) GetCreatorUrl(service, creatorId, tld string) string {
+	return fmt.Sprintf("%s/%s/user/%s", a.GetUrl(tld), service, creatorId)
+}
+
+// getBackupCookieDomain returns the domain of the session cookie on the backup domain, e.g. "kemono.su"
+func (a *Archive) getBackupCookieDomain() string {
+	return utils.GetSessionCookieInfo(a.BackupSite).Domain
+}
+
+// getUrlFromCookie returns the URL and the top-level domain of the archive based on the domain of the session cookie.
+//
+// The backup domain is used if no cookies were provided as the posts and creators are public.
+func (a *Archive) getUrlFromCookie(cookies []*http.Cookie, isApi bool) (string, string, error) {
+	if len(cookies) == 0 {
+		if isApi {
+			return a.GetApiUrl(utils.KEMONO_BACKUP_TLD), utils.KEMONO_BACKUP_TLD, nil
+		}
+		return a.GetUrl(utils.KEMONO_BACKUP_TLD), utils.KEMONO_BACKUP_TLD, nil
+	}
+	for _, c := range cookies {
+		if c.Name == utils.KEMONO_SESSION_COOKIE_NAME && c.Domain == a.getBackupCookieDomain() {
+			if isApi {
+				return a.GetApiUrl(utils.KEMONO_BACKUP_TLD), utils.KEMONO_BACKUP_TLD, nil
+			}
+			return a.GetUrl(utils.KEMONO_BACKUP_TLD), utils.KEMONO_BACKUP_TLD, nil
+		}
+	}
+	return "", "", errSessionCookieNotFound
+}
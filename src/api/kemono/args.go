@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
-	"regexp"
+	"strings"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono/models"
@@ -15,39 +15,16 @@ import (
 )
 
 const (
-	BASE_REGEX_STR             = `https://kemono\.(?P<topLevelDomain>party|su)/(?P<service>patreon|fanbox|gumroad|subscribestar|dlsite|fantia|boosty)/user/(?P<creatorId>[\w-]+)`
 	BASE_POST_SUFFIX_REGEX_STR = `/post/(?P<postId>\d+)`
 	TLD_GROUP_NAME             = "topLevelDomain"
 	SERVICE_GROUP_NAME         = "service"
 	CREATOR_ID_GROUP_NAME      = "creatorId"
 	POST_ID_GROUP_NAME         = "postId"
+	SERVER_ID_GROUP_NAME       = "serverId"
+	CHANNEL_ID_GROUP_NAME      = "channelId"
 	API_MAX_CONCURRENT         = 3
 )
 
-var (
-	POST_URL_REGEX = regexp.MustCompile(
-		fmt.Sprintf(
-			`^%s%s$`,
-			BASE_REGEX_STR,
-			BASE_POST_SUFFIX_REGEX_STR,
-		),
-	)
-	POST_URL_REGEX_TLD_INDEX = POST_URL_REGEX.SubexpIndex(TLD_GROUP_NAME)
-	POST_URL_REGEX_SERVICE_INDEX = POST_URL_REGEX.SubexpIndex(SERVICE_GROUP_NAME)
-	POST_URL_REGEX_CREATOR_ID_INDEX = POST_URL_REGEX.SubexpIndex(CREATOR_ID_GROUP_NAME)
-	POST_URL_REGEX_POST_ID_INDEX = POST_URL_REGEX.SubexpIndex(POST_ID_GROUP_NAME)
-
-	CREATOR_URL_REGEX = regexp.MustCompile(
-		fmt.Sprintf(
-			`^%s$`,
-			BASE_REGEX_STR,
-		),
-	)
-	CREATOR_URL_REGEX_TLD_INDEX = CREATOR_URL_REGEX.SubexpIndex(TLD_GROUP_NAME)
-	CREATOR_URL_REGEX_SERVICE_INDEX = CREATOR_URL_REGEX.SubexpIndex(SERVICE_GROUP_NAME)
-	CREATOR_URL_REGEX_CREATOR_ID_INDEX = CREATOR_URL_REGEX.SubexpIndex(CREATOR_ID_GROUP_NAME)
-)
-
 type KemonoDl struct {
 	CreatorUrls     []string
 	CreatorPageNums []string
@@ -55,46 +32,58 @@ type KemonoDl struct {
 
 	PostUrls  []string
 	PostsToDl []*models.KemonoPostToDl
-}
 
-// HOTFIX: looks like kemono.party is no longer up and redirects to kemono.su
-func changeServiceToSu(service string) string {
-	if service == utils.KEMONO_TLD {
-		return utils.KEMONO_BACKUP_TLD
-	}
-	return service
+	DiscordUrls []string
+	DiscordToDl []*models.KemonoDiscordToDl
 }
 
-func ProcessCreatorUrls(creatorUrls []string, pageNums []string) []*models.KemonoCreatorToDl {
+func ProcessCreatorUrls(creatorUrls []string, pageNums []string, archive *Archive) []*models.KemonoCreatorToDl {
+	creatorUrlRegex := archive.CreatorUrlRegex
 	creatorsToDl := make([]*models.KemonoCreatorToDl, len(creatorUrls))
 	for i, creatorUrl := range creatorUrls {
-		matched := CREATOR_URL_REGEX.FindStringSubmatch(creatorUrl)
+		matched := creatorUrlRegex.FindStringSubmatch(creatorUrl)
 		creatorsToDl[i] = &models.KemonoCreatorToDl{
-			Service:   matched[CREATOR_URL_REGEX_SERVICE_INDEX],
-			CreatorId: matched[CREATOR_URL_REGEX_CREATOR_ID_INDEX],
+			Service:   matched[creatorUrlRegex.SubexpIndex(SERVICE_GROUP_NAME)],
+			CreatorId: matched[creatorUrlRegex.SubexpIndex(CREATOR_ID_GROUP_NAME)],
 			PageNum:   pageNums[i],
-			Tld:       changeServiceToSu(matched[CREATOR_URL_REGEX_TLD_INDEX]),
+			Tld:       matched[creatorUrlRegex.SubexpIndex(TLD_GROUP_NAME)],
 		}
 	}
 
 	return creatorsToDl
 }
 
-func ProcessPostUrls(postUrls []string) []*models.KemonoPostToDl {
+func ProcessPostUrls(postUrls []string, archive *Archive) []*models.KemonoPostToDl {
+	postUrlRegex := archive.PostUrlRegex
 	postsToDl := make([]*models.KemonoPostToDl, len(postUrls))
 	for i, postUrl := range postUrls {
-		matched := POST_URL_REGEX.FindStringSubmatch(postUrl)
+		matched := postUrlRegex.FindStringSubmatch(postUrl)
 		postsToDl[i] = &models.KemonoPostToDl{
-			Service:   matched[POST_URL_REGEX_SERVICE_INDEX],
-			CreatorId: matched[POST_URL_REGEX_CREATOR_ID_INDEX],
-			PostId:    matched[POST_URL_REGEX_POST_ID_INDEX],
-			Tld:       matched[POST_URL_REGEX_TLD_INDEX],
+			Service:   matched[postUrlRegex.SubexpIndex(SERVICE_GROUP_NAME)],
+			CreatorId: matched[postUrlRegex.SubexpIndex(CREATOR_ID_GROUP_NAME)],
+			PostId:    matched[postUrlRegex.SubexpIndex(POST_ID_GROUP_NAME)],
+			Tld:       matched[postUrlRegex.SubexpIndex(TLD_GROUP_NAME)],
 		}
 	}
 
 	return postsToDl
 }
 
+func ProcessDiscordUrls(discordUrls []string, archive *Archive) []*models.KemonoDiscordToDl {
+	discordUrlRegex := archive.DiscordUrlRegex
+	discordToDl := make([]*models.KemonoDiscordToDl, len(discordUrls))
+	for i, discordUrl := range discordUrls {
+		matched := discordUrlRegex.FindStringSubmatch(discordUrl)
+		discordToDl[i] = &models.KemonoDiscordToDl{
+			ServerId:  matched[discordUrlRegex.SubexpIndex(SERVER_ID_GROUP_NAME)],
+			ChannelId: matched[discordUrlRegex.SubexpIndex(CHANNEL_ID_GROUP_NAME)],
+			Tld:       matched[discordUrlRegex.SubexpIndex(TLD_GROUP_NAME)],
+		}
+	}
+
+	return discordToDl
+}
+
 // RemoveDuplicates removes duplicate creators and posts from the slice
 func (k *KemonoDl) RemoveDuplicates() {
 	if len(k.CreatorsToDl) > 0 {
@@ -111,6 +100,20 @@ func (k *KemonoDl) RemoveDuplicates() {
 		k.CreatorsToDl = newCreatorSlice
 	}
 
+	if len(k.DiscordToDl) > 0 {
+		newDiscordSlice := make([]*models.KemonoDiscordToDl, 0, len(k.DiscordToDl))
+		seen := make(map[string]struct{})
+		for _, server := range k.DiscordToDl {
+			key := fmt.Sprintf("%s/%s", server.ServerId, server.ChannelId)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			newDiscordSlice = append(newDiscordSlice, server)
+		}
+		k.DiscordToDl = newDiscordSlice
+	}
+
 	if len(k.PostsToDl) == 0 {
 		return
 	}
@@ -127,31 +130,62 @@ func (k *KemonoDl) RemoveDuplicates() {
 	k.PostsToDl = newPostSlice
 }
 
-func (k *KemonoDl) ValidateArgs() {
-	valid, outlier := utils.SliceMatchesRegex(CREATOR_URL_REGEX, k.CreatorUrls)
+// ValidateArgs validates the creator, post, and Discord server URLs against the URL formats of the
+// given Kemono-compatible archive and exits the program if any of them are invalid.
+func (k *KemonoDl) ValidateArgs(archive *Archive) {
+	valid, outlier := utils.SliceMatchesRegex(archive.CreatorUrlRegex, k.CreatorUrls)
 	if !valid {
 		color.Red(
 			fmt.Sprintf(
-				"kemono error %d: invalid creator URL found for kemono party: %s",
+				"%s error %d: invalid creator URL found for %s: %s",
+				archive.Site,
 				utils.INPUT_ERROR,
+				strings.ToLower(archive.Title),
 				outlier,
 			),
 		)
 		os.Exit(1)
 	}
 
-	valid, outlier = utils.SliceMatchesRegex(POST_URL_REGEX, k.PostUrls)
+	valid, outlier = utils.SliceMatchesRegex(archive.PostUrlRegex, k.PostUrls)
 	if !valid {
 		color.Red(
 			fmt.Sprintf(
-				"kemono error %d: invalid post URL found for kemono party: %s",
+				"%s error %d: invalid post URL found for %s: %s",
+				archive.Site,
 				utils.INPUT_ERROR,
+				strings.ToLower(archive.Title),
 				outlier,
 			),
 		)
 		os.Exit(1)
 	}
 
+	if len(k.DiscordUrls) > 0 {
+		if archive.DiscordUrlRegex == nil {
+			color.Red(
+				"%s error %d: %s does not archive Discord servers",
+				archive.Site,
+				utils.INPUT_ERROR,
+				archive.Title,
+			)
+			os.Exit(1)
+		}
+		valid, outlier = utils.SliceMatchesRegex(archive.DiscordUrlRegex, k.DiscordUrls)
+		if !valid {
+			color.Red(
+				fmt.Sprintf(
+					"%s error %d: invalid Discord server URL found for %s: %s",
+					archive.Site,
+					utils.INPUT_ERROR,
+					strings.ToLower(archive.Title),
+					outlier,
+				),
+			)
+			os.Exit(1)
+		}
+	}
+
 	if len(k.CreatorUrls) > 0 {
 		if len(k.CreatorPageNums) == 0 {
 			k.CreatorPageNums = make([]string, len(k.CreatorUrls))
@@ -164,24 +198,51 @@ func (k *KemonoDl) ValidateArgs() {
 				},
 			)
 		}
-		creatorsToDl := ProcessCreatorUrls(k.CreatorUrls, k.CreatorPageNums)
+		creatorsToDl := ProcessCreatorUrls(k.CreatorUrls, k.CreatorPageNums, archive)
 		k.CreatorsToDl = append(k.CreatorsToDl, creatorsToDl...)
 		k.CreatorUrls = nil
 		k.CreatorPageNums = nil
 	}
 	if len(k.PostUrls) > 0 {
-		postsToDl := ProcessPostUrls(k.PostUrls)
+		postsToDl := ProcessPostUrls(k.PostUrls, archive)
 		k.PostsToDl = append(k.PostsToDl, postsToDl...)
 		k.PostUrls = nil
 	}
+	if len(k.DiscordUrls) > 0 {
+		discordToDl := ProcessDiscordUrls(k.DiscordUrls, archive)
+		k.DiscordToDl = append(k.DiscordToDl, discordToDl...)
+		k.DiscordUrls = nil
+	}
 	k.RemoveDuplicates()
 }
 
 // KemonoDlOptions is the struct that contains the arguments for Kemono download options.
 type KemonoDlOptions struct {
+	// Archive is the Kemono-compatible archive to download from like Kemono Party or Coomer Party.
+	Archive *Archive
+
 	DlAttachments bool
 	DlGdrive      bool
 
+	// Whether to download the earlier revisions of the posts,
+	// and the comments, DMs, announcements, and fancards
+	DlRevisions     bool
+	DlComments      bool
+	DlDms           bool
+	DlAnnouncements bool
+	DlFancards      bool
+
+	// TextFormat is the format of the saved comments, DMs, and announcements
+	// which will be saved as JSON if it is left empty
+	TextFormat string
+
+	// DiscordTranscript is the format of the transcript of the messages in each Discord channel
+	DiscordTranscript string
+
+	// CrossReference is the mode for the posts that have already been downloaded natively
+	// from Pixiv Fanbox or Fantia. Leave empty to download them from Kemono Party regardless.
+	CrossReference string
+
 	Configs       *configs.Config
 
 	// GdriveClient is the Google Drive client to be
@@ -192,18 +253,88 @@ type KemonoDlOptions struct {
 	SessionCookies  []*http.Cookie
 }
 
-// ValidateArgs validates the session cookie ID of the Kemono account to download from.
+// IsGuest returns true if no session cookie was provided, in which case
+// only the public posts, creators, and Discord servers can be downloaded.
+func (k *KemonoDlOptions) IsGuest() bool {
+	return len(k.SessionCookies) == 0
+}
+
+// HasContentToDl returns true if the user wants to download or save anything from the posts or Discord servers,
+// i.e. the attachments, GDrive links, revisions, comments, DMs, announcements, fancards, or Discord transcripts.
+func (k *KemonoDlOptions) HasContentToDl() bool {
+	return k.DlAttachments || k.DlGdrive || k.DlRevisions || k.DlComments ||
+		k.DlDms || k.DlAnnouncements || k.DlFancards || k.DiscordTranscript != ""
+}
+
+// ValidateFavArgs exits the program if the user wants to download from their
+// favourites without a session cookie as the favourites are tied to their account.
+//
+// Should be called after ValidateArgs.
+func (k *KemonoDlOptions) ValidateFavArgs(dlFavCreators, dlFavPosts bool) {
+	if (dlFavCreators || dlFavPosts) && k.IsGuest() {
+		color.Red(
+			"%s error %d: a session cookie is required to download from your %s favourites, please provide one with the --session or --cookie_file flag",
+			k.Archive.Site,
+			utils.INPUT_ERROR,
+			k.Archive.Title,
+		)
+		os.Exit(1)
+	}
+}
+
+// ValidateArgs validates the session cookie ID of the Kemono account to download from if provided.
 // It also validates the Google Drive client if the user wants to download to Google Drive.
 //
 // Should be called after initialising the struct.
 func (k *KemonoDlOptions) ValidateArgs(userAgent string) {
 	if k.SessionCookieId != "" {
 		k.SessionCookies = []*http.Cookie{
-			api.VerifyAndGetCookie(utils.KEMONO, k.SessionCookieId, userAgent),
+			api.VerifyAndGetCookie(k.Archive.Site, k.SessionCookieId, userAgent),
 		}
-	} else {
-		color.Red("kemono error %d: session cookie ID is required", utils.INPUT_ERROR)
-		os.Exit(1)
+	}
+
+	if k.CrossReference != "" {
+		k.CrossReference = utils.ValidateStrArgs(
+			strings.ToLower(k.CrossReference),
+			ACCEPTED_CROSS_REF_MODES,
+			[]string{
+				fmt.Sprintf(
+					"kemono error %d: cross-reference mode %q is not allowed",
+					utils.INPUT_ERROR,
+					k.CrossReference,
+				),
+			},
+		)
+	}
+
+	if k.TextFormat != "" {
+		k.TextFormat = utils.ValidateStrArgs(
+			strings.ToLower(k.TextFormat),
+			ACCEPTED_TEXT_FORMATS,
+			[]string{
+				fmt.Sprintf(
+					"%s error %d: text format %q is not allowed",
+					k.Archive.Site,
+					utils.INPUT_ERROR,
+					k.TextFormat,
+				),
+			},
+		)
+	}
+
+	if k.DiscordTranscript != "" {
+		k.DiscordTranscript = utils.ValidateStrArgs(
+			strings.ToLower(k.DiscordTranscript),
+			ACCEPTED_TRANSCRIPT_FORMATS,
+			[]string{
+				fmt.Sprintf(
+					"%s error %d: Discord transcript format %q is not allowed",
+					k.Archive.Site,
+					utils.INPUT_ERROR,
+					k.DiscordTranscript,
+				),
+			},
+		)
 	}
 
 	if k.DlGdrive && k.GdriveClient == nil {
@@ -0,0 +1,195 @@
+package kemono
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/manifest"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+)
+
+// Cross-reference modes for Kemono Party posts that were also downloaded from the original site
+const (
+	// CROSS_REF_SKIP skips the Kemono Party post entirely
+	CROSS_REF_SKIP = "skip"
+
+	// CROSS_REF_FILL only downloads the files of the Kemono Party
+	// post that are not in the natively downloaded post folder
+	CROSS_REF_FILL = "fill"
+)
+
+var ACCEPTED_CROSS_REF_MODES = []string{CROSS_REF_SKIP, CROSS_REF_FILL}
+
+// nativeSite is where the posts of a Kemono Party service are downloaded to by its native command
+type nativeSite struct {
+	Site   string
+	Folder string
+}
+
+// NATIVE_SITES maps the Kemono Party services to the sites that can be downloaded natively.
+//
+// The post IDs of these services on Kemono Party are the same as the post IDs on the original site.
+var NATIVE_SITES = map[string]nativeSite{
+	"fanbox": {Site: utils.PIXIV_FANBOX, Folder: "Pixiv-Fanbox"},
+	"fantia": {Site: utils.FANTIA, Folder: utils.FANTIA_TITLE},
+}
+
+// nativePost is a post that has been downloaded natively from the original site
+type nativePost struct {
+	checksums map[string]struct{}
+}
+
+// crossRef looks up the posts that have been downloaded natively from the original site.
+type crossRef struct {
+	mu sync.Mutex
+
+	// folders is a map of the post folders in the native download folder
+	// of each site keyed by the post ID, built the first time the site is looked up
+	folders map[string]map[string]string
+	posts   map[string]*nativePost
+}
+
+var nativeCrossRef = &crossRef{
+	folders: make(map[string]map[string]string),
+	posts:   make(map[string]*nativePost),
+}
+
+// getPostIdFromFolderName returns the post ID from a post folder name in the format "[postId] title"
+func getPostIdFromFolderName(folderName string) string {
+	if !strings.HasPrefix(folderName, "[") {
+		return ""
+	}
+	endIdx := strings.Index(folderName, "]")
+	if endIdx == -1 {
+		return ""
+	}
+	return folderName[1:endIdx]
+}
+
+// indexFolders returns the post folders in the native download folder of the site keyed by their post ID.
+func (c *crossRef) indexFolders(site nativeSite, downloadPath string) map[string]string {
+	if folders, ok := c.folders[site.Site]; ok {
+		return folders
+	}
+
+	folders := make(map[string]string)
+	c.folders[site.Site] = folders
+	siteFolderPath := filepath.Join(downloadPath, site.Folder)
+	creatorFolders, err := os.ReadDir(siteFolderPath)
+	if err != nil {
+		return folders
+	}
+	for _, creatorFolder := range creatorFolders {
+		if !creatorFolder.IsDir() {
+			continue
+		}
+		creatorFolderPath := filepath.Join(siteFolderPath, creatorFolder.Name())
+		postFolders, err := os.ReadDir(creatorFolderPath)
+		if err != nil {
+			continue
+		}
+		for _, postFolder := range postFolders {
+			if !postFolder.IsDir() {
+				continue
+			}
+			if postId := getPostIdFromFolderName(postFolder.Name()); postId != "" {
+				folders[postId] = filepath.Join(creatorFolderPath, postFolder.Name())
+			}
+		}
+	}
+	return folders
+}
+
+// addFolderChecksums adds the SHA-256 checksums of the files in the post folder
+// from its manifest or by hashing the files if the folder has no manifest.
+func addFolderChecksums(folderPath string, checksums map[string]struct{}) {
+	manifestPath := filepath.Join(folderPath, manifest.MANIFEST_FILENAME)
+	if postManifest, err := manifest.Load(manifestPath); err == nil && len(postManifest.Files) > 0 {
+		for _, entry := range postManifest.Files {
+			checksums[entry.Sha256] = struct{}{}
+		}
+		return
+	}
+
+	filepath.WalkDir(folderPath, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if _, checksum, err := manifest.HashFile(path); err == nil {
+			checksums[checksum] = struct{}{}
+		}
+		return nil
+	})
+}
+
+// getPost returns the natively downloaded post of the Kemono Party post or nil if it has not been downloaded natively.
+func (c *crossRef) getPost(service, postId, downloadPath string) *nativePost {
+	site, ok := NATIVE_SITES[service]
+	if !ok {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := site.Site + "/" + postId
+	if post, ok := c.posts[key]; ok {
+		return post
+	}
+
+	var post *nativePost
+	entry, err := history.GetEntry(site.Site, postId)
+	if err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+	}
+	folderPath := c.indexFolders(site, downloadPath)[postId]
+	if entry != nil || folderPath != "" {
+		post = &nativePost{checksums: make(map[string]struct{})}
+		if entry != nil {
+			for _, file := range entry.Files {
+				post.checksums[file.Checksum] = struct{}{}
+			}
+		}
+		if folderPath != "" {
+			addFolderChecksums(folderPath, post.checksums)
+		}
+	}
+	c.posts[key] = post
+	return post
+}
+
+// filterNativeFiles removes the files that have been downloaded natively from the
+// original site based on their SHA-256 checksum that is known before the download.
+func filterNativeFiles(toDownload []*request.ToDownload, post *nativePost) ([]*request.ToDownload, int) {
+	filtered := make([]*request.ToDownload, 0, len(toDownload))
+	for _, urlInfo := range toDownload {
+		if urlInfo.Expected != nil && urlInfo.Expected.Sha256 != "" {
+			if _, ok := post.checksums[urlInfo.Expected.Sha256]; ok {
+				continue
+			}
+		}
+		filtered = append(filtered, urlInfo)
+	}
+	return filtered, len(toDownload) - len(filtered)
+}
+
+// printCrossRefMsg prints the number of posts and files that were not downloaded from
+// Kemono Party as they have already been downloaded natively from the original site.
+func printCrossRefMsg(skippedPosts, skippedFiles int) {
+	if skippedPosts > 0 {
+		color.Yellow(
+			"Skipped %d Kemono Party post(s) that have already been downloaded from Pixiv Fanbox or Fantia.",
+			skippedPosts,
+		)
+	}
+	if skippedFiles > 0 {
+		color.Yellow(
+			"Skipped %d Kemono Party file(s) that have already been downloaded from Pixiv Fanbox or Fantia.",
+			skippedFiles,
+		)
+	}
+}
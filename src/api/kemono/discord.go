@@ -0,0 +1,334 @@
+package kemono
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/render"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const (
+	DISCORD_SERVICE = "discord"
+
+	// DISCORD_TRANSCRIPT_FILENAME is the filename of the transcript in each channel folder without its extension
+	DISCORD_TRANSCRIPT_FILENAME = "messages"
+)
+
+// Formats of the transcript of the messages in each Discord channel
+const (
+	JSONL_TRANSCRIPT_FORMAT    = "jsonl"
+	MARKDOWN_TRANSCRIPT_FORMAT = render.MARKDOWN_FORMAT
+)
+
+var ACCEPTED_TRANSCRIPT_FORMATS = []string{JSONL_TRANSCRIPT_FORMAT, MARKDOWN_TRANSCRIPT_FORMAT}
+
+var discordImageExts = map[string]struct{}{
+	".jpg":  {},
+	".jpeg": {},
+	".png":  {},
+	".gif":  {},
+	".webp": {},
+}
+
+func getDiscordChannels(server *models.KemonoDiscordToDl, dlOptions *KemonoDlOptions) (models.KemonoDiscordChannelJson, error) {
+	useHttp3 := utils.IsHttp3Supported(dlOptions.Archive.Site, true)
+	res, err := request.CallRequest(
+		&request.RequestArgs{
+			Url: fmt.Sprintf(
+				"%s/discord/channel/lookup/%s",
+				dlOptions.Archive.GetApiUrl(server.Tld),
+				server.ServerId,
+			),
+			Method:      "GET",
+			Headers:     getKemonoPartyHeaders(),
+			UserAgent:   dlOptions.Configs.UserAgent,
+			Cookies:     dlOptions.SessionCookies,
+			Http2:       !useHttp3,
+			Http3:       useHttp3,
+			CheckStatus: true,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var channels models.KemonoDiscordChannelJson
+	if err := utils.LoadJsonFromResponse(res, &channels); err != nil {
+		return nil, err
+	}
+	if server.ChannelId == "" {
+		return channels, nil
+	}
+
+	for idx, channel := range channels {
+		if channel.Id == server.ChannelId {
+			return channels[idx : idx+1], nil
+		}
+	}
+	return nil, fmt.Errorf(
+		"kemono error %d: channel %s could not be found in the Discord server %s",
+		utils.INPUT_ERROR,
+		server.ChannelId,
+		server.ServerId,
+	)
+}
+
+// isOlderMessage returns true if the first Discord message ID is older than the second one.
+//
+// Discord message IDs are snowflakes that increase over time.
+func isOlderMessage(id1, id2 string) bool {
+	if len(id1) != len(id2) {
+		return len(id1) < len(id2)
+	}
+	return id1 < id2
+}
+
+// getDiscordMessages returns all the messages in the Discord channel from the oldest to the newest.
+func getDiscordMessages(channelId, tld string, dlOptions *KemonoDlOptions) (models.KemonoDiscordJson, error) {
+	useHttp3 := utils.IsHttp3Supported(dlOptions.Archive.Site, true)
+	var messages models.KemonoDiscordJson
+	params := make(map[string]string)
+	curOffset := 0
+	for {
+		params["o"] = strconv.Itoa(curOffset)
+		res, err := request.CallRequest(
+			&request.RequestArgs{
+				Url: fmt.Sprintf(
+					"%s/discord/channel/%s",
+					dlOptions.Archive.GetApiUrl(tld),
+					channelId,
+				),
+				Method:      "GET",
+				Headers:     getKemonoPartyHeaders(),
+				UserAgent:   dlOptions.Configs.UserAgent,
+				Cookies:     dlOptions.SessionCookies,
+				Params:      params,
+				Http2:       !useHttp3,
+				Http3:       useHttp3,
+				CheckStatus: true,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		var resJson models.KemonoDiscordJson
+		if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
+			return nil, err
+		}
+		messages = append(messages, resJson...)
+		if len(resJson) < utils.KEMONO_DISCORD_PER_PAGE {
+			break
+		}
+		curOffset += utils.KEMONO_DISCORD_PER_PAGE
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		return isOlderMessage(messages[i].Id, messages[j].Id)
+	})
+	return messages, nil
+}
+
+func getDiscordAttachmentUrl(path, tld string, archive *Archive) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return archive.GetDataUrl(tld, path)
+}
+
+// getDiscordAttachmentName returns the filename of the attachment prefixed with the
+// message ID as attachments from different messages often have the same name like "image.png".
+func getDiscordAttachmentName(messageId, name, path string) string {
+	if name == "" {
+		name = utils.GetLastPartOfUrl(path)
+	}
+	return utils.CleanPathName(messageId + "_" + name)
+}
+
+// writeJsonlTranscript writes the raw JSON of each message to the transcript file, one message per line.
+func writeJsonlTranscript(messages models.KemonoDiscordJson, filePath string) error {
+	var buf bytes.Buffer
+	for _, message := range messages {
+		if err := json.Compact(&buf, message.Raw); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+	}
+
+	os.MkdirAll(filepath.Dir(filePath), 0755)
+	return os.WriteFile(filePath, buf.Bytes(), 0666)
+}
+
+// getMarkdownTranscript returns the transcript of the messages to be rendered
+// after the attachments have been downloaded so that they can be linked to the downloaded files.
+func getMarkdownTranscript(messages models.KemonoDiscordJson, title, url, channelFolderPath, tld string, pathVars *utils.PathVars, archive *Archive) *render.Document {
+	doc := render.NewTranscript(title, url, channelFolderPath, DISCORD_TRANSCRIPT_FILENAME, MARKDOWN_TRANSCRIPT_FORMAT, pathVars)
+	for _, message := range messages {
+		header := fmt.Sprintf(" %s", message.Published)
+		if message.Edited != "" {
+			header += fmt.Sprintf(" (edited %s)", message.Edited)
+		}
+		doc.AddBlocks(
+			&render.Block{
+				Type: render.PARAGRAPH_BLOCK,
+				Spans: []*render.Span{
+					{Text: message.Author.Username, Bold: true},
+					{Text: header},
+				},
+			},
+			render.TextBlock(message.Content),
+		)
+		for _, embed := range message.Embeds {
+			text := embed.Title
+			if text == "" {
+				text = embed.Url
+			}
+			doc.AddBlocks(&render.Block{
+				Type:  render.PARAGRAPH_BLOCK,
+				Spans: []*render.Span{{Text: text, Link: embed.Url}},
+			})
+		}
+		for _, attachment := range message.Attachments {
+			blockType := render.FILE_BLOCK
+			if _, ok := discordImageExts[strings.ToLower(filepath.Ext(attachment.Path))]; ok {
+				blockType = render.IMAGE_BLOCK
+			}
+			doc.AddBlocks(&render.Block{
+				Type: blockType,
+				Url:  getDiscordAttachmentUrl(attachment.Path, tld, archive),
+				Name: attachment.Name,
+			})
+		}
+	}
+	return doc
+}
+
+// processDiscordChannel returns the attachments of the messages in the Discord channel
+// to be downloaded to the channel folder and saves the transcript of the messages.
+func processDiscordChannel(server *models.KemonoDiscordToDl, channelId, channelName, downloadPath string, dlOptions *KemonoDlOptions) ([]*request.ToDownload, error) {
+	messages, err := getDiscordMessages(channelId, server.Tld, dlOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := dlOptions.Archive
+	channelFolderPath := filepath.Join(
+		downloadPath,
+		archive.FolderName,
+		DISCORD_SERVICE,
+		server.ServerId,
+		utils.CleanPathName(fmt.Sprintf("%s [%s]", channelName, channelId)),
+	)
+
+	var toDownload []*request.ToDownload
+	if dlOptions.DlAttachments {
+		for _, message := range messages {
+			for _, attachment := range message.Attachments {
+				toDownload = append(toDownload, &request.ToDownload{
+					Url:      getDiscordAttachmentUrl(attachment.Path, server.Tld, archive),
+					FilePath: filepath.Join(channelFolderPath, getDiscordAttachmentName(message.Id, attachment.Name, attachment.Path)),
+					Expected: getExpectedFile(attachment.Path),
+				})
+			}
+		}
+	}
+
+	pathVars := &utils.PathVars{
+		Site:        archive.Site,
+		CreatorName: server.ServerId,
+		CreatorId:   server.ServerId,
+		PostId:      channelId,
+		PostTitle:   channelName,
+	}
+	request.SetPathVars(toDownload, pathVars, channelFolderPath, utils.ATTACHMENT_FOLDER)
+	if dlOptions.DiscordTranscript == "" || dlOptions.Configs.DryRun || len(messages) == 0 {
+		return toDownload, nil
+	}
+
+	channelUrl := fmt.Sprintf("%s/discord/server/%s#%s", archive.GetUrl(server.Tld), server.ServerId, channelId)
+	if dlOptions.DiscordTranscript == MARKDOWN_TRANSCRIPT_FORMAT {
+		render.AddPending(
+			getMarkdownTranscript(messages, "#"+channelName, channelUrl, channelFolderPath, server.Tld, pathVars, archive),
+		)
+		return toDownload, nil
+	}
+
+	filePath := getTextFilePath(
+		channelFolderPath,
+		DISCORD_TRANSCRIPT_FILENAME+"."+JSONL_TRANSCRIPT_FORMAT,
+		pathVars,
+		dlOptions.Configs,
+	)
+	if err := writeJsonlTranscript(messages, filePath); err != nil {
+		return toDownload, fmt.Errorf(
+			"kemono error %d: failed to save the transcript of the Discord channel %s to %s, more info => %v",
+			utils.OS_ERROR,
+			channelId,
+			filePath,
+			err,
+		)
+	}
+	return toDownload, nil
+}
+
+func getDiscordServers(servers []*models.KemonoDiscordToDl, downloadPath string, dlOptions *KemonoDlOptions) []*request.ToDownload {
+	var errSlice []error
+	var urlsToDownload []*request.ToDownload
+	serverLen := len(servers)
+	baseMsg := "Getting Discord server messages from " + dlOptions.Archive.Title + " [%d/" + fmt.Sprintf("%d]...", serverLen)
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		fmt.Sprintf(
+			baseMsg,
+			0,
+		),
+		fmt.Sprintf(
+			"Finished getting %d Discord server messages from %s!",
+			serverLen,
+			dlOptions.Archive.Title,
+		),
+		fmt.Sprintf(
+			"Something went wrong while getting %d Discord server messages from %s.\nPlease refer to the logs for more details.",
+			serverLen,
+			dlOptions.Archive.Title,
+		),
+		serverLen,
+	)
+	progress.Start()
+	for _, server := range servers {
+		channels, err := getDiscordChannels(server, dlOptions)
+		if err != nil {
+			errSlice = append(errSlice, err)
+			progress.MsgIncrement(baseMsg)
+			continue
+		}
+
+		for _, channel := range channels {
+			toDownload, err := processDiscordChannel(server, channel.Id, channel.Name, downloadPath, dlOptions)
+			if err != nil {
+				errSlice = append(errSlice, err)
+			}
+			urlsToDownload = append(urlsToDownload, toDownload...)
+		}
+		progress.MsgIncrement(baseMsg)
+	}
+
+	hasError := false
+	if len(errSlice) > 0 {
+		hasError = true
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	progress.Stop(hasError)
+	return urlsToDownload
+}
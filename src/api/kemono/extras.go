@@ -0,0 +1,314 @@
+package kemono
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/render"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const (
+	REVISIONS_FOLDER = "revisions"
+	FANCARDS_FOLDER  = "fancards"
+
+	// Filenames of the saved text items without their extension
+	COMMENTS_FILENAME      = "comments"
+	DMS_FILENAME           = "dms"
+	ANNOUNCEMENTS_FILENAME = "announcements"
+)
+
+// Formats of the saved comments, DMs, and announcements
+const (
+	JSON_TEXT_FORMAT     = "json"
+	MARKDOWN_TEXT_FORMAT = render.MARKDOWN_FORMAT
+)
+
+var ACCEPTED_TEXT_FORMATS = []string{JSON_TEXT_FORMAT, MARKDOWN_TEXT_FORMAT}
+
+// textItem is a comment, DM, or announcement to be saved
+type textItem struct {
+	Author    string
+	Published string
+	Content   string
+}
+
+// getTextFilePath returns the file path of a saved text file like a transcript
+// in the folder or based on the output path template if one is configured.
+func getTextFilePath(folderPath, filename string, pathVars *utils.PathVars, config *configs.Config) string {
+	if config.PathTemplate != nil && pathVars != nil {
+		return config.PathTemplate.Execute(utils.DOWNLOAD_PATH, pathVars.ForFile(render.BODY_KIND, 0), filename)
+	}
+	return filepath.Join(folderPath, filename)
+}
+
+// getJson sends a GET request to the API of the archive and returns the raw JSON response
+// after unmarshalling it into the given format.
+func getJson(apiPath, tld string, format any, dlOptions *KemonoDlOptions) ([]byte, error) {
+	useHttp3 := utils.IsHttp3Supported(dlOptions.Archive.Site, true)
+	res, err := request.CallRequest(
+		&request.RequestArgs{
+			Url:         dlOptions.Archive.GetApiUrl(tld) + apiPath,
+			Method:      "GET",
+			Headers:     getKemonoPartyHeaders(),
+			UserAgent:   dlOptions.Configs.UserAgent,
+			Cookies:     dlOptions.SessionCookies,
+			Http2:       !useHttp3,
+			Http3:       useHttp3,
+			CheckStatus: true,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := utils.ReadResBody(res)
+	if err != nil {
+		return nil, err
+	}
+	if err := utils.LoadJsonFromBytes(body, format); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// saveTextItems saves the text items as "<filename>.json" with the raw JSON response
+// or as "<filename>.md" which will be rendered after the files have been downloaded.
+func saveTextItems(rawJson []byte, items []*textItem, title, url, folderPath, filename, tld string, pathVars *utils.PathVars, dlOptions *KemonoDlOptions) error {
+	if len(items) == 0 || dlOptions.Configs.DryRun {
+		return nil
+	}
+
+	if dlOptions.TextFormat == MARKDOWN_TEXT_FORMAT {
+		doc := render.NewTranscript(title, url, folderPath, filename, MARKDOWN_TEXT_FORMAT, pathVars)
+		for _, item := range items {
+			doc.AddBlocks(&render.Block{
+				Type: render.PARAGRAPH_BLOCK,
+				Spans: []*render.Span{
+					{Text: item.Author, Bold: true},
+					{Text: " " + item.Published},
+				},
+			})
+			doc.AddBlocks(render.BlocksFromHtml(item.Content, func(src string) string {
+				if strings.HasPrefix(src, "/") {
+					return dlOptions.Archive.GetDataUrl(tld, src)
+				}
+				return src
+			})...)
+		}
+		render.AddPending(doc)
+		return nil
+	}
+
+	filePath := getTextFilePath(folderPath, filename+"."+JSON_TEXT_FORMAT, pathVars, dlOptions.Configs)
+	var buf bytes.Buffer
+	err := json.Indent(&buf, rawJson, "", "    ")
+	if err == nil {
+		os.MkdirAll(filepath.Dir(filePath), 0755)
+		err = os.WriteFile(filePath, buf.Bytes(), 0666)
+	}
+	if err != nil {
+		return fmt.Errorf(
+			"kemono error %d: failed to save the %s to %s, more info => %v",
+			utils.OS_ERROR,
+			filename,
+			filePath,
+			err,
+		)
+	}
+	return nil
+}
+
+// revisionFiles contains the files of an earlier revision of a post
+type revisionFiles struct {
+	RevisionId string
+	Files      []*request.ToDownload
+}
+
+// getRevisionFiles returns the files of the earlier revisions of the post to be downloaded to
+// "revisions/<revisionId>/" in the post folder, excluding the files that are identical to the current files.
+func getRevisionFiles(resJson *models.MainKemonoJson, postFolderPath, tld string, postFiles []*request.ToDownload, dlOptions *KemonoDlOptions) []*revisionFiles {
+	var revisions models.KemonoJson
+	_, err := getJson(
+		fmt.Sprintf("/%s/user/%s/post/%s/revisions", resJson.Service, resJson.User, resJson.Id),
+		tld,
+		&revisions,
+		dlOptions,
+	)
+	if err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+		return nil
+	}
+
+	seenChecksums := make(map[string]struct{})
+	for _, urlInfo := range postFiles {
+		if urlInfo.Expected != nil && urlInfo.Expected.Sha256 != "" {
+			seenChecksums[urlInfo.Expected.Sha256] = struct{}{}
+		}
+	}
+
+	var revisionsFiles []*revisionFiles
+	for _, revision := range revisions {
+		if revision.RevisionId == "" {
+			continue
+		}
+
+		revisionId := revision.RevisionId.String()
+		revisionFolderPath := filepath.Join(postFolderPath, REVISIONS_FOLDER, revisionId)
+		var toDownload []*request.ToDownload
+		for _, urlInfo := range getPostFiles(revision, revisionFolderPath, tld, dlOptions.Archive) {
+			if urlInfo.Expected != nil && urlInfo.Expected.Sha256 != "" {
+				if _, ok := seenChecksums[urlInfo.Expected.Sha256]; ok {
+					continue
+				}
+				seenChecksums[urlInfo.Expected.Sha256] = struct{}{}
+			}
+			toDownload = append(toDownload, urlInfo)
+		}
+		if len(toDownload) > 0 {
+			revisionsFiles = append(revisionsFiles, &revisionFiles{
+				RevisionId: revisionId,
+				Files:      toDownload,
+			})
+		}
+	}
+	return revisionsFiles
+}
+
+// saveComments saves the comments of the post to the post folder
+func saveComments(resJson *models.MainKemonoJson, postUrl, postFolderPath, tld string, pathVars *utils.PathVars, dlOptions *KemonoDlOptions) {
+	var comments models.KemonoCommentJson
+	rawJson, err := getJson(
+		fmt.Sprintf("/%s/user/%s/post/%s/comments", resJson.Service, resJson.User, resJson.Id),
+		tld,
+		&comments,
+		dlOptions,
+	)
+	if err == nil {
+		items := make([]*textItem, len(comments))
+		for idx, comment := range comments {
+			author := comment.CommenterName
+			if author == "" {
+				author = comment.Commenter
+			}
+			items[idx] = &textItem{
+				Author:    author,
+				Published: comment.Published,
+				Content:   comment.Content,
+			}
+		}
+		title := fmt.Sprintf("Comments on %s", resJson.Title)
+		err = saveTextItems(rawJson, items, title, postUrl, postFolderPath, COMMENTS_FILENAME, tld, pathVars, dlOptions)
+	}
+	if err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+	}
+}
+
+// getFancardUrlPath returns the URL path of the fancard file which is
+// stored like the other files on Kemono Party, e.g. "/data/ab/cd/abcd...ef.jpg".
+func getFancardUrlPath(hash, ext, path string) string {
+	if path != "" || len(hash) < 4 {
+		return path
+	}
+	return fmt.Sprintf("/data/%s/%s/%s%s", hash[:2], hash[2:4], hash, ext)
+}
+
+// getCreatorExtras saves the DMs and announcements of the creator to the creator folder
+// and returns the fancards of the creator to be downloaded to the "fancards" folder in the creator folder.
+func getCreatorExtras(creator *models.KemonoCreatorToDl, downloadPath string, dlOptions *KemonoDlOptions) []*request.ToDownload {
+	if !dlOptions.DlDms && !dlOptions.DlAnnouncements && !dlOptions.DlFancards {
+		return nil
+	}
+
+	archive := dlOptions.Archive
+	creatorName, creatorNamePath := getCreatorNamePath(creator.Service, creator.CreatorId, dlOptions)
+	creatorFolderPath := filepath.Join(
+		downloadPath,
+		archive.FolderName,
+		creator.Service,
+		utils.CleanPathName(creatorNamePath),
+	)
+	creatorUrl := archive.GetCreatorUrl(creator.Service, creator.CreatorId, creator.Tld)
+	apiPathPrefix := fmt.Sprintf("/%s/user/%s", creator.Service, creator.CreatorId)
+	pathVars := &utils.PathVars{
+		Site:        archive.Site,
+		CreatorName: creatorName,
+		CreatorId:   creator.CreatorId,
+	}
+
+	var errSlice []error
+	if dlOptions.DlAnnouncements {
+		var announcements models.KemonoAnnouncementJson
+		rawJson, err := getJson(apiPathPrefix+"/announcements", creator.Tld, &announcements, dlOptions)
+		if err == nil {
+			items := make([]*textItem, len(announcements))
+			for idx, announcement := range announcements {
+				items[idx] = &textItem{
+					Author:    creatorName,
+					Published: announcement.Published,
+					Content:   announcement.Content,
+				}
+			}
+			title := fmt.Sprintf("Announcements from %s", creatorName)
+			err = saveTextItems(rawJson, items, title, creatorUrl, creatorFolderPath, ANNOUNCEMENTS_FILENAME, creator.Tld, pathVars, dlOptions)
+		}
+		if err != nil {
+			errSlice = append(errSlice, err)
+		}
+	}
+
+	if dlOptions.DlDms {
+		var dms models.KemonoDmJson
+		rawJson, err := getJson(apiPathPrefix+"/dms", creator.Tld, &dms, dlOptions)
+		if err == nil {
+			items := make([]*textItem, len(dms))
+			for idx, dm := range dms {
+				items[idx] = &textItem{
+					Author:    creatorName,
+					Published: dm.Published,
+					Content:   dm.Content,
+				}
+			}
+			title := fmt.Sprintf("DMs from %s", creatorName)
+			err = saveTextItems(rawJson, items, title, creatorUrl, creatorFolderPath, DMS_FILENAME, creator.Tld, pathVars, dlOptions)
+		}
+		if err != nil {
+			errSlice = append(errSlice, err)
+		}
+	}
+
+	// fancards are only available for Pixiv Fanbox creators
+	var toDownload []*request.ToDownload
+	if dlOptions.DlFancards && creator.Service == "fanbox" {
+		var fancards models.KemonoFancardJson
+		if _, err := getJson(apiPathPrefix+"/fancards", creator.Tld, &fancards, dlOptions); err != nil {
+			errSlice = append(errSlice, err)
+		}
+		fancardsFolderPath := filepath.Join(creatorFolderPath, FANCARDS_FOLDER)
+		for _, fancard := range fancards {
+			urlPath := getFancardUrlPath(fancard.Hash, fancard.Ext, fancard.Path)
+			if urlPath == "" {
+				continue
+			}
+			toDownload = append(toDownload, &request.ToDownload{
+				Url:      archive.GetDataUrl(creator.Tld, urlPath),
+				FilePath: filepath.Join(fancardsFolderPath, utils.GetLastPartOfUrl(urlPath)),
+				Expected: getExpectedFile(urlPath),
+			})
+		}
+		request.SetPathVars(toDownload, pathVars, creatorFolderPath, FANCARDS_FOLDER)
+	}
+
+	if len(errSlice) > 0 {
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	return toDownload
+}
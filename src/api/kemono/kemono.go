@@ -1,42 +1,64 @@
 package kemono
 
 import (
+	"fmt"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono/models"
 	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/render"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
 
-func KemonoDownloadProcess(config *configs.Config, kemonoDl *KemonoDl, dlOptions *KemonoDlOptions, dlFav bool) {
-	if !dlOptions.DlAttachments && !dlOptions.DlGdrive {
+// filterPostsByHistory returns the posts that are not in the download history.
+func filterPostsByHistory(posts []*models.KemonoPostToDl, site string) []*models.KemonoPostToDl {
+	filteredPosts := make([]*models.KemonoPostToDl, 0, len(posts))
+	for _, post := range posts {
+		if !history.HasPost(site, getHistoryPostId(post.Service, post.PostId)) {
+			filteredPosts = append(filteredPosts, post)
+		}
+	}
+	history.PrintSkippedMsg(site, len(posts)-len(filteredPosts))
+	return filteredPosts
+}
+
+func KemonoDownloadProcess(config *configs.Config, kemonoDl *KemonoDl, dlOptions *KemonoDlOptions, dlFavCreators, dlFavPosts bool) {
+	if !dlOptions.HasContentToDl() {
 		return
 	}
 
+	archive := dlOptions.Archive
 	var toDownload, gdriveLinks []*request.ToDownload
-	if dlFav {
+	if dlFavCreators || dlFavPosts {
 		progress := spinner.New(
 			spinner.REQ_SPINNER,
 			"fgHiYellow",
-			"Getting favourites from Kemono Party...",
-			"Finished getting favourites from Kemono Party!",
-			"Something went wrong while getting favourites from Kemono Party.\nPlease refer to the logs for more details.",
+			fmt.Sprintf("Getting favourites from %s...", archive.Title),
+			fmt.Sprintf("Finished getting favourites from %s!", archive.Title),
+			fmt.Sprintf("Something went wrong while getting favourites from %s.\nPlease refer to the logs for more details.", archive.Title),
 			0,
 		)
 		progress.Start()
 		favToDl, favGdriveLinks, err := getFavourites(
 			utils.DOWNLOAD_PATH,
+			dlFavCreators,
+			dlFavPosts,
 			dlOptions,
 		)
 		hasErr := (err != nil)
 		if hasErr {
 			utils.LogError(err, "", false, utils.ERROR)
-		} else {
-			toDownload = favToDl
-			gdriveLinks = favGdriveLinks
 		}
+		toDownload = favToDl
+		gdriveLinks = favGdriveLinks
 		progress.Stop(hasErr)
 	}
 
+	if !config.IgnoreHistory {
+		kemonoDl.PostsToDl = filterPostsByHistory(kemonoDl.PostsToDl, archive.Site)
+	}
 	if len(kemonoDl.PostsToDl) > 0 {
 		postsToDl, gdriveLinksToDl := getMultiplePosts(
 			kemonoDl.PostsToDl,
@@ -55,6 +77,9 @@ func KemonoDownloadProcess(config *configs.Config, kemonoDl *KemonoDl, dlOptions
 		toDownload = append(toDownload, creatorsToDl...)
 		gdriveLinks = append(gdriveLinks, gdriveLinksToDl...)
 	}
+	if len(kemonoDl.DiscordToDl) > 0 {
+		toDownload = append(toDownload, getDiscordServers(kemonoDl.DiscordToDl, utils.DOWNLOAD_PATH, dlOptions)...)
+	}
 
 	var downloadedPosts bool
 	if len(toDownload) > 0 {
@@ -64,7 +89,7 @@ func KemonoDownloadProcess(config *configs.Config, kemonoDl *KemonoDl, dlOptions
 			&request.DlOptions{
 				MaxConcurrency: utils.PIXIV_MAX_CONCURRENT_DOWNLOADS,
 				Cookies:        dlOptions.SessionCookies,
-				UseHttp3:       utils.IsHttp3Supported(utils.KEMONO, false),
+				UseHttp3:       utils.IsHttp3Supported(archive.Site, false),
 			},
 			config,
 		)
@@ -74,9 +99,19 @@ func KemonoDownloadProcess(config *configs.Config, kemonoDl *KemonoDl, dlOptions
 		dlOptions.GdriveClient.DownloadGdriveUrls(gdriveLinks, config)
 	}
 
+	if config.DryRun {
+		request.PrintPlan(config)
+		return
+	}
+
+	// render the post bodies and save the processed posts and the sync states
+	// only after the files of the posts have been downloaded
+	render.RenderPending(config)
+	history.CommitPosts()
+	history.CommitSyncStates()
 	if downloadedPosts {
-		utils.AlertWithoutErr(utils.Title, "Downloaded all posts from Kemono Party!")
+		utils.AlertWithoutErr(utils.Title, fmt.Sprintf("Downloaded all posts from %s!", archive.Title))
 	} else {
-		utils.AlertWithoutErr(utils.Title, "No posts to download from Kemono Party!")
+		utils.AlertWithoutErr(utils.Title, fmt.Sprintf("No posts to download from %s!", archive.Title))
 	}
 }
@@ -1,5 +1,7 @@
 package models
 
+import "encoding/json"
+
 type MainKemonoJson struct {
 	Added       string `json:"added"`
 	Attachments []struct {
@@ -24,6 +26,21 @@ type MainKemonoJson struct {
 	SharedFile bool   `json:"shared_file"`
 	Title      string `json:"title"`
 	User       string `json:"user"`
+
+	// RevisionId is only set for the earlier revisions of a post
+	RevisionId json.Number `json:"revision_id"`
+
+	// Raw is the raw JSON of the post for the metadata file
+	Raw json.RawMessage `json:"-"`
+}
+
+func (p *MainKemonoJson) UnmarshalJSON(data []byte) error {
+	type mainKemonoJson MainKemonoJson
+	if err := json.Unmarshal(data, (*mainKemonoJson)(p)); err != nil {
+		return err
+	}
+	p.Raw = append(json.RawMessage(nil), data...)
+	return nil
 }
 
 type KemonoJson []*MainKemonoJson
@@ -37,6 +54,20 @@ type KemonoFavCreatorJson []struct {
 	Updated  string `json:"updated"`
 }
 
+// KemonoIndexedCreatorJson is the index of all the creators in the archive.
+//
+// The indexed and updated dates are either Unix timestamps or date strings depending on the archive version.
+type KemonoIndexedCreatorJson []*KemonoIndexedCreator
+
+type KemonoIndexedCreator struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	Service   string `json:"service"`
+	Indexed   any    `json:"indexed"`
+	Updated   any    `json:"updated"`
+	Favorited int    `json:"favorited"`
+}
+
 type KemonoPostToDl struct {
 	Service   string
 	CreatorId string
@@ -50,3 +81,82 @@ type KemonoCreatorToDl struct {
 	PageNum   string
 	Tld       string
 }
+
+type KemonoDiscordChannelJson []struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type KemonoDiscordMessageJson struct {
+	Id     string `json:"id"`
+	Author struct {
+		Id       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"author"`
+	Server      string `json:"server"`
+	Channel     string `json:"channel"`
+	Content     string `json:"content"`
+	Published   string `json:"published"`
+	Edited      string `json:"edited"`
+	Attachments []struct {
+		Name string `json:"name"`
+		Path string `json:"path"`
+	} `json:"attachments"`
+	Embeds []struct {
+		Url         string `json:"url"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	} `json:"embeds"`
+
+	// Raw is the raw JSON of the message for the JSONL transcript
+	Raw json.RawMessage `json:"-"`
+}
+
+func (m *KemonoDiscordMessageJson) UnmarshalJSON(data []byte) error {
+	type kemonoDiscordMessageJson KemonoDiscordMessageJson
+	if err := json.Unmarshal(data, (*kemonoDiscordMessageJson)(m)); err != nil {
+		return err
+	}
+	m.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+type KemonoDiscordJson []*KemonoDiscordMessageJson
+
+type KemonoDiscordToDl struct {
+	ServerId string
+
+	// ChannelId is the ID of the only channel to download from the server, if any
+	ChannelId string
+	Tld       string
+}
+
+type KemonoCommentJson []struct {
+	Id            string `json:"id"`
+	ParentId      string `json:"parent_id"`
+	Commenter     string `json:"commenter"`
+	CommenterName string `json:"commenter_name"`
+	Content       string `json:"content"`
+	Published     string `json:"published"`
+}
+
+type KemonoAnnouncementJson []struct {
+	Hash      string `json:"hash"`
+	Content   string `json:"content"`
+	Added     string `json:"added"`
+	Published string `json:"published"`
+}
+
+type KemonoDmJson []struct {
+	Hash      string `json:"hash"`
+	Content   string `json:"content"`
+	Added     string `json:"added"`
+	Published string `json:"published"`
+}
+
+type KemonoFancardJson []struct {
+	Id   json.Number `json:"id"`
+	Hash string      `json:"hash"`
+	Ext  string      `json:"ext"`
+	Path string      `json:"path"`
+}
@@ -5,8 +5,13 @@ import (
 	"strings"
 	"regexp"
 	"path/filepath"
+	"time"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/manifest"
+	"github.com/KJHJason/Cultured-Downloader-CLI/metadata"
+	"github.com/KJHJason/Cultured-Downloader-CLI/render"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
@@ -15,9 +20,21 @@ import (
 var (
 	imgSrcTagRegex = regexp.MustCompile(`(?i)<img[^>]+src=(?:\\)?"(?P<imgSrc>[^">]+)(?:\\)?"[^>]*>`)
 	imgSrcTagRegexIdx = imgSrcTagRegex.SubexpIndex("imgSrc")
+	sha256HexRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
 )
 
-func getInlineImages(content, postFolderPath, tld string) []*request.ToDownload {
+// getExpectedFile returns the expected SHA-256 checksum of the file
+// as Kemono Party stores its files using their SHA-256 checksum as the filename,
+// e.g. "/data/ab/cd/abcd...ef.jpg".
+func getExpectedFile(fileUrlPath string) *manifest.Expected {
+	filename := utils.RemoveExtFromFilename(utils.GetLastPartOfUrl(fileUrlPath))
+	if !sha256HexRegex.MatchString(filename) {
+		return nil
+	}
+	return &manifest.Expected{Sha256: filename}
+}
+
+func getInlineImages(content, postFolderPath, tld string, archive *Archive) []*request.ToDownload {
 	var toDownload []*request.ToDownload
 	for _, match := range imgSrcTagRegex.FindAllStringSubmatch(content, -1) {
 		imgSrc := match[imgSrcTagRegexIdx]
@@ -25,8 +42,9 @@ func getInlineImages(content, postFolderPath, tld string) []*request.ToDownload
 			continue
 		}
 		toDownload = append(toDownload, &request.ToDownload{
-			Url:      getKemonoUrl(tld) + imgSrc,
+			Url:      archive.GetDataUrl(tld, imgSrc),
 			FilePath: filepath.Join(postFolderPath, utils.IMAGES_FOLDER, utils.GetLastPartOfUrl(imgSrc)),
+			Expected: getExpectedFile(imgSrc),
 		})
 	}
 	return toDownload
@@ -41,23 +59,51 @@ func getKemonoFilePath(postFolderPath, childDir, fileName string) string {
 	return filepath.Join(postFolderPath, childDir, fileName)
 }
 
-func processJson(resJson *models.MainKemonoJson, tld, downloadPath string, dlOptions *KemonoDlOptions) ([]*request.ToDownload, []*request.ToDownload) {
-	var creatorNamePath string
-	if creatorName, err := getCreatorName(resJson.Service, resJson.User, dlOptions); err != nil {
+// getCreatorNamePath returns the name of the creator and the name of the creator folder.
+//
+// If the name of the creator could not be obtained, the creator ID will be used instead.
+func getCreatorNamePath(service, userId string, dlOptions *KemonoDlOptions) (string, string) {
+	creatorName, err := getCreatorName(service, userId, dlOptions)
+	if err != nil {
 		err = fmt.Errorf(
 			"error getting creator name for %q (%s)... falling back to creator ID! (Details below)\n%v",
-			resJson.User,
-			resJson.Service,
+			userId,
+			service,
 			err,
 		)
 		utils.LogError(err, "", false, utils.ERROR)
-		creatorNamePath = resJson.User
-	} else {
-		creatorNamePath = fmt.Sprintf("%s [%s]", creatorName, resJson.User)
+		return userId, userId
+	}
+	return creatorName, fmt.Sprintf("%s [%s]", creatorName, userId)
+}
+
+// getPostFiles returns the inline images, attachments, and the file of the post to be downloaded to the post folder
+func getPostFiles(resJson *models.MainKemonoJson, postFolderPath, tld string, archive *Archive) []*request.ToDownload {
+	toDownload := getInlineImages(resJson.Content, postFolderPath, tld, archive)
+	for _, attachment := range resJson.Attachments {
+		toDownload = append(toDownload, &request.ToDownload{
+			Url:      archive.GetDataUrl(tld, attachment.Path),
+			FilePath: getKemonoFilePath(postFolderPath, utils.KEMONO_CONTENT_FOLDER, attachment.Name),
+			Expected: getExpectedFile(attachment.Path),
+		})
+	}
+
+	if resJson.File.Path != "" { 
+		// usually is the thumbnail of the post
+		toDownload = append(toDownload, &request.ToDownload{
+			Url:      archive.GetDataUrl(tld, resJson.File.Path),
+			FilePath: getKemonoFilePath(postFolderPath, "", resJson.File.Name),
+			Expected: getExpectedFile(resJson.File.Path),
+		})
 	}
+	return toDownload
+}
 
+func processJson(resJson *models.MainKemonoJson, tld, downloadPath string, dlOptions *KemonoDlOptions) ([]*request.ToDownload, []*request.ToDownload) {
+	archive := dlOptions.Archive
+	creatorName, creatorNamePath := getCreatorNamePath(resJson.Service, resJson.User, dlOptions)
 	postFolderPath := utils.GetPostFolder(
-		filepath.Join(downloadPath, "Kemono-Party", resJson.Service),
+		filepath.Join(downloadPath, archive.FolderName, resJson.Service),
 		creatorNamePath,
 		resJson.Id,
 		resJson.Title,
@@ -65,15 +111,9 @@ func processJson(resJson *models.MainKemonoJson, tld, downloadPath string, dlOpt
 
 	var gdriveLinks []*request.ToDownload
 	var toDownload []*request.ToDownload
+	var revisionsFiles []*revisionFiles
 	if dlOptions.DlAttachments {
-		toDownload = getInlineImages(resJson.Content, postFolderPath, tld)
-		for _, attachment := range resJson.Attachments {
-			toDownload = append(toDownload, &request.ToDownload{
-				Url:      getKemonoUrl(tld) + attachment.Path,
-				FilePath: getKemonoFilePath(postFolderPath, utils.KEMONO_CONTENT_FOLDER, attachment.Name),
-			})
-		}
-
+		toDownload = getPostFiles(resJson, postFolderPath, tld, archive)
 		if resJson.Embed.Url != "" {
 			embedsDirPath := filepath.Join(postFolderPath, utils.KEMONO_EMBEDS_FOLDER)
 			if dlOptions.Configs.LogUrls {
@@ -87,12 +127,8 @@ func processJson(resJson *models.MainKemonoJson, tld, downloadPath string, dlOpt
 			}
 		}
 
-		if resJson.File.Path != "" { 
-			// usually is the thumbnail of the post
-			toDownload = append(toDownload, &request.ToDownload{
-				Url:      getKemonoUrl(tld) + resJson.File.Path,
-				FilePath: getKemonoFilePath(postFolderPath, "", resJson.File.Name),
-			})
+		if dlOptions.DlRevisions {
+			revisionsFiles = getRevisionFiles(resJson, postFolderPath, tld, toDownload, dlOptions)
 		}
 	}
 
@@ -103,15 +139,114 @@ func processJson(resJson *models.MainKemonoJson, tld, downloadPath string, dlOpt
 		dlOptions.Configs.LogUrls,
 	)
 	gdriveLinks = append(gdriveLinks, contentGdriveLinks...)
+
+	historyPost := &history.Post{
+		Site:          archive.Site,
+		CreatorId:     resJson.User,
+		PostId:        getHistoryPostId(resJson.Service, resJson.Id),
+		SyncCreatorId: getHistoryPostId(resJson.Service, resJson.User),
+	}
+	for _, urlInfo := range toDownload {
+		urlInfo.Post = historyPost
+	}
+	for _, gdriveLink := range gdriveLinks {
+		gdriveLink.Post = historyPost
+	}
+	for _, revision := range revisionsFiles {
+		for _, urlInfo := range revision.Files {
+			urlInfo.Post = historyPost
+		}
+	}
+	if len(toDownload) == 0 && len(revisionsFiles) == 0 && dlOptions.DlAttachments {
+		// record text-only and GDrive-only posts as there are no files for the download process to record
+		history.AddProcessedPost(historyPost)
+	}
+
+	publishedAt, _ := time.Parse(KEMONO_PUBLISHED_LAYOUT, resJson.Published)
+	pathVars := &utils.PathVars{
+		Site:        archive.Site,
+		CreatorName: creatorName,
+		CreatorId:   resJson.User,
+		PostId:      resJson.Id,
+		PostTitle:   resJson.Title,
+		PublishedAt: publishedAt,
+	}
+	request.SetPathVars(toDownload, pathVars, postFolderPath, utils.THUMBNAIL_KIND)
+	request.SetPathVars(gdriveLinks, pathVars, postFolderPath, utils.GDRIVE_FOLDER)
+	for _, revision := range revisionsFiles {
+		// the post folder is used as the root so that all the revision files have the revision kind
+		request.SetPathVars(revision.Files, pathVars.ForRevision(revision.RevisionId), postFolderPath, utils.REVISION_KIND)
+		toDownload = append(toDownload, revision.Files...)
+	}
+
+	postUrl := fmt.Sprintf("%s/%s/user/%s/post/%s", archive.GetUrl(tld), resJson.Service, resJson.User, resJson.Id)
+	if dlOptions.Configs.BodyFormat != "" {
+		doc := render.NewDocument(resJson.Title, postUrl, postFolderPath, pathVars)
+		doc.AddBlocks(render.BlocksFromHtml(resJson.Content, func(src string) string {
+			if strings.HasPrefix(src, "/") {
+				return archive.GetDataUrl(tld, src)
+			}
+			return src
+		})...)
+		render.AddPending(doc)
+	}
+	metadata.Save(
+		&metadata.Post{
+			Site:        archive.Site,
+			Url:         postUrl,
+			PostId:      resJson.Id,
+			Title:       resJson.Title,
+			Body:        resJson.Content,
+			CreatorId:   resJson.User,
+			CreatorName: creatorName,
+			PublishedAt: metadata.ParseTime(KEMONO_PUBLISHED_LAYOUT, resJson.Published),
+			EditedAt:    metadata.ParseTime(KEMONO_PUBLISHED_LAYOUT, resJson.Edited),
+			Raw:         resJson.Raw,
+		},
+		postFolderPath,
+		pathVars,
+		dlOptions.Configs,
+	)
+	if dlOptions.DlComments {
+		saveComments(resJson, postUrl, postFolderPath, tld, pathVars, dlOptions)
+	}
 	return toDownload, gdriveLinks
 }
 
+// Since post IDs on Kemono Party are only unique within each service,
+// the post ID in the download history is prefixed with the service.
+func getHistoryPostId(service, postId string) string {
+	return service + "/" + postId
+}
+
 func processMultipleJson(resJson models.KemonoJson, tld, downloadPath string, dlOptions *KemonoDlOptions) ([]*request.ToDownload, []*request.ToDownload) {
 	var urlsToDownload, gdriveLinks []*request.ToDownload
+	skipped, crossRefPosts, crossRefFiles := 0, 0, 0
 	for _, post := range resJson {
+		if !dlOptions.Configs.IgnoreHistory && history.HasPost(dlOptions.Archive.Site, getHistoryPostId(post.Service, post.Id)) {
+			skipped++
+			continue
+		}
+
+		var nativePost *nativePost
+		if dlOptions.CrossReference != "" {
+			nativePost = nativeCrossRef.getPost(post.Service, post.Id, downloadPath)
+			if nativePost != nil && dlOptions.CrossReference == CROSS_REF_SKIP {
+				crossRefPosts++
+				continue
+			}
+		}
+
 		toDownload, foundGdriveLinks := processJson(post, tld, downloadPath, dlOptions)
+		if nativePost != nil {
+			var filteredFiles int
+			toDownload, filteredFiles = filterNativeFiles(toDownload, nativePost)
+			crossRefFiles += filteredFiles
+		}
 		urlsToDownload = append(urlsToDownload, toDownload...)
 		gdriveLinks = append(gdriveLinks, foundGdriveLinks...)
 	}
+	history.PrintSkippedMsg(dlOptions.Archive.Site, skipped)
+	printCrossRefMsg(crossRefPosts, crossRefFiles)
 	return urlsToDownload, gdriveLinks
 }
@@ -0,0 +1,112 @@
+package kemono
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Date formats used by older versions of the creator index
+var indexDateLayouts = []string{
+	http.TimeFormat,
+	time.RFC1123,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// FormatIndexDate returns the indexed or updated date of a creator in the creator index as "YYYY-MM-DD".
+func FormatIndexDate(value any) string {
+	switch date := value.(type) {
+	case float64:
+		return time.Unix(int64(date), 0).UTC().Format(time.DateOnly)
+	case string:
+		for _, layout := range indexDateLayouts {
+			if parsed, err := time.Parse(layout, date); err == nil {
+				return parsed.Format(time.DateOnly)
+			}
+		}
+		if date != "" {
+			return date
+		}
+	}
+	return "unknown"
+}
+
+func getCreatorIndex(archive *Archive, userAgent string) (models.KemonoIndexedCreatorJson, error) {
+	useHttp3 := utils.IsHttp3Supported(archive.Site, true)
+	res, err := request.CallRequest(
+		&request.RequestArgs{
+			Url:         archive.GetApiUrl(utils.KEMONO_BACKUP_TLD) + "/creators.txt",
+			Method:      "GET",
+			Headers:     getKemonoPartyHeaders(),
+			UserAgent:   userAgent,
+			Http2:       !useHttp3,
+			Http3:       useHttp3,
+			CheckStatus: true,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"%s error %d: failed to get the creator index from %s, more info => %v",
+			archive.Site,
+			utils.CONNECTION_ERROR,
+			archive.Title,
+			err,
+		)
+	}
+
+	var creators models.KemonoIndexedCreatorJson
+	if err := utils.LoadJsonFromResponse(res, &creators); err != nil {
+		return nil, err
+	}
+	return creators, nil
+}
+
+// SearchCreators returns the creators in the creator index of the archive whose name contains
+// the query or whose ID is the query, optionally only from the given service.
+//
+// Creators with a matching ID are returned first followed by the rest sorted by their number of favourites.
+func SearchCreators(query, service, userAgent string, archive *Archive) (models.KemonoIndexedCreatorJson, error) {
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		fmt.Sprintf("Searching for creators on %s...", archive.Title),
+		fmt.Sprintf("Finished searching for creators on %s!", archive.Title),
+		fmt.Sprintf("Something went wrong while searching for creators on %s.\nPlease refer to the logs for more details.", archive.Title),
+		0,
+	)
+	progress.Start()
+	creators, err := getCreatorIndex(archive, userAgent)
+	if err != nil {
+		progress.Stop(true)
+		return nil, err
+	}
+	progress.Stop(false)
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	var matches models.KemonoIndexedCreatorJson
+	for _, creator := range creators {
+		if service != "" && creator.Service != service {
+			continue
+		}
+		if creator.Id == query || strings.Contains(strings.ToLower(creator.Name), query) {
+			matches = append(matches, creator)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		iIsId, jIsId := matches[i].Id == query, matches[j].Id == query
+		if iIsId != jIsId {
+			return iIsId
+		}
+		return matches[i].Favorited > matches[j].Favorited
+	})
+	return matches, nil
+}
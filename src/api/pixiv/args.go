@@ -1,28 +1,78 @@
 package pixiv
 
-import "github.com/KJHJason/Cultured-Downloader-CLI/utils"
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+)
 
 // PixivDl contains the IDs of the Pixiv artworks and
 // illustrators and Tag Names to download.
 type PixivDl struct {
 	ArtworkIds []string
 
+	// SeriesIds are the IDs of the manga series to download all the chapters of
+	SeriesIds []string
+
 	IllustratorIds      []string
 	IllustratorPageNums []string
 
+	// IllustratorMaxWorks is the maximum number of the newest works to download from
+	// each illustrator, e.g. the users that the user is following, if any.
+	IllustratorMaxWorks map[string]int
+
 	TagNames         []string
 	TagNamesPageNums []string
+
+	// BookmarkVisibility is the visibility of the user's bookmarks
+	// to download, "public", "private", or "all", if not empty.
+	BookmarkVisibility string
+	BookmarkTag        string
+	BookmarkPageNum    string
+
+	// RankingMode is the mode of the ranking to download, e.g. "daily", if not empty.
+	RankingMode    string
+	RankingDate    string
+	RankingPageNum string
+
+	DlFollowingFeed      bool
+	FollowingFeedPageNum string
+
+	// DlFollowingUsers downloads the works of all the users that the user is following
+	// with FollowingUsersPageNum to limit the pages of works downloaded from each user
+	// and FollowingUsersMaxWorks to limit the number of the newest works downloaded from each user.
+	DlFollowingUsers       bool
+	FollowingUsersPageNum  string
+	FollowingUsersMaxWorks int
+
+	// FollowingUsersOutput is the path of the text file to save the users that the user is following to
+	FollowingUsersOutput string
+
+	NovelIds       []string
+	NovelSeriesIds []string
+
+	NovelUserIds      []string
+	NovelUserPageNums []string
+
+	NovelTagNames    []string
+	NovelTagPageNums []string
 }
 
-// ValidateArgs validates the IDs of the Pixiv artworks and illustrators to download.
+// ValidateArgs validates the IDs of the Pixiv artworks, manga series, illustrators, and novels to download.
 //
-// It also validates the page numbers of the tag names to download.
+// It also validates the page numbers of the tag names, bookmarks, ranking, following feed, following users, and novels to download.
 //
 // Should be called after initialising the struct.
 func (p *PixivDl) ValidateArgs() {
 	utils.ValidateIds(p.ArtworkIds)
+	utils.ValidateIds(p.SeriesIds)
 	utils.ValidateIds(p.IllustratorIds)
 	p.ArtworkIds = utils.RemoveSliceDuplicates(p.ArtworkIds)
+	p.SeriesIds = utils.RemoveSliceDuplicates(p.SeriesIds)
 
 	if len(p.IllustratorPageNums) > 0 {
 		utils.ValidatePageNumInput(
@@ -55,4 +105,72 @@ func (p *PixivDl) ValidateArgs() {
 		p.TagNames,
 		p.TagNamesPageNums,
 	)
+
+	if p.BookmarkVisibility != "" {
+		p.BookmarkVisibility = strings.ToLower(p.BookmarkVisibility)
+		utils.ValidateStrArgs(
+			p.BookmarkVisibility,
+			pixivcommon.ACCEPTED_BOOKMARK_VISIBILITIES,
+			[]string{
+				fmt.Sprintf(
+					"pixiv error %d: Bookmark visibility %s is not allowed",
+					utils.INPUT_ERROR,
+					p.BookmarkVisibility,
+				),
+			},
+		)
+	}
+
+	utils.ValidateIds(p.NovelIds)
+	utils.ValidateIds(p.NovelSeriesIds)
+	utils.ValidateIds(p.NovelUserIds)
+	p.NovelIds = utils.RemoveSliceDuplicates(p.NovelIds)
+	p.NovelSeriesIds = utils.RemoveSliceDuplicates(p.NovelSeriesIds)
+
+	if len(p.NovelUserPageNums) > 0 {
+		utils.ValidatePageNumInput(
+			len(p.NovelUserIds),
+			p.NovelUserPageNums,
+			[]string{
+				"Number of novel user ID(s) and novel users' page numbers must be equal.",
+			},
+		)
+	} else {
+		p.NovelUserPageNums = make([]string, len(p.NovelUserIds))
+	}
+	p.NovelUserIds, p.NovelUserPageNums = utils.RemoveDuplicateIdAndPageNum(
+		p.NovelUserIds,
+		p.NovelUserPageNums,
+	)
+
+	if len(p.NovelTagPageNums) > 0 {
+		utils.ValidatePageNumInput(
+			len(p.NovelTagNames),
+			p.NovelTagPageNums,
+			[]string{
+				"Number of novel tag names and novel tag names' page numbers must be equal.",
+			},
+		)
+	} else {
+		p.NovelTagPageNums = make([]string, len(p.NovelTagNames))
+	}
+	p.NovelTagNames, p.NovelTagPageNums = utils.RemoveDuplicateIdAndPageNum(
+		p.NovelTagNames,
+		p.NovelTagPageNums,
+	)
+
+	p.RankingMode = strings.ToLower(p.RankingMode)
+	for _, pageNum := range []string{p.BookmarkPageNum, p.RankingPageNum, p.FollowingFeedPageNum, p.FollowingUsersPageNum} {
+		if pageNum != "" {
+			utils.ValidatePageNumInput(1, []string{pageNum}, nil)
+		}
+	}
+	if p.FollowingUsersMaxWorks < 0 {
+		color.Red(
+			"pixiv error %d: the maximum number of works to download from each user you are following cannot be negative, got %d",
+			utils.INPUT_ERROR,
+			p.FollowingUsersMaxWorks,
+		)
+		os.Exit(1)
+	}
 }
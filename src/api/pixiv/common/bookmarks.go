@@ -0,0 +1,18 @@
+package pixivcommon
+
+// Visibilities of the user's bookmarks to download
+const (
+	BOOKMARKS_PUBLIC  = "public"
+	BOOKMARKS_PRIVATE = "private"
+	BOOKMARKS_ALL     = "all"
+)
+
+var ACCEPTED_BOOKMARK_VISIBILITIES = []string{BOOKMARKS_PUBLIC, BOOKMARKS_PRIVATE, BOOKMARKS_ALL}
+
+// GetBookmarkVisibilities returns the public and/or private visibility to get the bookmarks of
+func GetBookmarkVisibilities(visibility string) []string {
+	if visibility == BOOKMARKS_ALL {
+		return []string{BOOKMARKS_PUBLIC, BOOKMARKS_PRIVATE}
+	}
+	return []string{visibility}
+}
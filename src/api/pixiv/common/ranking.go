@@ -0,0 +1,85 @@
+package pixivcommon
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+)
+
+// RANKING_DATE_LAYOUT is the format of the date of the ranking to download
+const RANKING_DATE_LAYOUT = time.DateOnly
+
+// Modes of the rankings on Pixiv based on the modes of Pixiv's web ranking page.
+//
+// The manga rankings are downloaded with the same modes by setting the artwork type to "manga".
+var (
+	ACCEPTED_RANKING_MODE = []string{
+		"daily", "weekly", "monthly",
+		"rookie", "original",
+		"male", "female",
+		"daily_r18", "weekly_r18",
+		"male_r18", "female_r18",
+	}
+
+	// modes that do not have a manga ranking
+	noMangaRankingModes = []string{
+		"original",
+		"male", "female",
+		"male_r18", "female_r18",
+	}
+)
+
+// IsR18RankingMode returns true if the ranking mode only contains R-18 artworks.
+func IsR18RankingMode(mode string) bool {
+	return strings.HasSuffix(mode, "_r18")
+}
+
+// ValidateRankingArgs validates the ranking mode and date against the rating mode and artwork type
+// of the download options and exits the program if the ranking cannot be downloaded with them.
+func ValidateRankingArgs(mode, date, ratingMode, artworkType string) {
+	utils.ValidateStrArgs(
+		mode,
+		ACCEPTED_RANKING_MODE,
+		[]string{
+			fmt.Sprintf(
+				"pixiv error %d: Ranking mode %s is not allowed",
+				utils.INPUT_ERROR,
+				mode,
+			),
+		},
+	)
+
+	if IsR18RankingMode(mode) && ratingMode == "safe" {
+		color.Red(
+			"pixiv error %d: the %q ranking only contains R-18 artworks which cannot be downloaded with the \"safe\" rating mode",
+			utils.INPUT_ERROR,
+			mode,
+		)
+		os.Exit(1)
+	}
+	if artworkType == "manga" && utils.SliceContains(noMangaRankingModes, mode) {
+		color.Red(
+			"pixiv error %d: the %q ranking does not have a manga ranking, please use a different --artwork_type",
+			utils.INPUT_ERROR,
+			mode,
+		)
+		os.Exit(1)
+	}
+
+	if date == "" {
+		return
+	}
+	rankingDate, err := time.Parse(RANKING_DATE_LAYOUT, date)
+	if err != nil || rankingDate.After(time.Now()) {
+		color.Red(
+			"pixiv error %d: invalid ranking date %q, please follow the format \"YYYY-MM-DD\" with a date that is not in the future",
+			utils.INPUT_ERROR,
+			date,
+		)
+		os.Exit(1)
+	}
+}
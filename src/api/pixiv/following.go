@@ -0,0 +1,67 @@
+package pixiv
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+)
+
+// addFollowingUsers adds the users that the user is following to the illustrators to download
+// with the page number and the maximum number of works of the following users to limit the works
+// downloaded from each user. The users that were already given as illustrators are not limited.
+//
+// The users are also saved to the text file of the following users if one was given unless it is a dry run.
+func (p *PixivDl) addFollowingUsers(userIds []string, dryRun bool) {
+	userIds = utils.RemoveSliceDuplicates(userIds)
+	if p.FollowingUsersOutput != "" && !dryRun {
+		if err := exportFollowingUsers(userIds, p.FollowingUsersPageNum, p.FollowingUsersOutput); err != nil {
+			utils.LogError(err, "", false, utils.ERROR)
+		} else {
+			color.Green("Saved %d user(s) that you are following to %s", len(userIds), p.FollowingUsersOutput)
+		}
+	}
+
+	for _, userId := range userIds {
+		if p.FollowingUsersMaxWorks > 0 && !utils.SliceContains(p.IllustratorIds, userId) {
+			if p.IllustratorMaxWorks == nil {
+				p.IllustratorMaxWorks = make(map[string]int)
+			}
+			p.IllustratorMaxWorks[userId] = p.FollowingUsersMaxWorks
+		}
+		p.IllustratorIds = append(p.IllustratorIds, userId)
+		p.IllustratorPageNums = append(p.IllustratorPageNums, p.FollowingUsersPageNum)
+	}
+	p.IllustratorIds, p.IllustratorPageNums = utils.RemoveDuplicateIdAndPageNum(
+		p.IllustratorIds,
+		p.IllustratorPageNums,
+	)
+}
+
+// exportFollowingUsers saves the users to the text file, one user URL per line with the page number if any,
+// in the format that is accepted by the --txt_filepath flag of the pixiv command.
+func exportFollowingUsers(userIds []string, pageNum, filePath string) error {
+	var lines strings.Builder
+	for _, userId := range userIds {
+		lines.WriteString(pixivcommon.GetUserUrl(userId))
+		if pageNum != "" {
+			lines.WriteString("; " + pageNum)
+		}
+		lines.WriteString("\n")
+	}
+
+	os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err := os.WriteFile(filePath, []byte(lines.String()), 0666); err != nil {
+		return fmt.Errorf(
+			"pixiv error %d: failed to save the users you are following to %s, more info => %v",
+			utils.OS_ERROR,
+			filePath,
+			err,
+		)
+	}
+	return nil
+}
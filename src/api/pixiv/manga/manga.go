@@ -0,0 +1,271 @@
+package manga
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/render"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+)
+
+// Modes of packaging the chapters of the manga series as CBZ files
+const (
+	CBZ_NONE    = ""
+	CBZ_CHAPTER = "chapter"
+	CBZ_SERIES  = "series"
+)
+
+var ACCEPTED_CBZ_MODES = []string{
+	CBZ_NONE,
+	CBZ_CHAPTER,
+	CBZ_SERIES,
+}
+
+// MangaOptions is the struct that contains the
+// configs for the processing of the manga series after getting their chapters from Pixiv.
+type MangaOptions struct {
+	// CbzMode is "chapter" to package each chapter as a CBZ file,
+	// "series" to package all the chapters of the series as one CBZ file, or empty to not package any
+	CbzMode string
+}
+
+// ValidateArgs validates the arguments of the manga process options.
+//
+// Should be called after initialising the struct.
+func (m *MangaOptions) ValidateArgs() {
+	m.CbzMode = strings.ToLower(m.CbzMode)
+	utils.ValidateStrArgs(
+		m.CbzMode,
+		ACCEPTED_CBZ_MODES,
+		[]string{
+			fmt.Sprintf(
+				"pixiv error %d: CBZ mode %s is not allowed",
+				utils.INPUT_ERROR,
+				m.CbzMode,
+			),
+		},
+	)
+}
+
+// KeepsDownloadedChapters returns true if the chapters of the manga series that are in the download history
+// should still be retrieved so that their downloaded pages are included in the series CBZ file.
+//
+// The pages that already exist are not downloaded again.
+func (m *MangaOptions) KeepsDownloadedChapters() bool {
+	return m.CbzMode == CBZ_SERIES
+}
+
+// GetMangaSeriesUrl returns the URL of the manga series on Pixiv.
+func GetMangaSeriesUrl(userId, seriesId string) string {
+	return fmt.Sprintf("%s/user/%s/series/%s", utils.PIXIV_URL, userId, seriesId)
+}
+
+// ChapterFromArtworkDetails returns the chapter from the artwork details of Pixiv's web API.
+//
+// The order of the chapter is from the series navigation data which may be missing for some artworks.
+func ChapterFromArtworkDetails(artworkId string, artworkDetails *models.ArtworkDetails) *models.MangaChapter {
+	body := artworkDetails.Body
+	chapter := &models.MangaChapter{
+		ArtworkId:  artworkId,
+		Title:      body.Title,
+		Caption:    body.Description,
+		UserId:     body.UserId,
+		UserName:   body.UserName,
+		PageCount:  body.PageCount,
+		CreateDate: body.CreateDate,
+		IsUgoira:   body.IllustType == 2, // 2 is the illust type of Ugoira
+	}
+	for _, tag := range body.Tags.Tags {
+		chapter.Tags = append(chapter.Tags, tag.Tag)
+	}
+	if body.SeriesNavData != nil {
+		chapter.SeriesId = body.SeriesNavData.SeriesId.String()
+		chapter.SeriesTitle = body.SeriesNavData.Title
+		chapter.Order = body.SeriesNavData.Order
+	}
+	return chapter
+}
+
+// ChapterFromMobileIllust returns the chapter from the artwork JSON of Pixiv's mobile API.
+//
+// The order of the chapter is not set as it is not included in the artwork JSON.
+func ChapterFromMobileIllust(illustJson *models.PixivMobileIllustJson) *models.MangaChapter {
+	chapter := &models.MangaChapter{
+		ArtworkId:  strconv.Itoa(illustJson.Id),
+		Title:      illustJson.Title,
+		Caption:    illustJson.Caption,
+		UserId:     strconv.Itoa(illustJson.User.Id),
+		UserName:   illustJson.User.Name,
+		PageCount:  illustJson.PageCount,
+		CreateDate: illustJson.CreateDate,
+		IsUgoira:   illustJson.Type == "ugoira",
+	}
+	for _, tag := range illustJson.Tags {
+		chapter.Tags = append(chapter.Tags, tag.Name)
+	}
+	if illustJson.Series != nil {
+		chapter.SeriesId = strconv.Itoa(illustJson.Series.Id)
+		chapter.SeriesTitle = illustJson.Series.Title
+	}
+	return chapter
+}
+
+// newComicInfo returns the ComicInfo.xml metadata of the chapter.
+func newComicInfo(chapter *models.MangaChapter) *render.ComicInfo {
+	comicInfo := &render.ComicInfo{
+		Title:   chapter.Title,
+		Series:  chapter.SeriesTitle,
+		Summary: chapter.Caption,
+		Writer:  chapter.UserName,
+		Web:     fmt.Sprintf("%s/artworks/%s", utils.PIXIV_URL, chapter.ArtworkId),
+		Tags:    strings.Join(chapter.Tags, ","),
+		Manga:   "Yes",
+	}
+	if chapter.Order > 0 {
+		comicInfo.Number = strconv.Itoa(chapter.Order)
+	}
+	if createdAt, err := time.Parse(time.RFC3339, chapter.CreateDate); err == nil {
+		comicInfo.Year, comicInfo.Month, comicInfo.Day = createdAt.Year(), int(createdAt.Month()), createdAt.Day()
+	}
+	return comicInfo
+}
+
+// newPathVars returns the path variables of the chapter for the output path template
+func newPathVars(chapter *models.MangaChapter) *utils.PathVars {
+	createdAt, _ := time.Parse(time.RFC3339, chapter.CreateDate)
+	return &utils.PathVars{
+		Site:        utils.PIXIV,
+		CreatorName: chapter.UserName,
+		CreatorId:   chapter.UserId,
+		PostId:      chapter.ArtworkId,
+		PostTitle:   chapter.Title,
+		PublishedAt: createdAt,
+	}
+}
+
+// getComicFilePath returns the file path of the CBZ file in the folder
+// or based on the output path template with the path variables if one was given.
+func getComicFilePath(folderPath, filename string, pathVars *utils.PathVars, config *configs.Config) string {
+	if config.PathTemplate != nil {
+		return config.PathTemplate.Execute(utils.DOWNLOAD_PATH, pathVars.ForFile(render.CBZ_KIND, 0), filename)
+	}
+	return filepath.Join(folderPath, filename)
+}
+
+// addChapterComic adds the chapter as a pending CBZ file in the artwork's folder.
+func addChapterComic(chapter *models.MangaChapter, pageUrls []string, downloadPath string, config *configs.Config) {
+	comic := render.NewComic(
+		newComicInfo(chapter),
+		getComicFilePath(
+			utils.GetPostFolder(
+				filepath.Join(downloadPath, utils.PIXIV_TITLE),
+				chapter.UserName,
+				chapter.ArtworkId,
+				chapter.Title,
+			),
+			utils.CleanPathName(chapter.Title)+"."+render.CBZ_FORMAT,
+			newPathVars(chapter),
+			config,
+		),
+	)
+	comic.PageUrls = pageUrls
+	render.AddPendingComic(comic)
+}
+
+// addSeriesComic adds the chapters of the same series, which are in their order, as one pending CBZ file.
+//
+// For the output path template, the series is used as the post with the date of its first chapter.
+func addSeriesComic(chapters []*models.MangaChapter, pageUrls map[string][]string, downloadPath string, config *configs.Config) {
+	firstChapter := chapters[0]
+	seriesTitle := firstChapter.SeriesTitle
+	if seriesTitle == "" {
+		seriesTitle = firstChapter.Title
+	}
+
+	comicInfo := newComicInfo(firstChapter)
+	comicInfo.Title = seriesTitle
+	comicInfo.Series = seriesTitle
+	comicInfo.Number = ""
+	comicInfo.Count = len(chapters)
+	comicInfo.Web = GetMangaSeriesUrl(firstChapter.UserId, firstChapter.SeriesId)
+
+	pathVars := newPathVars(firstChapter)
+	pathVars.PostId = firstChapter.SeriesId
+	pathVars.PostTitle = seriesTitle
+
+	var tags []string
+	comic := render.NewComic(
+		comicInfo,
+		getComicFilePath(
+			filepath.Join(downloadPath, utils.PIXIV_TITLE, utils.CleanPathName(firstChapter.UserName)),
+			fmt.Sprintf("[series_%s] %s.%s", firstChapter.SeriesId, utils.CleanPathName(seriesTitle), render.CBZ_FORMAT),
+			pathVars,
+			config,
+		),
+	)
+	for _, chapter := range chapters {
+		tags = append(tags, chapter.Tags...)
+		comic.PageUrls = append(comic.PageUrls, pageUrls[chapter.ArtworkId]...)
+	}
+	comicInfo.Tags = strings.Join(utils.RemoveSliceDuplicates(tags), ",")
+	render.AddPendingComic(comic)
+}
+
+// AddPendingComics adds the chapters of the manga series as pending CBZ files based on the CBZ mode
+// with the pages being the images of the chapters to download in their order.
+//
+// The CBZ files are saved after the images have been downloaded when render.RenderPending is called.
+// Chapters without any images to download, e.g. due to the download history in the "chapter" mode, are not packaged.
+// Ugoira chapters are not packaged either as their frames are converted to an animation instead.
+func AddPendingComics(chapters []*models.MangaChapter, toDownload []*request.ToDownload, downloadPath string, mangaOptions *MangaOptions, config *configs.Config) {
+	if mangaOptions.CbzMode == CBZ_NONE || config.DryRun {
+		return
+	}
+
+	// the same artwork may be retrieved more than once from the different sources
+	seenUrls := make(map[string]struct{})
+	pageUrls := make(map[string][]string)
+	for _, artwork := range toDownload {
+		if _, ok := seenUrls[artwork.Url]; ok || artwork.Post == nil {
+			continue
+		}
+		seenUrls[artwork.Url] = struct{}{}
+		pageUrls[artwork.Post.PostId] = append(pageUrls[artwork.Post.PostId], artwork.Url)
+	}
+
+	var seriesIds []string
+	seriesChapters := make(map[string][]*models.MangaChapter)
+	for _, chapter := range chapters {
+		if chapter.IsUgoira {
+			color.Yellow(
+				"Chapter %s (ID: %s) of manga series ID %s is a Ugoira and will not be packaged in the CBZ file...",
+				chapter.Title,
+				chapter.ArtworkId,
+				chapter.SeriesId,
+			)
+			continue
+		}
+		if len(pageUrls[chapter.ArtworkId]) == 0 {
+			continue
+		}
+		if mangaOptions.CbzMode == CBZ_CHAPTER {
+			addChapterComic(chapter, pageUrls[chapter.ArtworkId], downloadPath, config)
+			continue
+		}
+
+		if _, ok := seriesChapters[chapter.SeriesId]; !ok {
+			seriesIds = append(seriesIds, chapter.SeriesId)
+		}
+		seriesChapters[chapter.SeriesId] = append(seriesChapters[chapter.SeriesId], chapter)
+	}
+	for _, seriesId := range seriesIds {
+		addSeriesComic(seriesChapters[seriesId], pageUrls, downloadPath, config)
+	}
+}
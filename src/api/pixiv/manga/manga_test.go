@@ -0,0 +1,52 @@
+package manga
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+func TestGetComicFilePath(t *testing.T) {
+	chapter := &models.MangaChapter{
+		ArtworkId:  "123",
+		Title:      "Chapter 1",
+		UserId:     "456",
+		UserName:   "Artist",
+		CreateDate: "2024-01-02T03:04:05+09:00",
+		SeriesId:   "789",
+	}
+	tests := []struct {
+		name         string
+		pathTemplate string
+		want         string
+	}{
+		{
+			name: "default folder",
+			want: filepath.Join("folder", "Chapter 1.cbz"),
+		},
+		{
+			name:         "path template",
+			pathTemplate: "{site}/{creator_id}/{yyyy-mm-dd}_{post_id}/{kind}/{filename}",
+			want:         filepath.Join(utils.DOWNLOAD_PATH, "pixiv", "456", "2024-01-02_123", "cbz", "Chapter 1.cbz"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := &configs.Config{}
+			if test.pathTemplate != "" {
+				pathTemplate, err := utils.ParsePathTemplate(test.pathTemplate)
+				if err != nil {
+					t.Fatal(err)
+				}
+				config.PathTemplate = pathTemplate
+			}
+			if got := getComicFilePath("folder", "Chapter 1.cbz", newPathVars(chapter), config); got != test.want {
+				t.Errorf("getComicFilePath() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package manga
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// MangaSeries contains the title of a manga series and the order of its artworks
+type MangaSeries struct {
+	Id         string
+	Title      string
+	ArtworkIds []string
+	Orders     map[string]int
+}
+
+// GetMangaSeries returns the artwork IDs of the manga series sorted by their order in the series
+// from Pixiv's web API as the mobile API does not return the order of the artworks.
+//
+// getReqArgs returns the request arguments for the URL and its parameters and
+// sleep is called between the requests of the pages.
+func GetMangaSeries(seriesId string, getReqArgs func(url string, params map[string]string) *request.RequestArgs, sleep func()) (*MangaSeries, error) {
+	series := &MangaSeries{
+		Id:     seriesId,
+		Orders: make(map[string]int),
+	}
+	url := fmt.Sprintf("%s/series/%s", utils.PIXIV_API_URL, seriesId)
+	for page := 1; ; page++ {
+		res, err := request.CallRequest(
+			getReqArgs(url, map[string]string{"p": strconv.Itoa(page)}),
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"pixiv error %d: failed to get page %d of manga series ID %s, more info => %v",
+				utils.CONNECTION_ERROR,
+				page,
+				seriesId,
+				err,
+			)
+		}
+
+		var resJson models.PixivWebMangaSeriesJson
+		if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
+			return nil, err
+		}
+		for _, illustSeries := range resJson.Body.IllustSeries {
+			if illustSeries.Id == seriesId {
+				series.Title = illustSeries.Title
+			}
+		}
+
+		pageSeries := resJson.Body.Page.Series
+		for _, work := range pageSeries {
+			artworkId := work.WorkId.String()
+			if _, ok := series.Orders[artworkId]; !ok {
+				series.ArtworkIds = append(series.ArtworkIds, artworkId)
+			}
+			series.Orders[artworkId] = work.Order
+		}
+		if len(pageSeries) == 0 || len(series.ArtworkIds) >= resJson.Body.Page.Total {
+			break
+		}
+		sleep()
+	}
+
+	sort.SliceStable(series.ArtworkIds, func(i, j int) bool {
+		return series.Orders[series.ArtworkIds[i]] < series.Orders[series.ArtworkIds[j]]
+	})
+	return series, nil
+}
@@ -2,12 +2,15 @@ package pixivmobile
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/ugoira"
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
@@ -17,6 +20,13 @@ type offsetArgs struct {
 	minOffset int
 	maxOffset int
 	hasMax    bool
+
+	// used to stop at the first artwork that has been seen from the last sync
+	syncState    *history.SyncState
+	newSyncState *history.SyncState
+
+	// maxWorks is the maximum number of works to get, if more than 0
+	maxWorks int
 }
 
 // Returns the Ugoira structure with the necessary information to download the ugoira
@@ -158,6 +168,7 @@ func (pixiv *PixivMobile) getIllustratorPostMainLogic(params map[string]string,
 	var artworksToDownload []*request.ToDownload
 	nextUrl := pixiv.baseUrl + "/v1/user/illusts"
 
+	works := 0
 	curOffset := offsetArg.minOffset
 	for nextUrl != "" {
 		res, err := pixiv.SendRequest(
@@ -182,6 +193,28 @@ func (pixiv *PixivMobile) getIllustratorPostMainLogic(params map[string]string,
 			return nil, nil, []error{err}
 		}
 
+		reachedSeenArtwork := false
+		if offsetArg.syncState != nil {
+			var unseenArtworks []*models.PixivMobileIllustJson
+			for _, artwork := range resJson.Illusts {
+				artworkId := strconv.Itoa(artwork.Id)
+				offsetArg.newSyncState.Update(artworkId, time.Time{})
+				if offsetArg.syncState.IsSeen(artworkId, time.Time{}) {
+					reachedSeenArtwork = true
+					continue
+				}
+				unseenArtworks = append(unseenArtworks, artwork)
+			}
+			resJson.Illusts = unseenArtworks
+		}
+
+		reachedMaxWorks := false
+		if offsetArg.maxWorks > 0 && works+len(resJson.Illusts) >= offsetArg.maxWorks {
+			resJson.Illusts = resJson.Illusts[:offsetArg.maxWorks-works]
+			reachedMaxWorks = true
+		}
+		works += len(resJson.Illusts)
+
 		artworks, ugoira, errS := pixiv.processMultipleArtworkJson(&resJson, downloadPath)
 		if len(errS) > 0 {
 			errSlice = append(errSlice, errS...)
@@ -192,7 +225,7 @@ func (pixiv *PixivMobile) getIllustratorPostMainLogic(params map[string]string,
 		curOffset += 30
 		params["offset"] = strconv.Itoa(curOffset)
 		jsonNextUrl := resJson.NextUrl
-		if jsonNextUrl == nil || (offsetArg.hasMax && curOffset >= offsetArg.maxOffset) {
+		if jsonNextUrl == nil || reachedSeenArtwork || reachedMaxWorks || (offsetArg.hasMax && curOffset >= offsetArg.maxOffset) {
 			nextUrl = ""
 		} else {
 			nextUrl = *jsonNextUrl
@@ -202,8 +235,57 @@ func (pixiv *PixivMobile) getIllustratorPostMainLogic(params map[string]string,
 	return artworksToDownload, ugoiraSlice, errSlice
 }
 
+// limitNewestWorks only keeps the files and the ugoira of the newest works up to maxWorks, or all of them if maxWorks is 0.
+//
+// The works are compared by their artwork IDs as they are incrementing.
+func limitNewestWorks(artworks []*request.ToDownload, ugoiraSlice []*models.Ugoira, maxWorks int) ([]*request.ToDownload, []*models.Ugoira) {
+	if maxWorks <= 0 {
+		return artworks, ugoiraSlice
+	}
+
+	var artworkIds []int
+	seen := make(map[int]bool)
+	addArtworkId := func(post *history.Post) {
+		if artworkId, err := strconv.Atoi(post.PostId); err == nil && !seen[artworkId] {
+			seen[artworkId] = true
+			artworkIds = append(artworkIds, artworkId)
+		}
+	}
+	for _, artwork := range artworks {
+		addArtworkId(artwork.Post)
+	}
+	for _, ugoira := range ugoiraSlice {
+		addArtworkId(ugoira.Post)
+	}
+	if len(artworkIds) <= maxWorks {
+		return artworks, ugoiraSlice
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(artworkIds)))
+	oldestKept := artworkIds[maxWorks-1]
+
+	isKept := func(post *history.Post) bool {
+		artworkId, err := strconv.Atoi(post.PostId)
+		return err == nil && artworkId >= oldestKept
+	}
+	var keptArtworks []*request.ToDownload
+	for _, artwork := range artworks {
+		if isKept(artwork.Post) {
+			keptArtworks = append(keptArtworks, artwork)
+		}
+	}
+	var keptUgoira []*models.Ugoira
+	for _, ugoira := range ugoiraSlice {
+		if isKept(ugoira.Post) {
+			keptUgoira = append(keptUgoira, ugoira)
+		}
+	}
+	return keptArtworks, keptUgoira
+}
+
 // Query Pixiv's API (mobile) to get all the posts JSON(s) of a user ID
-func (pixiv *PixivMobile) getIllustratorPosts(userId, pageNum, downloadPath, artworkType string) ([]*request.ToDownload, []*models.Ugoira, []error) {
+//
+// If maxWorks is more than 0, only the newest works up to maxWorks are returned.
+func (pixiv *PixivMobile) getIllustratorPosts(userId, pageNum, downloadPath, artworkType string, maxWorks int, syncMode bool) ([]*request.ToDownload, []*models.Ugoira, []error) {
 	minPage, maxPage, hasMax, err := utils.GetMinMaxFromStr(pageNum)
 	if err != nil {
 		return nil, nil, []error{err}
@@ -224,6 +306,12 @@ func (pixiv *PixivMobile) getIllustratorPosts(userId, pageNum, downloadPath, art
 		minOffset: minOffset,
 		maxOffset: maxOffset,
 		hasMax:    hasMax,
+		maxWorks:  maxWorks,
+	}
+	if syncMode {
+		offsetArgs.syncState = history.GetSyncState(utils.PIXIV, userId)
+		offsetArgs.newSyncState = &history.SyncState{}
+		*offsetArgs.newSyncState = *offsetArgs.syncState
 	}
 	artworksToDl, ugoiraSlice, errSlice := pixiv.getIllustratorPostMainLogic(
 		params,
@@ -245,11 +333,22 @@ func (pixiv *PixivMobile) getIllustratorPosts(userId, pageNum, downloadPath, art
 		artworksToDl = append(artworksToDl, artworksToDl2...)
 		ugoiraSlice = append(ugoiraSlice, ugoiraSlice2...)
 		errSlice = append(errSlice, errSlice2...)
+
+		// both the illustrations and the manga are limited separately
+		// so only keep the newest works from both of them
+		artworksToDl, ugoiraSlice = limitNewestWorks(artworksToDl, ugoiraSlice, maxWorks)
+	}
+
+	if syncMode && len(errSlice) == 0 {
+		history.SetPendingSyncState(offsetArgs.newSyncState)
 	}
 	return artworksToDl, ugoiraSlice, errSlice
 }
 
-func (pixiv *PixivMobile) GetMultipleIllustratorPosts(userIds, pageNums []string, downloadPath, artworkType string) ([]*request.ToDownload, []*models.Ugoira) {
+// GetMultipleIllustratorPosts gets the posts of multiple illustrators
+//
+// maxWorks limits the number of the newest works of the illustrators in it, e.g. the users that the user is following.
+func (pixiv *PixivMobile) GetMultipleIllustratorPosts(userIds, pageNums []string, maxWorks map[string]int, downloadPath, artworkType string, syncMode bool) ([]*request.ToDownload, []*models.Ugoira) {
 	userIdsLen := len(userIds)
 	lastIdx := userIdsLen - 1
 
@@ -281,6 +380,8 @@ func (pixiv *PixivMobile) GetMultipleIllustratorPosts(userIds, pageNums []string
 			pageNums[idx],
 			downloadPath,
 			artworkType,
+			maxWorks[userId],
+			syncMode,
 		)
 		if err != nil {
 			errSlice = append(errSlice, err...)
@@ -0,0 +1,69 @@
+package pixivmobile
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+)
+
+func TestLimitNewestWorks(t *testing.T) {
+	newArtwork := func(postId, url string) *request.ToDownload {
+		return &request.ToDownload{Url: url, Post: &history.Post{PostId: postId}}
+	}
+	// the illustrations are listed before the manga like when both are downloaded
+	artworks := []*request.ToDownload{
+		newArtwork("30", "illust30_p0"),
+		newArtwork("30", "illust30_p1"),
+		newArtwork("10", "illust10_p0"),
+		newArtwork("40", "manga40_p0"),
+		newArtwork("20", "manga20_p0"),
+	}
+	ugoiraSlice := []*models.Ugoira{{Url: "ugoira35", Post: &history.Post{PostId: "35"}}}
+
+	tests := []struct {
+		name        string
+		maxWorks    int
+		wantUrls    []string
+		wantUgoiras []string
+	}{
+		{
+			name:        "no limit",
+			maxWorks:    0,
+			wantUrls:    []string{"illust30_p0", "illust30_p1", "illust10_p0", "manga40_p0", "manga20_p0"},
+			wantUgoiras: []string{"ugoira35"},
+		},
+		{
+			name:        "all the pages of a kept work are kept",
+			maxWorks:    3,
+			wantUrls:    []string{"illust30_p0", "illust30_p1", "manga40_p0"},
+			wantUgoiras: []string{"ugoira35"},
+		},
+		{
+			name:     "newest work only",
+			maxWorks: 1,
+			wantUrls: []string{"manga40_p0"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gotArtworks, gotUgoiraSlice := limitNewestWorks(artworks, ugoiraSlice, test.maxWorks)
+			var gotUrls, gotUgoiras []string
+			for _, artwork := range gotArtworks {
+				gotUrls = append(gotUrls, artwork.Url)
+			}
+			for _, ugoira := range gotUgoiraSlice {
+				gotUgoiras = append(gotUgoiras, ugoira.Url)
+			}
+			if !reflect.DeepEqual(gotUrls, test.wantUrls) {
+				t.Errorf("artworks = %v, want %v", gotUrls, test.wantUrls)
+			}
+			if !reflect.DeepEqual(gotUgoiras, test.wantUgoiras) {
+				t.Errorf("ugoira = %v, want %v", gotUgoiras, test.wantUgoiras)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
 	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 	"github.com/fatih/color"
@@ -105,6 +106,7 @@ func (p *PixivMobileDlOptions) ValidateArgs(userAgent string) {
 
 	if p.RefreshToken != "" {
 		p.MobileClient = NewPixivMobile(p.RefreshToken, 10)
+		p.MobileClient.configs = p.Configs
 		if p.RatingMode != "all" {
 			color.Red(
 				utils.CombineStringsWithNewline(
@@ -194,3 +196,13 @@ func (p *PixivMobileDlOptions) ValidateArgs(userAgent string) {
 		p.SortOrder = newSortOrder
 	}
 }
+
+// ValidateRankingArgs validates the ranking mode and the date of the ranking to download if any.
+//
+// Should be called after ValidateArgs.
+func (p *PixivMobileDlOptions) ValidateRankingArgs(mode, date string) {
+	if mode == "" {
+		return
+	}
+	pixivcommon.ValidateRankingArgs(mode, date, p.RatingMode, p.ArtworkType)
+}
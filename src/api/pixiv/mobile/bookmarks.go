@@ -0,0 +1,40 @@
+package pixivmobile
+
+import (
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// GetBookmarks returns the artworks that the user has bookmarked with the visibility
+// and optionally with the bookmark tag within the page range to be downloaded.
+func (pixiv *PixivMobile) GetBookmarks(visibility, tag, pageNum, downloadPath string) ([]*request.ToDownload, []*models.Ugoira) {
+	minPage, maxPage, hasMax, err := utils.GetMinMaxFromStr(pageNum)
+	if err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+		return nil, nil
+	}
+
+	var argsSlice []*pagedArgs
+	for _, restrict := range pixivcommon.GetBookmarkVisibilities(visibility) {
+		params := map[string]string{
+			"user_id":  pixiv.userId,
+			"restrict": restrict,
+			"filter":   "for_ios",
+		}
+		if tag != "" {
+			params["tag"] = tag
+		}
+		argsSlice = append(argsSlice, &pagedArgs{
+			url:         pixiv.baseUrl + "/v1/user/bookmarks/illust",
+			params:      params,
+			desc:        "your " + restrict + " bookmarks",
+			artworkType: "all",
+			minPage:     minPage,
+			maxPage:     maxPage,
+			hasMax:      hasMax,
+		})
+	}
+	return pixiv.getPagedArtworksWithSpinner("your bookmarks", downloadPath, argsSlice...)
+}
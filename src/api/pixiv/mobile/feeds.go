@@ -0,0 +1,211 @@
+package pixivmobile
+
+import (
+	"fmt"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Ranking modes of Pixiv's mobile API for the ranking modes of Pixiv's web ranking page
+var mobileRankingModes = map[string]string{
+	"daily":      "day",
+	"weekly":     "week",
+	"monthly":    "month",
+	"rookie":     "week_rookie",
+	"original":   "week_original",
+	"male":       "day_male",
+	"female":     "day_female",
+	"daily_r18":  "day_r18",
+	"weekly_r18": "week_r18",
+	"male_r18":   "day_male_r18",
+	"female_r18": "day_female_r18",
+}
+
+// pagedArgs contains the arguments for getting the artworks
+// from the pages of the paginated results of Pixiv's mobile API.
+type pagedArgs struct {
+	url    string
+	params map[string]string
+
+	// description of the results for the error messages, e.g. "your public bookmarks"
+	desc string
+
+	// artworkType is used to filter the artworks in the results, "illust", "manga", or "all"
+	artworkType string
+
+	minPage int
+	maxPage int
+	hasMax  bool
+
+	// isSeries is true if the results are the novels of a novel series in their order in the series
+	isSeries bool
+}
+
+// filterArtworksByType removes the artworks from the results that are not of the artwork type.
+func filterArtworksByType(resJson *models.PixivMobileArtworksJson, artworkType string) {
+	if artworkType == "all" {
+		return
+	}
+
+	filtered := resJson.Illusts[:0]
+	for _, artwork := range resJson.Illusts {
+		isManga := artwork.Type == "manga"
+		if isManga == (artworkType == "manga") {
+			filtered = append(filtered, artwork)
+		}
+	}
+	resJson.Illusts = filtered
+}
+
+// getPagedArtworks returns the artworks within the page range of the paginated results
+// by following the "next_url" of each page which contains the parameters for the next page.
+func (pixiv *PixivMobile) getPagedArtworks(args *pagedArgs, downloadPath string) ([]*request.ToDownload, []*models.Ugoira, []error) {
+	var errSlice []error
+	var ugoiraSlice []*models.Ugoira
+	var artworksToDownload []*request.ToDownload
+	params := args.params
+	page := 0
+	nextUrl := args.url
+	for nextUrl != "" {
+		page++
+		res, err := pixiv.SendRequest(
+			&request.RequestArgs{
+				Url:         nextUrl,
+				Params:      params,
+				CheckStatus: true,
+			},
+		)
+		if err != nil {
+			err = fmt.Errorf(
+				"pixiv mobile error %d: failed to get page %d of %s, more info => %v",
+				utils.CONNECTION_ERROR,
+				page,
+				args.desc,
+				err,
+			)
+			return artworksToDownload, ugoiraSlice, append(errSlice, err)
+		}
+
+		var resJson models.PixivMobileArtworksJson
+		if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
+			return artworksToDownload, ugoiraSlice, append(errSlice, err)
+		}
+
+		// the next URL already contains the parameters for the next page
+		params = nil
+		if page >= args.minPage {
+			filterArtworksByType(&resJson, args.artworkType)
+			artworks, ugoira, errS := pixiv.processMultipleArtworkJson(&resJson, downloadPath)
+			errSlice = append(errSlice, errS...)
+			artworksToDownload = append(artworksToDownload, artworks...)
+			ugoiraSlice = append(ugoiraSlice, ugoira...)
+		}
+
+		jsonNextUrl := resJson.NextUrl
+		if jsonNextUrl == nil || (args.hasMax && page >= args.maxPage) {
+			nextUrl = ""
+		} else {
+			nextUrl = *jsonNextUrl
+			pixiv.Sleep()
+		}
+	}
+	return artworksToDownload, ugoiraSlice, errSlice
+}
+
+// getPagedArtworksWithSpinner is the same as getPagedArtworks but for each of the args
+// and with a spinner showing the progress of getting the artworks of the desc.
+func (pixiv *PixivMobile) getPagedArtworksWithSpinner(desc, downloadPath string, argsSlice ...*pagedArgs) ([]*request.ToDownload, []*models.Ugoira) {
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		fmt.Sprintf("Getting %s from Pixiv's Mobile API...", desc),
+		fmt.Sprintf("Finished getting %s from Pixiv's Mobile API!", desc),
+		fmt.Sprintf("Something went wrong while getting %s from Pixiv's Mobile API!\nPlease refer to the logs for more details.", desc),
+		0,
+	)
+	progress.Start()
+	var errSlice []error
+	var ugoiraSlice []*models.Ugoira
+	var artworksToDownload []*request.ToDownload
+	for _, args := range argsSlice {
+		artworks, ugoira, errS := pixiv.getPagedArtworks(args, downloadPath)
+		errSlice = append(errSlice, errS...)
+		artworksToDownload = append(artworksToDownload, artworks...)
+		ugoiraSlice = append(ugoiraSlice, ugoira...)
+	}
+
+	hasErr := false
+	if len(errSlice) > 0 {
+		hasErr = true
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	progress.Stop(hasErr)
+	return artworksToDownload, ugoiraSlice
+}
+
+// GetRanking returns the artworks in the ranking with the mode on the date,
+// "YYYY-MM-DD" or empty for the latest ranking, within the page range to be downloaded.
+func (pixiv *PixivMobile) GetRanking(mode, date, pageNum, downloadPath string, dlOptions *PixivMobileDlOptions) ([]*request.ToDownload, []*models.Ugoira) {
+	minPage, maxPage, hasMax, err := utils.GetMinMaxFromStr(pageNum)
+	if err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+		return nil, nil
+	}
+
+	mobileMode := mobileRankingModes[mode]
+	if dlOptions.ArtworkType == "manga" {
+		mobileMode += "_manga"
+	}
+	params := map[string]string{
+		"mode":   mobileMode,
+		"filter": "for_ios",
+	}
+	if date != "" {
+		params["date"] = date
+	}
+
+	desc := fmt.Sprintf("the %s ranking", mode)
+	return pixiv.getPagedArtworksWithSpinner(
+		desc,
+		downloadPath,
+		&pagedArgs{
+			url:         pixiv.baseUrl + "/v1/illust/ranking",
+			params:      params,
+			desc:        desc,
+			artworkType: dlOptions.ArtworkType,
+			minPage:     minPage,
+			maxPage:     maxPage,
+			hasMax:      hasMax,
+		},
+	)
+}
+
+// GetFollowingFeed returns the new artworks from the users that
+// the user is following within the page range to be downloaded.
+func (pixiv *PixivMobile) GetFollowingFeed(pageNum, downloadPath string, dlOptions *PixivMobileDlOptions) ([]*request.ToDownload, []*models.Ugoira) {
+	minPage, maxPage, hasMax, err := utils.GetMinMaxFromStr(pageNum)
+	if err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+		return nil, nil
+	}
+
+	desc := "the new works from the users you are following"
+	return pixiv.getPagedArtworksWithSpinner(
+		desc,
+		downloadPath,
+		&pagedArgs{
+			url: pixiv.baseUrl + "/v2/illust/follow",
+			params: map[string]string{
+				"restrict": "all",
+			},
+			desc:        desc,
+			artworkType: dlOptions.ArtworkType,
+			minPage:     minPage,
+			maxPage:     maxPage,
+			hasMax:      hasMax,
+		},
+	)
+}
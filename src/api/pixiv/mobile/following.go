@@ -0,0 +1,87 @@
+package pixivmobile
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// getFollowingUsersLogic returns the IDs of the users that the user
+// is following with the visibility, "public" or "private".
+func (pixiv *PixivMobile) getFollowingUsersLogic(restrict string) ([]string, error) {
+	var userIds []string
+	params := map[string]string{
+		"user_id":  pixiv.userId,
+		"restrict": restrict,
+	}
+	nextUrl := pixiv.baseUrl + "/v1/user/following"
+	for nextUrl != "" {
+		res, err := pixiv.SendRequest(
+			&request.RequestArgs{
+				Url:         nextUrl,
+				Params:      params,
+				CheckStatus: true,
+			},
+		)
+		if err != nil {
+			return userIds, fmt.Errorf(
+				"pixiv mobile error %d: failed to get the %s users you are following, more info => %v",
+				utils.CONNECTION_ERROR,
+				restrict,
+				err,
+			)
+		}
+
+		var resJson models.PixivMobileFollowingJson
+		if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
+			return userIds, err
+		}
+		for _, userPreview := range resJson.UserPreviews {
+			userIds = append(userIds, strconv.Itoa(userPreview.User.Id))
+		}
+
+		// the next URL already contains the parameters for the next page
+		params = nil
+		if resJson.NextUrl == nil {
+			nextUrl = ""
+		} else {
+			nextUrl = *resJson.NextUrl
+			pixiv.Sleep()
+		}
+	}
+	return userIds, nil
+}
+
+// GetFollowingUsers returns the IDs of all the users, both public and private, that the user is following.
+func (pixiv *PixivMobile) GetFollowingUsers() []string {
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		"Getting the users you are following from Pixiv's Mobile API...",
+		"Finished getting the users you are following from Pixiv's Mobile API!",
+		"Something went wrong while getting the users you are following from Pixiv's Mobile API!\nPlease refer to the logs for more details.",
+		0,
+	)
+	progress.Start()
+	var errSlice []error
+	var userIds []string
+	for _, restrict := range []string{"public", "private"} {
+		restrictUserIds, err := pixiv.getFollowingUsersLogic(restrict)
+		if err != nil {
+			errSlice = append(errSlice, err)
+		}
+		userIds = append(userIds, restrictUserIds...)
+	}
+
+	hasErr := false
+	if len(errSlice) > 0 {
+		hasErr = true
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	progress.Stop(hasErr)
+	return userIds
+}
@@ -0,0 +1,422 @@
+package pixivmobile
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/novel"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// NOVEL_VIEWER_VERSION is the version of the novel webview of the Pixiv app
+// which embeds the text of the novel as a JSON in its HTML.
+const NOVEL_VIEWER_VERSION = "20221031_ai"
+
+// getNovelText returns the text and the uploaded images of the novel from the novel webview.
+func (pixiv *PixivMobile) getNovelText(novelId string) (*models.PixivMobileNovelTextJson, error) {
+	res, err := pixiv.SendRequest(
+		&request.RequestArgs{
+			Url: pixiv.baseUrl + "/webview/v2/novel",
+			Params: map[string]string{
+				"id":             novelId,
+				"viewer_version": NOVEL_VIEWER_VERSION,
+			},
+			CheckStatus: true,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"pixiv mobile error %d: failed to get the text of novel ID %s, more info => %v",
+			utils.CONNECTION_ERROR,
+			novelId,
+			err,
+		)
+	}
+
+	body, err := utils.ReadResBody(res)
+	if err != nil {
+		return nil, err
+	}
+
+	// the novel is in the HTML as "novel: {...},"
+	htmlContent := string(body)
+	jsonIdx := strings.Index(htmlContent, "novel: {")
+	if jsonIdx == -1 {
+		return nil, fmt.Errorf(
+			"pixiv mobile error %d: failed to find the text of novel ID %s in the novel webview",
+			utils.RESPONSE_ERROR,
+			novelId,
+		)
+	}
+
+	var textJson models.PixivMobileNovelTextJson
+	decoder := json.NewDecoder(strings.NewReader(htmlContent[jsonIdx+len("novel: "):]))
+	if err := decoder.Decode(&textJson); err != nil {
+		return nil, fmt.Errorf(
+			"pixiv mobile error %d: failed to parse the text of novel ID %s, more info => %v",
+			utils.JSON_ERROR,
+			novelId,
+			err,
+		)
+	}
+	return &textJson, nil
+}
+
+// getPixivImageUrls returns the original image URLs of the
+// "[pixivimage:]" tags in the novel which embed the pages of other artworks.
+func (pixiv *PixivMobile) getPixivImageUrls(novelId string, imageIds []string) (map[string]string, []error) {
+	var errSlice []error
+	imageUrls := make(map[string]string, len(imageIds))
+	artworks := make(map[string]*models.PixivMobileIllustJson)
+	for _, imageId := range imageIds {
+		artworkId, page := novel.SplitPixivImageId(imageId)
+		artwork, ok := artworks[artworkId]
+		if !ok {
+			res, err := pixiv.SendRequest(
+				&request.RequestArgs{
+					Url:         pixiv.baseUrl + "/v1/illust/detail",
+					Params:      map[string]string{"illust_id": artworkId},
+					CheckStatus: true,
+				},
+			)
+			if err != nil {
+				errSlice = append(errSlice, fmt.Errorf(
+					"pixiv mobile error %d: failed to get the image of artwork ID %s in novel ID %s, more info => %v",
+					utils.CONNECTION_ERROR,
+					artworkId,
+					novelId,
+					err,
+				))
+				continue
+			}
+
+			var artworkJson models.PixivMobileArtworkJson
+			if err := utils.LoadJsonFromResponse(res, &artworkJson); err != nil {
+				errSlice = append(errSlice, err)
+				continue
+			}
+			artwork = artworkJson.Illust
+			artworks[artworkId] = artwork
+			pixiv.Sleep()
+		}
+		if artwork == nil {
+			continue
+		}
+
+		if singlePageUrl := artwork.MetaSinglePage.OriginalImageUrl; singlePageUrl != "" {
+			if page == 1 {
+				imageUrls[imageId] = singlePageUrl
+			}
+		} else if page <= len(artwork.MetaPages) {
+			imageUrls[imageId] = artwork.MetaPages[page-1].ImageUrls.Original
+		}
+	}
+	return imageUrls, errSlice
+}
+
+// processNovelJson returns the details of the novel with its text
+// and the URLs of its embedded images from the novel webview.
+func (pixiv *PixivMobile) processNovelJson(novelJson *models.PixivMobileNovelJson) (*models.Novel, []error) {
+	novelId := strconv.Itoa(novelJson.Id)
+	novelInfo := &models.Novel{
+		Id:         novelId,
+		Title:      novelJson.Title,
+		Caption:    novelJson.Caption,
+		UserId:     strconv.Itoa(novelJson.User.Id),
+		UserName:   novelJson.User.Name,
+		CoverUrl:   novelJson.ImageUrls.Large,
+		CreateDate: novelJson.CreateDate,
+		Raw:        novelJson.Raw,
+	}
+	for _, tag := range novelJson.Tags {
+		novelInfo.Tags = append(novelInfo.Tags, tag.Name)
+	}
+	if novelJson.Series.Id != 0 {
+		novelInfo.SeriesId = strconv.Itoa(novelJson.Series.Id)
+		novelInfo.SeriesTitle = novelJson.Series.Title
+	}
+
+	textJson, err := pixiv.getNovelText(novelId)
+	if err != nil {
+		return nil, []error{err}
+	}
+	novelInfo.Content = textJson.Text
+
+	// the uploaded images will be an empty array instead of a map if there are none
+	if len(textJson.Images) > 0 && textJson.Images[0] == '{' {
+		var imagesJson models.PixivMobileNovelImagesJson
+		if err := json.Unmarshal(textJson.Images, &imagesJson); err != nil {
+			return nil, []error{fmt.Errorf(
+				"pixiv mobile error %d: failed to parse the images of novel ID %s, more info => %v",
+				utils.JSON_ERROR,
+				novelId,
+				err,
+			)}
+		}
+		novelInfo.UploadedImages = make(map[string]string, len(imagesJson))
+		for imageId, image := range imagesJson {
+			novelInfo.UploadedImages[imageId] = image.Urls.Original
+		}
+	}
+
+	var errSlice []error
+	if imageIds := novel.GetPixivImageIds(novelInfo.Content); len(imageIds) > 0 {
+		novelInfo.PixivImages, errSlice = pixiv.getPixivImageUrls(novelId, imageIds)
+	}
+	return novelInfo, errSlice
+}
+
+// processMultipleNovelJson is the same as processNovelJson but for multiple novels.
+//
+// If the novels are from a novel series, seriesOffset is added to the index of each novel for its order in the series.
+func (pixiv *PixivMobile) processMultipleNovelJson(novelJsons []*models.PixivMobileNovelJson, isSeries bool, seriesOffset int) ([]*models.Novel, []error) {
+	var errSlice []error
+	var novels []*models.Novel
+	for idx, novelJson := range novelJsons {
+		novelInfo, errS := pixiv.processNovelJson(novelJson)
+		errSlice = append(errSlice, errS...)
+		if novelInfo != nil {
+			if isSeries {
+				novelInfo.SeriesOrder = seriesOffset + idx + 1
+			}
+			novels = append(novels, novelInfo)
+		}
+		pixiv.Sleep()
+	}
+	return novels, errSlice
+}
+
+// getNovelDetails returns the details of the novel ID with its text.
+func (pixiv *PixivMobile) getNovelDetails(novelId string) (*models.Novel, []error) {
+	res, err := pixiv.SendRequest(
+		&request.RequestArgs{
+			Url:         pixiv.baseUrl + "/v2/novel/detail",
+			Params:      map[string]string{"novel_id": novelId},
+			CheckStatus: true,
+		},
+	)
+	if err != nil {
+		return nil, []error{fmt.Errorf(
+			"pixiv mobile error %d: failed to get the details of novel ID %s, more info => %v",
+			utils.CONNECTION_ERROR,
+			novelId,
+			err,
+		)}
+	}
+
+	var novelJson models.PixivMobileNovelDetailJson
+	if err := utils.LoadJsonFromResponse(res, &novelJson); err != nil {
+		return nil, []error{err}
+	}
+	if novelJson.Novel == nil {
+		return nil, nil
+	}
+	return pixiv.processNovelJson(novelJson.Novel)
+}
+
+// GetMultipleNovelDetails returns the details of the novels to be processed.
+func (pixiv *PixivMobile) GetMultipleNovelDetails(novelIds []string) []*models.Novel {
+	novelIdsLen := len(novelIds)
+	if novelIdsLen == 0 {
+		return nil
+	}
+
+	baseMsg := "Getting novel details from Pixiv's Mobile API [%d/" + fmt.Sprintf("%d]...", novelIdsLen)
+	progress := spinner.New(
+		spinner.JSON_SPINNER,
+		"fgHiYellow",
+		fmt.Sprintf(
+			baseMsg,
+			0,
+		),
+		fmt.Sprintf(
+			"Finished getting %d novel details from Pixiv's Mobile API!",
+			novelIdsLen,
+		),
+		fmt.Sprintf(
+			"Something went wrong while getting %d novel details from Pixiv's Mobile API!\nPlease refer to the logs for more details.",
+			novelIdsLen,
+		),
+		novelIdsLen,
+	)
+	progress.Start()
+	var errSlice []error
+	var novels []*models.Novel
+	for idx, novelId := range novelIds {
+		novelInfo, errS := pixiv.getNovelDetails(novelId)
+		errSlice = append(errSlice, errS...)
+		if novelInfo != nil {
+			novels = append(novels, novelInfo)
+		}
+
+		progress.MsgIncrement(baseMsg)
+		if idx != novelIdsLen-1 {
+			pixiv.Sleep()
+		}
+	}
+
+	hasErr := false
+	if len(errSlice) > 0 {
+		hasErr = true
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	progress.Stop(hasErr)
+	return novels
+}
+
+// getPagedNovels returns the novels within the page range of the paginated results
+// by following the "next_url" of each page which contains the parameters for the next page.
+func (pixiv *PixivMobile) getPagedNovels(args *pagedArgs) ([]*models.Novel, []error) {
+	var errSlice []error
+	var novels []*models.Novel
+	params := args.params
+	page := 0
+	novelsCount := 0
+	nextUrl := args.url
+	for nextUrl != "" {
+		page++
+		res, err := pixiv.SendRequest(
+			&request.RequestArgs{
+				Url:         nextUrl,
+				Params:      params,
+				CheckStatus: true,
+			},
+		)
+		if err != nil {
+			err = fmt.Errorf(
+				"pixiv mobile error %d: failed to get page %d of %s, more info => %v",
+				utils.CONNECTION_ERROR,
+				page,
+				args.desc,
+				err,
+			)
+			return novels, append(errSlice, err)
+		}
+
+		var resJson models.PixivMobileNovelsJson
+		if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
+			return novels, append(errSlice, err)
+		}
+
+		// the next URL already contains the parameters for the next page
+		params = nil
+		if page >= args.minPage {
+			pageNovels, errS := pixiv.processMultipleNovelJson(resJson.Novels, args.isSeries, novelsCount)
+			errSlice = append(errSlice, errS...)
+			novels = append(novels, pageNovels...)
+		}
+		novelsCount += len(resJson.Novels)
+
+		jsonNextUrl := resJson.NextUrl
+		if jsonNextUrl == nil || (args.hasMax && page >= args.maxPage) {
+			nextUrl = ""
+		} else {
+			nextUrl = *jsonNextUrl
+			pixiv.Sleep()
+		}
+	}
+	return novels, errSlice
+}
+
+// getPagedNovelsWithSpinner is the same as getPagedNovels but for each of the args
+// and with a spinner showing the progress of getting the novels of the desc.
+func (pixiv *PixivMobile) getPagedNovelsWithSpinner(desc string, argsSlice ...*pagedArgs) []*models.Novel {
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		fmt.Sprintf("Getting %s from Pixiv's Mobile API...", desc),
+		fmt.Sprintf("Finished getting %s from Pixiv's Mobile API!", desc),
+		fmt.Sprintf("Something went wrong while getting %s from Pixiv's Mobile API!\nPlease refer to the logs for more details.", desc),
+		0,
+	)
+	progress.Start()
+	var errSlice []error
+	var novels []*models.Novel
+	for _, args := range argsSlice {
+		pagedNovels, errS := pixiv.getPagedNovels(args)
+		errSlice = append(errSlice, errS...)
+		novels = append(novels, pagedNovels...)
+	}
+
+	hasErr := false
+	if len(errSlice) > 0 {
+		hasErr = true
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	progress.Stop(hasErr)
+	return novels
+}
+
+// GetMultipleNovelSeries returns all the novels in the novel series to be processed.
+func (pixiv *PixivMobile) GetMultipleNovelSeries(seriesIds []string) []*models.Novel {
+	var argsSlice []*pagedArgs
+	for _, seriesId := range seriesIds {
+		argsSlice = append(argsSlice, &pagedArgs{
+			url:      pixiv.baseUrl + "/v2/novel/series",
+			params:   map[string]string{"series_id": seriesId},
+			desc:     fmt.Sprintf("novel series ID %s", seriesId),
+			isSeries: true,
+		})
+	}
+	return pixiv.getPagedNovelsWithSpinner("the novels from novel series", argsSlice...)
+}
+
+// GetMultipleUserNovels returns the novels of the users within the page ranges to be processed.
+func (pixiv *PixivMobile) GetMultipleUserNovels(userIds, pageNums []string) []*models.Novel {
+	var errSlice []error
+	var argsSlice []*pagedArgs
+	for idx, userId := range userIds {
+		minPage, maxPage, hasMax, err := utils.GetMinMaxFromStr(pageNums[idx])
+		if err != nil {
+			errSlice = append(errSlice, err)
+			continue
+		}
+		argsSlice = append(argsSlice, &pagedArgs{
+			url:     pixiv.baseUrl + "/v1/user/novels",
+			params:  map[string]string{"user_id": userId},
+			desc:    fmt.Sprintf("the novels of user ID %s", userId),
+			minPage: minPage,
+			maxPage: maxPage,
+			hasMax:  hasMax,
+		})
+	}
+	if len(errSlice) > 0 {
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	return pixiv.getPagedNovelsWithSpinner("the novels from user(s)", argsSlice...)
+}
+
+// NovelTagSearch returns the novels in the search results of the tags within the page ranges to be processed.
+func (pixiv *PixivMobile) NovelTagSearch(tagNames, pageNums []string, dlOptions *PixivMobileDlOptions) []*models.Novel {
+	var errSlice []error
+	var argsSlice []*pagedArgs
+	for idx, tagName := range tagNames {
+		minPage, maxPage, hasMax, err := utils.GetMinMaxFromStr(pageNums[idx])
+		if err != nil {
+			errSlice = append(errSlice, err)
+			continue
+		}
+		argsSlice = append(argsSlice, &pagedArgs{
+			url: pixiv.baseUrl + "/v1/search/novel",
+			params: map[string]string{
+				"word":          tagName,
+				"search_target": dlOptions.SearchMode,
+				"sort":          dlOptions.SortOrder,
+				"filter":        "for_ios",
+			},
+			desc:    fmt.Sprintf("the novel search results for %q", tagName),
+			minPage: minPage,
+			maxPage: maxPage,
+			hasMax:  hasMax,
+		})
+	}
+	if len(errSlice) > 0 {
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	return pixiv.getPagedNovelsWithSpinner("the novels from tag search(es)", argsSlice...)
+}
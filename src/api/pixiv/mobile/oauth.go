@@ -167,6 +167,7 @@ func (pixiv *PixivMobile) refreshAccessToken() error {
 	}
 
 	expiresIn := oauthJson.ExpiresIn - 15 // usually 3600 but minus 15 seconds to be safe
+	pixiv.userId = oauthJson.User.Id
 	pixiv.accessTokenMap.accessToken = oauthJson.AccessToken
 	pixiv.accessTokenMap.expiresAt = time.Now().Add(time.Duration(expiresIn) * time.Second)
 	return nil
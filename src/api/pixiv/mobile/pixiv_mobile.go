@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 	"github.com/fatih/color"
@@ -23,8 +24,12 @@ type PixivMobile struct {
 	redirectUri  string
 	refreshToken string
 
+	// userId is the ID of the user of the refresh token
+	userId string
+
 	// User given arguments
 	apiTimeout int
+	configs    *configs.Config
 
 	// Access token information
 	accessTokenMu  sync.Mutex
@@ -101,13 +106,14 @@ func (pixiv *PixivMobile) SendRequest(reqArgs *request.RequestArgs) (*http.Respo
 	reqArgs.Http2 = !useHttp3
 	reqArgs.ValidateArgs()
 
-	req, err := http.NewRequest(reqArgs.Method, reqArgs.Url, nil)
+	req, err := http.NewRequestWithContext(reqArgs.Context, reqArgs.Method, reqArgs.Url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshed, err := pixiv.refreshTokenIfReq()
-	if err != nil {
+	// the access token is refreshed before the headers are set
+	// so the request will be sent with the refreshed access token
+	if _, err := pixiv.refreshTokenIfReq(); err != nil {
 		return nil, err
 	}
 
@@ -119,20 +125,48 @@ func (pixiv *PixivMobile) SendRequest(reqArgs *request.RequestArgs) (*http.Respo
 	var res *http.Response
 	client := request.GetHttpClient(reqArgs)
 	client.Timeout = time.Duration(reqArgs.Timeout) * time.Second
-	for i := 1; i <= utils.RETRY_COUNTER; i++ {
+	policy := reqArgs.RetryPolicy
+	attempts := 0
+	for i := 1; i <= policy.MaxAttempts; i++ {
+		attempts = i
+		if err = request.WaitForHost(req.Context(), reqArgs.Url); err != nil {
+			return nil, err
+		}
+
 		res, err = client.Do(req)
 		if err == nil {
-			if refreshed {
-				continue
-			} else if res.StatusCode == 200 || !reqArgs.CheckStatus {
+			if request.IsSuccessStatus(res.StatusCode) || !reqArgs.CheckStatus {
 				return res, nil
 			}
+			res.Body.Close()
+			if !policy.IsRetryableStatus(res.StatusCode) {
+				break
+			}
+		} else if !policy.RetryTransportErrors {
+			break
+		}
+
+		if i < policy.MaxAttempts {
+			delay, canRetry := policy.GetDelay(i, res)
+			if !canRetry {
+				break
+			}
+			if err := request.SleepWithContext(req.Context(), delay); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err == nil && res != nil {
+		return nil, &request.StatusError{
+			Url:        reqArgs.Url,
+			StatusCode: res.StatusCode,
+			Status:     res.Status,
+			Attempts:   attempts,
 		}
-		time.Sleep(utils.GetRandomDelay())
 	}
 	return nil, fmt.Errorf(
-		"request to %s failed after %d retries",
+		"request to %s failed after %d attempt(s)",
 		reqArgs.Url,
-		utils.RETRY_COUNTER,
+		attempts,
 	)
 }
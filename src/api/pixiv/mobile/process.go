@@ -1,10 +1,15 @@
 package pixivmobile
 
 import (
+	"fmt"
 	"strconv"
 	"path/filepath"
+	"time"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/manifest"
+	"github.com/KJHJason/Cultured-Downloader-CLI/metadata"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
@@ -23,11 +28,43 @@ func (pixiv *PixivMobile) processArtworkJson(artworkJson *models.PixivMobileIllu
 		filepath.Join(downloadPath, utils.PIXIV_TITLE), illustratorName, artworkId, artworkTitle,
 	)
 
+	historyPost := &history.Post{
+		Site:      utils.PIXIV,
+		CreatorId: strconv.Itoa(artworkJson.User.Id),
+		PostId:    artworkId,
+	}
+	createdAt, _ := time.Parse(time.RFC3339, artworkJson.CreateDate)
+	pathVars := &utils.PathVars{
+		Site:        utils.PIXIV,
+		CreatorName: illustratorName,
+		CreatorId:   historyPost.CreatorId,
+		PostId:      artworkId,
+		PostTitle:   artworkTitle,
+		PublishedAt: createdAt,
+	}
+	postMetadata := &metadata.Post{
+		Site:        utils.PIXIV,
+		Url:         fmt.Sprintf("%s/artworks/%s", utils.PIXIV_URL, artworkId),
+		PostId:      artworkId,
+		Title:       artworkTitle,
+		Body:        artworkJson.Caption,
+		CreatorId:   historyPost.CreatorId,
+		CreatorName: illustratorName,
+		PublishedAt: metadata.ParseTime(time.RFC3339, artworkJson.CreateDate),
+		Raw:         artworkJson.Raw,
+	}
+	for _, tag := range artworkJson.Tags {
+		postMetadata.Tags = append(postMetadata.Tags, tag.Name)
+	}
+	metadata.Save(postMetadata, artworkFolderPath, pathVars, pixiv.configs)
+
 	if artworkType == "ugoira" {
 		ugoiraInfo, err := pixiv.getUgoiraMetadata(artworkId, artworkFolderPath)
 		if err != nil {
 			return nil, nil, err
 		}
+		ugoiraInfo.Post = historyPost
+		ugoiraInfo.PathVars = pathVars.ForFile(utils.UGOIRA_KIND, 1)
 		return nil, ugoiraInfo, nil
 	}
 
@@ -37,6 +74,8 @@ func (pixiv *PixivMobile) processArtworkJson(artworkJson *models.PixivMobileIllu
 		artworksToDownload = append(artworksToDownload, &request.ToDownload{
 			Url:      singlePageImageUrl,
 			FilePath: artworkFolderPath,
+			Post:     historyPost,
+			Expected: &manifest.Expected{Width: artworkJson.Width, Height: artworkJson.Height},
 		})
 	} else {
 		for _, image := range artworkJson.MetaPages {
@@ -44,9 +83,11 @@ func (pixiv *PixivMobile) processArtworkJson(artworkJson *models.PixivMobileIllu
 			artworksToDownload = append(artworksToDownload, &request.ToDownload{
 				Url:      imageUrl,
 				FilePath: artworkFolderPath,
+				Post:     historyPost,
 			})
 		}
 	}
+	request.SetPathVars(artworksToDownload, pathVars, artworkFolderPath, utils.ARTWORK_KIND)
 	return artworksToDownload, nil, nil
 }
 
@@ -0,0 +1,202 @@
+package pixivmobile
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/manga"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// getMangaSeriesIllusts returns the title of the manga series and its artworks
+// from the newest to the oldest as the mobile API does not return their order in the series.
+func (pixiv *PixivMobile) getMangaSeriesIllusts(seriesId string) (string, []*models.PixivMobileIllustJson, error) {
+	var seriesTitle string
+	var illusts []*models.PixivMobileIllustJson
+	seenIllusts := make(map[int]struct{})
+	addIllust := func(illust *models.PixivMobileIllustJson) {
+		if illust == nil {
+			return
+		}
+		if _, ok := seenIllusts[illust.Id]; !ok {
+			seenIllusts[illust.Id] = struct{}{}
+			illusts = append(illusts, illust)
+		}
+	}
+
+	params := map[string]string{"illust_series_id": seriesId}
+	page := 0
+	nextUrl := pixiv.baseUrl + "/v1/illust/series"
+	for nextUrl != "" {
+		page++
+		res, err := pixiv.SendRequest(
+			&request.RequestArgs{
+				Url:         nextUrl,
+				Params:      params,
+				CheckStatus: true,
+			},
+		)
+		if err != nil {
+			return "", nil, fmt.Errorf(
+				"pixiv mobile error %d: failed to get page %d of manga series ID %s, more info => %v",
+				utils.CONNECTION_ERROR,
+				page,
+				seriesId,
+				err,
+			)
+		}
+
+		var resJson models.PixivMobileMangaSeriesJson
+		if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
+			return "", nil, err
+		}
+		seriesTitle = resJson.IllustSeriesDetail.Title
+		addIllust(resJson.IllustSeriesFirstIllust)
+		for _, illust := range resJson.Illusts {
+			addIllust(illust)
+		}
+
+		// the next URL already contains the parameters for the next page
+		params = nil
+		if resJson.NextUrl == nil {
+			nextUrl = ""
+		} else {
+			nextUrl = *resJson.NextUrl
+			pixiv.Sleep()
+		}
+	}
+
+	return seriesTitle, illusts, nil
+}
+
+// getMangaSeriesOrders returns the order of the artworks in the manga series from Pixiv's web API.
+func (pixiv *PixivMobile) getMangaSeriesOrders(seriesId string) (map[string]int, error) {
+	series, err := manga.GetMangaSeries(
+		seriesId,
+		func(url string, params map[string]string) *request.RequestArgs {
+			useHttp3 := utils.IsHttp3Supported(utils.PIXIV, true)
+			return &request.RequestArgs{
+				Url:         url,
+				Method:      "GET",
+				Headers:     pixivcommon.GetPixivRequestHeaders(),
+				Params:      params,
+				CheckStatus: true,
+				UserAgent:   pixiv.configs.UserAgent,
+				Http2:       !useHttp3,
+				Http3:       useHttp3,
+			}
+		},
+		pixiv.Sleep,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return series.Orders, nil
+}
+
+// sortMangaSeriesIllusts sorts the artworks by their order in the series.
+//
+// If the orders could not be retrieved, the artworks are sorted by their IDs which are incrementing
+// and any artworks without an order are placed after the ordered ones.
+func sortMangaSeriesIllusts(illusts []*models.PixivMobileIllustJson, orders map[string]int) {
+	sort.SliceStable(illusts, func(i, j int) bool {
+		orderI, okI := orders[strconv.Itoa(illusts[i].Id)]
+		orderJ, okJ := orders[strconv.Itoa(illusts[j].Id)]
+		if okI && okJ {
+			return orderI < orderJ
+		}
+		if okI != okJ {
+			return okI
+		}
+		return illusts[i].Id < illusts[j].Id
+	})
+}
+
+// GetMultipleMangaSeries returns the images and Ugoira of all the chapters of the manga series
+// to download and the details of the chapters in their order in each series.
+func (pixiv *PixivMobile) GetMultipleMangaSeries(seriesIds []string, downloadPath string) ([]*request.ToDownload, []*models.Ugoira, []*models.MangaChapter) {
+	seriesIdsLen := len(seriesIds)
+	baseMsg := "Getting chapters from manga series on Pixiv's Mobile API [%d/" + fmt.Sprintf("%d]...", seriesIdsLen)
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		fmt.Sprintf(
+			baseMsg,
+			0,
+		),
+		fmt.Sprintf(
+			"Finished getting chapters from %d manga series on Pixiv's Mobile API!",
+			seriesIdsLen,
+		),
+		fmt.Sprintf(
+			"Something went wrong while getting chapters from %d manga series on Pixiv's Mobile API!\nPlease refer to the logs for more details.",
+			seriesIdsLen,
+		),
+		seriesIdsLen,
+	)
+	progress.Start()
+	var errSlice []error
+	var ugoiraSlice []*models.Ugoira
+	var artworksToDownload []*request.ToDownload
+	var chapters []*models.MangaChapter
+	seenArtworks := make(map[int]struct{})
+	for idx, seriesId := range seriesIds {
+		seriesTitle, illusts, err := pixiv.getMangaSeriesIllusts(seriesId)
+		if err != nil {
+			errSlice = append(errSlice, err)
+		}
+
+		var orders map[string]int
+		if len(illusts) > 0 {
+			pixiv.Sleep()
+			orders, err = pixiv.getMangaSeriesOrders(seriesId)
+			if err != nil {
+				// the chapters are still downloaded but sorted by their artwork IDs without their numbers
+				errSlice = append(errSlice, err)
+			}
+		}
+		sortMangaSeriesIllusts(illusts, orders)
+
+		var uniqueIllusts []*models.PixivMobileIllustJson
+		for _, illust := range illusts {
+			chapter := manga.ChapterFromMobileIllust(illust)
+			chapter.SeriesId = seriesId
+			chapter.Order = orders[strconv.Itoa(illust.Id)]
+			if seriesTitle != "" {
+				chapter.SeriesTitle = seriesTitle
+			}
+			if _, ok := seenArtworks[illust.Id]; ok {
+				continue
+			}
+			seenArtworks[illust.Id] = struct{}{}
+			chapters = append(chapters, chapter)
+			uniqueIllusts = append(uniqueIllusts, illust)
+		}
+
+		artworks, ugoira, errS := pixiv.processMultipleArtworkJson(
+			&models.PixivMobileArtworksJson{Illusts: uniqueIllusts},
+			downloadPath,
+		)
+		errSlice = append(errSlice, errS...)
+		artworksToDownload = append(artworksToDownload, artworks...)
+		ugoiraSlice = append(ugoiraSlice, ugoira...)
+
+		if idx != seriesIdsLen-1 {
+			pixiv.Sleep()
+		}
+		progress.MsgIncrement(baseMsg)
+	}
+
+	hasErr := false
+	if len(errSlice) > 0 {
+		hasErr = true
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	progress.Stop(hasErr)
+	return artworksToDownload, ugoiraSlice, chapters
+}
@@ -1,9 +1,16 @@
 package models
 
+import (
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
 type Ugoira struct {
 	Url      string
 	FilePath string
 	Frames   map[string]int64
+	Post     *history.Post
+	PathVars *utils.PathVars
 }
 
 type UgoiraFramesJson []struct {
@@ -0,0 +1,24 @@
+package models
+
+// MangaChapter contains the details of an artwork in a Pixiv manga series from either the web or mobile API
+type MangaChapter struct {
+	ArtworkId string
+	Title     string
+	Caption   string
+	UserId    string
+	UserName  string
+	Tags      []string
+	PageCount int
+
+	// CreateDate is in the RFC3339 format
+	CreateDate string
+
+	SeriesId    string
+	SeriesTitle string
+
+	// Order is the chapter number of the artwork in the series starting from 1
+	Order int
+
+	// IsUgoira is true if the chapter is a Ugoira which cannot be packaged as a CBZ file
+	IsUgoira bool
+}
@@ -0,0 +1,35 @@
+package models
+
+import "encoding/json"
+
+// Novel contains the details of a Pixiv novel from either the web or mobile API
+type Novel struct {
+	Id       string
+	Title    string
+	Caption  string
+	UserId   string
+	UserName string
+	CoverUrl string
+	Tags     []string
+
+	// Content is the text of the novel with Pixiv's markup like "[newpage]"
+	Content string
+
+	// CreateDate and UploadDate are in the RFC3339 format
+	CreateDate string
+	UploadDate string
+
+	// SeriesId is empty if the novel is not part of a series
+	SeriesId    string
+	SeriesTitle string
+	SeriesOrder int
+
+	// UploadedImages maps the IDs in "[uploadedimage:<id>]" to the image URLs
+	UploadedImages map[string]string
+
+	// PixivImages maps the IDs in "[pixivimage:<id>]" or "[pixivimage:<id>-<page>]" to the image URLs
+	PixivImages map[string]string
+
+	// Raw is the raw JSON of the novel for the metadata file
+	Raw json.RawMessage
+}
@@ -1,8 +1,13 @@
 package models
 
+import "encoding/json"
+
 type PixivOauthJson struct {
 	AccessToken string `json:"access_token"`
 	ExpiresIn   float64 `json:"expires_in"`
+	User        struct {
+		Id string `json:"id"`
+	} `json:"user"`
 }
 
 type PixivOauthFlowJson struct {
@@ -19,11 +24,20 @@ type UgoiraJson struct {
 }
 
 type PixivMobileIllustJson struct {
-	Id    int    `json:"id"`
-	Title string `json:"title"`
-	Type  string `json:"type"`
+	Id     int    `json:"id"`
+	Title  string `json:"title"`
+	Type   string `json:"type"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+
+	CreateDate string `json:"create_date"`
+	Caption    string `json:"caption"`
+	Tags       []struct {
+		Name string `json:"name"`
+	} `json:"tags"`
 
 	User struct {
+		Id    int    `json:"id"`
 		Name  string `json:"name"`
 	} `json:"user"`
 
@@ -36,6 +50,25 @@ type PixivMobileIllustJson struct {
 			Original string `json:"original"`
 		} `json:"image_urls"`
 	} `json:"meta_pages"`
+	PageCount int `json:"page_count"`
+
+	// Series is nil if the artwork is not part of a manga series
+	Series *struct {
+		Id    int    `json:"id"`
+		Title string `json:"title"`
+	} `json:"series"`
+
+	// Raw is the raw JSON of the artwork for the metadata file
+	Raw json.RawMessage `json:"-"`
+}
+
+func (p *PixivMobileIllustJson) UnmarshalJSON(data []byte) error {
+	type pixivMobileIllustJson PixivMobileIllustJson
+	if err := json.Unmarshal(data, (*pixivMobileIllustJson)(p)); err != nil {
+		return err
+	}
+	p.Raw = append(json.RawMessage(nil), data...)
+	return nil
 }
 
 type PixivMobileArtworkJson struct {
@@ -45,3 +78,81 @@ type PixivMobileArtworksJson struct {
 	Illusts []*PixivMobileIllustJson `json:"illusts"`
 	NextUrl *string                  `json:"next_url"`
 }
+
+type PixivMobileFollowingJson struct {
+	UserPreviews []struct {
+		User struct {
+			Id   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"user"`
+	} `json:"user_previews"`
+	NextUrl *string `json:"next_url"`
+}
+
+type PixivMobileMangaSeriesJson struct {
+	IllustSeriesDetail struct {
+		Id    int    `json:"id"`
+		Title string `json:"title"`
+	} `json:"illust_series_detail"`
+	IllustSeriesFirstIllust *PixivMobileIllustJson   `json:"illust_series_first_illust"`
+	Illusts                 []*PixivMobileIllustJson `json:"illusts"`
+	NextUrl                 *string                  `json:"next_url"`
+}
+
+type PixivMobileNovelJson struct {
+	Id         int    `json:"id"`
+	Title      string `json:"title"`
+	Caption    string `json:"caption"`
+	CreateDate string `json:"create_date"`
+	Tags       []struct {
+		Name string `json:"name"`
+	} `json:"tags"`
+	ImageUrls struct {
+		Large string `json:"large"`
+	} `json:"image_urls"`
+	User struct {
+		Id   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"user"`
+
+	// Series is an empty object if the novel is not part of a series
+	Series struct {
+		Id    int    `json:"id"`
+		Title string `json:"title"`
+	} `json:"series"`
+
+	// Raw is the raw JSON of the novel for the metadata file
+	Raw json.RawMessage `json:"-"`
+}
+
+func (p *PixivMobileNovelJson) UnmarshalJSON(data []byte) error {
+	type pixivMobileNovelJson PixivMobileNovelJson
+	if err := json.Unmarshal(data, (*pixivMobileNovelJson)(p)); err != nil {
+		return err
+	}
+	p.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+type PixivMobileNovelDetailJson struct {
+	Novel *PixivMobileNovelJson `json:"novel"`
+}
+
+type PixivMobileNovelsJson struct {
+	Novels  []*PixivMobileNovelJson `json:"novels"`
+	NextUrl *string                 `json:"next_url"`
+}
+
+// PixivMobileNovelTextJson is the JSON of the novel in the HTML of the novel webview
+type PixivMobileNovelTextJson struct {
+	Text string `json:"text"`
+
+	// Images is a map of the uploaded images in the novel or an empty array if there are none
+	Images json.RawMessage `json:"images"`
+}
+
+type PixivMobileNovelImagesJson map[string]struct {
+	Urls struct {
+		Original string `json:"original"`
+	} `json:"urls"`
+}
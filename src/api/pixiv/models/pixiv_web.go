@@ -1,11 +1,43 @@
 package models
 
+import "encoding/json"
+
 type ArtworkDetails struct {
 	Body struct {
+		UserId     string `json:"userId"`
 		UserName   string `json:"userName"`
 		Title      string `json:"title"`
 		IllustType int64  `json:"illustType"`
+		CreateDate string `json:"createDate"`
+
+		Description string `json:"description"`
+		UploadDate  string `json:"uploadDate"`
+		Tags        struct {
+			Tags []struct {
+				Tag string `json:"tag"`
+			} `json:"tags"`
+		} `json:"tags"`
+		PageCount int `json:"pageCount"`
+
+		// SeriesNavData is nil if the artwork is not part of a manga series
+		SeriesNavData *struct {
+			SeriesId json.Number `json:"seriesId"`
+			Title    string      `json:"title"`
+			Order    int         `json:"order"`
+		} `json:"seriesNavData"`
+	}
+
+	// Raw is the raw JSON of the artwork for the metadata file
+	Raw json.RawMessage `json:"-"`
+}
+
+func (a *ArtworkDetails) UnmarshalJSON(data []byte) error {
+	type artworkDetails ArtworkDetails
+	if err := json.Unmarshal(data, (*artworkDetails)(a)); err != nil {
+		return err
 	}
+	a.Raw = append(json.RawMessage(nil), data...)
+	return nil
 }
 
 type PixivWebArtworkUgoiraJson struct {
@@ -40,9 +72,140 @@ type PixivTag struct {
 	} `json:"body"`
 }
 
+type PixivWebBookmarksJson struct {
+	Body struct {
+		Works []struct {
+			// Id is a number instead of a string for deleted artworks
+			Id       json.Number `json:"id"`
+			IsMasked bool        `json:"isMasked"`
+		} `json:"works"`
+		Total int `json:"total"`
+	} `json:"body"`
+}
+
+type PixivWebFollowingJson struct {
+	Body struct {
+		Users []struct {
+			UserId   string `json:"userId"`
+			UserName string `json:"userName"`
+		} `json:"users"`
+		Total int `json:"total"`
+	} `json:"body"`
+}
+
+type PixivWebRankingJson struct {
+	Contents []struct {
+		IllustId   int    `json:"illust_id"`
+		IllustType string `json:"illust_type"`
+	} `json:"contents"`
+
+	// Next is the next page number or false if there are no more pages
+	Next any `json:"next"`
+}
+
+type PixivWebFollowingFeedJson struct {
+	Body struct {
+		Page struct {
+			Ids        []int `json:"ids"`
+			IsLastPage bool  `json:"isLastPage"`
+		} `json:"page"`
+		Thumbnails struct {
+			Illust []struct {
+				Id         string `json:"id"`
+				IllustType int    `json:"illustType"`
+				XRestrict  int    `json:"xRestrict"`
+			} `json:"illust"`
+		} `json:"thumbnails"`
+	} `json:"body"`
+}
+
 type PixivWebIllustratorJson struct {
     Body struct {
         Illusts interface{} `json:"illusts"`
         Manga   interface{} `json:"manga"`
     } `json:"body"`
 }
+
+type PixivWebNovelJson struct {
+	Body struct {
+		Id          string `json:"id"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Content     string `json:"content"`
+		CoverUrl    string `json:"coverUrl"`
+		UserId      string `json:"userId"`
+		UserName    string `json:"userName"`
+		CreateDate  string `json:"createDate"`
+		UploadDate  string `json:"uploadDate"`
+		Tags        struct {
+			Tags []struct {
+				Tag string `json:"tag"`
+			} `json:"tags"`
+		} `json:"tags"`
+
+		SeriesNavData *struct {
+			SeriesId json.Number `json:"seriesId"`
+			Title    string      `json:"title"`
+			Order    int         `json:"order"`
+		} `json:"seriesNavData"`
+
+		TextEmbeddedImages map[string]struct {
+			Urls struct {
+				Original string `json:"original"`
+			} `json:"urls"`
+		} `json:"textEmbeddedImages"`
+	} `json:"body"`
+
+	// Raw is the raw JSON of the novel for the metadata file
+	Raw json.RawMessage `json:"-"`
+}
+
+func (n *PixivWebNovelJson) UnmarshalJSON(data []byte) error {
+	type pixivWebNovelJson PixivWebNovelJson
+	if err := json.Unmarshal(data, (*pixivWebNovelJson)(n)); err != nil {
+		return err
+	}
+	n.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+type PixivWebNovelSeriesContentJson struct {
+	Body struct {
+		SeriesContents []struct {
+			Id string `json:"id"`
+		} `json:"seriesContents"`
+	} `json:"body"`
+}
+
+type PixivWebUserNovelsJson struct {
+	Body struct {
+		// Novels is a map of the novel IDs or an empty slice if the user has no novels
+		Novels any `json:"novels"`
+	} `json:"body"`
+}
+
+type PixivWebNovelSearchJson struct {
+	Body struct {
+		Novel struct {
+			Data []struct {
+				Id string `json:"id"`
+			} `json:"data"`
+		} `json:"novel"`
+	} `json:"body"`
+}
+
+type PixivWebMangaSeriesJson struct {
+	Body struct {
+		IllustSeries []struct {
+			Id    string `json:"id"`
+			Title string `json:"title"`
+		} `json:"illustSeries"`
+		Page struct {
+			Series []struct {
+				WorkId json.Number `json:"workId"`
+				Order  int         `json:"order"`
+			} `json:"series"`
+			Total int `json:"total"`
+		} `json:"page"`
+	} `json:"body"`
+}
@@ -0,0 +1,164 @@
+package novel
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/render"
+)
+
+var (
+	// Pixiv's markup tags that are on their own in the novel text
+	BLOCK_TAG_REGEX = regexp.MustCompile(
+		`\[newpage\]|\[chapter:((?:\[\[.*?\]\]|[^\]\n])*)\]|\[pixivimage:\s*(\d+(?:-\d+)?)\]|\[uploadedimage:\s*(\d+)\]`,
+	)
+	BLOCK_TAG_CHAPTER_INDEX        = 1
+	BLOCK_TAG_PIXIV_IMAGE_INDEX    = 2
+	BLOCK_TAG_UPLOADED_IMAGE_INDEX = 3
+
+	// Pixiv's markup tags within the text of the novel
+	INLINE_TAG_REGEX = regexp.MustCompile(
+		`\[\[rb:\s*(.+?)\s*>\s*(.+?)\s*\]\]|\[\[jumpuri:\s*(.+?)\s*>\s*(.+?)\s*\]\]|\[jump:\s*(\d+)\]`,
+	)
+
+	PIXIV_IMAGE_REGEX   = regexp.MustCompile(`\[pixivimage:\s*(\d+(?:-\d+)?)\]`)
+	PARAGRAPH_SEP_REGEX = regexp.MustCompile(`\n(?:[ \t\x{3000}]*\n)+`)
+)
+
+// GetPixivImageIds returns the unique IDs in the "[pixivimage:<id>]" and
+// "[pixivimage:<id>-<page>]" tags of the novel text to get the image URLs of.
+func GetPixivImageIds(content string) []string {
+	var imageIds []string
+	seen := make(map[string]struct{})
+	for _, matched := range PIXIV_IMAGE_REGEX.FindAllStringSubmatch(content, -1) {
+		if _, ok := seen[matched[1]]; !ok {
+			seen[matched[1]] = struct{}{}
+			imageIds = append(imageIds, matched[1])
+		}
+	}
+	return imageIds
+}
+
+// SplitPixivImageId returns the artwork ID and the 1-based page number of the ID in a "[pixivimage:]" tag.
+func SplitPixivImageId(imageId string) (string, int) {
+	artworkId, page, hasPage := strings.Cut(imageId, "-")
+	if !hasPage {
+		return artworkId, 1
+	}
+	pageNum, err := strconv.Atoi(page)
+	if err != nil || pageNum < 1 {
+		return artworkId, 1
+	}
+	return artworkId, pageNum
+}
+
+// parseInlineTags returns the spans of the text with the ruby and links in Pixiv's markup.
+func parseInlineTags(text string) []*render.Span {
+	var spans []*render.Span
+	lastIdx := 0
+	for _, loc := range INLINE_TAG_REGEX.FindAllStringSubmatchIndex(text, -1) {
+		if loc[0] > lastIdx {
+			spans = append(spans, &render.Span{Text: text[lastIdx:loc[0]]})
+		}
+		lastIdx = loc[1]
+
+		switch {
+		case loc[2] != -1: // [[rb:<text> > <ruby>]]
+			spans = append(spans, &render.Span{
+				Text: text[loc[2]:loc[3]],
+				Ruby: text[loc[4]:loc[5]],
+			})
+		case loc[6] != -1: // [[jumpuri:<text> > <url>]]
+			spans = append(spans, &render.Span{
+				Text: text[loc[6]:loc[7]],
+				Link: text[loc[8]:loc[9]],
+			})
+		default: // [jump:<page>] which links to a page of the novel
+			spans = append(spans, &render.Span{Text: "(p. " + text[loc[10]:loc[11]] + ")"})
+		}
+	}
+	if lastIdx < len(text) {
+		spans = append(spans, &render.Span{Text: text[lastIdx:]})
+	}
+	return spans
+}
+
+// textToBlocks returns the paragraphs of the text which are separated by empty lines.
+//
+// Blank text like the line breaks between two block tags has no paragraphs.
+func textToBlocks(text string) []*render.Block {
+	var blocks []*render.Block
+	for _, paragraph := range PARAGRAPH_SEP_REGEX.Split(strings.Trim(text, "\n"), -1) {
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+		blocks = append(blocks, &render.Block{
+			Type:  render.PARAGRAPH_BLOCK,
+			Spans: parseInlineTags(paragraph),
+		})
+	}
+	return blocks
+}
+
+// imageBlock returns the image block of the image URL or a link to
+// the page of the image if its URL could not be retrieved.
+func imageBlock(imageUrl, name, pageUrl string) *render.Block {
+	if imageUrl == "" {
+		return &render.Block{
+			Type:  render.PARAGRAPH_BLOCK,
+			Spans: []*render.Span{{Text: name, Link: pageUrl}},
+		}
+	}
+	return &render.Block{
+		Type: render.IMAGE_BLOCK,
+		Url:  imageUrl,
+		Name: name,
+	}
+}
+
+// ParseNovelText converts the novel text with Pixiv's markup into blocks where
+// "[newpage]" is a page break, "[chapter:<title>]" is a header, "[[rb:<text> > <ruby>]]" is a ruby text,
+// and "[pixivimage:<id>]" and "[uploadedimage:<id>]" are images.
+func ParseNovelText(novel *models.Novel) []*render.Block {
+	content := strings.ReplaceAll(novel.Content, "\r\n", "\n")
+
+	var blocks []*render.Block
+	lastIdx := 0
+	for _, loc := range BLOCK_TAG_REGEX.FindAllStringSubmatchIndex(content, -1) {
+		blocks = append(blocks, textToBlocks(content[lastIdx:loc[0]])...)
+		lastIdx = loc[1]
+
+		chapterIdx := BLOCK_TAG_CHAPTER_INDEX * 2
+		pixivImageIdx := BLOCK_TAG_PIXIV_IMAGE_INDEX * 2
+		uploadedImageIdx := BLOCK_TAG_UPLOADED_IMAGE_INDEX * 2
+		switch {
+		case loc[chapterIdx] != -1:
+			blocks = append(blocks, &render.Block{
+				Type:  render.HEADER_BLOCK,
+				Spans: parseInlineTags(content[loc[chapterIdx]:loc[chapterIdx+1]]),
+			})
+		case loc[pixivImageIdx] != -1:
+			imageId := content[loc[pixivImageIdx]:loc[pixivImageIdx+1]]
+			artworkId, _ := SplitPixivImageId(imageId)
+			blocks = append(blocks, imageBlock(
+				novel.PixivImages[imageId],
+				"pixivimage:"+imageId,
+				pixivcommon.GetIllustUrl(artworkId),
+			))
+		case loc[uploadedImageIdx] != -1:
+			imageId := content[loc[uploadedImageIdx]:loc[uploadedImageIdx+1]]
+			blocks = append(blocks, imageBlock(
+				novel.UploadedImages[imageId],
+				"uploadedimage:"+imageId,
+				GetNovelUrl(novel.Id),
+			))
+		default: // [newpage]
+			blocks = append(blocks, &render.Block{Type: render.PAGE_BREAK_BLOCK})
+		}
+	}
+	blocks = append(blocks, textToBlocks(content[lastIdx:])...)
+	return blocks
+}
@@ -0,0 +1,153 @@
+package novel
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/render"
+)
+
+func TestParseNovelText(t *testing.T) {
+	tests := []struct {
+		name  string
+		novel *models.Novel
+		want  []*render.Block
+	}{
+		{
+			name:  "paragraphs separated by empty lines",
+			novel: &models.Novel{Content: "first line\nsecond line\n\n　\nnext paragraph"},
+			want: []*render.Block{
+				{Type: render.PARAGRAPH_BLOCK, Spans: []*render.Span{{Text: "first line\nsecond line"}}},
+				{Type: render.PARAGRAPH_BLOCK, Spans: []*render.Span{{Text: "next paragraph"}}},
+			},
+		},
+		{
+			name:  "windows line endings",
+			novel: &models.Novel{Content: "one\r\n\r\ntwo"},
+			want: []*render.Block{
+				{Type: render.PARAGRAPH_BLOCK, Spans: []*render.Span{{Text: "one"}}},
+				{Type: render.PARAGRAPH_BLOCK, Spans: []*render.Span{{Text: "two"}}},
+			},
+		},
+		{
+			name:  "page break and chapter",
+			novel: &models.Novel{Content: "page one\n[newpage]\n[chapter:Chapter 2]\npage two"},
+			want: []*render.Block{
+				{Type: render.PARAGRAPH_BLOCK, Spans: []*render.Span{{Text: "page one"}}},
+				{Type: render.PAGE_BREAK_BLOCK},
+				{Type: render.HEADER_BLOCK, Spans: []*render.Span{{Text: "Chapter 2"}}},
+				{Type: render.PARAGRAPH_BLOCK, Spans: []*render.Span{{Text: "page two"}}},
+			},
+		},
+		{
+			name:  "chapter with ruby",
+			novel: &models.Novel{Content: "[chapter:[[rb:漢字 > かんじ]]の章]"},
+			want: []*render.Block{
+				{Type: render.HEADER_BLOCK, Spans: []*render.Span{{Text: "漢字", Ruby: "かんじ"}, {Text: "の章"}}},
+			},
+		},
+		{
+			name:  "ruby, link, and page jump",
+			novel: &models.Novel{Content: "a [[rb:字 > じ]] b [[jumpuri:site > https://example.com]] see [jump:3]"},
+			want: []*render.Block{
+				{Type: render.PARAGRAPH_BLOCK, Spans: []*render.Span{
+					{Text: "a "},
+					{Text: "字", Ruby: "じ"},
+					{Text: " b "},
+					{Text: "site", Link: "https://example.com"},
+					{Text: " see "},
+					{Text: "(p. 3)"},
+				}},
+			},
+		},
+		{
+			name: "images with and without URLs",
+			novel: &models.Novel{
+				Id:             "100",
+				Content:        "[pixivimage:123-2]\n[uploadedimage:456]\n[uploadedimage:789]",
+				PixivImages:    map[string]string{"123-2": "https://i.pximg.net/123_p1.jpg"},
+				UploadedImages: map[string]string{"456": "https://i.pximg.net/456.jpg"},
+			},
+			want: []*render.Block{
+				{Type: render.IMAGE_BLOCK, Url: "https://i.pximg.net/123_p1.jpg", Name: "pixivimage:123-2"},
+				{Type: render.IMAGE_BLOCK, Url: "https://i.pximg.net/456.jpg", Name: "uploadedimage:456"},
+				{Type: render.PARAGRAPH_BLOCK, Spans: []*render.Span{{Text: "uploadedimage:789", Link: GetNovelUrl("100")}}},
+			},
+		},
+		{
+			name:  "missing pixiv image links to the artwork",
+			novel: &models.Novel{Content: "[pixivimage:123]"},
+			want: []*render.Block{
+				{Type: render.PARAGRAPH_BLOCK, Spans: []*render.Span{{Text: "pixivimage:123", Link: pixivcommon.GetIllustUrl("123")}}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ParseNovelText(test.novel)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseNovelText() = %s, want %s", blocksString(got), blocksString(test.want))
+			}
+		})
+	}
+}
+
+func blocksString(blocks []*render.Block) string {
+	str := "["
+	for _, block := range blocks {
+		str += "{" + block.Type + " " + block.Url + " " + block.Name
+		for _, span := range block.Spans {
+			str += " " + span.Text + "|" + span.Ruby + "|" + span.Link
+		}
+		str += "}"
+	}
+	return str + "]"
+}
+
+func TestGetPixivImageIds(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{name: "no images", content: "text only", want: nil},
+		{name: "duplicates are removed", content: "[pixivimage:1][pixivimage: 2-3]\n[pixivimage:1]", want: []string{"1", "2-3"}},
+		{name: "uploaded images are ignored", content: "[uploadedimage:4][pixivimage:5]", want: []string{"5"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := GetPixivImageIds(test.content); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("GetPixivImageIds(%q) = %v, want %v", test.content, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSplitPixivImageId(t *testing.T) {
+	tests := []struct {
+		imageId   string
+		artworkId string
+		page      int
+	}{
+		{imageId: "123", artworkId: "123", page: 1},
+		{imageId: "123-4", artworkId: "123", page: 4},
+		{imageId: "123-0", artworkId: "123", page: 1},
+		{imageId: "123-x", artworkId: "123", page: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.imageId, func(t *testing.T) {
+			artworkId, page := SplitPixivImageId(test.imageId)
+			if artworkId != test.artworkId || page != test.page {
+				t.Errorf(
+					"SplitPixivImageId(%q) = (%q, %d), want (%q, %d)",
+					test.imageId, artworkId, page, test.artworkId, test.page,
+				)
+			}
+		})
+	}
+}
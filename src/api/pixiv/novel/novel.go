@@ -0,0 +1,286 @@
+package novel
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/metadata"
+	"github.com/KJHJason/Cultured-Downloader-CLI/render"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const (
+	// NOVELS_FOLDER is the folder in the illustrator's folder where the novels are saved
+	NOVELS_FOLDER = "novels"
+
+	// NOVEL_FILENAME is the filename of the Markdown file of the novel text without the extension
+	NOVEL_FILENAME = "novel"
+)
+
+// Modes of saving the novels as EPUB files
+const (
+	EPUB_NONE   = ""
+	EPUB_NOVEL  = "novel"
+	EPUB_SERIES = "series"
+)
+
+var ACCEPTED_EPUB_MODES = []string{
+	EPUB_NONE,
+	EPUB_NOVEL,
+	EPUB_SERIES,
+}
+
+// NovelOptions is the struct that contains the
+// configs for the processing of the novels after getting their details from Pixiv.
+type NovelOptions struct {
+	// EpubMode is "novel" to save each novel as an EPUB file,
+	// "series" to save the novels of the same series as one EPUB file, or empty to not save any
+	EpubMode string
+}
+
+// ValidateArgs validates the arguments of the novel process options.
+//
+// Should be called after initialising the struct.
+func (n *NovelOptions) ValidateArgs() {
+	n.EpubMode = strings.ToLower(n.EpubMode)
+	utils.ValidateStrArgs(
+		n.EpubMode,
+		ACCEPTED_EPUB_MODES,
+		[]string{
+			fmt.Sprintf(
+				"pixiv error %d: EPUB mode %s is not allowed",
+				utils.INPUT_ERROR,
+				n.EpubMode,
+			),
+		},
+	)
+}
+
+// GetNovelUrl returns the URL of the novel on Pixiv.
+func GetNovelUrl(novelId string) string {
+	return fmt.Sprintf("%s/novel/show.php?id=%s", utils.PIXIV_URL, novelId)
+}
+
+// GetNovelSeriesUrl returns the URL of the novel series on Pixiv.
+func GetNovelSeriesUrl(seriesId string) string {
+	return fmt.Sprintf("%s/novel/series/%s", utils.PIXIV_URL, seriesId)
+}
+
+// getNovelsFolder returns the folder where the novels of the user are saved.
+func getNovelsFolder(downloadPath, userName string) string {
+	return filepath.Join(
+		downloadPath,
+		utils.PIXIV_TITLE,
+		utils.CleanPathName(userName),
+		NOVELS_FOLDER,
+	)
+}
+
+// getNovelFolder returns the folder where the novel and its images are saved.
+func getNovelFolder(downloadPath string, novel *models.Novel) string {
+	return filepath.Join(
+		getNovelsFolder(downloadPath, novel.UserName),
+		fmt.Sprintf("[%s] %s", novel.Id, utils.CleanPathName(novel.Title)),
+	)
+}
+
+// getImageFilename returns the filename of the image URL with the given name, e.g. "cover.jpg".
+func getImageFilename(imageUrl, name string) string {
+	ext := ".jpg"
+	if parsedUrl, err := url.Parse(imageUrl); err == nil && path.Ext(parsedUrl.Path) != "" {
+		ext = path.Ext(parsedUrl.Path)
+	}
+	return utils.CleanPathName(name) + ext
+}
+
+// processNovel returns the cover and the embedded images of the novel to download
+// and the document of the novel text which will be rendered after the images have been downloaded.
+func processNovel(novel *models.Novel, downloadPath string, config *configs.Config) ([]*request.ToDownload, *render.Document) {
+	novelFolderPath := getNovelFolder(downloadPath, novel)
+
+	var toDownload []*request.ToDownload
+	if novel.CoverUrl != "" {
+		toDownload = append(toDownload, &request.ToDownload{
+			Url:      novel.CoverUrl,
+			FilePath: filepath.Join(novelFolderPath, getImageFilename(novel.CoverUrl, "cover")),
+		})
+	}
+
+	imagesFolderPath := filepath.Join(novelFolderPath, utils.IMAGES_FOLDER)
+	sortedImageIds := make([]string, 0, len(novel.UploadedImages)+len(novel.PixivImages))
+	for imageId := range novel.UploadedImages {
+		sortedImageIds = append(sortedImageIds, "uploadedimage:"+imageId)
+	}
+	for imageId := range novel.PixivImages {
+		sortedImageIds = append(sortedImageIds, "pixivimage:"+imageId)
+	}
+	sort.Strings(sortedImageIds)
+	for _, name := range sortedImageIds {
+		kind, imageId, _ := strings.Cut(name, ":")
+		imageUrl := novel.UploadedImages[imageId]
+		if kind == "pixivimage" {
+			imageUrl = novel.PixivImages[imageId]
+		}
+		if imageUrl == "" {
+			continue
+		}
+		toDownload = append(toDownload, &request.ToDownload{
+			Url:      imageUrl,
+			FilePath: filepath.Join(imagesFolderPath, getImageFilename(imageUrl, kind+"_"+imageId)),
+		})
+	}
+
+	// the novels are not added to the download history as
+	// their IDs are separate from the IDs of the artworks on Pixiv
+	createdAt, _ := time.Parse(time.RFC3339, novel.CreateDate)
+	pathVars := &utils.PathVars{
+		Site:        utils.PIXIV,
+		CreatorName: novel.UserName,
+		CreatorId:   novel.UserId,
+		PostId:      novel.Id,
+		PostTitle:   novel.Title,
+		PublishedAt: createdAt,
+	}
+	request.SetPathVars(toDownload, pathVars, novelFolderPath, utils.THUMBNAIL_KIND)
+
+	novelUrl := GetNovelUrl(novel.Id)
+	metadata.Save(
+		&metadata.Post{
+			Site:        utils.PIXIV,
+			Url:         novelUrl,
+			PostId:      novel.Id,
+			Title:       novel.Title,
+			Body:        novel.Caption,
+			CreatorId:   novel.UserId,
+			CreatorName: novel.UserName,
+			PublishedAt: metadata.ParseTime(time.RFC3339, novel.CreateDate),
+			EditedAt:    metadata.ParseTime(time.RFC3339, novel.UploadDate),
+			Tags:        novel.Tags,
+			Raw:         novel.Raw,
+		},
+		novelFolderPath,
+		pathVars,
+		config,
+	)
+
+	doc := render.NewTranscript(novel.Title, novelUrl, novelFolderPath, NOVEL_FILENAME, render.MARKDOWN_FORMAT, pathVars)
+	if novel.CoverUrl != "" {
+		doc.AddBlocks(&render.Block{
+			Type: render.IMAGE_BLOCK,
+			Url:  novel.CoverUrl,
+			Name: "cover",
+		})
+	}
+	doc.AddBlocks(ParseNovelText(novel)...)
+	return toDownload, doc
+}
+
+// addSeriesBooks adds the novels of the same series as one pending EPUB file
+// with the novels sorted by their order in the series.
+func addSeriesBooks(novels []*models.Novel, docs map[string]*render.Document, downloadPath string) {
+	var seriesIds []string
+	seriesNovels := make(map[string][]*models.Novel)
+	for _, novel := range novels {
+		seriesId := novel.SeriesId
+		if seriesId == "" {
+			// novels that are not part of a series are saved on their own
+			seriesId = "novel_" + novel.Id
+		}
+		if _, ok := seriesNovels[seriesId]; !ok {
+			seriesIds = append(seriesIds, seriesId)
+		}
+		seriesNovels[seriesId] = append(seriesNovels[seriesId], novel)
+	}
+
+	for _, seriesId := range seriesIds {
+		novelsInSeries := seriesNovels[seriesId]
+		firstNovel := novelsInSeries[0]
+		if firstNovel.SeriesId == "" {
+			addNovelBook(firstNovel, docs[firstNovel.Id], downloadPath)
+			continue
+		}
+
+		sort.SliceStable(novelsInSeries, func(i, j int) bool {
+			return novelsInSeries[i].SeriesOrder < novelsInSeries[j].SeriesOrder
+		})
+		firstNovel = novelsInSeries[0]
+		seriesTitle := firstNovel.SeriesTitle
+		if seriesTitle == "" {
+			seriesTitle = firstNovel.Title
+		}
+
+		book := render.NewBook(
+			seriesTitle,
+			firstNovel.UserName,
+			GetNovelSeriesUrl(seriesId),
+			filepath.Join(
+				getNovelsFolder(downloadPath, firstNovel.UserName),
+				fmt.Sprintf("[series_%s] %s.%s", seriesId, utils.CleanPathName(seriesTitle), render.EPUB_FORMAT),
+			),
+		)
+		book.CoverUrl = firstNovel.CoverUrl
+		for _, novel := range novelsInSeries {
+			book.Chapters = append(book.Chapters, docs[novel.Id])
+		}
+		render.AddPendingBook(book)
+	}
+}
+
+// addNovelBook adds the novel as a pending EPUB file in the novel's folder.
+func addNovelBook(novel *models.Novel, doc *render.Document, downloadPath string) {
+	book := render.NewBook(
+		novel.Title,
+		novel.UserName,
+		GetNovelUrl(novel.Id),
+		filepath.Join(
+			getNovelFolder(downloadPath, novel),
+			utils.CleanPathName(novel.Title)+"."+render.EPUB_FORMAT,
+		),
+	)
+	book.CoverUrl = novel.CoverUrl
+	book.Chapters = []*render.Document{doc}
+	render.AddPendingBook(book)
+}
+
+// ProcessNovels returns the cover and embedded images of the novels to download.
+//
+// The novel text is saved as a Markdown file and as EPUB files based on the EPUB mode
+// after the images have been downloaded when render.RenderPending is called.
+func ProcessNovels(novels []*models.Novel, downloadPath string, novelOptions *NovelOptions, config *configs.Config) []*request.ToDownload {
+	var toDownload []*request.ToDownload
+	var uniqueNovels []*models.Novel
+	docs := make(map[string]*render.Document, len(novels))
+	for _, novel := range novels {
+		if _, ok := docs[novel.Id]; ok {
+			continue
+		}
+		novelToDl, doc := processNovel(novel, downloadPath, config)
+		toDownload = append(toDownload, novelToDl...)
+		uniqueNovels = append(uniqueNovels, novel)
+		docs[novel.Id] = doc
+		if !config.DryRun {
+			render.AddPending(doc)
+		}
+	}
+	if config.DryRun {
+		return toDownload
+	}
+
+	switch novelOptions.EpubMode {
+	case EPUB_NOVEL:
+		for _, novel := range uniqueNovels {
+			addNovelBook(novel, docs[novel.Id], downloadPath)
+		}
+	case EPUB_SERIES:
+		addSeriesBooks(uniqueNovels, docs, downloadPath)
+	}
+	return toDownload
+}
@@ -0,0 +1,46 @@
+package pixiv
+
+import (
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/mobile"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/web"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// hasNovels returns true if there are any novels, novel series, novel users, or novel tags to download.
+func (p *PixivDl) hasNovels() bool {
+	return len(p.NovelIds) > 0 || len(p.NovelSeriesIds) > 0 || len(p.NovelUserIds) > 0 || len(p.NovelTagNames) > 0
+}
+
+// getWebNovels returns the details of all the novels to download using Pixiv's web API.
+func (p *PixivDl) getWebNovels(dlOptions *pixivweb.PixivWebDlOptions) []*models.Novel {
+	novelIds := p.NovelIds
+	if len(p.NovelSeriesIds) > 0 {
+		novelIds = append(novelIds, pixivweb.GetMultipleNovelSeries(p.NovelSeriesIds, dlOptions)...)
+	}
+	if len(p.NovelUserIds) > 0 {
+		novelIds = append(novelIds, pixivweb.GetMultipleUserNovels(p.NovelUserIds, p.NovelUserPageNums, dlOptions)...)
+	}
+	if len(p.NovelTagNames) > 0 {
+		novelIds = append(novelIds, pixivweb.NovelTagSearch(p.NovelTagNames, p.NovelTagPageNums, dlOptions)...)
+	}
+	return pixivweb.GetMultipleNovelDetails(utils.RemoveSliceDuplicates(novelIds), dlOptions)
+}
+
+// getMobileNovels returns the details of all the novels to download using Pixiv's mobile API.
+//
+// Duplicate novels from the different sources are removed when the novels are processed.
+func (p *PixivDl) getMobileNovels(dlOptions *pixivmobile.PixivMobileDlOptions) []*models.Novel {
+	client := dlOptions.MobileClient
+	novels := client.GetMultipleNovelDetails(p.NovelIds)
+	if len(p.NovelSeriesIds) > 0 {
+		novels = append(novels, client.GetMultipleNovelSeries(p.NovelSeriesIds)...)
+	}
+	if len(p.NovelUserIds) > 0 {
+		novels = append(novels, client.GetMultipleUserNovels(p.NovelUserIds, p.NovelUserPageNums)...)
+	}
+	if len(p.NovelTagNames) > 0 {
+		novels = append(novels, client.NovelTagSearch(p.NovelTagNames, p.NovelTagPageNums, dlOptions)...)
+	}
+	return novels
+}
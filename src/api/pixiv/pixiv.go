@@ -3,11 +3,15 @@ package pixiv
 import (
 	"fmt"
 
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/manga"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/novel"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/ugoira"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/web"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/mobile"
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/render"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
@@ -21,14 +25,55 @@ func alertUser(artworksToDl []*request.ToDownload, ugoiraToDl []*models.Ugoira)
 	}
 }
 
+// filterArtworksByHistory removes the artworks that are in the download history.
+//
+// Used for the results from Pixiv's mobile API as the artwork details are retrieved along with the artwork IDs.
+func filterArtworksByHistory(artworksToDl []*request.ToDownload, ugoiraToDl []*models.Ugoira) ([]*request.ToDownload, []*models.Ugoira) {
+	skippedPosts := make(map[string]struct{})
+	isInHistory := func(post *history.Post) bool {
+		if post == nil {
+			return false
+		}
+		if _, skipped := skippedPosts[post.PostId]; skipped {
+			return true
+		}
+		if history.HasPost(utils.PIXIV, post.PostId) {
+			skippedPosts[post.PostId] = struct{}{}
+			return true
+		}
+		return false
+	}
+
+	filteredArtworks := make([]*request.ToDownload, 0, len(artworksToDl))
+	for _, artwork := range artworksToDl {
+		if !isInHistory(artwork.Post) {
+			filteredArtworks = append(filteredArtworks, artwork)
+		}
+	}
+	filteredUgoira := make([]*models.Ugoira, 0, len(ugoiraToDl))
+	for _, ugoira := range ugoiraToDl {
+		if !isInHistory(ugoira.Post) {
+			filteredUgoira = append(filteredUgoira, ugoira)
+		}
+	}
+	history.PrintSkippedMsg(utils.PIXIV, len(skippedPosts))
+	return filteredArtworks, filteredUgoira
+}
+
 // Start the download process for Pixiv
-func PixivWebDownloadProcess(pixivDl *PixivDl, pixivDlOptions *pixivweb.PixivWebDlOptions, pixivUgoiraOptions *ugoira.UgoiraOptions) {
+func PixivWebDownloadProcess(pixivDl *PixivDl, pixivDlOptions *pixivweb.PixivWebDlOptions, pixivUgoiraOptions *ugoira.UgoiraOptions, pixivNovelOptions *novel.NovelOptions, pixivMangaOptions *manga.MangaOptions) {
 	var ugoiraToDl []*models.Ugoira
 	var artworksToDl []*request.ToDownload
+	var chapters []*models.MangaChapter
+	if pixivDl.DlFollowingUsers {
+		pixivDl.addFollowingUsers(pixivweb.GetFollowingUsers(pixivDlOptions), pixivDlOptions.Configs.DryRun)
+	}
+
 	if len(pixivDl.IllustratorIds) > 0 {
 		artworkIdsSlice := pixivweb.GetMultipleIllustratorPosts(
 			pixivDl.IllustratorIds,
 			pixivDl.IllustratorPageNums,
+			pixivDl.IllustratorMaxWorks,
 			utils.DOWNLOAD_PATH,
 			pixivDlOptions,
 		)
@@ -36,6 +81,51 @@ func PixivWebDownloadProcess(pixivDl *PixivDl, pixivDlOptions *pixivweb.PixivWeb
 		pixivDl.ArtworkIds = utils.RemoveSliceDuplicates(pixivDl.ArtworkIds)
 	}
 
+	if pixivDl.BookmarkVisibility != "" {
+		bookmarkIds := pixivweb.GetBookmarks(
+			pixivDl.BookmarkVisibility,
+			pixivDl.BookmarkTag,
+			pixivDl.BookmarkPageNum,
+			pixivDlOptions,
+		)
+		pixivDl.ArtworkIds = append(pixivDl.ArtworkIds, bookmarkIds...)
+		pixivDl.ArtworkIds = utils.RemoveSliceDuplicates(pixivDl.ArtworkIds)
+	}
+
+	if pixivDl.RankingMode != "" {
+		rankingIds := pixivweb.GetRanking(
+			pixivDl.RankingMode,
+			pixivDl.RankingDate,
+			pixivDl.RankingPageNum,
+			pixivDlOptions,
+		)
+		pixivDl.ArtworkIds = append(pixivDl.ArtworkIds, rankingIds...)
+		pixivDl.ArtworkIds = utils.RemoveSliceDuplicates(pixivDl.ArtworkIds)
+	}
+
+	if pixivDl.DlFollowingFeed {
+		feedIds := pixivweb.GetFollowingFeed(
+			pixivDl.FollowingFeedPageNum,
+			pixivDlOptions,
+		)
+		pixivDl.ArtworkIds = append(pixivDl.ArtworkIds, feedIds...)
+		pixivDl.ArtworkIds = utils.RemoveSliceDuplicates(pixivDl.ArtworkIds)
+	}
+
+	if len(pixivDl.SeriesIds) > 0 {
+		var artworkSlice []*request.ToDownload
+		var ugoiraSlice []*models.Ugoira
+		artworkSlice, ugoiraSlice, chapters = pixivweb.GetMultipleMangaSeries(
+			pixivDl.SeriesIds,
+			utils.DOWNLOAD_PATH,
+			pixivMangaOptions.KeepsDownloadedChapters(),
+			pixivDlOptions,
+		)
+		artworksToDl = append(artworksToDl, artworkSlice...)
+		ugoiraToDl = append(ugoiraToDl, ugoiraSlice...)
+		pixivDl.removeChapterIds(chapters)
+	}
+
 	if len(pixivDl.ArtworkIds) > 0 {
 		artworkSlice, ugoiraSlice := pixivweb.GetMultipleArtworkDetails(
 			pixivDl.ArtworkIds,
@@ -84,6 +174,23 @@ func PixivWebDownloadProcess(pixivDl *PixivDl, pixivDlOptions *pixivweb.PixivWeb
 		progress.Stop(hasErr)
 	}
 
+	manga.AddPendingComics(
+		chapters,
+		artworksToDl,
+		utils.DOWNLOAD_PATH,
+		pixivMangaOptions,
+		pixivDlOptions.Configs,
+	)
+	if pixivDl.hasNovels() {
+		novelsToDl := novel.ProcessNovels(
+			pixivDl.getWebNovels(pixivDlOptions),
+			utils.DOWNLOAD_PATH,
+			pixivNovelOptions,
+			pixivDlOptions.Configs,
+		)
+		artworksToDl = append(artworksToDl, novelsToDl...)
+	}
+
 	if len(artworksToDl) > 0 {
 		request.DownloadUrls(
 			artworksToDl,
@@ -109,24 +216,57 @@ func PixivWebDownloadProcess(pixivDl *PixivDl, pixivDlOptions *pixivweb.PixivWeb
 		)
 	}
 
+	if pixivDlOptions.Configs.DryRun {
+		request.PrintPlan(pixivDlOptions.Configs)
+		return
+	}
+
+	// render the novels and manga series and save the sync states
+	// only after the artworks and images of the novels have been downloaded
+	render.RenderPending(pixivDlOptions.Configs)
+	history.CommitSyncStates()
 	alertUser(artworksToDl, ugoiraToDl)
 }
 
 // Start the download process for Pixiv
-func PixivMobileDownloadProcess(pixivDl *PixivDl, pixivDlOptions *pixivmobile.PixivMobileDlOptions, pixivUgoiraOptions *ugoira.UgoiraOptions) {
+func PixivMobileDownloadProcess(pixivDl *PixivDl, pixivDlOptions *pixivmobile.PixivMobileDlOptions, pixivUgoiraOptions *ugoira.UgoiraOptions, pixivNovelOptions *novel.NovelOptions, pixivMangaOptions *manga.MangaOptions) {
 	var ugoiraToDl []*models.Ugoira
 	var artworksToDl []*request.ToDownload
+	var chapters []*models.MangaChapter
+	if pixivDl.DlFollowingUsers {
+		pixivDl.addFollowingUsers(pixivDlOptions.MobileClient.GetFollowingUsers(), pixivDlOptions.Configs.DryRun)
+	}
+
 	if len(pixivDl.IllustratorIds) > 0 {
 		artworkSlice, ugoiraSlice := pixivDlOptions.MobileClient.GetMultipleIllustratorPosts(
 			pixivDl.IllustratorIds,
 			pixivDl.IllustratorPageNums,
+			pixivDl.IllustratorMaxWorks,
 			utils.DOWNLOAD_PATH,
 			pixivDlOptions.ArtworkType,
+			pixivDlOptions.Configs.Sync,
 		)
 		artworksToDl = artworkSlice
 		ugoiraToDl = ugoiraSlice
 	}
 
+	var seriesArtworksToDl []*request.ToDownload
+	var seriesUgoiraToDl []*models.Ugoira
+	if len(pixivDl.SeriesIds) > 0 {
+		seriesArtworksToDl, seriesUgoiraToDl, chapters = pixivDlOptions.MobileClient.GetMultipleMangaSeries(
+			pixivDl.SeriesIds,
+			utils.DOWNLOAD_PATH,
+		)
+		pixivDl.removeChapterIds(chapters)
+	}
+	if !pixivMangaOptions.KeepsDownloadedChapters() {
+		artworksToDl = append(artworksToDl, seriesArtworksToDl...)
+		ugoiraToDl = append(ugoiraToDl, seriesUgoiraToDl...)
+	}
+
+	if !pixivDlOptions.Configs.IgnoreHistory {
+		pixivDl.ArtworkIds = history.FilterPosts(utils.PIXIV, pixivDl.ArtworkIds)
+	}
 	if len(pixivDl.ArtworkIds) > 0 {
 		artworkSlice, ugoiraSlice := pixivDlOptions.MobileClient.GetMultipleArtworkDetails(
 			pixivDl.ArtworkIds,
@@ -174,6 +314,63 @@ func PixivMobileDownloadProcess(pixivDl *PixivDl, pixivDlOptions *pixivmobile.Pi
 		progress.Stop(hasErr)
 	}
 
+	if pixivDl.BookmarkVisibility != "" {
+		artworkSlice, ugoiraSlice := pixivDlOptions.MobileClient.GetBookmarks(
+			pixivDl.BookmarkVisibility,
+			pixivDl.BookmarkTag,
+			pixivDl.BookmarkPageNum,
+			utils.DOWNLOAD_PATH,
+		)
+		artworksToDl = append(artworksToDl, artworkSlice...)
+		ugoiraToDl = append(ugoiraToDl, ugoiraSlice...)
+	}
+
+	if pixivDl.RankingMode != "" {
+		artworkSlice, ugoiraSlice := pixivDlOptions.MobileClient.GetRanking(
+			pixivDl.RankingMode,
+			pixivDl.RankingDate,
+			pixivDl.RankingPageNum,
+			utils.DOWNLOAD_PATH,
+			pixivDlOptions,
+		)
+		artworksToDl = append(artworksToDl, artworkSlice...)
+		ugoiraToDl = append(ugoiraToDl, ugoiraSlice...)
+	}
+
+	if pixivDl.DlFollowingFeed {
+		artworkSlice, ugoiraSlice := pixivDlOptions.MobileClient.GetFollowingFeed(
+			pixivDl.FollowingFeedPageNum,
+			utils.DOWNLOAD_PATH,
+			pixivDlOptions,
+		)
+		artworksToDl = append(artworksToDl, artworkSlice...)
+		ugoiraToDl = append(ugoiraToDl, ugoiraSlice...)
+	}
+
+	if !pixivDlOptions.Configs.IgnoreHistory {
+		artworksToDl, ugoiraToDl = filterArtworksByHistory(artworksToDl, ugoiraToDl)
+	}
+	if pixivMangaOptions.KeepsDownloadedChapters() {
+		// the chapters in the download history are kept for the series CBZ files
+		artworksToDl = append(artworksToDl, seriesArtworksToDl...)
+		ugoiraToDl = append(ugoiraToDl, seriesUgoiraToDl...)
+	}
+	manga.AddPendingComics(
+		chapters,
+		artworksToDl,
+		utils.DOWNLOAD_PATH,
+		pixivMangaOptions,
+		pixivDlOptions.Configs,
+	)
+	if pixivDl.hasNovels() {
+		novelsToDl := novel.ProcessNovels(
+			pixivDl.getMobileNovels(pixivDlOptions),
+			utils.DOWNLOAD_PATH,
+			pixivNovelOptions,
+			pixivDlOptions.Configs,
+		)
+		artworksToDl = append(artworksToDl, novelsToDl...)
+	}
 	if len(artworksToDl) > 0 {
 		request.DownloadUrls(
 			artworksToDl,
@@ -198,5 +395,14 @@ func PixivMobileDownloadProcess(pixivDl *PixivDl, pixivDlOptions *pixivmobile.Pi
 		)
 	}
 
+	if pixivDlOptions.Configs.DryRun {
+		request.PrintPlan(pixivDlOptions.Configs)
+		return
+	}
+
+	// render the novels and manga series and save the sync states
+	// only after the artworks and images of the novels have been downloaded
+	render.RenderPending(pixivDlOptions.Configs)
+	history.CommitSyncStates()
 	alertUser(artworksToDl, ugoiraToDl)
 }
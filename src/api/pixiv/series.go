@@ -0,0 +1,22 @@
+package pixiv
+
+import (
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+)
+
+// removeChapterIds removes the artwork IDs of the chapters of the manga series from the artwork IDs
+// to download as the chapters have already been retrieved along with the manga series.
+func (p *PixivDl) removeChapterIds(chapters []*models.MangaChapter) {
+	chapterIds := make(map[string]struct{}, len(chapters))
+	for _, chapter := range chapters {
+		chapterIds[chapter.ArtworkId] = struct{}{}
+	}
+
+	artworkIds := p.ArtworkIds[:0]
+	for _, artworkId := range p.ArtworkIds {
+		if _, ok := chapterIds[artworkId]; !ok {
+			artworkIds = append(artworkIds, artworkId)
+		}
+	}
+	p.ArtworkIds = artworkIds
+}
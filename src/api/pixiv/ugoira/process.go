@@ -13,6 +13,7 @@ import (
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
 	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
@@ -86,8 +87,14 @@ func ConvertUgoira(ugoiraInfo *models.Ugoira, imagesFolderPath string, ugoiraFfm
 }
 
 // Returns the ugoira's zip file path and the ugoira's converted file path
-func GetUgoiraFilePaths(ugoireFilePath, ugoiraUrl, outputFormat string) (string, string) {
-	filePath := filepath.Join(ugoireFilePath, utils.GetLastPartOfUrl(ugoiraUrl))
+//
+// If a path template is given, it will be used instead of the ugoira's default file path.
+func GetUgoiraFilePaths(ugoira *models.Ugoira, outputFormat string, pathTemplate *utils.PathTemplate) (string, string) {
+	filename := utils.GetLastPartOfUrl(ugoira.Url)
+	filePath := filepath.Join(ugoira.FilePath, filename)
+	if pathTemplate != nil && ugoira.PathVars != nil {
+		filePath = pathTemplate.Execute(utils.DOWNLOAD_PATH, ugoira.PathVars, filename)
+	}
 	outputFilePath := utils.RemoveExtFromFilename(filePath) + outputFormat
 	return filePath, outputFilePath
 }
@@ -130,7 +137,7 @@ func convertMultipleUgoira(ugoiraArgs *UgoiraArgs, ugoiraOptions *UgoiraOptions,
 	)
 	progress.Start()
 	for i, ugoira := range ugoiraArgs.ToDownload {
-		zipFilePath, outputPath := GetUgoiraFilePaths(ugoira.FilePath, ugoira.Url, ugoiraOptions.OutputFormat)
+		zipFilePath, outputPath := GetUgoiraFilePaths(ugoira, ugoiraOptions.OutputFormat, config.PathTemplate)
 		if utils.PathExists(outputPath) {
 			progress.MsgIncrement(baseMsg)
 			continue
@@ -163,6 +170,7 @@ func convertMultipleUgoira(ugoiraArgs *UgoiraArgs, ugoiraOptions *UgoiraOptions,
 				err,
 			)
 			errSlice = append(errSlice, err)
+			history.MarkPostFailed(ugoira.Post)
 			progress.MsgIncrement(baseMsg)
 			continue
 		}
@@ -178,6 +186,7 @@ func convertMultipleUgoira(ugoiraArgs *UgoiraArgs, ugoiraOptions *UgoiraOptions,
 		)
 		if err != nil {
 			errSlice = append(errSlice, err)
+			history.MarkPostFailed(ugoira.Post)
 		} else if ugoiraOptions.DeleteZip {
 			os.Remove(zipFilePath)
 		}
@@ -203,14 +212,16 @@ func DownloadMultipleUgoira(ugoiraArgs *UgoiraArgs, ugoiraOptions *UgoiraOptions
 	var urlsToDownload []*request.ToDownload
 	for _, ugoira := range ugoiraArgs.ToDownload {
 		filePath, outputFilePath := GetUgoiraFilePaths(
-			ugoira.FilePath,
-			ugoira.Url,
+			ugoira,
 			ugoiraOptions.OutputFormat,
+			config.PathTemplate,
 		)
 		if !utils.PathExists(outputFilePath) {
 			urlsToDownload = append(urlsToDownload, &request.ToDownload{
 				Url:      ugoira.Url,
-				FilePath: filePath,
+				FilePath:  filePath,
+				Post:      ugoira.Post,
+				Temporary: true,
 			})
 		}
 	}
@@ -237,6 +248,9 @@ func DownloadMultipleUgoira(ugoiraArgs *UgoiraArgs, ugoiraOptions *UgoiraOptions
 		config,    // Note: if isMobileApi is true, custom user-agent will be ignored
 		reqHandler,
 	)
+	if config.DryRun {
+		return
+	}
 
 	convertMultipleUgoira(ugoiraArgs, ugoiraOptions, config)
 }
@@ -5,9 +5,12 @@ import (
 	"net/http"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/metadata"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
@@ -87,11 +90,24 @@ func getArtworkUrlsToDlLogic(artworkType int64, artworkId string, reqArgs *reque
 	return artworkUrlsRes, nil
 }
 
+// addGuestErrDetails adds the likely reason to the error when an artwork could not be retrieved
+// without a session cookie as Pixiv hides R-18 artworks from users who are not logged in.
+func addGuestErrDetails(err error, artworkId string, dlOptions *PixivWebDlOptions) error {
+	if !dlOptions.IsGuest() {
+		return err
+	}
+	return fmt.Errorf(
+		"%v\ndetails: artwork ID %s may be an R-18 artwork which requires the --session, --cookie_file, or --refresh_token flag to download",
+		err,
+		artworkId,
+	)
+}
+
 // Retrieves details of an artwork ID and returns
-// the folder path to download the artwork to, the JSON response, and the artwork type
-func getArtworkDetails(artworkId, downloadPath string, dlOptions *PixivWebDlOptions) ([]*request.ToDownload, *models.Ugoira, error) {
+// the URLs or the Ugoira to download and the artwork details
+func getArtworkDetails(artworkId, downloadPath string, dlOptions *PixivWebDlOptions) ([]*request.ToDownload, *models.Ugoira, *models.ArtworkDetails, error) {
 	if artworkId == "" {
-		return nil, nil, nil
+		return nil, nil, nil, nil
 	}
 
 	url := fmt.Sprintf("%s/illust/%s", utils.PIXIV_API_URL, artworkId)
@@ -110,7 +126,7 @@ func getArtworkDetails(artworkId, downloadPath string, dlOptions *PixivWebDlOpti
 	}
 	artworkDetailsJsonRes, err := getArtworkDetailsLogic(artworkId, reqArgs)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, addGuestErrDetails(err, artworkId, dlOptions)
 	}
 
 	artworkJsonBody := artworkDetailsJsonRes.Body
@@ -126,7 +142,7 @@ func getArtworkDetails(artworkId, downloadPath string, dlOptions *PixivWebDlOpti
 	artworkType := artworkJsonBody.IllustType
 	artworkUrlsRes, err := getArtworkUrlsToDlLogic(artworkType, artworkId, reqArgs)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, addGuestErrDetails(err, artworkId, dlOptions)
 	}
 
 	urlsToDl, ugoiraInfo, err := processArtworkJson(
@@ -135,14 +151,67 @@ func getArtworkDetails(artworkId, downloadPath string, dlOptions *PixivWebDlOpti
 		artworkPostDir,
 	)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+
+	historyPost := &history.Post{
+		Site:      utils.PIXIV,
+		CreatorId: artworkJsonBody.UserId,
+		PostId:    artworkId,
 	}
-	return urlsToDl, ugoiraInfo, nil
+	createdAt, _ := time.Parse(time.RFC3339, artworkJsonBody.CreateDate)
+	pathVars := &utils.PathVars{
+		Site:        utils.PIXIV,
+		CreatorName: illustratorName,
+		CreatorId:   artworkJsonBody.UserId,
+		PostId:      artworkId,
+		PostTitle:   artworkName,
+		PublishedAt: createdAt,
+	}
+	if ugoiraInfo != nil {
+		ugoiraInfo.Post = historyPost
+		ugoiraInfo.PathVars = pathVars.ForFile(utils.UGOIRA_KIND, 1)
+	}
+	for _, urlInfo := range urlsToDl {
+		urlInfo.Post = historyPost
+	}
+	request.SetPathVars(urlsToDl, pathVars, artworkPostDir, utils.ARTWORK_KIND)
+
+	postMetadata := &metadata.Post{
+		Site:        utils.PIXIV,
+		Url:         fmt.Sprintf("%s/artworks/%s", utils.PIXIV_URL, artworkId),
+		PostId:      artworkId,
+		Title:       artworkName,
+		Body:        artworkJsonBody.Description,
+		CreatorId:   artworkJsonBody.UserId,
+		CreatorName: illustratorName,
+		PublishedAt: metadata.ParseTime(time.RFC3339, artworkJsonBody.CreateDate),
+		EditedAt:    metadata.ParseTime(time.RFC3339, artworkJsonBody.UploadDate),
+		Raw:         artworkDetailsJsonRes.Raw,
+	}
+	for _, tag := range artworkJsonBody.Tags.Tags {
+		postMetadata.Tags = append(postMetadata.Tags, tag.Tag)
+	}
+	metadata.Save(postMetadata, artworkPostDir, pathVars, dlOptions.Configs)
+	return urlsToDl, ugoiraInfo, artworkDetailsJsonRes, nil
 }
 
 // Retrieves multiple artwork details based on the given slice of artwork IDs
 // and returns a map to use for downloading and a slice of Ugoira structures
 func GetMultipleArtworkDetails(artworkIds []string, downloadPath string, dlOptions *PixivWebDlOptions) ([]*request.ToDownload, []*models.Ugoira) {
+	if !dlOptions.Configs.IgnoreHistory {
+		artworkIds = history.FilterPosts(utils.PIXIV, artworkIds)
+	}
+	return getMultipleArtworkDetailsLogic(artworkIds, downloadPath, dlOptions, nil)
+}
+
+// getMultipleArtworkDetailsLogic is the same as GetMultipleArtworkDetails without filtering the artwork IDs by
+// the download history but calls onDetails, if not nil, with the details of each artwork that was successfully processed.
+func getMultipleArtworkDetailsLogic(artworkIds []string, downloadPath string, dlOptions *PixivWebDlOptions, onDetails func(artworkId string, artworkDetails *models.ArtworkDetails)) ([]*request.ToDownload, []*models.Ugoira) {
+	if len(artworkIds) == 0 {
+		return nil, nil
+	}
+
 	var errSlice []error
 	var ugoiraDetails []*models.Ugoira
 	var artworkDetails []*request.ToDownload
@@ -169,7 +238,7 @@ func GetMultipleArtworkDetails(artworkIds []string, downloadPath string, dlOptio
 	)
 	progress.Start()
 	for _, artworkId := range artworkIds {
-		artworksToDl, ugoiraInfo, err := getArtworkDetails(
+		artworksToDl, ugoiraInfo, artworkJson, err := getArtworkDetails(
 			artworkId,
 			downloadPath,
 			dlOptions,
@@ -185,6 +254,9 @@ func GetMultipleArtworkDetails(artworkIds []string, downloadPath string, dlOptio
 		} else {
 			artworkDetails = append(artworkDetails, artworksToDl...)
 		}
+		if onDetails != nil {
+			onDetails(artworkId, artworkJson)
+		}
 
 		progress.MsgIncrement(baseMsg)
 		if artworkId != lastArtworkId {
@@ -203,7 +275,9 @@ func GetMultipleArtworkDetails(artworkIds []string, downloadPath string, dlOptio
 }
 
 // Query Pixiv's API for all the illustrator's posts
-func getIllustratorPosts(illustratorId, pageNum string, dlOptions *PixivWebDlOptions) ([]string, error) {
+//
+// If maxWorks is more than 0, only the newest works up to maxWorks are returned.
+func getIllustratorPosts(illustratorId, pageNum string, maxWorks int, dlOptions *PixivWebDlOptions) ([]string, error) {
 	headers := pixivcommon.GetPixivRequestHeaders()
 	headers["Referer"] = pixivcommon.GetIllustUrl(illustratorId)
 	url := fmt.Sprintf("%s/user/%s/profile/all", utils.PIXIV_API_URL, illustratorId)
@@ -243,11 +317,40 @@ func getIllustratorPosts(illustratorId, pageNum string, dlOptions *PixivWebDlOpt
 		return nil, err
 	}
 	artworkIds, err := processIllustratorPostJson(&jsonBody, pageNum, dlOptions)
-	return artworkIds, err
+	if err != nil {
+		return nil, err
+	}
+	artworkIds = limitNewestArtworkIds(artworkIds, maxWorks)
+	if !dlOptions.Configs.Sync {
+		return artworkIds, nil
+	}
+	return filterSyncedArtworkIds(illustratorId, artworkIds), nil
+}
+
+// filterSyncedArtworkIds returns the artwork IDs that are newer
+// than the newest artwork of the illustrator seen from the last sync.
+//
+// Since Pixiv's API returns all the illustrator's artwork IDs in a single request,
+// the artwork IDs are compared instead as they are incrementing.
+func filterSyncedArtworkIds(illustratorId string, artworkIds []string) []string {
+	syncState := history.GetSyncState(utils.PIXIV, illustratorId)
+	newSyncState := *syncState
+
+	var unseenArtworkIds []string
+	for _, artworkId := range artworkIds {
+		newSyncState.Update(artworkId, time.Time{})
+		if !syncState.IsSeen(artworkId, time.Time{}) {
+			unseenArtworkIds = append(unseenArtworkIds, artworkId)
+		}
+	}
+	history.SetPendingSyncState(&newSyncState)
+	return unseenArtworkIds
 }
 
 // Get posts from multiple illustrators and returns a slice of artwork IDs
-func GetMultipleIllustratorPosts(illustratorIds, pageNums []string, downloadPath string, dlOptions *PixivWebDlOptions) []string {
+//
+// maxWorks limits the number of the newest works of the illustrators in it, e.g. the users that the user is following.
+func GetMultipleIllustratorPosts(illustratorIds, pageNums []string, maxWorks map[string]int, downloadPath string, dlOptions *PixivWebDlOptions) []string {
 	var errSlice []error
 	var artworkIdsSlice []string
 	illustratorIdsLen := len(illustratorIds)
@@ -276,6 +379,7 @@ func GetMultipleIllustratorPosts(illustratorIds, pageNums []string, downloadPath
 		artworkIds, err := getIllustratorPosts(
 			illustratorId,
 			pageNums[idx],
+			maxWorks[illustratorId],
 			dlOptions,
 		)
 		if err != nil {
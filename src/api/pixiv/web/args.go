@@ -3,11 +3,14 @@ package pixivweb
 import (
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
 	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
 )
 
 // PixivToDl is the struct that contains the arguments of Pixiv download options.
@@ -22,6 +25,10 @@ type PixivWebDlOptions struct {
 
 	SessionCookies  []*http.Cookie
 	SessionCookieId string
+
+	// UserId is the ID of the logged in user which is only set if the
+	// user's bookmarks or the users they are following are to be downloaded
+	UserId string
 }
 
 var (
@@ -109,4 +116,102 @@ func (p *PixivWebDlOptions) ValidateArgs(userAgent string) {
 			api.VerifyAndGetCookie(utils.PIXIV, p.SessionCookieId, userAgent),
 		}
 	}
+
+	if p.IsGuest() {
+		// Pixiv only shows all ages artworks to users who are not logged in
+		if p.RatingMode == "r18" {
+			color.Red(
+				"pixiv error %d: a session cookie or refresh token is required to download R-18 artworks, please provide one with the --session, --cookie_file, or --refresh_token flag",
+				utils.INPUT_ERROR,
+			)
+			os.Exit(1)
+		}
+		p.RatingMode = "safe"
+		color.Yellow("No Pixiv session cookie or refresh token was provided, only public all ages artworks will be downloaded.")
+	}
+}
+
+// IsGuest returns true if no session cookie was provided, in which
+// case only the public all ages artworks can be downloaded.
+func (p *PixivWebDlOptions) IsGuest() bool {
+	return len(p.SessionCookies) == 0
+}
+
+// exitIfGuest exits the program if no session cookie was provided
+// as it is required to download the given content.
+func (p *PixivWebDlOptions) exitIfGuest(content string) {
+	if !p.IsGuest() {
+		return
+	}
+	color.Red(
+		"pixiv error %d: a session cookie or refresh token is required to download %s, please provide one with the --session, --cookie_file, or --refresh_token flag",
+		utils.INPUT_ERROR,
+		content,
+	)
+	os.Exit(1)
+}
+
+// setUserIdFromSession sets the user ID from the session cookie as it is required
+// to download the content of the user and exits the program if it cannot be found.
+func (p *PixivWebDlOptions) setUserIdFromSession(content string) {
+	p.exitIfGuest(content)
+
+	// the value of the "PHPSESSID" cookie is in the format "<user ID>_<random string>"
+	sessionCookieName := utils.GetSessionCookieInfo(utils.PIXIV).Name
+	for _, cookie := range p.SessionCookies {
+		if cookie.Name != sessionCookieName {
+			continue
+		}
+		if userId, _, found := strings.Cut(cookie.Value, "_"); found && utils.NUMBER_REGEX.MatchString(userId) {
+			p.UserId = userId
+			return
+		}
+	}
+	color.Red(
+		"pixiv error %d: failed to get your user ID from the session cookie to download %s",
+		utils.INPUT_ERROR,
+		content,
+	)
+	os.Exit(1)
+}
+
+// ValidateBookmarkArgs exits the program if the user wants to download their bookmarks
+// without a session cookie and sets the user ID from the session cookie otherwise.
+//
+// Should be called after ValidateArgs.
+func (p *PixivWebDlOptions) ValidateBookmarkArgs(dlBookmarks bool) {
+	if dlBookmarks {
+		p.setUserIdFromSession("your bookmarks")
+	}
+}
+
+// ValidateFollowingUsersArgs exits the program if the user wants to download the works of the users
+// they are following without a session cookie and sets the user ID from the session cookie otherwise.
+//
+// Should be called after ValidateArgs.
+func (p *PixivWebDlOptions) ValidateFollowingUsersArgs(dlFollowingUsers bool) {
+	if dlFollowingUsers {
+		p.setUserIdFromSession("the works of the users you are following")
+	}
+}
+
+// ValidateRankingArgs validates the ranking mode and the date of the ranking to download if any.
+//
+// Should be called after ValidateArgs.
+func (p *PixivWebDlOptions) ValidateRankingArgs(mode, date string) {
+	if mode == "" {
+		return
+	}
+	if pixivcommon.IsR18RankingMode(mode) {
+		p.exitIfGuest(fmt.Sprintf("the %s ranking", mode))
+	}
+	pixivcommon.ValidateRankingArgs(mode, date, p.RatingMode, p.ArtworkType)
+}
+
+// ValidateFollowingFeedArgs exits the program if the user wants to download the new works
+// from the users they are following without a session cookie.
+func (p *PixivWebDlOptions) ValidateFollowingFeedArgs(dlFollowingFeed bool) {
+	if dlFollowingFeed {
+		p.exitIfGuest("the new works from the users you are following")
+	}
 }
@@ -0,0 +1,120 @@
+package pixivweb
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// BOOKMARKS_PER_PAGE is the number of bookmarks on each page of the user's bookmarks on Pixiv
+const BOOKMARKS_PER_PAGE = 48
+
+// getBookmarksLogic returns the IDs of the bookmarked artworks with the visibility, "show" for public
+// or "hide" for private, within the page range. Bookmarked artworks that have been deleted are skipped.
+func getBookmarksLogic(rest, tag string, pageNumArgs *pageNumArgs, dlOptions *PixivWebDlOptions) ([]string, error) {
+	headers := pixivcommon.GetPixivRequestHeaders()
+	headers["Referer"] = fmt.Sprintf("%s/bookmarks/artworks", pixivcommon.GetUserUrl(dlOptions.UserId))
+	params := map[string]string{
+		"tag":   tag,
+		"limit": strconv.Itoa(BOOKMARKS_PER_PAGE),
+		"rest":  rest,
+	}
+
+	useHttp3 := utils.IsHttp3Supported(utils.PIXIV, true)
+	var artworkIds []string
+	for page := pageNumArgs.minPage; !pageNumArgs.hasMax || page <= pageNumArgs.maxPage; page++ {
+		params["offset"] = strconv.Itoa((page - 1) * BOOKMARKS_PER_PAGE)
+		res, err := request.CallRequest(
+			&request.RequestArgs{
+				Url:         fmt.Sprintf("%s/user/%s/illusts/bookmarks", utils.PIXIV_API_URL, dlOptions.UserId),
+				Method:      "GET",
+				Cookies:     dlOptions.SessionCookies,
+				Headers:     headers,
+				Params:      params,
+				CheckStatus: true,
+				UserAgent:   dlOptions.Configs.UserAgent,
+				Http2:       !useHttp3,
+				Http3:       useHttp3,
+			},
+		)
+		if err != nil {
+			return artworkIds, fmt.Errorf(
+				"pixiv error %d: failed to get the bookmarks on page %d, more info => %v",
+				utils.CONNECTION_ERROR,
+				page,
+				err,
+			)
+		}
+
+		var resJson models.PixivWebBookmarksJson
+		if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
+			return artworkIds, err
+		}
+		for _, work := range resJson.Body.Works {
+			if !work.IsMasked {
+				artworkIds = append(artworkIds, work.Id.String())
+			}
+		}
+
+		if len(resJson.Body.Works) < BOOKMARKS_PER_PAGE || page*BOOKMARKS_PER_PAGE >= resJson.Body.Total {
+			break
+		}
+		pixivSleep()
+	}
+	return artworkIds, nil
+}
+
+// GetBookmarks returns the IDs of the artworks that the user has bookmarked with the visibility
+// and optionally with the bookmark tag within the page range to be downloaded like the other artworks.
+func GetBookmarks(visibility, tag, pageNum string, dlOptions *PixivWebDlOptions) []string {
+	minPage, maxPage, hasMax, err := utils.GetMinMaxFromStr(pageNum)
+	if err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+		return nil
+	}
+
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		"Getting your bookmarks from Pixiv...",
+		"Finished getting your bookmarks from Pixiv!",
+		"Something went wrong while getting your bookmarks from Pixiv!\nPlease refer to the logs for more details.",
+		0,
+	)
+	progress.Start()
+	var errSlice []error
+	var artworkIds []string
+	for _, visibility := range pixivcommon.GetBookmarkVisibilities(visibility) {
+		rest := "show"
+		if visibility == pixivcommon.BOOKMARKS_PRIVATE {
+			rest = "hide"
+		}
+		bookmarkIds, err := getBookmarksLogic(
+			rest,
+			tag,
+			&pageNumArgs{
+				minPage: minPage,
+				maxPage: maxPage,
+				hasMax:  hasMax,
+			},
+			dlOptions,
+		)
+		if err != nil {
+			errSlice = append(errSlice, err)
+		}
+		artworkIds = append(artworkIds, bookmarkIds...)
+	}
+
+	hasErr := false
+	if len(errSlice) > 0 {
+		hasErr = true
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	progress.Stop(hasErr)
+	return artworkIds
+}
@@ -0,0 +1,232 @@
+package pixivweb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// isArtworkTypeWanted returns true if the artwork with the illust type, 0 for illustrations,
+// 1 for manga, and 2 for ugoira, should be downloaded based on the artwork type of the download options.
+func isArtworkTypeWanted(illustType int, artworkType string) bool {
+	switch artworkType {
+	case "illust_and_ugoira":
+		return illustType != 1
+	case "manga":
+		return illustType == 1
+	default:
+		return true
+	}
+}
+
+// getPagedArtworkIds calls getPage for each page within the page range until it returns
+// true for being the last page and returns the artwork IDs from all the pages.
+func getPagedArtworkIds(pageNumArgs *pageNumArgs, getPage func(page int) ([]string, bool, error)) ([]string, error) {
+	var artworkIds []string
+	for page := pageNumArgs.minPage; !pageNumArgs.hasMax || page <= pageNumArgs.maxPage; page++ {
+		pageArtworkIds, isLastPage, err := getPage(page)
+		if err != nil {
+			return artworkIds, err
+		}
+
+		artworkIds = append(artworkIds, pageArtworkIds...)
+		if isLastPage {
+			break
+		}
+		pixivSleep()
+	}
+	return artworkIds, nil
+}
+
+// getRankingPage returns the IDs of the artworks on the page of the ranking
+// and whether the page is the last page of the ranking.
+func getRankingPage(mode, date string, page int, dlOptions *PixivWebDlOptions) ([]string, bool, error) {
+	content := "all"
+	if dlOptions.ArtworkType == "manga" {
+		content = "manga"
+	}
+	params := map[string]string{
+		"mode":    mode,
+		"content": content,
+		"p":       strconv.Itoa(page),
+		"format":  "json",
+	}
+	if date != "" {
+		params["date"] = date
+	}
+
+	useHttp3 := utils.IsHttp3Supported(utils.PIXIV, true)
+	res, err := request.CallRequest(
+		&request.RequestArgs{
+			Url:         utils.PIXIV_URL + "/ranking.php",
+			Method:      "GET",
+			Cookies:     dlOptions.SessionCookies,
+			Headers:     pixivcommon.GetPixivRequestHeaders(),
+			Params:      params,
+			CheckStatus: true,
+			UserAgent:   dlOptions.Configs.UserAgent,
+			Http2:       !useHttp3,
+			Http3:       useHttp3,
+		},
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf(
+			"pixiv error %d: failed to get page %d of the %s ranking, more info => %v",
+			utils.CONNECTION_ERROR,
+			page,
+			mode,
+			err,
+		)
+	}
+
+	var resJson models.PixivWebRankingJson
+	if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
+		return nil, false, err
+	}
+
+	var artworkIds []string
+	for _, artwork := range resJson.Contents {
+		illustType, _ := strconv.Atoi(artwork.IllustType)
+		if isArtworkTypeWanted(illustType, dlOptions.ArtworkType) {
+			artworkIds = append(artworkIds, strconv.Itoa(artwork.IllustId))
+		}
+	}
+
+	// "next" is false on the last page of the ranking
+	_, hasNext := resJson.Next.(float64)
+	return artworkIds, !hasNext, nil
+}
+
+// GetRanking returns the IDs of the artworks in the ranking with the mode on the date,
+// "YYYY-MM-DD" or empty for the latest ranking, within the page range to be downloaded.
+func GetRanking(mode, date, pageNum string, dlOptions *PixivWebDlOptions) []string {
+	minPage, maxPage, hasMax, err := utils.GetMinMaxFromStr(pageNum)
+	if err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+		return nil
+	}
+	if date != "" {
+		// the web API only accepts the date in the format "YYYYMMDD"
+		date = strings.ReplaceAll(date, "-", "")
+	}
+
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		fmt.Sprintf("Getting the %s ranking from Pixiv...", mode),
+		fmt.Sprintf("Finished getting the %s ranking from Pixiv!", mode),
+		fmt.Sprintf("Something went wrong while getting the %s ranking from Pixiv!\nPlease refer to the logs for more details.", mode),
+		0,
+	)
+	progress.Start()
+	artworkIds, err := getPagedArtworkIds(
+		&pageNumArgs{
+			minPage: minPage,
+			maxPage: maxPage,
+			hasMax:  hasMax,
+		},
+		func(page int) ([]string, bool, error) {
+			return getRankingPage(mode, date, page, dlOptions)
+		},
+	)
+	if err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+	}
+	progress.Stop(err != nil)
+	return artworkIds
+}
+
+// getFollowingFeedPage returns the IDs of the artworks on the page of the new works
+// from the users that the user is following and whether the page is the last page.
+func getFollowingFeedPage(page int, dlOptions *PixivWebDlOptions) ([]string, bool, error) {
+	mode := "all"
+	if dlOptions.RatingMode == "r18" {
+		mode = "r18"
+	}
+
+	useHttp3 := utils.IsHttp3Supported(utils.PIXIV, true)
+	headers := pixivcommon.GetPixivRequestHeaders()
+	headers["Referer"] = utils.PIXIV_URL + "/bookmark_new_illust.php"
+	res, err := request.CallRequest(
+		&request.RequestArgs{
+			Url:     utils.PIXIV_API_URL + "/follow_latest/illust",
+			Method:  "GET",
+			Cookies: dlOptions.SessionCookies,
+			Headers: headers,
+			Params: map[string]string{
+				"p":    strconv.Itoa(page),
+				"mode": mode,
+			},
+			CheckStatus: true,
+			UserAgent:   dlOptions.Configs.UserAgent,
+			Http2:       !useHttp3,
+			Http3:       useHttp3,
+		},
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf(
+			"pixiv error %d: failed to get page %d of the new works from the users you are following, more info => %v",
+			utils.CONNECTION_ERROR,
+			page,
+			err,
+		)
+	}
+
+	var resJson models.PixivWebFollowingFeedJson
+	if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
+		return nil, false, err
+	}
+
+	var artworkIds []string
+	for _, artwork := range resJson.Body.Thumbnails.Illust {
+		if dlOptions.RatingMode == "safe" && artwork.XRestrict != 0 {
+			continue
+		}
+		if isArtworkTypeWanted(artwork.IllustType, dlOptions.ArtworkType) {
+			artworkIds = append(artworkIds, artwork.Id)
+		}
+	}
+	isLastPage := resJson.Body.Page.IsLastPage || len(resJson.Body.Page.Ids) == 0
+	return artworkIds, isLastPage, nil
+}
+
+// GetFollowingFeed returns the IDs of the new artworks from the users that
+// the user is following within the page range to be downloaded.
+func GetFollowingFeed(pageNum string, dlOptions *PixivWebDlOptions) []string {
+	minPage, maxPage, hasMax, err := utils.GetMinMaxFromStr(pageNum)
+	if err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+		return nil
+	}
+
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		"Getting the new works from the users you are following on Pixiv...",
+		"Finished getting the new works from the users you are following on Pixiv!",
+		"Something went wrong while getting the new works from the users you are following on Pixiv!\nPlease refer to the logs for more details.",
+		0,
+	)
+	progress.Start()
+	artworkIds, err := getPagedArtworkIds(
+		&pageNumArgs{
+			minPage: minPage,
+			maxPage: maxPage,
+			hasMax:  hasMax,
+		},
+		func(page int) ([]string, bool, error) {
+			return getFollowingFeedPage(page, dlOptions)
+		},
+	)
+	if err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+	}
+	progress.Stop(err != nil)
+	return artworkIds
+}
@@ -0,0 +1,97 @@
+package pixivweb
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// FOLLOWING_PER_PAGE is the number of users on each page of the users that the user is following on Pixiv
+const FOLLOWING_PER_PAGE = 24
+
+// getFollowingUsersLogic returns the IDs of the users that the user is
+// following with the visibility, "show" for public or "hide" for private.
+func getFollowingUsersLogic(rest string, dlOptions *PixivWebDlOptions) ([]string, error) {
+	headers := pixivcommon.GetPixivRequestHeaders()
+	headers["Referer"] = fmt.Sprintf("%s/following", pixivcommon.GetUserUrl(dlOptions.UserId))
+	params := map[string]string{
+		"limit": strconv.Itoa(FOLLOWING_PER_PAGE),
+		"rest":  rest,
+	}
+
+	useHttp3 := utils.IsHttp3Supported(utils.PIXIV, true)
+	var userIds []string
+	for offset := 0; ; offset += FOLLOWING_PER_PAGE {
+		params["offset"] = strconv.Itoa(offset)
+		res, err := request.CallRequest(
+			&request.RequestArgs{
+				Url:         fmt.Sprintf("%s/user/%s/following", utils.PIXIV_API_URL, dlOptions.UserId),
+				Method:      "GET",
+				Cookies:     dlOptions.SessionCookies,
+				Headers:     headers,
+				Params:      params,
+				CheckStatus: true,
+				UserAgent:   dlOptions.Configs.UserAgent,
+				Http2:       !useHttp3,
+				Http3:       useHttp3,
+			},
+		)
+		if err != nil {
+			return userIds, fmt.Errorf(
+				"pixiv error %d: failed to get the users you are following at offset %d, more info => %v",
+				utils.CONNECTION_ERROR,
+				offset,
+				err,
+			)
+		}
+
+		var resJson models.PixivWebFollowingJson
+		if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
+			return userIds, err
+		}
+		for _, user := range resJson.Body.Users {
+			userIds = append(userIds, user.UserId)
+		}
+
+		if len(resJson.Body.Users) < FOLLOWING_PER_PAGE || offset+FOLLOWING_PER_PAGE >= resJson.Body.Total {
+			break
+		}
+		pixivSleep()
+	}
+	return userIds, nil
+}
+
+// GetFollowingUsers returns the IDs of all the users, both public and private, that the user is following.
+func GetFollowingUsers(dlOptions *PixivWebDlOptions) []string {
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		"Getting the users you are following on Pixiv...",
+		"Finished getting the users you are following on Pixiv!",
+		"Something went wrong while getting the users you are following on Pixiv!\nPlease refer to the logs for more details.",
+		0,
+	)
+	progress.Start()
+	var errSlice []error
+	var userIds []string
+	for _, rest := range []string{"show", "hide"} {
+		restUserIds, err := getFollowingUsersLogic(rest, dlOptions)
+		if err != nil {
+			errSlice = append(errSlice, err)
+		}
+		userIds = append(userIds, restUserIds...)
+	}
+
+	hasErr := false
+	if len(errSlice) > 0 {
+		hasErr = true
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	progress.Stop(hasErr)
+	return userIds
+}
@@ -0,0 +1,490 @@
+package pixivweb
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/novel"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// NOVEL_SERIES_PER_PAGE is the number of novels on each page of the contents of a novel series on Pixiv
+const NOVEL_SERIES_PER_PAGE = 30
+
+// getNovelReqArgs returns the request arguments for Pixiv's novel API with the URL and referer.
+func getNovelReqArgs(url, referer string, params map[string]string, dlOptions *PixivWebDlOptions) *request.RequestArgs {
+	headers := pixivcommon.GetPixivRequestHeaders()
+	headers["Referer"] = referer
+
+	useHttp3 := utils.IsHttp3Supported(utils.PIXIV, true)
+	return &request.RequestArgs{
+		Url:         url,
+		Method:      "GET",
+		Cookies:     dlOptions.SessionCookies,
+		Headers:     headers,
+		Params:      params,
+		CheckStatus: true,
+		UserAgent:   dlOptions.Configs.UserAgent,
+		Http2:       !useHttp3,
+		Http3:       useHttp3,
+	}
+}
+
+// getPixivImageUrls returns the original image URLs of the
+// "[pixivimage:]" tags in the novel which embed the pages of other artworks.
+func getPixivImageUrls(novelId string, imageIds []string, dlOptions *PixivWebDlOptions) (map[string]string, []error) {
+	var errSlice []error
+	imageUrls := make(map[string]string, len(imageIds))
+	artworkPages := make(map[string]*models.PixivWebArtworkJson)
+	for _, imageId := range imageIds {
+		artworkId, page := novel.SplitPixivImageId(imageId)
+		artworkJson, ok := artworkPages[artworkId]
+		if !ok {
+			res, err := request.CallRequest(
+				getNovelReqArgs(
+					fmt.Sprintf("%s/illust/%s/pages", utils.PIXIV_API_URL, artworkId),
+					novel.GetNovelUrl(novelId),
+					nil,
+					dlOptions,
+				),
+			)
+			if err != nil {
+				errSlice = append(errSlice, fmt.Errorf(
+					"pixiv error %d: failed to get the image of artwork ID %s in novel ID %s, more info => %v",
+					utils.CONNECTION_ERROR,
+					artworkId,
+					novelId,
+					err,
+				))
+				continue
+			}
+
+			artworkJson = &models.PixivWebArtworkJson{}
+			if err := utils.LoadJsonFromResponse(res, artworkJson); err != nil {
+				errSlice = append(errSlice, err)
+				continue
+			}
+			artworkPages[artworkId] = artworkJson
+			pixivSleep()
+		}
+
+		if page <= len(artworkJson.Body) {
+			imageUrls[imageId] = artworkJson.Body[page-1].Urls.Original
+		}
+	}
+	return imageUrls, errSlice
+}
+
+// getNovelDetails returns the details and text of the novel with the URLs of its embedded images.
+func getNovelDetails(novelId string, dlOptions *PixivWebDlOptions) (*models.Novel, []error) {
+	res, err := request.CallRequest(
+		getNovelReqArgs(
+			fmt.Sprintf("%s/novel/%s", utils.PIXIV_API_URL, novelId),
+			novel.GetNovelUrl(novelId),
+			nil,
+			dlOptions,
+		),
+	)
+	if err != nil {
+		err = fmt.Errorf(
+			"pixiv error %d: failed to get the details of novel ID %s, more info => %v",
+			utils.CONNECTION_ERROR,
+			novelId,
+			err,
+		)
+		if dlOptions.IsGuest() {
+			err = fmt.Errorf(
+				"%v\ndetails: novel ID %s may be an R-18 novel which requires the --session, --cookie_file, or --refresh_token flag to download",
+				err,
+				novelId,
+			)
+		}
+		return nil, []error{err}
+	}
+
+	var novelJson models.PixivWebNovelJson
+	if err := utils.LoadJsonFromResponse(res, &novelJson); err != nil {
+		return nil, []error{err}
+	}
+
+	body := novelJson.Body
+	novelInfo := &models.Novel{
+		Id:             body.Id,
+		Title:          body.Title,
+		Caption:        body.Description,
+		UserId:         body.UserId,
+		UserName:       body.UserName,
+		CoverUrl:       body.CoverUrl,
+		Content:        body.Content,
+		CreateDate:     body.CreateDate,
+		UploadDate:     body.UploadDate,
+		UploadedImages: make(map[string]string, len(body.TextEmbeddedImages)),
+		Raw:            novelJson.Raw,
+	}
+	for _, tag := range body.Tags.Tags {
+		novelInfo.Tags = append(novelInfo.Tags, tag.Tag)
+	}
+	for imageId, image := range body.TextEmbeddedImages {
+		novelInfo.UploadedImages[imageId] = image.Urls.Original
+	}
+	if series := body.SeriesNavData; series != nil {
+		novelInfo.SeriesId = series.SeriesId.String()
+		novelInfo.SeriesTitle = series.Title
+		novelInfo.SeriesOrder = series.Order
+	}
+
+	var errSlice []error
+	if imageIds := novel.GetPixivImageIds(body.Content); len(imageIds) > 0 {
+		novelInfo.PixivImages, errSlice = getPixivImageUrls(novelId, imageIds, dlOptions)
+	}
+	return novelInfo, errSlice
+}
+
+// GetMultipleNovelDetails returns the details of the novels to be processed.
+func GetMultipleNovelDetails(novelIds []string, dlOptions *PixivWebDlOptions) []*models.Novel {
+	novelIdsLen := len(novelIds)
+	if novelIdsLen == 0 {
+		return nil
+	}
+
+	baseMsg := "Getting novel details from Pixiv [%d/" + fmt.Sprintf("%d]...", novelIdsLen)
+	progress := spinner.New(
+		spinner.JSON_SPINNER,
+		"fgHiYellow",
+		fmt.Sprintf(
+			baseMsg,
+			0,
+		),
+		fmt.Sprintf(
+			"Finished getting %d novel details from Pixiv!",
+			novelIdsLen,
+		),
+		fmt.Sprintf(
+			"Something went wrong while getting %d novel details from Pixiv!\nPlease refer to the logs for more details.",
+			novelIdsLen,
+		),
+		novelIdsLen,
+	)
+	progress.Start()
+	var errSlice []error
+	var novels []*models.Novel
+	for idx, novelId := range novelIds {
+		novelInfo, errS := getNovelDetails(novelId, dlOptions)
+		errSlice = append(errSlice, errS...)
+		if novelInfo != nil {
+			novels = append(novels, novelInfo)
+		}
+
+		progress.MsgIncrement(baseMsg)
+		if idx != novelIdsLen-1 {
+			pixivSleep()
+		}
+	}
+
+	hasErr := false
+	if len(errSlice) > 0 {
+		hasErr = true
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	progress.Stop(hasErr)
+	return novels
+}
+
+// getNovelSeriesIds returns the IDs of all the novels in the novel series.
+func getNovelSeriesIds(seriesId string, dlOptions *PixivWebDlOptions) ([]string, error) {
+	var novelIds []string
+	params := map[string]string{
+		"limit":    strconv.Itoa(NOVEL_SERIES_PER_PAGE),
+		"order_by": "asc",
+	}
+	for lastOrder := 0; ; lastOrder += NOVEL_SERIES_PER_PAGE {
+		params["last_order"] = strconv.Itoa(lastOrder)
+		res, err := request.CallRequest(
+			getNovelReqArgs(
+				fmt.Sprintf("%s/novel/series_content/%s", utils.PIXIV_API_URL, seriesId),
+				novel.GetNovelSeriesUrl(seriesId),
+				params,
+				dlOptions,
+			),
+		)
+		if err != nil {
+			return novelIds, fmt.Errorf(
+				"pixiv error %d: failed to get the novels of novel series ID %s, more info => %v",
+				utils.CONNECTION_ERROR,
+				seriesId,
+				err,
+			)
+		}
+
+		var resJson models.PixivWebNovelSeriesContentJson
+		if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
+			return novelIds, err
+		}
+		for _, seriesContent := range resJson.Body.SeriesContents {
+			novelIds = append(novelIds, seriesContent.Id)
+		}
+		if len(resJson.Body.SeriesContents) < NOVEL_SERIES_PER_PAGE {
+			return novelIds, nil
+		}
+		pixivSleep()
+	}
+}
+
+// GetMultipleNovelSeries returns the IDs of the novels in the novel series to be downloaded.
+func GetMultipleNovelSeries(seriesIds []string, dlOptions *PixivWebDlOptions) []string {
+	seriesIdsLen := len(seriesIds)
+	baseMsg := "Getting novels from novel series on Pixiv [%d/" + fmt.Sprintf("%d]...", seriesIdsLen)
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		fmt.Sprintf(
+			baseMsg,
+			0,
+		),
+		fmt.Sprintf(
+			"Finished getting novels from %d novel series on Pixiv!",
+			seriesIdsLen,
+		),
+		fmt.Sprintf(
+			"Something went wrong while getting novels from %d novel series on Pixiv!\nPlease refer to the logs for more details.",
+			seriesIdsLen,
+		),
+		seriesIdsLen,
+	)
+	progress.Start()
+	var errSlice []error
+	var novelIds []string
+	for idx, seriesId := range seriesIds {
+		seriesNovelIds, err := getNovelSeriesIds(seriesId, dlOptions)
+		if err != nil {
+			errSlice = append(errSlice, err)
+		}
+		novelIds = append(novelIds, seriesNovelIds...)
+
+		if idx != seriesIdsLen-1 {
+			pixivSleep()
+		}
+		progress.MsgIncrement(baseMsg)
+	}
+
+	hasErr := false
+	if len(errSlice) > 0 {
+		hasErr = true
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	progress.Stop(hasErr)
+	return novelIds
+}
+
+// getUserNovelIds returns the IDs of the user's novels, from the newest to the oldest, within the page range.
+func getUserNovelIds(userId, pageNum string, dlOptions *PixivWebDlOptions) ([]string, error) {
+	minPage, maxPage, hasMax, err := utils.GetMinMaxFromStr(pageNum)
+	if err != nil {
+		return nil, err
+	}
+	minOffset, maxOffset := pixivcommon.ConvertPageNumToOffset(minPage, maxPage, utils.PIXIV_PER_PAGE, false)
+
+	res, err := request.CallRequest(
+		getNovelReqArgs(
+			fmt.Sprintf("%s/user/%s/profile/all", utils.PIXIV_API_URL, userId),
+			pixivcommon.GetUserUrl(userId),
+			nil,
+			dlOptions,
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"pixiv error %d: failed to get the novels of user ID %s, more info => %v",
+			utils.CONNECTION_ERROR,
+			userId,
+			err,
+		)
+	}
+
+	var resJson models.PixivWebUserNovelsJson
+	if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
+		return nil, err
+	}
+
+	// where there are no novels, it will be an empty slice instead of a map
+	novelsMap, ok := resJson.Body.Novels.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	sortedNovelIds := make([]string, 0, len(novelsMap))
+	for novelId := range novelsMap {
+		sortedNovelIds = append(sortedNovelIds, novelId)
+	}
+	// novel IDs are incrementing so the newest novels will be first
+	sort.Slice(sortedNovelIds, func(i, j int) bool {
+		iId, _ := strconv.Atoi(sortedNovelIds[i])
+		jId, _ := strconv.Atoi(sortedNovelIds[j])
+		return iId > jId
+	})
+
+	var novelIds []string
+	for idx, novelId := range sortedNovelIds {
+		curOffset := idx + 1
+		if curOffset < minOffset {
+			continue
+		}
+		if hasMax && curOffset > maxOffset {
+			break
+		}
+		novelIds = append(novelIds, novelId)
+	}
+	return novelIds, nil
+}
+
+// GetMultipleUserNovels returns the IDs of the novels of the users to be downloaded.
+func GetMultipleUserNovels(userIds, pageNums []string, dlOptions *PixivWebDlOptions) []string {
+	userIdsLen := len(userIds)
+	baseMsg := "Getting novels from user(s) on Pixiv [%d/" + fmt.Sprintf("%d]...", userIdsLen)
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		fmt.Sprintf(
+			baseMsg,
+			0,
+		),
+		fmt.Sprintf(
+			"Finished getting novels from %d user(s) on Pixiv!",
+			userIdsLen,
+		),
+		fmt.Sprintf(
+			"Something went wrong while getting novels from %d user(s) on Pixiv!\nPlease refer to the logs for more details.",
+			userIdsLen,
+		),
+		userIdsLen,
+	)
+	progress.Start()
+	var errSlice []error
+	var novelIds []string
+	for idx, userId := range userIds {
+		userNovelIds, err := getUserNovelIds(userId, pageNums[idx], dlOptions)
+		if err != nil {
+			errSlice = append(errSlice, err)
+		} else {
+			novelIds = append(novelIds, userNovelIds...)
+		}
+
+		if idx != userIdsLen-1 {
+			pixivSleep()
+		}
+		progress.MsgIncrement(baseMsg)
+	}
+
+	hasErr := false
+	if len(errSlice) > 0 {
+		hasErr = true
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	progress.Stop(hasErr)
+	return novelIds
+}
+
+// getNovelSearchPage returns the IDs of the novels on the page
+// of the tag search results and whether the page is the last page.
+func getNovelSearchPage(tagName string, page int, dlOptions *PixivWebDlOptions) ([]string, bool, error) {
+	res, err := request.CallRequest(
+		getNovelReqArgs(
+			fmt.Sprintf("%s/search/novels/%s", utils.PIXIV_API_URL, tagName),
+			fmt.Sprintf("%s/tags/%s/novels", utils.PIXIV_URL, tagName),
+			map[string]string{
+				"word":   tagName,
+				"s_mode": dlOptions.SearchMode,
+				"order":  dlOptions.SortOrder,
+				"mode":   dlOptions.RatingMode,
+				"p":      strconv.Itoa(page),
+			},
+			dlOptions,
+		),
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf(
+			"pixiv error %d: failed to get page %d of the novel search results for %s, more info => %v",
+			utils.CONNECTION_ERROR,
+			page,
+			tagName,
+			err,
+		)
+	}
+
+	var resJson models.PixivWebNovelSearchJson
+	if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
+		return nil, false, err
+	}
+
+	var novelIds []string
+	for _, novelInfo := range resJson.Body.Novel.Data {
+		novelIds = append(novelIds, novelInfo.Id)
+	}
+	return novelIds, len(novelIds) == 0, nil
+}
+
+// NovelTagSearch returns the IDs of the novels in the search results of the tags to be downloaded.
+func NovelTagSearch(tagNames, pageNums []string, dlOptions *PixivWebDlOptions) []string {
+	tagNamesLen := len(tagNames)
+	baseMsg := "Searching for novels with tag(s) on Pixiv [%d/" + fmt.Sprintf("%d]...", tagNamesLen)
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		fmt.Sprintf(
+			baseMsg,
+			0,
+		),
+		fmt.Sprintf(
+			"Finished searching for novels with %d tag(s) on Pixiv!",
+			tagNamesLen,
+		),
+		fmt.Sprintf(
+			"Something went wrong while searching for novels with %d tag(s) on Pixiv!\nPlease refer to the logs for more details.",
+			tagNamesLen,
+		),
+		tagNamesLen,
+	)
+	progress.Start()
+	var errSlice []error
+	var novelIds []string
+	for idx, tagName := range tagNames {
+		minPage, maxPage, hasMax, err := utils.GetMinMaxFromStr(pageNums[idx])
+		if err != nil {
+			errSlice = append(errSlice, err)
+			progress.MsgIncrement(baseMsg)
+			continue
+		}
+
+		tagNovelIds, err := getPagedArtworkIds(
+			&pageNumArgs{
+				minPage: minPage,
+				maxPage: maxPage,
+				hasMax:  hasMax,
+			},
+			func(page int) ([]string, bool, error) {
+				return getNovelSearchPage(tagName, page, dlOptions)
+			},
+		)
+		if err != nil {
+			errSlice = append(errSlice, err)
+		}
+		novelIds = append(novelIds, tagNovelIds...)
+
+		if idx != tagNamesLen-1 {
+			pixivSleep()
+		}
+		progress.MsgIncrement(baseMsg)
+	}
+
+	hasErr := false
+	if len(errSlice) > 0 {
+		hasErr = true
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	progress.Stop(hasErr)
+	return novelIds
+}
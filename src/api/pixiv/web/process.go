@@ -2,10 +2,12 @@ package pixivweb
 
 import (
 	"net/http"
+	"sort"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/ugoira"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
+	"github.com/KJHJason/Cultured-Downloader-CLI/manifest"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
@@ -62,6 +64,26 @@ func processIllustratorPostJson(resJson *models.PixivWebIllustratorJson, pageNum
 	return artworkIds, nil
 }
 
+// limitNewestArtworkIds returns the newest artwork IDs up to maxWorks, newest first, or all of them if maxWorks is 0.
+//
+// The artwork IDs are compared as they are incrementing since the
+// illustrator's artworks are returned in no particular order.
+func limitNewestArtworkIds(artworkIds []string, maxWorks int) []string {
+	if maxWorks <= 0 || len(artworkIds) <= maxWorks {
+		return artworkIds
+	}
+
+	sorted := make([]string, len(artworkIds))
+	copy(sorted, artworkIds)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if len(sorted[i]) != len(sorted[j]) {
+			return len(sorted[i]) > len(sorted[j])
+		}
+		return sorted[i] > sorted[j]
+	})
+	return sorted[:maxWorks]
+}
+
 // Process the artwork details JSON and returns a map of urls
 // with its file path or a Ugoira struct (One of them will be null depending on the artworkType)
 func processArtworkJson(res *http.Response, artworkType int64, postDownloadDir string) ([]*request.ToDownload, *models.Ugoira, error) {
@@ -91,6 +113,7 @@ func processArtworkJson(res *http.Response, artworkType int64, postDownloadDir s
 		urlsToDownload = append(urlsToDownload, &request.ToDownload{
 			Url:      artworkUrl.Urls.Original,
 			FilePath: postDownloadDir,
+			Expected: &manifest.Expected{Width: artworkUrl.Width, Height: artworkUrl.Height},
 		})
 	}
 	return urlsToDownload, nil, nil
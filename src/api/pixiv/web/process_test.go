@@ -0,0 +1,28 @@
+package pixivweb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLimitNewestArtworkIds(t *testing.T) {
+	artworkIds := []string{"98", "1000", "99", "100"}
+	tests := []struct {
+		name     string
+		maxWorks int
+		want     []string
+	}{
+		{name: "no limit", maxWorks: 0, want: artworkIds},
+		{name: "limit above the number of works", maxWorks: 10, want: artworkIds},
+		{name: "newest works by their IDs", maxWorks: 2, want: []string{"1000", "100"}},
+		{name: "single work", maxWorks: 1, want: []string{"1000"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := limitNewestArtworkIds(artworkIds, test.maxWorks); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("limitNewestArtworkIds(%d) = %v, want %v", test.maxWorks, got, test.want)
+			}
+		})
+	}
+}
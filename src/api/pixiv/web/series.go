@@ -0,0 +1,103 @@
+package pixivweb
+
+import (
+	"fmt"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/manga"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// getMangaSeries returns the artwork IDs of the manga series sorted by their order in the series.
+func getMangaSeries(seriesId string, dlOptions *PixivWebDlOptions) (*manga.MangaSeries, error) {
+	return manga.GetMangaSeries(
+		seriesId,
+		func(url string, params map[string]string) *request.RequestArgs {
+			return getNovelReqArgs(url, utils.PIXIV_URL, params, dlOptions)
+		},
+		pixivSleep,
+	)
+}
+
+// GetMultipleMangaSeries returns the images and Ugoira of all the chapters of the manga series
+// to download and the details of the chapters in their order in each series.
+//
+// The chapters in the download history are skipped unless keepDownloaded is true.
+func GetMultipleMangaSeries(seriesIds []string, downloadPath string, keepDownloaded bool, dlOptions *PixivWebDlOptions) ([]*request.ToDownload, []*models.Ugoira, []*models.MangaChapter) {
+	seriesIdsLen := len(seriesIds)
+	baseMsg := "Getting chapters from manga series on Pixiv [%d/" + fmt.Sprintf("%d]...", seriesIdsLen)
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		fmt.Sprintf(
+			baseMsg,
+			0,
+		),
+		fmt.Sprintf(
+			"Finished getting chapters from %d manga series on Pixiv!",
+			seriesIdsLen,
+		),
+		fmt.Sprintf(
+			"Something went wrong while getting chapters from %d manga series on Pixiv!\nPlease refer to the logs for more details.",
+			seriesIdsLen,
+		),
+		seriesIdsLen,
+	)
+	progress.Start()
+	var errSlice []error
+	var artworkIds []string
+	seriesOfArtworks := make(map[string]*manga.MangaSeries)
+	for idx, seriesId := range seriesIds {
+		series, err := getMangaSeries(seriesId, dlOptions)
+		if err != nil {
+			errSlice = append(errSlice, err)
+		} else {
+			for _, artworkId := range series.ArtworkIds {
+				if _, ok := seriesOfArtworks[artworkId]; !ok {
+					artworkIds = append(artworkIds, artworkId)
+					seriesOfArtworks[artworkId] = series
+				}
+			}
+		}
+
+		if idx != seriesIdsLen-1 {
+			pixivSleep()
+		}
+		progress.MsgIncrement(baseMsg)
+	}
+
+	hasErr := false
+	if len(errSlice) > 0 {
+		hasErr = true
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	progress.Stop(hasErr)
+	if !keepDownloaded && !dlOptions.Configs.IgnoreHistory {
+		artworkIds = history.FilterPosts(utils.PIXIV, artworkIds)
+	}
+	if len(artworkIds) == 0 {
+		return nil, nil, nil
+	}
+
+	var chapters []*models.MangaChapter
+	artworksToDl, ugoiraToDl := getMultipleArtworkDetailsLogic(
+		artworkIds,
+		downloadPath,
+		dlOptions,
+		func(artworkId string, artworkDetails *models.ArtworkDetails) {
+			// the series details take precedence as the navigation data of the artwork may be missing
+			series := seriesOfArtworks[artworkId]
+			chapter := manga.ChapterFromArtworkDetails(artworkId, artworkDetails)
+			chapter.SeriesId = series.Id
+			chapter.Order = series.Orders[artworkId]
+			if series.Title != "" {
+				chapter.SeriesTitle = series.Title
+			}
+			chapters = append(chapters, chapter)
+		},
+	)
+	return artworksToDl, ugoiraToDl, chapters
+}
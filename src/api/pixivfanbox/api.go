@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixivfanbox/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
@@ -166,6 +168,71 @@ type resStruct struct {
 	err  error
 }
 
+func getCreatorPostsPage(reqUrl string, dlOptions *PixivFanboxDlOptions) (*models.FanboxCreatorPostsJson, error) {
+	useHttp3 := utils.IsHttp3Supported(utils.PIXIV_FANBOX, true)
+	res, err := request.CallRequest(
+		&request.RequestArgs{
+			Method:    "GET",
+			Url:       reqUrl,
+			Cookies:   dlOptions.SessionCookies,
+			Headers:   GetPixivFanboxHeaders(),
+			UserAgent: dlOptions.Configs.UserAgent,
+			Http2:     !useHttp3,
+			Http3:     useHttp3,
+		},
+	)
+	if err != nil || res.StatusCode != 200 {
+		if err == nil {
+			res.Body.Close()
+			err = fmt.Errorf("%s response", res.Status)
+		}
+		return nil, fmt.Errorf(
+			"pixiv fanbox error %d: failed to get post for %s, more info => %v",
+			utils.CONNECTION_ERROR,
+			reqUrl,
+			err,
+		)
+	}
+
+	var resJson *models.FanboxCreatorPostsJson
+	if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
+		return nil, err
+	}
+	return resJson, nil
+}
+
+// syncFanboxPosts gets the creator's posts page by page
+// until it reaches a post that has been seen from the last sync.
+func syncFanboxPosts(creatorId string, paginatedUrls []string, dlOptions *PixivFanboxDlOptions) ([]string, error) {
+	syncState := history.GetSyncState(utils.PIXIV_FANBOX, creatorId)
+	newSyncState := *syncState
+
+	var postIds []string
+	for _, paginatedUrl := range paginatedUrls {
+		resJson, err := getCreatorPostsPage(paginatedUrl, dlOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		reachedSeenPost := false
+		for _, post := range resJson.Body.Items {
+			publishedAt, _ := time.Parse(time.RFC3339, post.PublishedDatetime)
+			newSyncState.Update(post.Id, publishedAt)
+			if syncState.IsSeen(post.Id, publishedAt) {
+				reachedSeenPost = true
+				continue
+			}
+			postIds = append(postIds, post.Id)
+		}
+		if reachedSeenPost {
+			break
+		}
+	}
+
+	history.SetPendingSyncState(&newSyncState)
+	return postIds, nil
+}
+
 // GetFanboxCreatorPosts returns a slice of post IDs for a given creator
 func getFanboxPosts(creatorId, pageNum string, dlOptions *PixivFanboxDlOptions) ([]string, error) {
 	paginatedUrls, err := getCreatorPaginatedPosts(creatorId, dlOptions)
@@ -178,8 +245,17 @@ func getFanboxPosts(creatorId, pageNum string, dlOptions *PixivFanboxDlOptions)
 		return nil, err
 	}
 
-	useHttp3 := utils.IsHttp3Supported(utils.PIXIV_FANBOX, true)
-	headers := GetPixivFanboxHeaders()
+	if minPage > len(paginatedUrls) {
+		return nil, nil
+	}
+	if hasMax && maxPage < len(paginatedUrls) {
+		paginatedUrls = paginatedUrls[:maxPage]
+	}
+	paginatedUrls = paginatedUrls[minPage-1:]
+	if dlOptions.Configs.Sync {
+		return syncFanboxPosts(creatorId, paginatedUrls, dlOptions)
+	}
+
 	var wg sync.WaitGroup
 	maxConcurrency := utils.MAX_API_CALLS
 	if len(paginatedUrls) < maxConcurrency {
@@ -187,15 +263,7 @@ func getFanboxPosts(creatorId, pageNum string, dlOptions *PixivFanboxDlOptions)
 	}
 	queue := make(chan struct{}, maxConcurrency)
 	resChan := make(chan *resStruct, len(paginatedUrls))
-	for idx, paginatedUrl := range paginatedUrls {
-		curPage := idx + 1
-		if curPage < minPage {
-			continue
-		}
-		if hasMax && curPage > maxPage {
-			break
-		}
-
+	for _, paginatedUrl := range paginatedUrls {
 		wg.Add(1)
 		go func(reqUrl string) {
 			defer func() {
@@ -203,36 +271,8 @@ func getFanboxPosts(creatorId, pageNum string, dlOptions *PixivFanboxDlOptions)
 				<-queue
 			}()
 			queue <- struct{}{}
-			res, err := request.CallRequest(
-				&request.RequestArgs{
-					Method:    "GET",
-					Url:       reqUrl,
-					Cookies:   dlOptions.SessionCookies,
-					Headers:   headers,
-					UserAgent: dlOptions.Configs.UserAgent,
-					Http2:     !useHttp3,
-					Http3:     useHttp3,
-				},
-			)
-			if err != nil || res.StatusCode != 200 {
-				if err == nil {
-					res.Body.Close()
-				}
-				utils.LogError(
-					err,
-					fmt.Sprintf("failed to get post for %s", reqUrl),
-					false,
-					utils.ERROR,
-				)
-				return
-			}
-
-			var resJson *models.FanboxCreatorPostsJson
-			if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
-				resChan <- &resStruct{err: err}
-			} else {
-				resChan <- &resStruct{json: resJson}
-			}
+			resJson, err := getCreatorPostsPage(reqUrl, dlOptions)
+			resChan <- &resStruct{json: resJson, err: err}
 		}(paginatedUrl)
 	}
 	wg.Wait()
@@ -9,20 +9,41 @@ type CreatorPaginatedPostsJson struct {
 type FanboxCreatorPostsJson struct {
 	Body struct {
 		Items []struct {
-			Id string `json:"id"`
+			Id                string `json:"id"`
+			PublishedDatetime string `json:"publishedDatetime"`
 		} `json:"items"`
 	} `json:"body"`
 }
 
 type FanboxPostJson struct {
 	Body struct {
-		Id            string          `json:"id"`
-		Title         string          `json:"title"`
-		Type          string          `json:"type"`
-		CreatorId     string          `json:"creatorId"`
-		CoverImageUrl string          `json:"coverImageUrl"`
-		Body          json.RawMessage `json:"body"`
+		Id                string          `json:"id"`
+		Title             string          `json:"title"`
+		Type              string          `json:"type"`
+		CreatorId         string          `json:"creatorId"`
+		CoverImageUrl     string          `json:"coverImageUrl"`
+		PublishedDatetime string          `json:"publishedDatetime"`
+		UpdatedDatetime   string          `json:"updatedDatetime"`
+		FeeRequired       int             `json:"feeRequired"`
+		Tags              []string        `json:"tags"`
+		Excerpt           string          `json:"excerpt"`
+		Body              json.RawMessage `json:"body"`
+		User              struct {
+			Name string `json:"name"`
+		} `json:"user"`
 	} `json:"body"`
+
+	// Raw is the raw JSON of the post for the metadata file
+	Raw json.RawMessage `json:"-"`
+}
+
+func (p *FanboxPostJson) UnmarshalJSON(data []byte) error {
+	type fanboxPostJson FanboxPostJson
+	if err := json.Unmarshal(data, (*fanboxPostJson)(p)); err != nil {
+		return err
+	}
+	p.Raw = append(json.RawMessage(nil), data...)
+	return nil
 }
 
 type FanboxFilePostJson struct {
@@ -1,6 +1,8 @@
 package pixivfanbox
 
 import (
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/render"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
@@ -17,6 +19,10 @@ func PixivFanboxDownloadProcess(pixivFanboxDl *PixivFanboxDl, pixivFanboxDlOptio
 		)
 	}
 
+	if !pixivFanboxDlOptions.Configs.IgnoreHistory {
+		pixivFanboxDl.PostIds = history.FilterPosts(utils.PIXIV_FANBOX, pixivFanboxDl.PostIds)
+	}
+
 	var urlsToDownload, gdriveUrlsToDownload []*request.ToDownload
 	if len(pixivFanboxDl.PostIds) > 0 {
 		urlsToDownload, gdriveUrlsToDownload = pixivFanboxDl.getPostDetails(
@@ -43,6 +49,16 @@ func PixivFanboxDownloadProcess(pixivFanboxDl *PixivFanboxDl, pixivFanboxDlOptio
 		pixivFanboxDlOptions.GdriveClient.DownloadGdriveUrls(gdriveUrlsToDownload, pixivFanboxDlOptions.Configs)
 	}
 
+	if pixivFanboxDlOptions.Configs.DryRun {
+		request.PrintPlan(pixivFanboxDlOptions.Configs)
+		return
+	}
+
+	// render the post bodies and save the processed posts and the sync states
+	// only after the files of the posts have been downloaded
+	render.RenderPending(pixivFanboxDlOptions.Configs)
+	history.CommitPosts()
+	history.CommitSyncStates()
 	if downloadedPosts {
 		utils.AlertWithoutErr(utils.Title, "Downloaded all posts from Pixiv Fanbox!")
 	} else {
@@ -5,9 +5,15 @@ import (
 	"fmt"
 	"net/http"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixivfanbox/models"
 	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/manifest"
+	"github.com/KJHJason/Cultured-Downloader-CLI/metadata"
+	"github.com/KJHJason/Cultured-Downloader-CLI/render"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
@@ -67,6 +73,7 @@ func processFanboxArticlePost(postBody json.RawMessage, postFolderPath string, d
 			urlsSlice = append(urlsSlice, &request.ToDownload{
 				Url:      imageInfo.OriginalUrl,
 				FilePath: filepath.Join(postFolderPath, utils.IMAGES_FOLDER),
+				Expected: &manifest.Expected{Width: imageInfo.Width, Height: imageInfo.Height},
 			})
 		}
 	}
@@ -79,6 +86,7 @@ func processFanboxArticlePost(postBody json.RawMessage, postFolderPath string, d
 			urlsSlice = append(urlsSlice, &request.ToDownload{
 				Url:      attachmentUrl,
 				FilePath: filepath.Join(postFolderPath, utils.ATTACHMENT_FOLDER, filename),
+				Expected: &manifest.Expected{Size: int64(attachmentInfo.Size)},
 			})
 		}
 	}
@@ -161,6 +169,7 @@ func processFanboxFilePost(postBody json.RawMessage, postFolderPath string, dlOp
 			urlsSlice = append(urlsSlice, &request.ToDownload{
 				Url:      fileUrl,
 				FilePath: filePath,
+				Expected: &manifest.Expected{Size: int64(fileInfo.Size)},
 			})
 		}
 	}
@@ -208,6 +217,7 @@ func processFanboxImagePost(postBody json.RawMessage, postFolderPath string, dlO
 			urlsSlice = append(urlsSlice, &request.ToDownload{
 				Url:      fileUrl,
 				FilePath: filePath,
+				Expected: &manifest.Expected{Width: fileInfo.Width, Height: fileInfo.Height},
 			})
 		}
 	}
@@ -248,6 +258,7 @@ func processFanboxPostJson(res *http.Response, downloadPath string, dlOptions *P
 	postType := postJson.Type
 	postBody := postJson.Body
 	if postBody == nil {
+		setFanboxPostInfo(postFolderPath, urlsSlice, nil, &post, dlOptions)
 		return urlsSlice, nil, nil
 	}
 
@@ -286,9 +297,101 @@ func processFanboxPostJson(res *http.Response, downloadPath string, dlOptions *P
 		return nil, nil, err
 	}
 	urlsSlice = append(urlsSlice, newUrlsSlice...)
+	setFanboxPostInfo(postFolderPath, urlsSlice, gdriveLinks, &post, dlOptions)
 	return urlsSlice, gdriveLinks, nil
 }
 
+// getFanboxPostText returns the text of the post body.
+// For locked posts with no body, the post's excerpt will be returned instead.
+func getFanboxPostText(post *models.FanboxPostJson) string {
+	postJson := post.Body
+	if postJson.Body == nil {
+		return postJson.Excerpt
+	}
+
+	if postJson.Type == "article" {
+		var articleJson models.FanboxArticleJson
+		if err := json.Unmarshal(postJson.Body, &articleJson); err != nil {
+			return ""
+		}
+		var textLines []string
+		for _, articleBlock := range articleJson.Blocks {
+			if articleBlock.Text != "" {
+				textLines = append(textLines, articleBlock.Text)
+			}
+		}
+		return strings.Join(textLines, "\n")
+	}
+
+	var textContent models.FanboxTextPostJson
+	if err := json.Unmarshal(postJson.Body, &textContent); err != nil {
+		return ""
+	}
+	return textContent.Text
+}
+
+// getPostMetadata returns the metadata of the Pixiv Fanbox post
+func getPostMetadata(post *models.FanboxPostJson) *metadata.Post {
+	postJson := post.Body
+	postMetadata := &metadata.Post{
+		Site:        utils.PIXIV_FANBOX,
+		Url:         getFanboxPostUrl(postJson.CreatorId, postJson.Id),
+		PostId:      postJson.Id,
+		Title:       postJson.Title,
+		Body:        getFanboxPostText(post),
+		CreatorId:   postJson.CreatorId,
+		CreatorName: postJson.User.Name,
+		PublishedAt: metadata.ParseTime(time.RFC3339, postJson.PublishedDatetime),
+		EditedAt:    metadata.ParseTime(time.RFC3339, postJson.UpdatedDatetime),
+		Tags:        postJson.Tags,
+		Raw:         post.Raw,
+	}
+	if postJson.FeeRequired > 0 {
+		postMetadata.Fee = &metadata.Fee{
+			Price:    postJson.FeeRequired,
+			Currency: "JPY",
+		}
+	}
+	return postMetadata
+}
+
+// setFanboxPostInfo sets the download history post and the path template variables of the post's files
+// and saves the post's metadata file and queues its body to be rendered if enabled.
+func setFanboxPostInfo(postFolderPath string, urlsSlice, gdriveLinks []*request.ToDownload, post *models.FanboxPostJson, dlOptions *PixivFanboxDlOptions) {
+	postJson := post.Body
+	historyPost := &history.Post{
+		Site:      utils.PIXIV_FANBOX,
+		CreatorId: postJson.CreatorId,
+		PostId:    postJson.Id,
+	}
+	for _, urlInfo := range urlsSlice {
+		urlInfo.Post = historyPost
+	}
+	for _, gdriveLink := range gdriveLinks {
+		gdriveLink.Post = historyPost
+	}
+	if len(urlsSlice) == 0 && dlOptions.DlThumbnails && dlOptions.DlImages && dlOptions.DlAttachments {
+		// record text-only and GDrive-only posts as there are no files for the download process to record
+		history.AddProcessedPost(historyPost)
+	}
+
+	publishedAt, _ := time.Parse(time.RFC3339, postJson.PublishedDatetime)
+	pathVars := &utils.PathVars{
+		Site:        utils.PIXIV_FANBOX,
+		CreatorName: postJson.User.Name,
+		CreatorId:   postJson.CreatorId,
+		PostId:      postJson.Id,
+		PostTitle:   postJson.Title,
+		PublishedAt: publishedAt,
+	}
+	request.SetPathVars(urlsSlice, pathVars, postFolderPath, utils.THUMBNAIL_KIND)
+	request.SetPathVars(gdriveLinks, pathVars, postFolderPath, utils.GDRIVE_FOLDER)
+	metadata.Save(getPostMetadata(post), postFolderPath, pathVars, dlOptions.Configs)
+	if dlOptions.Configs.BodyFormat != "" {
+		render.AddPending(getFanboxPostDocument(post, postFolderPath, pathVars))
+	}
+}
+
 func processMultiplePostJson(resChan chan *http.Response, dlOptions *PixivFanboxDlOptions) ([]*request.ToDownload, []*request.ToDownload) {
 	// parse the responses
 	var errSlice []error
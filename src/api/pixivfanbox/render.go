@@ -0,0 +1,165 @@
+package pixivfanbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"unicode/utf16"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixivfanbox/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/render"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+func getFanboxPostUrl(creatorId, postId string) string {
+	return fmt.Sprintf("https://www.fanbox.cc/@%s/posts/%s", creatorId, postId)
+}
+
+type articleTextRange struct {
+	offset int
+	length int
+	bold   bool
+	link   string
+}
+
+// getArticleSpans splits the text of an article block into spans based on its styles and links.
+//
+// Note that the offsets and lengths of the styles and links are in UTF-16 code units.
+func getArticleSpans(text string, ranges []*articleTextRange) []*render.Span {
+	encodedText := utf16.Encode([]rune(text))
+	textLen := len(encodedText)
+
+	boundaries := []int{0, textLen}
+	for _, textRange := range ranges {
+		boundaries = append(boundaries, textRange.offset, textRange.offset+textRange.length)
+	}
+	sort.Ints(boundaries)
+
+	var spans []*render.Span
+	for idx := 0; idx < len(boundaries)-1; idx++ {
+		start, end := boundaries[idx], boundaries[idx+1]
+		if start == end || start < 0 || end > textLen {
+			continue
+		}
+
+		span := &render.Span{Text: string(utf16.Decode(encodedText[start:end]))}
+		for _, textRange := range ranges {
+			if start < textRange.offset || end > textRange.offset+textRange.length {
+				continue
+			}
+			if textRange.bold {
+				span.Bold = true
+			}
+			if textRange.link != "" {
+				span.Link = textRange.link
+			}
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+func getArticleBlocks(articleJson *models.FanboxArticleJson) []*render.Block {
+	var blocks []*render.Block
+	for _, articleBlock := range articleJson.Blocks {
+		switch articleBlock.Type {
+		case "image":
+			if imageInfo, ok := articleJson.ImageMap[articleBlock.ImageID]; ok {
+				blocks = append(blocks, &render.Block{
+					Type: render.IMAGE_BLOCK,
+					Url:  imageInfo.OriginalUrl,
+				})
+			}
+		case "file":
+			if fileInfo, ok := articleJson.FileMap[articleBlock.FileID]; ok {
+				blocks = append(blocks, &render.Block{
+					Type: render.FILE_BLOCK,
+					Url:  fileInfo.Url,
+					Name: fileInfo.Name + "." + fileInfo.Extension,
+				})
+			}
+		case "p", "header":
+			var ranges []*articleTextRange
+			for _, style := range articleBlock.Styles {
+				ranges = append(ranges, &articleTextRange{
+					offset: style.Offset,
+					length: style.Length,
+					bold:   style.Type == "bold",
+				})
+			}
+			for _, link := range articleBlock.Links {
+				ranges = append(ranges, &articleTextRange{
+					offset: link.Offset,
+					length: link.Length,
+					link:   link.Url,
+				})
+			}
+
+			blockType := render.PARAGRAPH_BLOCK
+			if articleBlock.Type == "header" {
+				blockType = render.HEADER_BLOCK
+			}
+			blocks = append(blocks, &render.Block{
+				Type:  blockType,
+				Spans: getArticleSpans(articleBlock.Text, ranges),
+			})
+		}
+	}
+	return blocks
+}
+
+// getFanboxPostDocument returns the post body to be rendered with the images and files in the post.
+func getFanboxPostDocument(post *models.FanboxPostJson, postFolderPath string, pathVars *utils.PathVars) *render.Document {
+	postJson := post.Body
+	doc := render.NewDocument(
+		postJson.Title,
+		getFanboxPostUrl(postJson.CreatorId, postJson.Id),
+		postFolderPath,
+		pathVars,
+	)
+	if postJson.CoverImageUrl != "" {
+		doc.AddBlocks(&render.Block{Type: render.IMAGE_BLOCK, Url: postJson.CoverImageUrl})
+	}
+	if postJson.Body == nil {
+		doc.AddBlocks(render.TextBlock(postJson.Excerpt))
+		return doc
+	}
+
+	switch postJson.Type {
+	case "article":
+		var articleJson models.FanboxArticleJson
+		if err := json.Unmarshal(postJson.Body, &articleJson); err == nil {
+			doc.AddBlocks(getArticleBlocks(&articleJson)...)
+		}
+	case "image":
+		var imagePostJson models.FanboxImagePostJson
+		if err := json.Unmarshal(postJson.Body, &imagePostJson); err == nil {
+			for _, imageInfo := range imagePostJson.Images {
+				doc.AddBlocks(&render.Block{
+					Type: render.IMAGE_BLOCK,
+					Url:  imageInfo.OriginalUrl,
+				})
+			}
+			doc.AddBlocks(render.TextBlock(imagePostJson.Text))
+		}
+	case "file":
+		var filePostJson models.FanboxFilePostJson
+		if err := json.Unmarshal(postJson.Body, &filePostJson); err == nil {
+			for _, fileInfo := range filePostJson.Files {
+				blockType := render.FILE_BLOCK
+				if utils.SliceContains(pixivFanboxAllowedImageExt, fileInfo.Extension) {
+					blockType = render.IMAGE_BLOCK
+				}
+				doc.AddBlocks(&render.Block{
+					Type: blockType,
+					Url:  fileInfo.Url,
+					Name: fileInfo.Name + "." + fileInfo.Extension,
+				})
+			}
+			doc.AddBlocks(render.TextBlock(filePostJson.Text))
+		}
+	default:
+		doc.AddBlocks(render.TextBlock(getFanboxPostText(post)))
+	}
+	return doc
+}
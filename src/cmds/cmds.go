@@ -1,7 +1,17 @@
 package cmds
 
 import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/dedup"
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/render"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
 
@@ -9,6 +19,122 @@ func getMultipleIdsMsg() string {
 	return "For multiple IDs, separate them with a comma.\nExample: \"12345,67891\" (without the quotes)"
 }
 
+// parsePathTemplate parses the output path template and exits the program if it is invalid
+func parsePathTemplate(pathTemplate string) *utils.PathTemplate {
+	if pathTemplate == "" {
+		return nil
+	}
+
+	parsedTemplate, err := utils.ParsePathTemplate(pathTemplate)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	return parsedTemplate
+}
+
+// validateBodyFormat validates the post body format and exits the program if it is invalid
+func validateBodyFormat(bodyFormat string) string {
+	bodyFormat = strings.ToLower(bodyFormat)
+	if bodyFormat == "" {
+		return ""
+	}
+
+	return utils.ValidateStrArgs(
+		bodyFormat,
+		render.ACCEPTED_FORMATS,
+		[]string{
+			fmt.Sprintf(
+				"error %d: post body format %q is not allowed",
+				utils.INPUT_ERROR,
+				bodyFormat,
+			),
+		},
+	)
+}
+
+// validateDedupMode validates the deduplication mode and exits the program if it is invalid
+func validateDedupMode(dedupMode string) string {
+	dedupMode = strings.ToLower(dedupMode)
+	if dedupMode == "" {
+		return ""
+	}
+
+	return utils.ValidateStrArgs(
+		dedupMode,
+		dedup.ACCEPTED_MODES,
+		[]string{
+			fmt.Sprintf(
+				"error %d: deduplication mode %q is not allowed",
+				utils.INPUT_ERROR,
+				dedupMode,
+			),
+		},
+	)
+}
+
+// applyDryRun validates the dry-run options of the config and disables the log files
+// in the post folders and the changes to the download history if the dry-run mode is enabled.
+func applyDryRun(config *configs.Config) {
+	if !config.DryRun {
+		return
+	}
+
+	config.DryRunFormat = utils.ValidateStrArgs(
+		strings.ToLower(config.DryRunFormat),
+		request.ACCEPTED_PLAN_FORMATS,
+		[]string{
+			fmt.Sprintf(
+				"error %d: dry-run format %q is not allowed",
+				utils.INPUT_ERROR,
+				config.DryRunFormat,
+			),
+		},
+	)
+	utils.DisableLogToPath()
+	history.SetReadOnly()
+}
+
+// applyRateLimits sets the shared bandwidth limit and the per-host rate limits
+// of the requests and exits the program if any of them are invalid
+func applyRateLimits(limitRate string, hostRateLimits []string) {
+	if limitRate != "" {
+		bytesPerSec, err := request.ParseByteRate(limitRate)
+		if err != nil {
+			color.Red(err.Error())
+			os.Exit(1)
+		}
+		request.SetBandwidthLimit(bytesPerSec)
+	}
+
+	if len(hostRateLimits) == 0 {
+		return
+	}
+	limits := make(map[string]float64, len(hostRateLimits))
+	for _, hostRateLimit := range hostRateLimits {
+		host, reqPerSec, err := request.ParseHostRateLimit(hostRateLimit)
+		if err != nil {
+			color.Red(err.Error())
+			os.Exit(1)
+		}
+		limits[host] = reqPerSec
+	}
+	request.SetHostRateLimits(limits)
+}
+
+// applyMirrors sets the groups of equivalent hosts that the requests fail
+// over between and exits the program if any of them are invalid
+func applyMirrors(mirrorGroups []string) {
+	for _, mirrorGroup := range mirrorGroups {
+		hosts, err := request.ParseMirrorGroup(mirrorGroup)
+		if err != nil {
+			color.Red(err.Error())
+			os.Exit(1)
+		}
+		request.AddMirrorGroup(hosts, "")
+	}
+}
+
 type textFilePath struct {
 	variable *string
 	desc     string
@@ -21,6 +147,17 @@ type commonFlags struct {
 	gdriveApiKeyVar         *string 
 	gdriveServiceAccPathVar *string
 	logUrlsVar              *bool
+	ignoreHistoryVar        *bool
+	syncVar                 *bool
+	pathTemplateVar         *string
+	saveMetadataVar         *bool
+	bodyFormatVar           *string
+	dryRunVar               *bool
+	dryRunFormatVar         *string
+	dryRunOutputVar         *string
+	limitRateVar            *string
+	hostRateLimitsVar       *[]string
+	dedupVar                *string
 	textFile                textFilePath
 }
 
@@ -34,6 +171,17 @@ func init() {
 			gdriveApiKeyVar:         &fantiaGdriveApiKey,
 			gdriveServiceAccPathVar: &fantiaGdriveServiceAccPath,
 			logUrlsVar:              &fantiaLogUrls,
+			ignoreHistoryVar:        &fantiaIgnoreHistory,
+			syncVar:                 &fantiaSync,
+			pathTemplateVar:         &fantiaPathTemplate,
+			saveMetadataVar:         &fantiaSaveMetadata,
+			dryRunVar:               &fantiaDryRun,
+			dryRunFormatVar:         &fantiaDryRunFormat,
+			dryRunOutputVar:         &fantiaDryRunOutput,
+			limitRateVar:            &fantiaLimitRate,
+			hostRateLimitsVar:       &fantiaHostRateLimits,
+			dedupVar:                &fantiaDedup,
+			bodyFormatVar:           &fantiaBodyFormat,
 			textFile: textFilePath {
 				variable: &fantiaDlTextFile,
 				desc:     "Path to a text file containing Fanclub and/or post URL(s) to download from Fantia.",
@@ -47,6 +195,17 @@ func init() {
 			gdriveApiKeyVar:         &fanboxGdriveApiKey,
 			gdriveServiceAccPathVar: &fanboxGdriveApiKey,
 			logUrlsVar:              &fanboxLogUrls,
+			ignoreHistoryVar:        &fanboxIgnoreHistory,
+			syncVar:                 &fanboxSync,
+			pathTemplateVar:         &fanboxPathTemplate,
+			saveMetadataVar:         &fanboxSaveMetadata,
+			dryRunVar:               &fanboxDryRun,
+			dryRunFormatVar:         &fanboxDryRunFormat,
+			dryRunOutputVar:         &fanboxDryRunOutput,
+			limitRateVar:            &fanboxLimitRate,
+			hostRateLimitsVar:       &fanboxHostRateLimits,
+			dedupVar:                &fanboxDedup,
+			bodyFormatVar:           &fanboxBodyFormat,
 			textFile: textFilePath {
 				variable: &fanboxDlTextFile,
 				desc:     "Path to a text file containing creator and/or post URL(s) to download from Pixiv Fanbox.",
@@ -54,9 +213,19 @@ func init() {
 		},
 		{
 			cmd: pixivCmd,
-			overwriteVar:  &pixivOverwrite,
-			cookieFileVar: &pixivCookieFile,
-			userAgentVar:  &pixivUserAgent,
+			overwriteVar:     &pixivOverwrite,
+			cookieFileVar:    &pixivCookieFile,
+			userAgentVar:     &pixivUserAgent,
+			ignoreHistoryVar: &pixivIgnoreHistory,
+			syncVar:          &pixivSync,
+			pathTemplateVar:  &pixivPathTemplate,
+			saveMetadataVar:  &pixivSaveMetadata,
+			dryRunVar:        &pixivDryRun,
+			dryRunFormatVar:  &pixivDryRunFormat,
+			dryRunOutputVar:  &pixivDryRunOutput,
+			limitRateVar:     &pixivLimitRate,
+			hostRateLimitsVar:&pixivHostRateLimits,
+			dedupVar:         &pixivDedup,
 			textFile: textFilePath {
 				variable: &pixivDlTextFile,
 				desc:     "Path to a text file containing artwork, illustrator, and tag name URL(s) to download from Pixiv.",
@@ -70,11 +239,46 @@ func init() {
 			gdriveApiKeyVar:         &kemonoGdriveApiKey,
 			gdriveServiceAccPathVar: &kemonoGdriveServiceAccPath,
 			logUrlsVar:              &kemonoLogUrls,
+			ignoreHistoryVar:        &kemonoIgnoreHistory,
+			syncVar:                 &kemonoSync,
+			pathTemplateVar:         &kemonoPathTemplate,
+			saveMetadataVar:         &kemonoSaveMetadata,
+			dryRunVar:               &kemonoDryRun,
+			dryRunFormatVar:         &kemonoDryRunFormat,
+			dryRunOutputVar:         &kemonoDryRunOutput,
+			limitRateVar:            &kemonoLimitRate,
+			hostRateLimitsVar:       &kemonoHostRateLimits,
+			dedupVar:                &kemonoDedup,
+			bodyFormatVar:           &kemonoBodyFormat,
 			textFile: textFilePath {
 				variable: &kemonoDlTextFile,
 				desc: "Path to a text file containing creator and/or post URL(s) to download from Kemono Party.",
 			},
 		},
+		{
+			cmd: coomerCmd,
+			overwriteVar:            &coomerOverwrite,
+			cookieFileVar:           &coomerCookieFile,
+			userAgentVar:            &coomerUserAgent,
+			gdriveApiKeyVar:         &coomerGdriveApiKey,
+			gdriveServiceAccPathVar: &coomerGdriveServiceAccPath,
+			logUrlsVar:              &coomerLogUrls,
+			ignoreHistoryVar:        &coomerIgnoreHistory,
+			syncVar:                 &coomerSync,
+			pathTemplateVar:         &coomerPathTemplate,
+			saveMetadataVar:         &coomerSaveMetadata,
+			dryRunVar:               &coomerDryRun,
+			dryRunFormatVar:         &coomerDryRunFormat,
+			dryRunOutputVar:         &coomerDryRunOutput,
+			limitRateVar:            &coomerLimitRate,
+			hostRateLimitsVar:       &coomerHostRateLimits,
+			dedupVar:                &coomerDedup,
+			bodyFormatVar:           &coomerBodyFormat,
+			textFile: textFilePath {
+				variable: &coomerDlTextFile,
+				desc: "Path to a text file containing creator and/or post URL(s) to download from Coomer Party.",
+			},
+		},
 	}
 	for _, cmdInfo := range commonCmdFlags {
 		cmd := cmdInfo.cmd
@@ -88,6 +292,97 @@ func init() {
 				"Usually used for Pixiv Fanbox when there are incomplete downloads.",
 			),
 		)
+		cmd.Flags().BoolVar(
+			cmdInfo.ignoreHistoryVar,
+			"ignore_history",
+			false,
+			utils.CombineStringsWithNewline(
+				"Download posts even if they are already in the download history.",
+				"Use the \"history\" command to view or remove entries from the download history.",
+			),
+		)
+		cmd.Flags().BoolVar(
+			cmdInfo.syncVar,
+			"sync",
+			false,
+			utils.CombineStringsWithNewline(
+				"Only get the posts of creators that are newer than the newest post seen from the last sync.",
+				"The newest post of each creator will be recorded after the download process.",
+			),
+		)
+		cmd.Flags().StringVar(
+			cmdInfo.pathTemplateVar,
+			"path_template",
+			"",
+			utils.CombineStringsWithNewline(
+				"Output path template of the downloaded files relative to the download path.",
+				"Variables: {site}, {creator}, {creator_id}, {post_id}, {title}, {yyyy}, {mm}, {dd}, {yyyy-mm-dd},",
+				"{index} (or {index:03} to zero-pad it), {filename}, {name}, {ext}, {kind} (e.g. images, attachments, thumbnail, revision, cbz),",
+				"and {revision_id} (the ID of the earlier revision of a Kemono Party post, empty otherwise).",
+				"Example: \"{site}/{creator_id}/{yyyy}/{yyyy-mm-dd}_{post_id}/{index:03}_{filename}\"",
+				"Leave blank to use the default folder layout.",
+			),
+		)
+		cmd.Flags().BoolVar(
+			cmdInfo.saveMetadataVar,
+			"save_metadata",
+			false,
+			utils.CombineStringsWithNewline(
+				"Save the metadata of each post such as its title, body text, publish date, tags, and fee tier",
+				"along with the raw API response to a \"post.json\" file in the post folder.",
+			),
+		)
+		cmd.Flags().BoolVar(
+			cmdInfo.dryRunVar,
+			"dry_run",
+			false,
+			utils.CombineStringsWithNewline(
+				"Only print the files that would be downloaded along with their expected sizes and file paths",
+				"and whether they would be skipped as they already exist without downloading or saving anything.",
+			),
+		)
+		cmd.Flags().StringVar(
+			cmdInfo.dryRunFormatVar,
+			"dry_run_format",
+			request.TABLE_PLAN_FORMAT,
+			"Format of the printed download plan for the --dry_run flag. Accepted values: \"table\", \"json\", or \"csv\".",
+		)
+		cmd.Flags().StringVar(
+			cmdInfo.dryRunOutputVar,
+			"dry_run_output",
+			"",
+			"File path to export the download plan to for the --dry_run flag instead of printing it.",
+		)
+		cmd.Flags().StringVar(
+			cmdInfo.limitRateVar,
+			"limit_rate",
+			"",
+			utils.CombineStringsWithNewline(
+				"Limit the total download speed shared across all downloads in bytes per second.",
+				"Supports the K, M, and G suffixes such as \"500K\" or \"5M\". Leave blank to not limit the download speed.",
+			),
+		)
+		cmd.Flags().StringSliceVar(
+			cmdInfo.hostRateLimitsVar,
+			"host_rate_limit",
+			[]string{},
+			utils.CombineStringsWithNewline(
+				"Limit the number of requests per second sent to a host (including its subdomains) in the format \"<host>=<requests per second>\".",
+				"For multiple hosts, separate them with a comma.",
+				"Example: \"i.pximg.net=2,kemono.su=0.5\" (without the quotes)",
+			),
+		)
+		cmd.Flags().StringVar(
+			cmdInfo.dedupVar,
+			"dedup",
+			"",
+			utils.CombineStringsWithNewline(
+				"Detect files that are identical to previously downloaded files across creators and sites by their SHA-256 checksum.",
+				"Identical files are stored as a \"hardlink\", a \"reflink\" (copy-on-write clone, falls back to a hardlink if unsupported),",
+				"or a \"reference\" to the existing file in the manifest and the rendered post body without storing it again.",
+				"Kemono Party files with the checksum in their URL will not be downloaded again. Leave blank to disable.",
+			),
+		)
 		cmd.Flags().StringVarP(
 			cmdInfo.userAgentVar,
 			"user_agent",
@@ -148,6 +443,18 @@ func init() {
 				),
 			)
 		}
+		if cmdInfo.bodyFormatVar != nil {
+			cmd.Flags().StringVar(
+				cmdInfo.bodyFormatVar,
+				"body_format",
+				"",
+				utils.CombineStringsWithNewline(
+					"Render the body text of each post to a \"post.md\" or \"post.html\" file in the post folder.",
+					"Inline images will be linked to the downloaded files so that the post can be read offline.",
+					"Accepted values: \"md\" or \"html\". Leave blank to not render the post body.",
+				),
+			)
+		}
 		RootCmd.AddCommand(cmd)
 	}
 }
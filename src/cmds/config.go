@@ -0,0 +1,368 @@
+package cmds
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	configProfile string
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Manage the config file and its profiles",
+		Long: utils.CombineStringsWithNewline(
+			fmt.Sprintf("The config file is located at %q.", configs.GetConfigFilePath()),
+			"Each profile contains the flag values of each command, e.g. \"fantia.session\" for the fantia command's --session flag.",
+			"The values are applied in the following order of precedence:",
+			fmt.Sprintf("environment variables (e.g. %s), flags, the selected profile, and the default values.", configs.GetEnvKey("fantia", "session")),
+			fmt.Sprintf("The profile can be selected using the --profile flag or the %s environment variable.", configs.PROFILE_ENV),
+		),
+	}
+	configGetCmd = &cobra.Command{
+		Use:   "get <command>.<flag>",
+		Short: "Get the value of a flag in the profile",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			configFile := loadConfigFile()
+			_, cmdName, flagName := parseConfigKey(args[0])
+			value, ok := configFile.Get(configProfile, cmdName, flagName)
+			if !ok {
+				color.Yellow("%s is not set in the %q profile.", args[0], configFile.ResolveProfileName(configProfile))
+				return
+			}
+			values, _ := configs.FormatValue(value)
+			fmt.Println(strings.Join(values, ","))
+		},
+	}
+	configSetCmd = &cobra.Command{
+		Use:   "set <command>.<flag> <value>",
+		Short: "Set the value of a flag in the profile",
+		Long: utils.CombineStringsWithNewline(
+			"Set the value of a flag in the profile.",
+			"For flags that accepts multiple values, separate them with a comma.",
+			"Use \"default_profile\" as the key to set the profile to use when the --profile flag is not provided.",
+		),
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			configFile := loadConfigFile()
+			if args[0] == "default_profile" {
+				configFile.DefaultProfile = args[1]
+			} else {
+				flag, cmdName, flagName := parseConfigKey(args[0])
+				value, err := convertConfigValue(flag, []string{args[1]}, false)
+				if err != nil {
+					color.Red(err.Error())
+					os.Exit(1)
+				}
+				configFile.Set(configProfile, cmdName, flagName, value)
+			}
+
+			if err := configFile.Save(); err != nil {
+				utils.LogError(err, "", true, utils.ERROR)
+			}
+			color.Green("Saved %s to %q.", args[0], configs.GetConfigFilePath())
+		},
+	}
+	configListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the values of all the profiles or the profile given by the --profile flag",
+		Run: func(cmd *cobra.Command, args []string) {
+			configFile := loadConfigFile()
+			profileNames := configFile.GetProfileNames()
+			if configProfile != "" {
+				profileNames = []string{configProfile}
+			}
+			if len(profileNames) == 0 {
+				color.Yellow("No profiles found in %q.", configs.GetConfigFilePath())
+				return
+			}
+
+			fmt.Printf("default_profile: %s\n", configFile.ResolveProfileName(""))
+			for _, profileName := range profileNames {
+				profile, err := configFile.GetProfile(profileName)
+				if err != nil {
+					color.Red(err.Error())
+					os.Exit(1)
+				}
+
+				fmt.Printf("[%s]\n", profileName)
+				for _, cmdName := range getSortedKeys(profile) {
+					for _, flagName := range getSortedKeys(profile[cmdName]) {
+						values, _ := configs.FormatValue(profile[cmdName][flagName])
+						fmt.Printf("\t%s.%s = %s\n", cmdName, flagName, strings.Join(values, ","))
+					}
+				}
+			}
+		},
+	}
+	configValidateCmd = &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the config file",
+		Run: func(cmd *cobra.Command, args []string) {
+			configFile := loadConfigFile()
+			var errSlice []error
+			if configFile.DefaultProfile != "" {
+				if _, ok := configFile.Profiles[configFile.DefaultProfile]; !ok {
+					errSlice = append(errSlice, fmt.Errorf(
+						"config error %d: default profile %q does not exist",
+						utils.INPUT_ERROR,
+						configFile.DefaultProfile,
+					))
+				}
+			}
+			for _, profileName := range configFile.GetProfileNames() {
+				errSlice = append(errSlice, validateProfile(profileName, configFile.Profiles[profileName])...)
+			}
+
+			if len(errSlice) > 0 {
+				for _, err := range errSlice {
+					color.Red(err.Error())
+				}
+				os.Exit(1)
+			}
+			color.Green("%q is valid.", configs.GetConfigFilePath())
+		},
+	}
+)
+
+// Returns the commands that can be configured in the profiles
+func getConfigurableCmds() []*cobra.Command {
+	return []*cobra.Command{fantiaCmd, pixivFanboxCmd, pixivCmd, kemonoCmd, coomerCmd}
+}
+
+// getConfigurableCmdNames returns the names of the configurable commands, e.g. "fantia, pixiv, or kemono"
+func getConfigurableCmdNames() string {
+	cmds := getConfigurableCmds()
+	cmdNames := make([]string, len(cmds))
+	for idx, cmd := range cmds {
+		cmdNames[idx] = cmd.Name()
+	}
+	lastIdx := len(cmdNames) - 1
+	return strings.Join(cmdNames[:lastIdx], ", ") + ", or " + cmdNames[lastIdx]
+}
+
+func getConfigurableCmd(cmdName string) *cobra.Command {
+	for _, cmd := range getConfigurableCmds() {
+		if cmd.Name() == cmdName {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func getSortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func loadConfigFile() *configs.ConfigFile {
+	configFile, err := configs.LoadConfigFile()
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	return configFile
+}
+
+// getConfigFlag returns the flag of the configurable command
+func getConfigFlag(cmdName, flagName string) (*pflag.Flag, error) {
+	cmd := getConfigurableCmd(cmdName)
+	if cmd == nil {
+		return nil, fmt.Errorf(
+			"config error %d: unknown command %q, expected one of %s",
+			utils.INPUT_ERROR,
+			cmdName,
+			getConfigurableCmdNames(),
+		)
+	}
+
+	flag := cmd.Flags().Lookup(flagName)
+	if flag == nil || flagName == "help" {
+		return nil, fmt.Errorf(
+			"config error %d: unknown flag %q for the %s command",
+			utils.INPUT_ERROR,
+			flagName,
+			cmdName,
+		)
+	}
+	return flag, nil
+}
+
+// parseConfigKey parses the "<command>.<flag>" key and exits the program if it is invalid
+func parseConfigKey(key string) (*pflag.Flag, string, string) {
+	cmdName, flagName, ok := strings.Cut(key, ".")
+	if !ok {
+		color.Red(
+			"config error %d: invalid key %q, expected the format \"<command>.<flag>\", e.g. \"fantia.session\"",
+			utils.INPUT_ERROR,
+			key,
+		)
+		os.Exit(1)
+	}
+
+	flag, err := getConfigFlag(cmdName, flagName)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	return flag, cmdName, flagName
+}
+
+// convertConfigValue converts the string value(s) to the type of the flag to be saved in the config file
+func convertConfigValue(flag *pflag.Flag, values []string, isList bool) (interface{}, error) {
+	if _, ok := flag.Value.(pflag.SliceValue); ok {
+		if isList {
+			return values, nil
+		}
+		return readAsCsv(values[0])
+	}
+
+	if isList {
+		return nil, fmt.Errorf(
+			"config error %d: the --%s flag does not accept multiple values",
+			utils.INPUT_ERROR,
+			flag.Name,
+		)
+	}
+
+	var err error
+	var value interface{}
+	switch flag.Value.Type() {
+	case "bool":
+		value, err = strconv.ParseBool(values[0])
+	case "int":
+		value, err = strconv.Atoi(values[0])
+	default:
+		value = values[0]
+	}
+	if err != nil {
+		return nil, fmt.Errorf(
+			"config error %d: invalid %s value, %q, for the --%s flag",
+			utils.INPUT_ERROR,
+			flag.Value.Type(),
+			values[0],
+			flag.Name,
+		)
+	}
+	return value, nil
+}
+
+func readAsCsv(value string) ([]string, error) {
+	if value == "" {
+		return []string{}, nil
+	}
+	return csv.NewReader(strings.NewReader(value)).Read()
+}
+
+func validateProfile(profileName string, profile configs.Profile) []error {
+	var errSlice []error
+	for _, cmdName := range getSortedKeys(profile) {
+		for _, flagName := range getSortedKeys(profile[cmdName]) {
+			flag, err := getConfigFlag(cmdName, flagName)
+			if err == nil {
+				values, isList := configs.FormatValue(profile[cmdName][flagName])
+				_, err = convertConfigValue(flag, values, isList)
+			}
+			if err != nil {
+				errSlice = append(errSlice, fmt.Errorf("[%s] %s.%s: %v", profileName, cmdName, flagName, err))
+			}
+		}
+	}
+	return errSlice
+}
+
+// setFlagValue sets the value of the flag and marks it as changed
+// so that it counts towards any required flags.
+func setFlagValue(flags *pflag.FlagSet, flag *pflag.Flag, values []string, isList bool) error {
+	if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+		if !isList {
+			var err error
+			if values, err = readAsCsv(values[0]); err != nil {
+				return err
+			}
+		}
+		if err := sliceValue.Replace(values); err != nil {
+			return err
+		}
+		flag.Changed = true
+		return nil
+	}
+
+	if isList {
+		return fmt.Errorf("the --%s flag does not accept multiple values", flag.Name)
+	}
+	return flags.Set(flag.Name, values[0])
+}
+
+// applyConfig applies the environment variables and the selected profile to the command's flags.
+//
+// Precedence: environment variables > flags > profile > default values.
+func applyConfig(cmd *cobra.Command) {
+	if getConfigurableCmd(cmd.Name()) != cmd {
+		return
+	}
+
+	configFile := loadConfigFile()
+	profileName := configProfile
+	if envProfile := os.Getenv(configs.PROFILE_ENV); envProfile != "" {
+		profileName = envProfile
+	}
+	profile, err := configFile.GetProfile(profileName)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+
+	var errSlice []error
+	cmdName := cmd.Name()
+	flags := cmd.Flags()
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Name == "help" {
+			return
+		}
+
+		source := configs.GetEnvKey(cmdName, flag.Name)
+		envValue, hasEnv := os.LookupEnv(source)
+		var err error
+		if hasEnv {
+			err = setFlagValue(flags, flag, []string{envValue}, false)
+		} else if value, ok := profile[cmdName][flag.Name]; ok && !flag.Changed {
+			source = fmt.Sprintf("%s.%s in the %q profile", cmdName, flag.Name, configFile.ResolveProfileName(profileName))
+			values, isList := configs.FormatValue(value)
+			err = setFlagValue(flags, flag, values, isList)
+		}
+		if err != nil {
+			errSlice = append(errSlice, fmt.Errorf(
+				"config error %d: failed to apply %s, more info => %v",
+				utils.INPUT_ERROR,
+				source,
+				err,
+			))
+		}
+	})
+	if len(errSlice) > 0 {
+		for _, err := range errSlice {
+			color.Red(err.Error())
+		}
+		os.Exit(1)
+	}
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd, configSetCmd, configListCmd, configValidateCmd)
+	RootCmd.AddCommand(configCmd)
+}
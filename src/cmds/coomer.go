@@ -0,0 +1,213 @@
+package cmds
+
+import (
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono"
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/KJHJason/Cultured-Downloader-CLI/cmds/textparser"
+	"github.com/spf13/cobra"
+)
+
+var (
+	coomerDlTextFile           string
+	coomerCookieFile           string
+	coomerSession              string
+	coomerCreatorUrls          []string
+	coomerPageNums             []string
+	coomerPostUrls             []string
+	coomerDlGdrive             bool
+	coomerGdriveApiKey         string
+	coomerGdriveServiceAccPath string
+	coomerDlAttachments        bool
+	coomerOverwrite            bool
+	coomerLogUrls              bool
+	coomerDlFav                bool
+	coomerDlFavCreators        bool
+	coomerDlFavPosts           bool
+	coomerUserAgent            string
+	coomerIgnoreHistory        bool
+	coomerSync                 bool
+	coomerPathTemplate         string
+	coomerSaveMetadata         bool
+	coomerBodyFormat           string
+	coomerDryRun               bool
+	coomerDryRunFormat         string
+	coomerDryRunOutput         string
+	coomerLimitRate            string
+	coomerHostRateLimits       []string
+	coomerMirrors              []string
+	coomerDedup                string
+	coomerCmd = &cobra.Command{
+		Use:   "coomer",
+		Short: "Download from Coomer Party",
+		Long:  "Supports downloads from creators and posts on Coomer Party.",
+		Run: func(cmd *cobra.Command, args []string) {
+			coomerConfig := &configs.Config{
+				OverwriteFiles: coomerOverwrite,
+				UserAgent:      coomerUserAgent,
+				LogUrls:        coomerLogUrls,
+				IgnoreHistory:  coomerIgnoreHistory,
+				Sync:           coomerSync,
+				PathTemplate:   parsePathTemplate(coomerPathTemplate),
+				SaveMetadata:   coomerSaveMetadata,
+				BodyFormat:     validateBodyFormat(coomerBodyFormat),
+				DryRun:         coomerDryRun,
+				DryRunFormat:   coomerDryRunFormat,
+				DryRunOutput:   coomerDryRunOutput,
+				Dedup:          validateDedupMode(coomerDedup),
+			}
+			applyDryRun(coomerConfig)
+			applyRateLimits(coomerLimitRate, coomerHostRateLimits)
+			applyMirrors(coomerMirrors)
+			var gdriveClient *gdrive.GDrive
+			if coomerGdriveApiKey != "" || coomerGdriveServiceAccPath != "" {
+				gdriveClient = gdrive.GetNewGDrive(
+					coomerGdriveApiKey,
+					coomerGdriveServiceAccPath,
+					coomerConfig,
+					utils.MAX_CONCURRENT_DOWNLOADS,
+				)
+			}
+
+			coomerDl := &kemono.KemonoDl{
+				CreatorUrls:     coomerCreatorUrls,
+				CreatorPageNums: coomerPageNums,
+				PostUrls:        coomerPostUrls,
+			}
+			if coomerDlTextFile != "" {
+				coomerPostToDl, coomerCreatorToDl := textparser.ParseKemonoTextFile(coomerDlTextFile, kemono.COOMER_ARCHIVE)
+				coomerDl.PostsToDl = coomerPostToDl
+				coomerDl.CreatorsToDl = coomerCreatorToDl
+			}
+			coomerDl.ValidateArgs(kemono.COOMER_ARCHIVE)
+
+			coomerDlOptions := &kemono.KemonoDlOptions{
+				Archive:         kemono.COOMER_ARCHIVE,
+				DlAttachments:   coomerDlAttachments,
+				DlGdrive:        coomerDlGdrive,
+				Configs:         coomerConfig,
+				SessionCookieId: coomerSession,
+				GdriveClient:    gdriveClient,
+			}
+			if coomerCookieFile != "" {
+				cookies, err := utils.ParseNetscapeCookieFile(
+					coomerCookieFile,
+					coomerSession,
+					utils.COOMER,
+				)
+				if err != nil {
+					utils.LogError(
+						err,
+						"",
+						true,
+						utils.ERROR,
+					)
+				}
+				coomerDlOptions.SessionCookies = cookies
+			}
+
+			coomerDlOptions.ValidateArgs(coomerUserAgent)
+			// --dl_fav downloads both the favourited creators and the favourited posts
+			dlFavCreators := coomerDlFav || coomerDlFavCreators
+			dlFavPosts := coomerDlFav || coomerDlFavPosts
+			coomerDlOptions.ValidateFavArgs(dlFavCreators, dlFavPosts)
+
+			utils.PrintWarningMsg()
+			kemono.KemonoDownloadProcess(
+				coomerConfig,
+				coomerDl,
+				coomerDlOptions,
+				dlFavCreators,
+				dlFavPosts,
+			)
+		},
+	}
+)
+
+func init() {
+	mutlipleUrlsMsg := "Multiple URLs can be supplied by separating them with a comma.\n" + 
+						"Example: \"https://coomer.party/service/user/123,https://coomer.party/service/user/456\" (without the quotes)"
+	coomerCmd.Flags().StringVarP(
+		&coomerSession,
+		"session",
+		"s",
+		"",
+		utils.CombineStringsWithNewline(
+			"Your Coomer Party \"session\" cookie value to use for the requests to Coomer Party.",
+			"Not required for public posts and creators but required to download from your favourites.",
+		),
+	)
+	coomerCmd.Flags().StringSliceVar(
+		&coomerCreatorUrls,
+		"creator_url",
+		[]string{},
+		utils.CombineStringsWithNewline(
+			"Coomer Party creator URL(s) to download from.",
+			mutlipleUrlsMsg,
+		),
+	)
+	coomerCmd.Flags().StringSliceVar(
+		&coomerPageNums,
+		"page_num",
+		[]string{},
+		utils.CombineStringsWithNewline(
+			"Min and max page numbers to search for corresponding to the order of the supplied Coomer Party creator URL(s).",
+			"Format: \"num\", \"minNum-maxNum\", or \"\" to download all pages",
+			"Leave blank to download all pages from each creator on Coomer Party.",
+		),
+	)
+	coomerCmd.Flags().StringSliceVar(
+		&coomerPostUrls,
+		"post_url",
+		[]string{},
+		utils.CombineStringsWithNewline(
+			"Coomer Party post URL(s) to download.",
+			mutlipleUrlsMsg,
+		),
+	)
+	coomerCmd.Flags().BoolVarP(
+		&coomerDlGdrive,
+		"dl_gdrive",
+		"g",
+		true,
+		"Whether to download the Google Drive links of a post on Coomer Party.",
+	)
+	coomerCmd.Flags().BoolVarP(
+		&coomerDlAttachments,
+		"dl_attachments",
+		"a",
+		true,
+		"Whether to download the attachments (images, zipped files, etc.) of a post on Coomer Party.",
+	)
+	coomerCmd.Flags().BoolVar(
+		&coomerDlFav,
+		"dl_fav",
+		false,
+		"Whether to download the posts and creators in your Coomer Party favourites.",
+	)
+	coomerCmd.Flags().BoolVar(
+		&coomerDlFavCreators,
+		"dl_fav_creators",
+		false,
+		"Whether to only download all the posts of the creators in your Coomer Party favourites.",
+	)
+	coomerCmd.Flags().BoolVar(
+		&coomerDlFavPosts,
+		"dl_fav_posts",
+		false,
+		"Whether to only download the posts that you have individually favourited on Coomer Party.",
+	)
+	coomerCmd.Flags().StringSliceVar(
+		&coomerMirrors,
+		"mirror",
+		[]string{},
+		utils.CombineStringsWithNewline(
+			"Group(s) of equivalent hosts separated by \"|\" to fail over between when a host of Coomer Party is down.",
+			"The fastest healthy host is used for the rest of the run and a group replaces the default group that shares a host with it.",
+			"The data servers that the downloads are redirected to are remembered when they are down and can be grouped too if they store the same files.",
+			"Default group: \"coomer.su|coomer.party\"",
+			"Example: \"coomer.party|coomer.su\" (without the quotes)",
+		),
+	)
+}
@@ -26,6 +26,17 @@ var (
 	fantiaAutoSolveCaptcha     bool
 	fantiaLogUrls              bool
 	fantiaUserAgent            string
+	fantiaIgnoreHistory        bool
+	fantiaSync                 bool
+	fantiaPathTemplate         string
+	fantiaSaveMetadata         bool
+	fantiaBodyFormat           string
+	fantiaDryRun               bool
+	fantiaDryRunFormat         string
+	fantiaDryRunOutput         string
+	fantiaLimitRate            string
+	fantiaHostRateLimits       []string
+	fantiaDedup                string
 	fantiaCmd = &cobra.Command{
 		Use:   "fantia",
 		Short: "Download from Fantia",
@@ -45,7 +56,18 @@ var (
 				OverwriteFiles: fantiaOverwrite,
 				UserAgent:      fantiaUserAgent,
 				LogUrls:        fantiaLogUrls,
+				IgnoreHistory:  fantiaIgnoreHistory,
+				Sync:           fantiaSync,
+				PathTemplate:   parsePathTemplate(fantiaPathTemplate),
+				SaveMetadata:   fantiaSaveMetadata,
+				BodyFormat:     validateBodyFormat(fantiaBodyFormat),
+				DryRun:         fantiaDryRun,
+				DryRunFormat:   fantiaDryRunFormat,
+				DryRunOutput:   fantiaDryRunOutput,
+				Dedup:          validateDedupMode(fantiaDedup),
 			}
+			applyDryRun(fantiaConfig)
+			applyRateLimits(fantiaLimitRate, fantiaHostRateLimits)
 
 			var gdriveClient *gdrive.GDrive
 			if fantiaGdriveApiKey != "" || fantiaGdriveServiceAccPath != "" {
@@ -0,0 +1,175 @@
+package cmds
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historySites = []string{
+		utils.FANTIA,
+		utils.PIXIV_FANBOX,
+		utils.PIXIV,
+		utils.KEMONO,
+		utils.COOMER,
+	}
+	historySite          string
+	historyCreatorId     string
+	historyPostId        string
+	historyShowFiles     bool
+	historyOlderThanDays int
+	historyCmd = &cobra.Command{
+		Use:   "history",
+		Short: "Manage the download history",
+		Long: utils.CombineStringsWithNewline(
+			"The download history keeps track of the posts that have been downloaded",
+			"so that they will be skipped on future runs unless the --ignore_history flag is used.",
+		),
+	}
+	historyListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the posts in the download history",
+		Run: func(cmd *cobra.Command, args []string) {
+			validateHistorySite(false)
+			entries, err := history.GetEntries(historySite, historyCreatorId)
+			if err != nil {
+				utils.LogError(err, "", true, utils.ERROR)
+			}
+			if len(entries) == 0 {
+				color.Yellow("No posts found in the download history.")
+				return
+			}
+
+			for _, entry := range entries {
+				var totalSize int64
+				for _, file := range entry.Files {
+					totalSize += file.Size
+				}
+				fmt.Printf(
+					"[%s] Creator: %s | Post: %s | Downloaded at: %s | %d file(s), %d bytes\n",
+					utils.GetReadableSiteStr(entry.Site),
+					entry.CreatorId,
+					entry.PostId,
+					entry.DownloadedAt.Format(time.RFC3339),
+					len(entry.Files),
+					totalSize,
+				)
+				if historyShowFiles {
+					for _, file := range entry.Files {
+						fmt.Printf("\t%s (%d bytes, sha256: %s)\n", file.FilePath, file.Size, file.Checksum)
+					}
+				}
+			}
+			color.Green("Found %d post(s) in the download history.", len(entries))
+		},
+	}
+	historyPruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Remove posts that were downloaded before a certain number of days ago",
+		Run: func(cmd *cobra.Command, args []string) {
+			validateHistorySite(false)
+			if historyOlderThanDays <= 0 {
+				color.Red(
+					"history error %d: --older_than must be more than 0 days.",
+					utils.INPUT_ERROR,
+				)
+				os.Exit(1)
+			}
+
+			before := time.Now().AddDate(0, 0, -historyOlderThanDays)
+			removed, err := history.Prune(historySite, before)
+			if err != nil {
+				utils.LogError(err, "", true, utils.ERROR)
+			}
+			color.Green("Removed %d post(s) from the download history.", removed)
+		},
+	}
+	historyForgetCmd = &cobra.Command{
+		Use:   "forget",
+		Short: "Remove the posts of a creator or a specific post from the download history",
+		Run: func(cmd *cobra.Command, args []string) {
+			validateHistorySite(true)
+			if historyCreatorId == "" && historyPostId == "" {
+				color.Red(
+					"history error %d: either --creator_id or --post_id must be provided.",
+					utils.INPUT_ERROR,
+				)
+				os.Exit(1)
+			}
+
+			removed, err := history.Forget(historySite, historyCreatorId, historyPostId)
+			if err != nil {
+				utils.LogError(err, "", true, utils.ERROR)
+			}
+			color.Green("Removed %d post(s) from the download history.", removed)
+		},
+	}
+)
+
+func validateHistorySite(required bool) {
+	if historySite == "" && !required {
+		return
+	}
+	utils.ValidateStrArgs(
+		historySite,
+		historySites,
+		[]string{
+			fmt.Sprintf(
+				"history error %d: invalid site, %q",
+				utils.INPUT_ERROR,
+				historySite,
+			),
+		},
+	)
+}
+
+func init() {
+	siteDesc := "Site of the posts (fantia, fanbox, pixiv, or kemono)."
+	for _, cmd := range []*cobra.Command{historyListCmd, historyPruneCmd, historyForgetCmd} {
+		cmd.Flags().StringVar(
+			&historySite,
+			"site",
+			"",
+			siteDesc,
+		)
+	}
+	for _, cmd := range []*cobra.Command{historyListCmd, historyForgetCmd} {
+		cmd.Flags().StringVar(
+			&historyCreatorId,
+			"creator_id",
+			"",
+			"ID of the creator (Fantia Fanclub ID, Pixiv Fanbox creator ID, Pixiv illustrator ID, or Kemono Party creator ID).",
+		)
+	}
+	historyListCmd.Flags().BoolVar(
+		&historyShowFiles,
+		"files",
+		false,
+		"Show the downloaded files of each post.",
+	)
+	historyPruneCmd.Flags().IntVar(
+		&historyOlderThanDays,
+		"older_than",
+		0,
+		"Remove posts that were downloaded more than this number of days ago.",
+	)
+	historyForgetCmd.Flags().StringVar(
+		&historyPostId,
+		"post_id",
+		"",
+		utils.CombineStringsWithNewline(
+			"ID of the post to remove.",
+			"For Kemono Party, the post ID is prefixed with its service, e.g. \"fanbox/123456\".",
+		),
+	)
+	historyForgetCmd.MarkFlagRequired("site")
+
+	historyCmd.AddCommand(historyListCmd, historyPruneCmd, historyForgetCmd)
+	RootCmd.AddCommand(historyCmd)
+}
@@ -16,14 +16,37 @@ var (
 	kemonoCreatorUrls          []string
 	kemonoPageNums             []string
 	kemonoPostUrls             []string
+	kemonoDiscordUrls          []string
+	kemonoDiscordTranscript    string
 	kemonoDlGdrive             bool
 	kemonoGdriveApiKey         string
 	kemonoGdriveServiceAccPath string
 	kemonoDlAttachments        bool
+	kemonoCrossReference       string
+	kemonoDlRevisions          bool
+	kemonoDlComments           bool
+	kemonoDlDms                bool
+	kemonoDlAnnouncements      bool
+	kemonoDlFancards           bool
+	kemonoTextFormat           string
 	kemonoOverwrite            bool
 	kemonoLogUrls              bool
 	kemonoDlFav                bool
+	kemonoDlFavCreators        bool
+	kemonoDlFavPosts           bool
 	kemonoUserAgent            string
+	kemonoIgnoreHistory        bool
+	kemonoSync                 bool
+	kemonoPathTemplate         string
+	kemonoSaveMetadata         bool
+	kemonoBodyFormat           string
+	kemonoDryRun               bool
+	kemonoDryRunFormat         string
+	kemonoDryRunOutput         string
+	kemonoLimitRate            string
+	kemonoHostRateLimits       []string
+	kemonoMirrors              []string
+	kemonoDedup                string
 	kemonoCmd = &cobra.Command{
 		Use:   "kemono",
 		Short: "Download from Kemono Party",
@@ -33,7 +56,19 @@ var (
 				OverwriteFiles: kemonoOverwrite,
 				UserAgent:      kemonoUserAgent,
 				LogUrls:        kemonoLogUrls,
+				IgnoreHistory:  kemonoIgnoreHistory,
+				Sync:           kemonoSync,
+				PathTemplate:   parsePathTemplate(kemonoPathTemplate),
+				SaveMetadata:   kemonoSaveMetadata,
+				BodyFormat:     validateBodyFormat(kemonoBodyFormat),
+				DryRun:         kemonoDryRun,
+				DryRunFormat:   kemonoDryRunFormat,
+				DryRunOutput:   kemonoDryRunOutput,
+				Dedup:          validateDedupMode(kemonoDedup),
 			}
+			applyDryRun(kemonoConfig)
+			applyRateLimits(kemonoLimitRate, kemonoHostRateLimits)
+			applyMirrors(kemonoMirrors)
 			var gdriveClient *gdrive.GDrive
 			if kemonoGdriveApiKey != "" || kemonoGdriveServiceAccPath != "" {
 				gdriveClient = gdrive.GetNewGDrive(
@@ -48,20 +83,30 @@ var (
 				CreatorUrls:     kemonoCreatorUrls,
 				CreatorPageNums: kemonoPageNums,
 				PostUrls:        kemonoPostUrls,
+				DiscordUrls:     kemonoDiscordUrls,
 			}
 			if kemonoDlTextFile != "" {
-				kemonoPostToDl, kemonoCreatorToDl := textparser.ParseKemonoTextFile(kemonoDlTextFile)
+				kemonoPostToDl, kemonoCreatorToDl := textparser.ParseKemonoTextFile(kemonoDlTextFile, kemono.KEMONO_ARCHIVE)
 				kemonoDl.PostsToDl = kemonoPostToDl
 				kemonoDl.CreatorsToDl = kemonoCreatorToDl
 			}
-			kemonoDl.ValidateArgs()
+			kemonoDl.ValidateArgs(kemono.KEMONO_ARCHIVE)
 
 			kemonoDlOptions := &kemono.KemonoDlOptions{
-				DlAttachments:   kemonoDlAttachments,
-				DlGdrive:        kemonoDlGdrive,
-				Configs:         kemonoConfig,
-				SessionCookieId: kemonoSession,
-				GdriveClient:    gdriveClient,
+				Archive:           kemono.KEMONO_ARCHIVE,
+				DlAttachments:     kemonoDlAttachments,
+				DlGdrive:          kemonoDlGdrive,
+				CrossReference:    kemonoCrossReference,
+				DlRevisions:       kemonoDlRevisions,
+				DlComments:        kemonoDlComments,
+				DlDms:             kemonoDlDms,
+				DlAnnouncements:   kemonoDlAnnouncements,
+				DlFancards:        kemonoDlFancards,
+				TextFormat:        kemonoTextFormat,
+				DiscordTranscript: kemonoDiscordTranscript,
+				Configs:           kemonoConfig,
+				SessionCookieId:   kemonoSession,
+				GdriveClient:      gdriveClient,
 			}
 			if kemonoCookieFile != "" {
 				cookies, err := utils.ParseNetscapeCookieFile(
@@ -81,13 +126,18 @@ var (
 			}
 
 			kemonoDlOptions.ValidateArgs(kemonoUserAgent)
+			// --dl_fav downloads both the favourited creators and the favourited posts
+			dlFavCreators := kemonoDlFav || kemonoDlFavCreators
+			dlFavPosts := kemonoDlFav || kemonoDlFavPosts
+			kemonoDlOptions.ValidateFavArgs(dlFavCreators, dlFavPosts)
 
 			utils.PrintWarningMsg()
 			kemono.KemonoDownloadProcess(
 				kemonoConfig,
 				kemonoDl,
 				kemonoDlOptions,
-				kemonoDlFav,
+				dlFavCreators,
+				dlFavPosts,
 			)
 		},
 	}
@@ -103,10 +153,9 @@ func init() {
 		"",
 		utils.CombineStringsWithNewline(
 			"Your Kemono Party \"session\" cookie value to use for the requests to Kemono Party.",
-			"Required to get pass Kemono Party's DDOS protection and to download from your favourites.",
+			"Not required for public posts and creators but required to download from your favourites.",
 		),
 	)
-	kemonoCmd.MarkFlagRequired("session")
 	kemonoCmd.Flags().StringSliceVar(
 		&kemonoCreatorUrls,
 		"creator_url",
@@ -135,6 +184,25 @@ func init() {
 			mutlipleUrlsMsg,
 		),
 	)
+	kemonoCmd.Flags().StringSliceVar(
+		&kemonoDiscordUrls,
+		"discord_url",
+		[]string{},
+		utils.CombineStringsWithNewline(
+			"Kemono Party Discord server URL(s) to download the attachments of every channel from.",
+			"Add the channel ID after the server ID to only download from that channel.",
+			"Example: \"https://kemono.su/discord/server/123,https://kemono.su/discord/server/456#789\" (without the quotes)",
+		),
+	)
+	kemonoCmd.Flags().StringVar(
+		&kemonoDiscordTranscript,
+		"discord_transcript",
+		kemono.JSONL_TRANSCRIPT_FORMAT,
+		utils.CombineStringsWithNewline(
+			"Format of the transcript of the messages saved in each Discord channel folder.",
+			"Accepted values: \"jsonl\" or \"md\". Leave blank to not save the transcripts.",
+		),
+	)
 	kemonoCmd.Flags().BoolVarP(
 		&kemonoDlGdrive,
 		"dl_gdrive",
@@ -149,4 +217,84 @@ func init() {
 		true,
 		"Whether to download the attachments (images, zipped files, etc.) of a post on Kemono Party.",
 	)
+	kemonoCmd.Flags().BoolVar(
+		&kemonoDlFav,
+		"dl_fav",
+		false,
+		"Whether to download the posts and creators in your Kemono Party favourites.",
+	)
+	kemonoCmd.Flags().BoolVar(
+		&kemonoDlFavCreators,
+		"dl_fav_creators",
+		false,
+		"Whether to only download all the posts of the creators in your Kemono Party favourites.",
+	)
+	kemonoCmd.Flags().BoolVar(
+		&kemonoDlFavPosts,
+		"dl_fav_posts",
+		false,
+		"Whether to only download the posts that you have individually favourited on Kemono Party.",
+	)
+	kemonoCmd.Flags().BoolVar(
+		&kemonoDlRevisions,
+		"dl_revisions",
+		false,
+		utils.CombineStringsWithNewline(
+			"Whether to download the files of the earlier revisions of a post on Kemono Party",
+			"to the \"revisions/<revision ID>\" folder in the post folder. Files identical to the current files are skipped.",
+		),
+	)
+	kemonoCmd.Flags().BoolVar(
+		&kemonoDlComments,
+		"dl_comments",
+		false,
+		"Whether to save the comments of a post on Kemono Party to the post folder.",
+	)
+	kemonoCmd.Flags().BoolVar(
+		&kemonoDlDms,
+		"dl_dms",
+		false,
+		"Whether to save the DMs of a creator on Kemono Party to the creator folder when downloading from the creator.",
+	)
+	kemonoCmd.Flags().BoolVar(
+		&kemonoDlAnnouncements,
+		"dl_announcements",
+		false,
+		"Whether to save the announcements of a creator on Kemono Party to the creator folder when downloading from the creator.",
+	)
+	kemonoCmd.Flags().BoolVar(
+		&kemonoDlFancards,
+		"dl_fancards",
+		false,
+		"Whether to download the fancards of a Pixiv Fanbox creator on Kemono Party to the \"fancards\" folder in the creator folder.",
+	)
+	kemonoCmd.Flags().StringVar(
+		&kemonoTextFormat,
+		"text_format",
+		kemono.JSON_TEXT_FORMAT,
+		"Format of the saved comments, DMs, and announcements. Accepted values: \"json\" or \"md\".",
+	)
+	kemonoCmd.Flags().StringVar(
+		&kemonoCrossReference,
+		"cross_reference",
+		"",
+		utils.CombineStringsWithNewline(
+			"How to handle the Pixiv Fanbox and Fantia posts on Kemono Party that have already been downloaded",
+			"with the pixiv_fanbox or fantia command, found in the download history or as \"[<post ID>] <title>\" folders.",
+			"Accepted values: \"skip\" to skip the post, \"fill\" to only download the files that are missing,",
+			"or leave blank to download the post from Kemono Party regardless.",
+		),
+	)
+	kemonoCmd.Flags().StringSliceVar(
+		&kemonoMirrors,
+		"mirror",
+		[]string{},
+		utils.CombineStringsWithNewline(
+			"Group(s) of equivalent hosts separated by \"|\" to fail over between when a host of Kemono Party is down.",
+			"The fastest healthy host is used for the rest of the run and a group replaces the default group that shares a host with it.",
+			"The data servers that the downloads are redirected to are remembered when they are down and can be grouped too if they store the same files.",
+			"Default group: \"kemono.su|kemono.party\"",
+			"Example: \"kemono.party|kemono.su\" (without the quotes)",
+		),
+	)
 }
@@ -0,0 +1,230 @@
+package cmds
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	kemonoSearchService   string
+	kemonoSearchLimit     int
+	kemonoSearchOutput    string
+	kemonoSearchSelect    string
+	kemonoSearchUserAgent string
+	kemonoSearchCmd = &cobra.Command{
+		Use:   "search <query>",
+		Short: "Search for creators on Kemono Party",
+		Long: utils.CombineStringsWithNewline(
+			"Search for creators on Kemono Party by their name or ID.",
+			"The chosen creators can be appended to a text file to be downloaded with the --txt_filepath flag.",
+		),
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			archive := kemono.KEMONO_ARCHIVE
+			if kemonoSearchService != "" {
+				utils.ValidateStrArgs(
+					kemonoSearchService,
+					archive.Services,
+					[]string{
+						fmt.Sprintf(
+							"kemono error %d: invalid service, %q",
+							utils.INPUT_ERROR,
+							kemonoSearchService,
+						),
+					},
+				)
+			}
+
+			creators, err := kemono.SearchCreators(
+				strings.Join(args, " "),
+				kemonoSearchService,
+				kemonoSearchUserAgent,
+				archive,
+			)
+			if err != nil {
+				utils.LogError(err, "", true, utils.ERROR)
+			}
+			if len(creators) == 0 {
+				color.Yellow("No creators found on %s.", archive.Title)
+				return
+			}
+
+			totalMatches := len(creators)
+			if kemonoSearchLimit > 0 && len(creators) > kemonoSearchLimit {
+				creators = creators[:kemonoSearchLimit]
+			}
+			for idx, creator := range creators {
+				fmt.Printf(
+					"%d. [%s] %s | ID: %s | Last updated: %s\n",
+					idx+1,
+					creator.Service,
+					creator.Name,
+					creator.Id,
+					kemono.FormatIndexDate(creator.Updated),
+				)
+			}
+			if totalMatches > len(creators) {
+				color.Green("Showing %d of %d creator(s) found on %s.", len(creators), totalMatches, archive.Title)
+			} else {
+				color.Green("Found %d creator(s) on %s.", totalMatches, archive.Title)
+			}
+
+			if kemonoSearchOutput != "" {
+				appendSearchResults(creators, archive)
+			}
+		},
+	}
+)
+
+// parseSearchSelection returns the indices of the chosen search results from
+// a selection like "1,3-5" or "all" where the numbers start from 1.
+func parseSearchSelection(selection string, numOfResults int) ([]int, error) {
+	selection = strings.TrimSpace(selection)
+	if strings.EqualFold(selection, "all") {
+		indices := make([]int, numOfResults)
+		for idx := range indices {
+			indices[idx] = idx
+		}
+		return indices, nil
+	}
+
+	var indices []int
+	seen := make(map[int]struct{})
+	for _, part := range strings.Split(selection, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		minStr, maxStr, isRange := strings.Cut(part, "-")
+		if !isRange {
+			maxStr = minStr
+		}
+		minNum, minErr := strconv.Atoi(strings.TrimSpace(minStr))
+		maxNum, maxErr := strconv.Atoi(strings.TrimSpace(maxStr))
+		if minErr != nil || maxErr != nil || minNum < 1 || maxNum > numOfResults || minNum > maxNum {
+			return nil, fmt.Errorf(
+				"kemono error %d: invalid selection %q, expecting numbers from 1 to %d",
+				utils.INPUT_ERROR,
+				part,
+				numOfResults,
+			)
+		}
+		for num := minNum; num <= maxNum; num++ {
+			if _, ok := seen[num]; !ok {
+				seen[num] = struct{}{}
+				indices = append(indices, num-1)
+			}
+		}
+	}
+	return indices, nil
+}
+
+// appendSearchResults appends the URLs of the chosen creators to the output text file,
+// one per line, in the format that is accepted by the --txt_filepath flag.
+func appendSearchResults(creators models.KemonoIndexedCreatorJson, archive *kemono.Archive) {
+	selection := kemonoSearchSelect
+	if selection == "" {
+		fmt.Printf("Enter the numbers of the creators to add to %s (e.g. \"1,3-5\" or \"all\"): ", kemonoSearchOutput)
+		input, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && input == "" {
+			color.Yellow("No creators were added to %s.", kemonoSearchOutput)
+			return
+		}
+		selection = input
+	}
+
+	indices, err := parseSearchSelection(selection, len(creators))
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+	if len(indices) == 0 {
+		color.Yellow("No creators were added to %s.", kemonoSearchOutput)
+		return
+	}
+
+	var lines strings.Builder
+	if content, err := os.ReadFile(kemonoSearchOutput); err == nil && len(content) > 0 && content[len(content)-1] != '\n' {
+		lines.WriteString("\n")
+	}
+	for _, idx := range indices {
+		lines.WriteString(archive.GetCreatorUrl(creators[idx].Service, creators[idx].Id, utils.KEMONO_BACKUP_TLD))
+		lines.WriteString("\n")
+	}
+
+	f, err := os.OpenFile(kemonoSearchOutput, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err == nil {
+		_, err = f.WriteString(lines.String())
+		f.Close()
+	}
+	if err != nil {
+		utils.LogError(
+			fmt.Errorf(
+				"kemono error %d: failed to append the creators to %s, more info => %v",
+				utils.OS_ERROR,
+				kemonoSearchOutput,
+				err,
+			),
+			"",
+			true,
+			utils.ERROR,
+		)
+	}
+	color.Green("Added %d creator(s) to %s.", len(indices), kemonoSearchOutput)
+}
+
+func init() {
+	kemonoSearchCmd.Flags().StringVar(
+		&kemonoSearchService,
+		"service",
+		"",
+		utils.CombineStringsWithNewline(
+			"Only search for creators from this service.",
+			fmt.Sprintf("Accepted values: %s", strings.Join(kemono.KEMONO_ARCHIVE.Services, ", ")),
+		),
+	)
+	kemonoSearchCmd.Flags().IntVar(
+		&kemonoSearchLimit,
+		"limit",
+		50,
+		"Maximum number of creators to show. Set to 0 to show all the creators found.",
+	)
+	kemonoSearchCmd.Flags().StringVarP(
+		&kemonoSearchOutput,
+		"output",
+		"o",
+		"",
+		utils.CombineStringsWithNewline(
+			"Path to a text file to append the URLs of the chosen creators to.",
+			"The text file can then be passed to the kemono command with the --txt_filepath flag.",
+		),
+	)
+	kemonoSearchCmd.Flags().StringVar(
+		&kemonoSearchSelect,
+		"select",
+		"",
+		utils.CombineStringsWithNewline(
+			"Numbers of the creators in the search results to append to the output text file, e.g. \"1,3-5\" or \"all\".",
+			"Leave blank to be prompted after the search results are shown.",
+		),
+	)
+	kemonoSearchCmd.Flags().StringVarP(
+		&kemonoSearchUserAgent,
+		"user_agent",
+		"u",
+		"",
+		"Set a custom user agent to use for the requests.",
+	)
+
+	kemonoCmd.AddCommand(kemonoSearchCmd)
+}
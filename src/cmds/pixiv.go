@@ -2,41 +2,73 @@ package cmds
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/web"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/mobile"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/manga"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/novel"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/ugoira"
 	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 	"github.com/KJHJason/Cultured-Downloader-CLI/cmds/textparser"
 	"github.com/spf13/cobra"
-	"github.com/fatih/color"
 )
 
 var (
-	pixivDlTextFile          string
-	pixivCookieFile          string
-	pixivFfmpegPath          string
-	pixivStartOauth          bool
-	pixivRefreshToken        string
-	pixivSession             string
-	deleteUgoiraZip          bool
-	ugoiraQuality            int
-	ugoiraOutputFormat       string
-	pixivArtworkIds          []string
-	pixivIllustratorIds      []string
-	pixivIllustratorPageNums []string
-	pixivTagNames            []string
-	pixivPageNums            []string
-	pixivSortOrder           string
-	pixivSearchMode          string
-	pixivRatingMode          string
-	pixivArtworkType         string
-	pixivOverwrite           bool
-	pixivUserAgent           string
+	pixivDlTextFile             string
+	pixivCookieFile             string
+	pixivFfmpegPath             string
+	pixivStartOauth             bool
+	pixivRefreshToken           string
+	pixivSession                string
+	deleteUgoiraZip             bool
+	ugoiraQuality               int
+	ugoiraOutputFormat          string
+	pixivArtworkIds             []string
+	pixivSeriesIds              []string
+	pixivCbz                    string
+	pixivIllustratorIds         []string
+	pixivIllustratorPageNums    []string
+	pixivTagNames               []string
+	pixivPageNums               []string
+	pixivDlBookmarks            string
+	pixivBookmarkTag            string
+	pixivBookmarkPageNum        string
+	pixivRanking                string
+	pixivRankingDate            string
+	pixivRankingPageNum         string
+	pixivDlFollowingFeed        bool
+	pixivFollowingFeedPageNum   string
+	pixivDlFollowingUsers       bool
+	pixivFollowingUsersPageNum  string
+	pixivFollowingUsersMaxWorks int
+	pixivFollowingUsersOutput   string
+	pixivNovelIds               []string
+	pixivNovelSeriesIds         []string
+	pixivNovelUserIds           []string
+	pixivNovelUserPageNums      []string
+	pixivNovelTagNames          []string
+	pixivNovelTagPageNums       []string
+	pixivNovelEpub              string
+	pixivSortOrder              string
+	pixivSearchMode             string
+	pixivRatingMode             string
+	pixivArtworkType            string
+	pixivOverwrite              bool
+	pixivUserAgent              string
+	pixivIgnoreHistory          bool
+	pixivSync                   bool
+	pixivPathTemplate           string
+	pixivSaveMetadata           bool
+	pixivDryRun                 bool
+	pixivDryRunFormat           string
+	pixivDryRunOutput           string
+	pixivLimitRate              string
+	pixivHostRateLimits         []string
+	pixivDedup                  string
 	pixivCmd = &cobra.Command{
 		Use:   "pixiv",
 		Short: "Download from Pixiv",
@@ -59,7 +91,17 @@ var (
 				FfmpegPath:     pixivFfmpegPath,
 				OverwriteFiles: pixivOverwrite,
 				UserAgent:      pixivUserAgent,
+				IgnoreHistory:  pixivIgnoreHistory,
+				Sync:           pixivSync,
+				PathTemplate:   parsePathTemplate(pixivPathTemplate),
+				SaveMetadata:   pixivSaveMetadata,
+				DryRun:         pixivDryRun,
+				DryRunFormat:   pixivDryRunFormat,
+				DryRunOutput:   pixivDryRunOutput,
+				Dedup:          validateDedupMode(pixivDedup),
 			}
+			applyDryRun(pixivConfig)
+			applyRateLimits(pixivLimitRate, pixivHostRateLimits)
 			pixivConfig.ValidateFfmpeg()
 
 			if pixivDlTextFile != "" {
@@ -77,11 +119,30 @@ var (
 				}
 			}
 			pixivDl := &pixiv.PixivDl{
-				ArtworkIds:          pixivArtworkIds,
-				IllustratorIds:      pixivIllustratorIds,
-				IllustratorPageNums: pixivIllustratorPageNums,
-				TagNames:            pixivTagNames,
-				TagNamesPageNums:    pixivPageNums,
+				ArtworkIds:             pixivArtworkIds,
+				SeriesIds:              pixivSeriesIds,
+				IllustratorIds:         pixivIllustratorIds,
+				IllustratorPageNums:    pixivIllustratorPageNums,
+				TagNames:               pixivTagNames,
+				TagNamesPageNums:       pixivPageNums,
+				BookmarkVisibility:     pixivDlBookmarks,
+				BookmarkTag:            pixivBookmarkTag,
+				BookmarkPageNum:        pixivBookmarkPageNum,
+				RankingMode:            pixivRanking,
+				RankingDate:            pixivRankingDate,
+				RankingPageNum:         pixivRankingPageNum,
+				DlFollowingFeed:        pixivDlFollowingFeed,
+				FollowingFeedPageNum:   pixivFollowingFeedPageNum,
+				DlFollowingUsers:       pixivDlFollowingUsers,
+				FollowingUsersPageNum:  pixivFollowingUsersPageNum,
+				FollowingUsersMaxWorks: pixivFollowingUsersMaxWorks,
+				FollowingUsersOutput:   pixivFollowingUsersOutput,
+				NovelIds:               pixivNovelIds,
+				NovelSeriesIds:         pixivNovelSeriesIds,
+				NovelUserIds:           pixivNovelUserIds,
+				NovelUserPageNums:      pixivNovelUserPageNums,
+				NovelTagNames:          pixivNovelTagNames,
+				NovelTagPageNums:       pixivNovelTagPageNums,
 			}
 			pixivDl.ValidateArgs()
 
@@ -92,10 +153,15 @@ var (
 			}
 			pixivUgoiraOptions.ValidateArgs()
 
-			if pixivRefreshToken == "" && pixivSession == "" {
-				color.Red("You must provide a refresh token or session cookie ID to download from Pixiv.")
-				os.Exit(1)
+			pixivNovelOptions := &novel.NovelOptions{
+				EpubMode: pixivNovelEpub,
 			}
+			pixivNovelOptions.ValidateArgs()
+
+			pixivMangaOptions := &manga.MangaOptions{
+				CbzMode: pixivCbz,
+			}
+			pixivMangaOptions.ValidateArgs()
 
 			utils.PrintWarningMsg()
 			if pixivRefreshToken != "" {
@@ -108,10 +174,13 @@ var (
 					RefreshToken:    pixivRefreshToken,
 				}
 				pixivDlOptions.ValidateArgs(pixivUserAgent)
+				pixivDlOptions.ValidateRankingArgs(pixivDl.RankingMode, pixivDl.RankingDate)
 				pixiv.PixivMobileDownloadProcess(
 					pixivDl,
 					pixivDlOptions,
 					pixivUgoiraOptions,
+					pixivNovelOptions,
+					pixivMangaOptions,
 				)
 			} else {
 				pixivDlOptions := &pixivweb.PixivWebDlOptions{
@@ -139,10 +208,16 @@ var (
 					pixivDlOptions.SessionCookies = cookies
 				}
 				pixivDlOptions.ValidateArgs(pixivUserAgent)
+				pixivDlOptions.ValidateBookmarkArgs(pixivDl.BookmarkVisibility != "")
+				pixivDlOptions.ValidateRankingArgs(pixivDl.RankingMode, pixivDl.RankingDate)
+				pixivDlOptions.ValidateFollowingFeedArgs(pixivDl.DlFollowingFeed)
+				pixivDlOptions.ValidateFollowingUsersArgs(pixivDl.DlFollowingUsers)
 				pixiv.PixivWebDownloadProcess(
 					pixivDl,
 					pixivDlOptions,
 					pixivUgoiraOptions,
+					pixivNovelOptions,
+					pixivMangaOptions,
 				)
 			}
 		},
@@ -185,7 +260,10 @@ func init() {
 		"session",
 		"s",
 		"",
-		"Your \"PHPSESSID\" cookie value to use for the requests to Pixiv.",
+		utils.CombineStringsWithNewline(
+			"Your \"PHPSESSID\" cookie value to use for the requests to Pixiv.",
+			"If neither this flag nor the \"--refresh_token\" flag is used, only public all ages artworks can be downloaded.",
+		),
 	)
 	pixivCmd.Flags().BoolVarP(
 		&deleteUgoiraZip,
@@ -233,6 +311,29 @@ func init() {
 			mutlipleIdsMsg,
 		),
 	)
+	pixivCmd.Flags().StringSliceVar(
+		&pixivSeriesIds,
+		"series",
+		[]string{},
+		utils.CombineStringsWithNewline(
+			"Manga series ID(s) to download all the chapters of in their order.",
+			mutlipleIdsMsg,
+		),
+	)
+	pixivCmd.Flags().StringVar(
+		&pixivCbz,
+		"cbz",
+		"",
+		utils.CombineStringsWithNewline(
+			"Also package the downloaded chapters of the manga series as CBZ files with a ComicInfo.xml file.",
+			"Accepted values:",
+			"- chapter: Package each chapter as its own CBZ file in the chapter's folder",
+			"- series: Package all the chapters of the same series as one CBZ file in the illustrator's folder",
+			"Leave blank to not package any CBZ files.",
+			"Note: In the \"chapter\" mode, the chapters that are skipped due to the download history are not packaged again.",
+			"In the \"series\" mode, the chapters in the download history are still included with their existing pages.",
+		),
+	)
 	pixivCmd.Flags().StringSliceVar(
 		&pixivIllustratorIds,
 		"illustrator_id",
@@ -272,6 +373,196 @@ func init() {
 			"Leave blank to search all pages for each tag name.",
 		),
 	)
+	pixivCmd.Flags().StringVar(
+		&pixivDlBookmarks,
+		"dl_bookmarks",
+		"",
+		utils.CombineStringsWithNewline(
+			"Download the artworks in your bookmarks with the given visibility.",
+			fmt.Sprintf(
+				"Accepted values: %s",
+				strings.Join(pixivcommon.ACCEPTED_BOOKMARK_VISIBILITIES, ", "),
+			),
+			"Requires a session cookie or a refresh token.",
+		),
+	)
+	pixivCmd.Flags().StringVar(
+		&pixivBookmarkTag,
+		"bookmark_tag",
+		"",
+		"Only download the bookmarked artworks with this bookmark tag when using the --dl_bookmarks flag.",
+	)
+	pixivCmd.Flags().StringVar(
+		&pixivBookmarkPageNum,
+		"bookmark_page_num",
+		"",
+		utils.CombineStringsWithNewline(
+			"Min and max page numbers of your bookmarks to download when using the --dl_bookmarks flag.",
+			"Format: \"num\", \"minNum-maxNum\", or \"\" to download all pages",
+			"Leave blank to download all pages of your bookmarks.",
+		),
+	)
+	pixivCmd.Flags().StringVar(
+		&pixivRanking,
+		"ranking",
+		"",
+		utils.CombineStringsWithNewline(
+			"Download the artworks in the Pixiv ranking with the given mode.",
+			fmt.Sprintf(
+				"Accepted values: %s",
+				strings.Join(pixivcommon.ACCEPTED_RANKING_MODE, ", "),
+			),
+			"Use the --artwork_type flag with \"manga\" to download the manga ranking instead.",
+		),
+	)
+	pixivCmd.Flags().StringVar(
+		&pixivRankingDate,
+		"ranking_date",
+		"",
+		utils.CombineStringsWithNewline(
+			"Date of the ranking to download when using the --ranking flag.",
+			"Format: \"YYYY-MM-DD\"",
+			"Leave blank to download the latest ranking.",
+		),
+	)
+	pixivCmd.Flags().StringVar(
+		&pixivRankingPageNum,
+		"ranking_page_num",
+		"",
+		utils.CombineStringsWithNewline(
+			"Min and max page numbers of the ranking to download when using the --ranking flag.",
+			"Format: \"num\", \"minNum-maxNum\", or \"\" to download all pages",
+			"Leave blank to download all pages of the ranking.",
+		),
+	)
+	pixivCmd.Flags().BoolVar(
+		&pixivDlFollowingFeed,
+		"dl_following_feed",
+		false,
+		utils.CombineStringsWithNewline(
+			"Download the new works from the users you are following.",
+			"Requires a session cookie or a refresh token.",
+		),
+	)
+	pixivCmd.Flags().StringVar(
+		&pixivFollowingFeedPageNum,
+		"following_feed_page_num",
+		"",
+		utils.CombineStringsWithNewline(
+			"Min and max page numbers of the new works from the users you are following to download.",
+			"Format: \"num\", \"minNum-maxNum\", or \"\" to download all pages",
+			"Leave blank to download all pages of the new works.",
+		),
+	)
+	pixivCmd.Flags().BoolVar(
+		&pixivDlFollowingUsers,
+		"dl_following_users",
+		false,
+		utils.CombineStringsWithNewline(
+			"Download the works of all the users, both public and private, that you are following.",
+			"Requires a session cookie or a refresh token.",
+		),
+	)
+	pixivCmd.Flags().StringVar(
+		&pixivFollowingUsersPageNum,
+		"following_users_page_num",
+		"",
+		utils.CombineStringsWithNewline(
+			"Min and max page numbers of the works to download from each user you are following",
+			fmt.Sprintf("when using the --dl_following_users flag where each page has %d works.", utils.PIXIV_PER_PAGE),
+			"Format: \"num\", \"minNum-maxNum\", or \"\" to download all pages",
+			"Leave blank to download all the works from each user.",
+		),
+	)
+	pixivCmd.Flags().IntVar(
+		&pixivFollowingUsersMaxWorks,
+		"following_users_max_works",
+		0,
+		utils.CombineStringsWithNewline(
+			"Maximum number of the newest works to download from each user you are following when using the --dl_following_users flag.",
+			"It is applied after the --following_users_page_num flag.",
+			"Leave it as 0 to download all the works from each user.",
+		),
+	)
+	pixivCmd.Flags().StringVar(
+		&pixivFollowingUsersOutput,
+		"following_users_output",
+		"",
+		utils.CombineStringsWithNewline(
+			"Path to a text file to save the URLs of the users you are following to when using the --dl_following_users flag.",
+			"The text file can then be passed to the pixiv command with the --txt_filepath flag.",
+			"The text file is not saved when using the --dry_run flag.",
+		),
+	)
+	pixivCmd.Flags().StringSliceVar(
+		&pixivNovelIds,
+		"novel_id",
+		[]string{},
+		utils.CombineStringsWithNewline(
+			"Novel ID(s) to download.",
+			mutlipleIdsMsg,
+		),
+	)
+	pixivCmd.Flags().StringSliceVar(
+		&pixivNovelSeriesIds,
+		"novel_series_id",
+		[]string{},
+		utils.CombineStringsWithNewline(
+			"Novel series ID(s) to download all the novels of.",
+			mutlipleIdsMsg,
+		),
+	)
+	pixivCmd.Flags().StringSliceVar(
+		&pixivNovelUserIds,
+		"novel_user_id",
+		[]string{},
+		utils.CombineStringsWithNewline(
+			"User ID(s) to download the novels of.",
+			mutlipleIdsMsg,
+		),
+	)
+	pixivCmd.Flags().StringSliceVar(
+		&pixivNovelUserPageNums,
+		"novel_user_page_num",
+		[]string{},
+		utils.CombineStringsWithNewline(
+			"Min and max page numbers of the novels to download corresponding to the order of the supplied novel user ID(s).",
+			"Format: \"num\", \"minNum-maxNum\", or \"\" to download all pages",
+			"Leave blank to download all the novels from each user.",
+		),
+	)
+	pixivCmd.Flags().StringSliceVar(
+		&pixivNovelTagNames,
+		"novel_tag",
+		[]string{},
+		utils.CombineStringsWithNewline(
+			"Tag names to search for and download related novels.",
+			"For multiple tags, separate them with a comma.",
+			"Example: \"tag name 1, tagName2\"",
+		),
+	)
+	pixivCmd.Flags().StringSliceVar(
+		&pixivNovelTagPageNums,
+		"novel_tag_page_num",
+		[]string{},
+		utils.CombineStringsWithNewline(
+			"Min and max page numbers to search for corresponding to the order of the supplied novel tag name(s).",
+			"Format: \"num\", \"minNum-maxNum\", or \"\" to download all pages",
+			"Leave blank to search all pages for each novel tag name.",
+		),
+	)
+	pixivCmd.Flags().StringVar(
+		&pixivNovelEpub,
+		"novel_epub",
+		"",
+		utils.CombineStringsWithNewline(
+			"Also save the downloaded novels as EPUB files in addition to the Markdown files.",
+			"Accepted values:",
+			"- novel: Save each novel as its own EPUB file in the novel's folder",
+			"- series: Save the novels of the same series as one EPUB file in the user's novels folder",
+			"Leave blank to not save any EPUB files.",
+		),
+	)
 	pixivCmd.Flags().StringVar(
 		&pixivSortOrder,
 		"sort_order",
@@ -307,6 +598,7 @@ func init() {
 			"- all: Include both R-18 and all ages artworks",
 			"Notes:",
 			"- If you're using the \"--refresh_token\" flag, only \"all\" is supported.",
+			"- If neither the \"--session\" nor the \"--refresh_token\" flag is used, only \"safe\" is supported.",
 		),
 	)
 	pixivCmd.Flags().StringVar(
@@ -25,6 +25,17 @@ var (
 	fanboxOverwriteFiles       bool
 	fanboxLogUrls              bool
 	fanboxUserAgent            string
+	fanboxIgnoreHistory        bool
+	fanboxSync                 bool
+	fanboxPathTemplate         string
+	fanboxSaveMetadata         bool
+	fanboxBodyFormat           string
+	fanboxDryRun               bool
+	fanboxDryRunFormat         string
+	fanboxDryRunOutput         string
+	fanboxLimitRate            string
+	fanboxHostRateLimits       []string
+	fanboxDedup                string
 	pixivFanboxCmd = &cobra.Command{
 		Use:   "pixiv_fanbox",
 		Short: "Download from Pixiv Fanbox",
@@ -34,7 +45,18 @@ var (
 				OverwriteFiles: fanboxOverwriteFiles,
 				UserAgent:      fanboxUserAgent,
 				LogUrls:        fanboxLogUrls,
+				IgnoreHistory:  fanboxIgnoreHistory,
+				Sync:           fanboxSync,
+				PathTemplate:   parsePathTemplate(fanboxPathTemplate),
+				SaveMetadata:   fanboxSaveMetadata,
+				BodyFormat:     validateBodyFormat(fanboxBodyFormat),
+				DryRun:         fanboxDryRun,
+				DryRunFormat:   fanboxDryRunFormat,
+				DryRunOutput:   fanboxDryRunOutput,
+				Dedup:          validateDedupMode(fanboxDedup),
 			}
+			applyDryRun(pixivFanboxConfig)
+			applyRateLimits(fanboxLimitRate, fanboxHostRateLimits)
 			var gdriveClient *gdrive.GDrive
 			if fanboxGdriveApiKey != "" || fanboxGdriveServiceAccPath != "" {
 				gdriveClient = gdrive.GetNewGDrive(
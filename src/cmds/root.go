@@ -19,6 +19,9 @@ var (
 		),
 		Short:   "Download images, videos, etc. from various websites like Fantia.",
 		Long:    "Cultured Downloader CLI is a command-line tool for downloading images, videos, etc. from various websites like Pixiv, Pixiv Fanbox, Fantia, and more.",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			applyConfig(cmd)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			if downloadPath != "" {
 				err := utils.SetDefaultDownloadPath(downloadPath)
@@ -46,5 +49,14 @@ func init() {
 			"had used the Cultured Downloader Python program, the program will automatically use the path you had set.",
 		),
 	)
+	RootCmd.PersistentFlags().StringVar(
+		&configProfile,
+		"profile",
+		"",
+		utils.CombineStringsWithNewline(
+			"Name of the profile in the config file to use for the flags that are not provided.",
+			"Use the \"config\" command to manage the profiles.",
+		),
+	)
 	RootCmd.CompletionOptions.HiddenDefaultCmd = true
 }
@@ -4,35 +4,34 @@ import (
 	"strings"
 	"regexp"
 
-	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono/models"
 )
 
-var (
-	K_POST_URL_REGEX = regexp.MustCompile(kemono.BASE_REGEX_STR + kemono.BASE_POST_SUFFIX_REGEX_STR)
-	K_POST_REGEX_SERVICE_INDEX = K_POST_URL_REGEX.SubexpIndex(kemono.SERVICE_GROUP_NAME)
-	K_POST_REGEX_CREATOR_ID_INDEX = K_POST_URL_REGEX.SubexpIndex(kemono.CREATOR_ID_GROUP_NAME)
-	K_POST_REGEX_POST_ID_INDEX = K_POST_URL_REGEX.SubexpIndex(kemono.POST_ID_GROUP_NAME)
+// ParseKemonoTextFile parses the text file at the given path with the URL formats of the given Kemono-compatible archive
+// like Kemono Party or Coomer Party and returns a slice of KemonoPostToDl and a slice of KemonoCreatorToDl.
+func ParseKemonoTextFile(textFilePath string, archive *kemono.Archive) ([]*models.KemonoPostToDl, []*models.KemonoCreatorToDl) {
+	postUrlRegex := regexp.MustCompile(archive.BaseRegexStr + kemono.BASE_POST_SUFFIX_REGEX_STR)
+	postRegexServiceIdx := postUrlRegex.SubexpIndex(kemono.SERVICE_GROUP_NAME)
+	postRegexCreatorIdIdx := postUrlRegex.SubexpIndex(kemono.CREATOR_ID_GROUP_NAME)
+	postRegexPostIdIdx := postUrlRegex.SubexpIndex(kemono.POST_ID_GROUP_NAME)
 
-	K_CREATOR_URL_REGEX = regexp.MustCompile(kemono.BASE_REGEX_STR + PAGE_NUM_REGEX_STR)
-	K_CREATOR_REGEX_CREATOR_ID_INDEX = K_CREATOR_URL_REGEX.SubexpIndex(kemono.CREATOR_ID_GROUP_NAME)
-	K_CREATOR_REGEX_PAGE_NUM_INDEX = K_CREATOR_URL_REGEX.SubexpIndex(PAGE_NUM_REGEX_GRP_NAME)
-)
+	creatorUrlRegex := regexp.MustCompile(archive.BaseRegexStr + PAGE_NUM_REGEX_STR)
+	creatorRegexServiceIdx := creatorUrlRegex.SubexpIndex(kemono.SERVICE_GROUP_NAME)
+	creatorRegexCreatorIdIdx := creatorUrlRegex.SubexpIndex(kemono.CREATOR_ID_GROUP_NAME)
+	creatorRegexPageNumIdx := creatorUrlRegex.SubexpIndex(PAGE_NUM_REGEX_GRP_NAME)
 
-// ParseKemonoTextFile parses the text file at the given path and returns a slice of KemonoPostToDl and a slice of KemonoCreatorToDl.
-func ParseKemonoTextFile(textFilePath string) ([]*models.KemonoPostToDl, []*models.KemonoCreatorToDl) {
-	lowercaseFanbox := strings.ToLower(utils.PIXIV_FANBOX_TITLE)
+	lowercaseTitle := strings.ToLower(archive.Title)
 	f, reader := openTextFile(
-		textFilePath, 
-		lowercaseFanbox,
+		textFilePath,
+		lowercaseTitle,
 	)
 	defer f.Close()
 
 	var postsToDl []*models.KemonoPostToDl
 	var creatorsToDl []*models.KemonoCreatorToDl
 	for {
-		lineBytes, isEof := readLine(reader, textFilePath, lowercaseFanbox)
+		lineBytes, isEof := readLine(reader, textFilePath, lowercaseTitle)
 		if isEof {
 			break
 		}
@@ -42,20 +41,20 @@ func ParseKemonoTextFile(textFilePath string) ([]*models.KemonoPostToDl, []*mode
 			continue
 		}
 
-		if matched := K_POST_URL_REGEX.FindStringSubmatch(url); matched != nil {
+		if matched := postUrlRegex.FindStringSubmatch(url); matched != nil {
 			postsToDl = append(postsToDl, &models.KemonoPostToDl{
-				Service: matched[K_POST_REGEX_SERVICE_INDEX],
-				CreatorId: matched[K_POST_REGEX_CREATOR_ID_INDEX],
-				PostId: matched[K_POST_REGEX_POST_ID_INDEX],
+				Service: matched[postRegexServiceIdx],
+				CreatorId: matched[postRegexCreatorIdIdx],
+				PostId: matched[postRegexPostIdIdx],
 			})
 			continue
 		}
 
-		if matched := K_CREATOR_URL_REGEX.FindStringSubmatch(url); matched != nil {
+		if matched := creatorUrlRegex.FindStringSubmatch(url); matched != nil {
 			creatorsToDl = append(creatorsToDl, &models.KemonoCreatorToDl{
-				Service: matched[K_POST_REGEX_SERVICE_INDEX],
-				CreatorId: matched[K_CREATOR_REGEX_CREATOR_ID_INDEX],
-				PageNum: matched[K_CREATOR_REGEX_PAGE_NUM_INDEX],
+				Service: matched[creatorRegexServiceIdx],
+				CreatorId: matched[creatorRegexCreatorIdIdx],
+				PageNum: matched[creatorRegexPageNumIdx],
 			})
 			continue
 		}
@@ -0,0 +1,126 @@
+package cmds
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/dedup"
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/manifest"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyRequeue bool
+	verifyCmd = &cobra.Command{
+		Use:   "verify <path>",
+		Short: "Verify the downloaded files against their SHA-256 manifests",
+		Long: utils.CombineStringsWithNewline(
+			"Re-hashes the downloaded files in the given folder (or manifest file) against the",
+			fmt.Sprintf("%q manifests saved in each post folder and reports the corrupted, truncated, or missing files.", manifest.MANIFEST_FILENAME),
+		),
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			manifestPaths, err := manifest.FindManifests(args[0])
+			if err != nil {
+				color.Red(
+					"verify error %d: failed to find the manifests in %s, more info => %v",
+					utils.INPUT_ERROR,
+					args[0],
+					err,
+				)
+				os.Exit(1)
+			}
+			if len(manifestPaths) == 0 {
+				color.Yellow("No manifests found in %s.", args[0])
+				return
+			}
+
+			counts := make(map[string]int)
+			requeued := 0
+			for _, manifestPath := range manifestPaths {
+				err := manifest.Verify(manifestPath, func(result *manifest.Result) {
+					counts[result.Status]++
+					if result.Status == manifest.OK_STATUS {
+						return
+					}
+
+					if result.Err != nil {
+						color.Red("[%s] %s (%v)", result.Status, result.FilePath, result.Err)
+					} else {
+						color.Red("[%s] %s", result.Status, result.FilePath)
+					}
+					if verifyRequeue && requeueFile(result) {
+						requeued++
+					}
+				})
+				if err != nil {
+					utils.LogError(err, "", false, utils.ERROR)
+				}
+			}
+
+			total := 0
+			for _, count := range counts {
+				total += count
+			}
+			color.Green(
+				"Verified %d file(s) in %d manifest(s): %d ok, %d corrupted, %d truncated, %d missing, and %d could not be read.",
+				total,
+				len(manifestPaths),
+				counts[manifest.OK_STATUS],
+				counts[manifest.CORRUPTED_STATUS],
+				counts[manifest.TRUNCATED_STATUS],
+				counts[manifest.MISSING_STATUS],
+				counts[manifest.ERROR_STATUS],
+			)
+			if verifyRequeue {
+				color.Green("Queued %d file(s) to be downloaded again on the next run.", requeued)
+			}
+		},
+	}
+)
+
+// requeueFile removes the corrupted or truncated file and removes its post from the
+// download history so that the file will be downloaded again on the next run.
+//
+// Files that other files refer to in the "reference" deduplication mode are skipped
+// as removing them would also remove the content of the other files.
+func requeueFile(result *manifest.Result) bool {
+	if result.Status == manifest.ERROR_STATUS {
+		return false
+	}
+	if result.Status != manifest.MISSING_STATUS && dedup.IsShared(result.Entry.Sha256, result.FilePath) {
+		color.Yellow("Skipped requeuing %s as other downloaded files refer to it.", result.FilePath)
+		return false
+	}
+	if result.Status != manifest.MISSING_STATUS {
+		if err := os.Remove(result.FilePath); err != nil {
+			utils.LogError(err, "", false, utils.ERROR)
+			return false
+		}
+	}
+
+	entry := result.Entry
+	if entry.Site != "" && entry.PostId != "" {
+		if _, err := history.Forget(entry.Site, "", entry.PostId); err != nil {
+			utils.LogError(err, "", false, utils.ERROR)
+			return false
+		}
+	}
+	return true
+}
+
+func init() {
+	verifyCmd.Flags().BoolVar(
+		&verifyRequeue,
+		"requeue",
+		false,
+		utils.CombineStringsWithNewline(
+			"Delete the corrupted and truncated files and remove their posts from the download history",
+			"so that they will be downloaded again on the next run.",
+		),
+	)
+	RootCmd.AddCommand(verifyCmd)
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"os/exec"
 
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 	"github.com/fatih/color"
 )
 
@@ -25,6 +26,41 @@ type Config struct {
 
 	// UserAgent is the user agent to be used in the download process
 	UserAgent      string
+
+	// IgnoreHistory is a flag to download posts
+	// even if they are already in the download history
+	IgnoreHistory  bool
+
+	// Sync is a flag to only get the posts of creators
+	// that are newer than the newest post seen from the last sync
+	Sync           bool
+
+	// PathTemplate is the output path template of the downloaded files.
+	// If nil, the default folder layout will be used.
+	PathTemplate   *utils.PathTemplate
+
+	// SaveMetadata is a flag to save the metadata of each post
+	// to a "post.json" file in the post folder
+	SaveMetadata   bool
+
+	// BodyFormat is the format ("md" or "html") to render the post body to.
+	// If empty, the post body will not be rendered.
+	BodyFormat     string
+
+	// DryRun is a flag to only print the files that would be downloaded
+	// without downloading or writing anything to the disk
+	DryRun         bool
+
+	// DryRunFormat is the format ("table", "json", or "csv") of the printed download plan
+	DryRunFormat   string
+
+	// DryRunOutput is the file path to save the download plan to.
+	// If empty, the download plan will be printed to stdout.
+	DryRunOutput   string
+
+	// Dedup is the mode ("hardlink", "reflink", or "reference") to store files that are identical
+	// to previously downloaded files. If empty, the files will not be deduplicated.
+	Dedup          string
 }
 
 func (c *Config) ValidateFfmpeg() {
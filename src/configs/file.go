@@ -0,0 +1,196 @@
+package configs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	CONFIG_FILENAME = "config.yaml"
+	DEFAULT_PROFILE = "default"
+
+	// ENV_PREFIX is the prefix of the environment variables that overrides the
+	// flags and the profile values, e.g. CULTURED_DOWNLOADER_FANTIA_SESSION.
+	ENV_PREFIX = "CULTURED_DOWNLOADER_"
+
+	// PROFILE_ENV is the environment variable used to select the profile.
+	PROFILE_ENV = ENV_PREFIX + "PROFILE"
+)
+
+// Profile contains the flag values of each command, e.g. profile["fantia"]["session"].
+type Profile map[string]map[string]interface{}
+
+// ConfigFile is the YAML config file that contains the named profiles.
+//
+// Example:
+//
+//	default_profile: work
+//	profiles:
+//	  work:
+//	    fantia:
+//	      session: <session cookie value>
+//	      dl_attachments: true
+//	    pixiv:
+//	      artwork_type: illust_and_ugoira
+type ConfigFile struct {
+	DefaultProfile string             `yaml:"default_profile,omitempty"`
+	Profiles       map[string]Profile `yaml:"profiles,omitempty"`
+}
+
+// Returns the path to the YAML config file
+func GetConfigFilePath() string {
+	return filepath.Join(utils.APP_PATH, CONFIG_FILENAME)
+}
+
+// LoadConfigFile loads the YAML config file.
+//
+// If the config file does not exist, an empty config file will be returned.
+func LoadConfigFile() (*ConfigFile, error) {
+	configFile := &ConfigFile{}
+	data, err := os.ReadFile(GetConfigFilePath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return configFile, nil
+		}
+		return nil, fmt.Errorf(
+			"config error %d: failed to read config file, %q, more info => %v",
+			utils.OS_ERROR,
+			GetConfigFilePath(),
+			err,
+		)
+	}
+
+	if err := yaml.Unmarshal(data, configFile); err != nil {
+		return nil, fmt.Errorf(
+			"config error %d: failed to parse config file, %q, more info => %v",
+			utils.INPUT_ERROR,
+			GetConfigFilePath(),
+			err,
+		)
+	}
+	return configFile, nil
+}
+
+// Save writes the config file to the disk
+func (c *ConfigFile) Save() error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf(
+			"config error %d: failed to marshal config file, more info => %v",
+			utils.UNEXPECTED_ERROR,
+			err,
+		)
+	}
+
+	os.MkdirAll(utils.APP_PATH, 0755)
+	if err := os.WriteFile(GetConfigFilePath(), data, 0600); err != nil {
+		return fmt.Errorf(
+			"config error %d: failed to write config file, more info => %v",
+			utils.OS_ERROR,
+			err,
+		)
+	}
+	return nil
+}
+
+// ResolveProfileName returns the name of the profile to use
+// if the given profile name is empty.
+func (c *ConfigFile) ResolveProfileName(profileName string) string {
+	if profileName != "" {
+		return profileName
+	}
+	if c.DefaultProfile != "" {
+		return c.DefaultProfile
+	}
+	return DEFAULT_PROFILE
+}
+
+// GetProfile returns the profile with the given name.
+//
+// If the profile name is empty, the default profile will be used
+// and an empty profile will be returned if it does not exist.
+func (c *ConfigFile) GetProfile(profileName string) (Profile, error) {
+	resolvedName := c.ResolveProfileName(profileName)
+	profile, ok := c.Profiles[resolvedName]
+	if ok {
+		return profile, nil
+	}
+
+	if profileName == "" && resolvedName == DEFAULT_PROFILE {
+		return Profile{}, nil
+	}
+	return nil, fmt.Errorf(
+		"config error %d: profile %q does not exist in %q",
+		utils.INPUT_ERROR,
+		resolvedName,
+		GetConfigFilePath(),
+	)
+}
+
+// GetProfileNames returns the sorted names of all the profiles
+func (c *ConfigFile) GetProfileNames() []string {
+	profileNames := make([]string, 0, len(c.Profiles))
+	for profileName := range c.Profiles {
+		profileNames = append(profileNames, profileName)
+	}
+	sort.Strings(profileNames)
+	return profileNames
+}
+
+// Get returns the value of the key in the command's section of the profile
+func (c *ConfigFile) Get(profileName, cmdName, key string) (interface{}, bool) {
+	profile, ok := c.Profiles[c.ResolveProfileName(profileName)]
+	if !ok {
+		return nil, false
+	}
+	value, ok := profile[cmdName][key]
+	return value, ok
+}
+
+// Set sets the value of the key in the command's section of the profile
+// and creates the profile and the section if they do not exist.
+func (c *ConfigFile) Set(profileName, cmdName, key string, value interface{}) {
+	profileName = c.ResolveProfileName(profileName)
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]Profile)
+	}
+	if c.Profiles[profileName] == nil {
+		c.Profiles[profileName] = make(Profile)
+	}
+	if c.Profiles[profileName][cmdName] == nil {
+		c.Profiles[profileName][cmdName] = make(map[string]interface{})
+	}
+	c.Profiles[profileName][cmdName][key] = value
+}
+
+// Returns the environment variable name for the command's flag,
+// e.g. "CULTURED_DOWNLOADER_PIXIV_FANBOX_SESSION" for the "pixiv_fanbox" command's "session" flag.
+func GetEnvKey(cmdName, key string) string {
+	return ENV_PREFIX + strings.ToUpper(cmdName+"_"+key)
+}
+
+// FormatValue converts the value from the config file to its string value(s)
+// and returns true if the value is a list.
+func FormatValue(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return []string{""}, false
+	case []string:
+		return v, true
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, elem := range v {
+			values = append(values, fmt.Sprint(elem))
+		}
+		return values, true
+	default:
+		return []string{fmt.Sprint(v)}, false
+	}
+}
@@ -0,0 +1,182 @@
+package dedup
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+)
+
+// Modes of materialising a file that is identical to a file in the content store
+const (
+	// HARDLINK_MODE creates a hardlink to the stored file
+	HARDLINK_MODE = "hardlink"
+
+	// REFLINK_MODE creates a copy-on-write clone of the stored file on
+	// file systems that support it like Btrfs and XFS and falls back to a hardlink otherwise.
+	REFLINK_MODE = "reflink"
+
+	// REFERENCE_MODE does not create the file and refers to the stored file
+	// in the manifest and the rendered post body instead.
+	REFERENCE_MODE = "reference"
+)
+
+var ACCEPTED_MODES = []string{HARDLINK_MODE, REFLINK_MODE, REFERENCE_MODE}
+
+var (
+	statsMu    sync.Mutex
+	dedupFiles int
+	savedBytes int64
+)
+
+func addSaved(size int64) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	dedupFiles++
+	savedBytes += size
+}
+
+// findStoredFile returns the path of the stored file with the given SHA-256 checksum.
+//
+// The recorded checksum is trusted instead of re-hashing the stored file on every lookup,
+// so only the size of the stored file is checked to make sure it has not been removed or truncated.
+// Returns an empty string if there is no valid stored file or if the stored file is the given file itself,
+// in which case the returned boolean is true as the content store does not have to be updated.
+func findStoredFile(checksum, filePath string) (string, int64, bool) {
+	content, err := history.GetContent(checksum)
+	if err != nil || content == nil {
+		return "", 0, false
+	}
+	if sameFile(content.FilePath, filePath) {
+		return "", 0, true
+	}
+
+	size, err := utils.GetFileSize(content.FilePath)
+	if err != nil || size != content.Size {
+		return "", 0, false
+	}
+	return content.FilePath, size, false
+}
+
+// IsShared returns true if the file is the stored file with the given SHA-256 checksum
+// and other files refer to it in the reference mode, in which case it should not be removed.
+func IsShared(checksum, filePath string) bool {
+	content, err := history.GetContent(checksum)
+	if err != nil || content == nil {
+		return false
+	}
+	return content.References > 0 && sameFile(content.FilePath, filePath)
+}
+
+func sameFile(path1, path2 string) bool {
+	if filepath.Clean(path1) == filepath.Clean(path2) {
+		return true
+	}
+	info1, err1 := os.Stat(path1)
+	info2, err2 := os.Stat(path2)
+	return err1 == nil && err2 == nil && os.SameFile(info1, info2)
+}
+
+// link creates the file at the destination path as a hardlink or a reflink of the source file
+func link(src, dest, mode string) error {
+	os.MkdirAll(filepath.Dir(dest), 0755)
+	if mode == REFLINK_MODE {
+		if err := reflink(src, dest); err == nil {
+			return nil
+		}
+	}
+	return os.Link(src, dest)
+}
+
+// Reuse materialises the file at the given path from the content store
+// if an identical file with the given SHA-256 checksum has been downloaded before.
+//
+// Returns the path of the file with the content which is the stored file for the
+// reference mode and false if the file has to be downloaded.
+func Reuse(checksum, filePath, mode string) (string, bool) {
+	src, size, _ := findStoredFile(checksum, filePath)
+	if src == "" {
+		return "", false
+	}
+
+	if mode != REFERENCE_MODE {
+		if err := link(src, filePath, mode); err != nil {
+			return "", false
+		}
+	} else if err := history.AddContentReference(checksum); err != nil {
+		return "", false
+	}
+	addSaved(size)
+	return src, true
+}
+
+// Deduplicate replaces the file downloaded by this run with a hardlink, a reflink, or a reference to an
+// identical file in the content store. If there is none, the file will be added to the content store.
+//
+// Returns the path of the file with the content which is the stored file for the reference mode.
+func Deduplicate(checksum, filePath string, size int64, mode string) (string, error) {
+	src, _, isStored := findStoredFile(checksum, filePath)
+	if isStored {
+		return filePath, nil
+	}
+	if src == "" {
+		return filePath, history.RecordContent(checksum, &history.Content{FilePath: filePath, Size: size})
+	}
+
+	if mode == REFERENCE_MODE {
+		if err := history.AddContentReference(checksum); err != nil {
+			return filePath, err
+		}
+		if err := os.Remove(filePath); err != nil {
+			return filePath, err
+		}
+		addSaved(size)
+		return src, nil
+	}
+
+	// link to a temporary file first so that the
+	// downloaded file is only replaced if the link was created
+	tempPath := filePath + ".dedup"
+	os.Remove(tempPath)
+	if err := link(src, tempPath, mode); err != nil {
+		// e.g. the files are on different file systems
+		return filePath, nil
+	}
+	if err := os.Rename(tempPath, filePath); err != nil {
+		os.Remove(tempPath)
+		return filePath, err
+	}
+	addSaved(size)
+	return filePath, nil
+}
+
+// Record adds the file that already existed before this run to the content store if there is
+// no valid stored file with the given SHA-256 checksum so that identical files can reuse it.
+//
+// Unlike Deduplicate, the file is never removed or replaced as it may have been placed there by the user.
+func Record(checksum, filePath string, size int64) error {
+	if src, _, isStored := findStoredFile(checksum, filePath); src != "" || isStored {
+		return nil
+	}
+	return history.RecordContent(checksum, &history.Content{FilePath: filePath, Size: size})
+}
+
+// PrintReport prints the number of deduplicated files and the disk space saved and resets the counters.
+func PrintReport() {
+	statsMu.Lock()
+	files, saved := dedupFiles, savedBytes
+	dedupFiles, savedBytes = 0, 0
+	statsMu.Unlock()
+
+	if files == 0 {
+		return
+	}
+	color.Green(
+		"Deduplicated %d file(s) with identical content, saving %s of disk space.",
+		files,
+		utils.FormatFileSize(saved),
+	)
+}
@@ -0,0 +1,247 @@
+package dedup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const testContent = "identical content"
+
+// TestMain stores the download history with the content store in a temporary folder
+func TestMain(m *testing.M) {
+	appPath, err := os.MkdirTemp("", "dedup-test")
+	if err != nil {
+		panic(err)
+	}
+	utils.APP_PATH = appPath
+	code := m.Run()
+	os.RemoveAll(appPath)
+	os.Exit(code)
+}
+
+// storedFile describes the file recorded in the content store before the test
+type storedFile int
+
+const (
+	noStoredFile storedFile = iota
+	validStoredFile
+	missingStoredFile
+	truncatedStoredFile
+	sameStoredFile // the recorded file is the file of the test itself
+)
+
+// setupStoredFile writes the stored file and records it in the content store with the test name as its checksum.
+//
+// Returns the checksum and the path of the stored file.
+func setupStoredFile(t *testing.T, dir, filePath string, stored storedFile, references int) (string, string) {
+	t.Helper()
+	checksum := t.Name()
+	storedPath := filepath.Join(dir, "stored", "file.bin")
+	switch stored {
+	case noStoredFile:
+		return checksum, ""
+	case validStoredFile, truncatedStoredFile:
+		content := testContent
+		if stored == truncatedStoredFile {
+			content = content[:5]
+		}
+		os.MkdirAll(filepath.Dir(storedPath), 0755)
+		if err := os.WriteFile(storedPath, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	case sameStoredFile:
+		storedPath = filePath
+	}
+
+	content := &history.Content{FilePath: storedPath, Size: int64(len(testContent)), References: references}
+	if err := history.RecordContent(checksum, content); err != nil {
+		t.Fatal(err)
+	}
+	return checksum, storedPath
+}
+
+func writeFile(t *testing.T, filePath string) {
+	t.Helper()
+	os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err := os.WriteFile(filePath, []byte(testContent), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func getContent(t *testing.T, checksum string) *history.Content {
+	t.Helper()
+	content, err := history.GetContent(checksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestReuse(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   string
+		stored storedFile
+
+		wantReused bool
+		wantFile   bool // whether the file is created at the file path
+		wantRefs   int
+	}{
+		{name: "hardlink", mode: HARDLINK_MODE, stored: validStoredFile, wantReused: true, wantFile: true},
+		{name: "reflink", mode: REFLINK_MODE, stored: validStoredFile, wantReused: true, wantFile: true},
+		{name: "reference", mode: REFERENCE_MODE, stored: validStoredFile, wantReused: true, wantRefs: 1},
+		{name: "no stored file", mode: HARDLINK_MODE, stored: noStoredFile},
+		{name: "stored file is missing", mode: HARDLINK_MODE, stored: missingStoredFile},
+		{name: "stored file is truncated", mode: REFLINK_MODE, stored: truncatedStoredFile},
+		{name: "stored file is missing in the reference mode", mode: REFERENCE_MODE, stored: missingStoredFile},
+		{name: "stored file is the file itself", mode: REFERENCE_MODE, stored: sameStoredFile},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			filePath := filepath.Join(dir, "post", "file.bin")
+			checksum, storedPath := setupStoredFile(t, dir, filePath, test.stored, 0)
+
+			src, reused := Reuse(checksum, filePath, test.mode)
+			if reused != test.wantReused {
+				t.Fatalf("Reuse() reused = %t, want %t", reused, test.wantReused)
+			}
+			if reused && src != storedPath {
+				t.Errorf("Reuse() = %q, want the stored file %q", src, storedPath)
+			}
+
+			data, err := os.ReadFile(filePath)
+			if hasFile := err == nil; hasFile != test.wantFile {
+				t.Fatalf("file exists = %t, want %t", hasFile, test.wantFile)
+			}
+			if test.wantFile && string(data) != testContent {
+				t.Errorf("file content = %q, want %q", data, testContent)
+			}
+			if test.stored != noStoredFile {
+				if refs := getContent(t, checksum).References; refs != test.wantRefs {
+					t.Errorf("references = %d, want %d", refs, test.wantRefs)
+				}
+			}
+		})
+	}
+}
+
+func TestDeduplicate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   string
+		stored storedFile
+
+		wantStoredSrc  bool // whether the returned path is the stored file instead of the file itself
+		wantFile       bool // whether the file is kept at the file path
+		wantLinked     bool // whether the file is a hardlink to the stored file
+		wantRecordedAt bool // whether the content store points at the file afterwards
+		wantRefs       int
+	}{
+		{name: "hardlink", mode: HARDLINK_MODE, stored: validStoredFile, wantFile: true, wantLinked: true},
+		{name: "reflink", mode: REFLINK_MODE, stored: validStoredFile, wantFile: true},
+		{name: "reference", mode: REFERENCE_MODE, stored: validStoredFile, wantStoredSrc: true, wantRefs: 1},
+		{name: "no stored file", mode: HARDLINK_MODE, stored: noStoredFile, wantFile: true, wantRecordedAt: true},
+		{name: "stored file is missing", mode: REFERENCE_MODE, stored: missingStoredFile, wantFile: true, wantRecordedAt: true},
+		{name: "stored file is truncated", mode: HARDLINK_MODE, stored: truncatedStoredFile, wantFile: true, wantRecordedAt: true},
+		{name: "stored file is the file itself", mode: REFERENCE_MODE, stored: sameStoredFile, wantFile: true, wantRecordedAt: true, wantRefs: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			filePath := filepath.Join(dir, "post", "file.bin")
+			writeFile(t, filePath)
+			references := 0
+			if test.stored == sameStoredFile {
+				// the references would be lost if the content store was rewritten for the file
+				references = 2
+			}
+			checksum, storedPath := setupStoredFile(t, dir, filePath, test.stored, references)
+
+			got, err := Deduplicate(checksum, filePath, int64(len(testContent)), test.mode)
+			if err != nil {
+				t.Fatalf("Deduplicate() error = %v", err)
+			}
+			want := filePath
+			if test.wantStoredSrc {
+				want = storedPath
+			}
+			if got != want {
+				t.Errorf("Deduplicate() = %q, want %q", got, want)
+			}
+
+			data, err := os.ReadFile(filePath)
+			if hasFile := err == nil; hasFile != test.wantFile {
+				t.Fatalf("file exists = %t, want %t", hasFile, test.wantFile)
+			}
+			if test.wantFile && string(data) != testContent {
+				t.Errorf("file content = %q, want %q", data, testContent)
+			}
+			if test.wantLinked && !sameFile(filePath, storedPath) {
+				t.Errorf("the file should be a hardlink to the stored file")
+			}
+			if _, err := os.Stat(filePath + ".dedup"); err == nil {
+				t.Errorf("the temporary link should be removed")
+			}
+
+			content := getContent(t, checksum)
+			if recordedAt := content.FilePath == filePath; recordedAt != test.wantRecordedAt {
+				t.Errorf("content store points at %q, want the file itself %t", content.FilePath, test.wantRecordedAt)
+			}
+			if content.References != test.wantRefs {
+				t.Errorf("references = %d, want %d", content.References, test.wantRefs)
+			}
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored storedFile
+
+		wantRecordedAt bool
+		wantRefs       int
+	}{
+		{name: "no stored file", stored: noStoredFile, wantRecordedAt: true},
+		{name: "valid stored file is kept", stored: validStoredFile},
+		{name: "stored file is missing", stored: missingStoredFile, wantRecordedAt: true},
+		{name: "stored file is truncated", stored: truncatedStoredFile, wantRecordedAt: true},
+		{name: "stored file is the file itself", stored: sameStoredFile, wantRecordedAt: true, wantRefs: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			filePath := filepath.Join(dir, "post", "file.bin")
+			writeFile(t, filePath)
+			references := 0
+			if test.stored == sameStoredFile {
+				references = 2
+			}
+			checksum, _ := setupStoredFile(t, dir, filePath, test.stored, references)
+
+			if err := Record(checksum, filePath, int64(len(testContent))); err != nil {
+				t.Fatalf("Record() error = %v", err)
+			}
+
+			// the file that already existed is never removed or replaced
+			if data, err := os.ReadFile(filePath); err != nil || string(data) != testContent {
+				t.Errorf("the pre-existing file should be kept as it is, got %q, %v", data, err)
+			}
+			content := getContent(t, checksum)
+			if recordedAt := content.FilePath == filePath; recordedAt != test.wantRecordedAt {
+				t.Errorf("content store points at %q, want the file itself %t", content.FilePath, test.wantRecordedAt)
+			}
+			if content.References != test.wantRefs {
+				t.Errorf("references = %d, want %d", content.References, test.wantRefs)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package dedup
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// reflink creates a copy-on-write clone of the source file using the FICLONE ioctl
+func reflink(src, dest string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	destFile, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	err = unix.IoctlFileClone(int(destFile.Fd()), int(srcFile.Fd()))
+	destFile.Close()
+	if err != nil {
+		os.Remove(dest)
+	}
+	return err
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package dedup
+
+import "errors"
+
+// reflink is only supported on Linux so a hardlink will be created instead
+func reflink(src, dest string) error {
+	return errors.New("reflinks are not supported on this OS")
+}
@@ -3,6 +3,8 @@ package gdrive
 import (
 	"context"
 	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,36 +15,53 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
 	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/manifest"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
 
-func md5HashFile(file *os.File) (string, error) {
+// fileHashes is the size and the checksums of a GDrive file which are
+// calculated in one pass to verify the file and to record it in the manifest.
+type fileHashes struct {
+	size   int64
+	md5    string
+	sha256 string
+}
+
+func hashFile(file *os.File) (*fileHashes, error) {
 	md5Checksum := md5.New()
-	_, err := io.Copy(md5Checksum, file)
+	sha256Checksum := sha256.New()
+	size, err := io.Copy(io.MultiWriter(md5Checksum, sha256Checksum), file)
 	if err != nil {
-		return "", fmt.Errorf(
-			"gdrive error %d: failed to calculate file's md5 checksum, more info => %v",
+		return nil, fmt.Errorf(
+			"gdrive error %d: failed to calculate file's checksums, more info => %v",
 			utils.OS_ERROR,
 			err,
 		)
 	}
-	return fmt.Sprintf("%x", md5Checksum.Sum(nil)), nil
+	return &fileHashes{
+		size:   size,
+		md5:    hex.EncodeToString(md5Checksum.Sum(nil)),
+		sha256: hex.EncodeToString(sha256Checksum.Sum(nil)),
+	}, nil
 }
 
-func checkIfCanSkipDl(filePath string, fileInfo *models.GdriveFileToDl) (bool, error) {
+// checkIfCanSkipDl returns true with the hashes of the existing file if it matches the GDrive file.
+func checkIfCanSkipDl(filePath string, fileInfo *models.GdriveFileToDl) (bool, *fileHashes, error) {
 	if !utils.PathExists(filePath) {
-		return false, nil
+		return false, nil, nil
 	}
 
 	// check the md5 checksum and the file size
 	file, err := os.OpenFile(filePath, os.O_RDONLY, 0666)
 	if err != nil {
-		return false, fmt.Errorf(
+		return false, nil, fmt.Errorf(
 			"gdrive error %d: failed to open file %q, more info => %v",
 			utils.OS_ERROR,
 			filePath,
@@ -53,7 +72,7 @@ func checkIfCanSkipDl(filePath string, fileInfo *models.GdriveFileToDl) (bool, e
 
 	fileStatInfo, err := file.Stat()
 	if err != nil {
-		return false, fmt.Errorf(
+		return false, nil, fmt.Errorf(
 			"gdrive error %d: failed to get file stat info of %q, more info => %v",
 			utils.OS_ERROR,
 			filePath,
@@ -63,23 +82,34 @@ func checkIfCanSkipDl(filePath string, fileInfo *models.GdriveFileToDl) (bool, e
 
 	fileSize := fileStatInfo.Size()
 	if strconv.FormatInt(fileSize, 10) != fileInfo.Size {
-		return false, nil
+		return false, nil, nil
 	}
 
-	md5Checksum, err := md5HashFile(file)
+	hashes, err := hashFile(file)
 	if err != nil {
-		return false, err
+		return false, nil, err
+	}
+	if hashes.md5 != fileInfo.Md5Checksum {
+		return false, nil, nil
 	}
-	return md5Checksum == fileInfo.Md5Checksum, nil
+	return true, hashes, nil
 }
 
 // Downloads the given GDrive file using GDrive API v3
 //
-// If the md5Checksum has a mismatch, the file will be overwritten and downloaded again
+// If the md5Checksum has a mismatch, the file will be overwritten and downloaded again.
+// Otherwise, if a ".part" file from a previously interrupted download exists, the download will be resumed.
 func (gdrive *GDrive) DownloadFile(fileInfo *models.GdriveFileToDl, filePath string, config *configs.Config, queue chan struct{}) error {
-	skipDl, err := checkIfCanSkipDl(filePath, fileInfo)
+	_, err := gdrive.downloadFile(fileInfo, filePath, config, queue)
+	return err
+}
+
+// downloadFile is the same as DownloadFile but also returns the hashes of the
+// downloaded or existing file, which is nil if the file does not exist, so that the file is only hashed once.
+func (gdrive *GDrive) downloadFile(fileInfo *models.GdriveFileToDl, filePath string, config *configs.Config, queue chan struct{}) (*fileHashes, error) {
+	skipDl, hashes, err := checkIfCanSkipDl(filePath, fileInfo)
 	if skipDl || err != nil {
-		return err
+		return hashes, err
 	}
 
 	// Create a context that can be cancelled when SIGINT/SIGTERM signal is received
@@ -97,10 +127,28 @@ func (gdrive *GDrive) DownloadFile(fileInfo *models.GdriveFileToDl, filePath str
 
 	queue <- struct{}{}
 
+	// resume the download from the ".part" file if possible
+	var rangeHeaders map[string]string
+	fileSize, _ := strconv.ParseInt(fileInfo.Size, 10, 64)
+	partialDl := &request.PartialDl{
+		ContentLength: fileSize,
+		Checksum:      fileInfo.Md5Checksum,
+	}
+	if offset := request.PrepareResume(filePath, partialDl); offset > 0 {
+		rangeHeaders = partialDl.GetRangeHeaders(offset)
+	}
+
 	var res *http.Response
 	url := fmt.Sprintf("%s/%s", gdrive.apiUrl, fileInfo.Id)
 	if gdrive.client != nil {
-		res, err = gdrive.client.Files.Get(fileInfo.Id).AcknowledgeAbuse(true).Context(ctx).Download()
+		fileCall := gdrive.client.Files.Get(fileInfo.Id).AcknowledgeAbuse(true).Context(ctx)
+		for k, v := range rangeHeaders {
+			fileCall.Header().Set(k, v)
+		}
+		if err = request.WaitForHost(ctx, url); err != nil {
+			return nil, err
+		}
+		res, err = fileCall.Download()
 	} else {
 		params := map[string]string{
 			"key":              gdrive.apiKey,
@@ -109,25 +157,112 @@ func (gdrive *GDrive) DownloadFile(fileInfo *models.GdriveFileToDl, filePath str
 		}
 		res, err = request.CallRequest(
 			&request.RequestArgs{
-				Url:       url,
-				Method:    "GET",
-				Timeout:   gdrive.downloadTimeout,
-				Params:    params,
-				Context:   ctx,
-				UserAgent: config.UserAgent,
-				Http2:     !HTTP3_SUPPORTED,
-				Http3:     HTTP3_SUPPORTED,
+				Url:         url,
+				Method:      "GET",
+				Timeout:     utils.DOWNLOAD_TIMEOUT,
+				IdleTimeout: gdrive.downloadIdleTimeout,
+				Params:      params,
+				Headers:     rangeHeaders,
+				Context:     ctx,
+				UserAgent:   config.UserAgent,
+				Http2:       !HTTP3_SUPPORTED,
+				Http3:       HTTP3_SUPPORTED,
 			},
 		)
 	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer res.Body.Close()
-	if res.StatusCode != 200 {
-		return getFailedApiCallErr(res)
+	if res.StatusCode != 200 && res.StatusCode != 206 {
+		return nil, getFailedApiCallErr(res)
+	}
+	if err := request.DlToFile(res, url, filePath); err != nil {
+		return nil, err
+	}
+	return verifyDownload(filePath, fileInfo)
+}
+
+// verifyDownload checks the size and the md5 checksum of the downloaded file
+// and removes the file if it does not match so that it will be downloaded again on the next run.
+func verifyDownload(filePath string, fileInfo *models.GdriveFileToDl) (*fileHashes, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"gdrive error %d: failed to open file %q, more info => %v",
+			utils.OS_ERROR,
+			filePath,
+			err,
+		)
+	}
+	hashes, err := hashFile(file)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var integrityErr *manifest.IntegrityError
+	if fileInfo.Size != "" && strconv.FormatInt(hashes.size, 10) != fileInfo.Size {
+		integrityErr = &manifest.IntegrityError{
+			FilePath: filePath,
+			Field:    "size",
+			Expected: fileInfo.Size + " bytes",
+			Actual:   fmt.Sprintf("%d bytes", hashes.size),
+		}
+	} else if fileInfo.Md5Checksum != "" && hashes.md5 != fileInfo.Md5Checksum {
+		integrityErr = &manifest.IntegrityError{
+			FilePath: filePath,
+			Field:    "md5",
+			Expected: fileInfo.Md5Checksum,
+			Actual:   hashes.md5,
+		}
+	}
+	if integrityErr != nil {
+		os.Remove(filePath)
+		return nil, integrityErr
+	}
+	return hashes, nil
+}
+
+// addToManifest records the downloaded GDrive file with its SHA-256 checksum in the manifest
+func addToManifest(recorder *manifest.Recorder, filePath string, hashes *fileHashes, fileInfo *models.GdriveFileToDl, config *configs.Config) {
+	entry := &manifest.Entry{
+		Url:        "https://drive.google.com/file/d/" + fileInfo.Id,
+		Size:       hashes.size,
+		Sha256:     hashes.sha256,
+		RecordedAt: time.Now(),
+	}
+	if expectedSize, err := strconv.ParseInt(fileInfo.Size, 10, 64); err == nil {
+		entry.Expected = &manifest.Expected{Size: expectedSize}
+	}
+	recorder.Add(manifest.GetPath(filePath, "", fileInfo.PathVars, config), filePath, entry)
+}
+
+// planFiles adds the GDrive files to the download plan in the dry-run mode
+func planFiles(files []*models.GdriveFileToDl, config *configs.Config) {
+	for _, file := range files {
+		filePath := filepath.Join(file.FilePath, file.Name)
+		if config.PathTemplate != nil && file.PathVars != nil {
+			filePath = config.PathTemplate.Execute(utils.DOWNLOAD_PATH, file.PathVars, file.Name)
+		}
+
+		planned := &request.PlannedDownload{
+			Url:      "https://drive.google.com/file/d/" + file.Id,
+			FilePath: filePath,
+			Size:     -1,
+			Action:   request.DOWNLOAD_ACTION,
+		}
+		if size, err := strconv.ParseInt(file.Size, 10, 64); err == nil {
+			planned.Size = size
+		}
+		if skipDl, _, err := checkIfCanSkipDl(filePath, file); err != nil {
+			planned.Action = request.ERROR_ACTION
+			planned.Error = err.Error()
+		} else if skipDl {
+			planned.Action = request.SKIP_ACTION
+		}
+		request.AddToPlan(planned)
 	}
-	return request.DlToFile(res, url, filePath)
 }
 
 func filterDownloads(files []*models.GdriveFileToDl) []*models.GdriveFileToDl {
@@ -174,7 +309,7 @@ func processGdriveDlError(errChan chan *models.GdriveError, progress *spinner.Sp
 
 	if killProgram {
 		progress.KillProgram(
-			"Stopped downloading GDrive files (incomplete downloads will be deleted)...",
+			"Stopped downloading GDrive files (incomplete downloads will be resumed on the next run)...",
 		)
 	}
 }
@@ -185,6 +320,10 @@ func (gdrive *GDrive) DownloadMultipleFiles(files []*models.GdriveFileToDl, conf
 	if len(allowedForDownload) == 0 {
 		return
 	}
+	if config.DryRun {
+		planFiles(allowedForDownload, config)
+		return
+	}
 
 	maxConcurrency := gdrive.maxDownloadWorkers
 	if len(allowedForDownload) < maxConcurrency {
@@ -193,6 +332,7 @@ func (gdrive *GDrive) DownloadMultipleFiles(files []*models.GdriveFileToDl, conf
 	var wg sync.WaitGroup
 	queue := make(chan struct{}, maxConcurrency)
 	errChan := make(chan *models.GdriveError, len(allowedForDownload))
+	manifestRecorder := manifest.NewRecorder()
 
 	baseMsg := "Downloading GDrive files [%d/" + fmt.Sprintf("%d]...", len(allowedForDownload))
 	progress := spinner.New(
@@ -221,10 +361,19 @@ func (gdrive *GDrive) DownloadMultipleFiles(files []*models.GdriveFileToDl, conf
 				<-queue
 			}()
 
-			os.MkdirAll(file.FilePath, 0755)
 			filePath := filepath.Join(file.FilePath, file.Name)
+			if config.PathTemplate != nil && file.PathVars != nil {
+				filePath = config.PathTemplate.Execute(utils.DOWNLOAD_PATH, file.PathVars, file.Name)
+			}
+			os.MkdirAll(filepath.Dir(filePath), 0755)
 
-			err := gdrive.DownloadFile(file, filePath, config, queue)
+			hashes, err := gdrive.downloadFile(file, filePath, config, queue)
+			if err == nil && hashes != nil {
+				addToManifest(manifestRecorder, filePath, hashes, file, config)
+			}
+			if err != nil {
+				history.MarkPostFailed(file.Post)
+			}
 			if err != nil && err != context.Canceled {
 				err = fmt.Errorf(
 					"failed to download file: %s (ID: %s, MIME Type: %s)\nRefer to error details below:\n%v",
@@ -251,6 +400,9 @@ func (gdrive *GDrive) DownloadMultipleFiles(files []*models.GdriveFileToDl, conf
 		processGdriveDlError(errChan, progress)
 	}
 	progress.Stop(hasErr)
+	if errSlice := manifestRecorder.Save(); len(errSlice) > 0 {
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
 }
 
 // Uses regex to extract the file ID and the file type (type: file, folder) from the given URL
@@ -292,6 +444,8 @@ func (gdrive *GDrive) getGdriveFileInfo(gdriveId *models.GDriveToDl, config *con
 			}
 		}
 		fileInfo.FilePath = gdriveId.FilePath
+		fileInfo.PathVars = gdriveId.PathVars
+		fileInfo.Post = gdriveId.Post
 		return []*models.GdriveFileToDl{fileInfo}, nil
 	case "folder":
 		filesInfo, err := gdrive.GetNestedFolderContents(
@@ -308,6 +462,8 @@ func (gdrive *GDrive) getGdriveFileInfo(gdriveId *models.GDriveToDl, config *con
 		var gdriveFilesInfo []*models.GdriveFileToDl
 		for _, fileInfo := range filesInfo {
 			fileInfo.FilePath = gdriveId.FilePath
+			fileInfo.PathVars = gdriveId.PathVars
+			fileInfo.Post = gdriveId.Post
 			gdriveFilesInfo = append(gdriveFilesInfo, fileInfo)
 		}
 		return gdriveFilesInfo, nil
@@ -338,7 +494,11 @@ func (gdrive *GDrive) DownloadGdriveUrls(gdriveUrls []*request.ToDownload, confi
 				Id:       fileId,
 				Type:     fileType,
 				FilePath: gdriveUrl.FilePath,
+				PathVars: gdriveUrl.PathVars,
+				Post:     gdriveUrl.Post,
 			})
+		} else {
+			history.MarkPostFailed(gdriveUrl.Post)
 		}
 	}
 
@@ -368,6 +528,7 @@ func (gdrive *GDrive) DownloadGdriveUrls(gdriveUrls []*request.ToDownload, confi
 		fileInfo, err := gdrive.getGdriveFileInfo(gdriveId, config)
 		if err != nil {
 			errSlice = append(errSlice, err)
+			history.MarkPostFailed(gdriveId.Post)
 		} else {
 			gdriveFilesInfo = append(gdriveFilesInfo, fileInfo...)
 		}
@@ -31,12 +31,12 @@ var (
 )
 
 type GDrive struct {
-	apiKey             string         // Google Drive API key to use
-	client             *drive.Service // Google Drive service client (if using service account credentials)
-	apiUrl             string         // https://www.googleapis.com/drive/v3/files
-	timeout            int            // timeout in seconds for GDrive API v3
-	downloadTimeout    int            // timeout in seconds for GDrive file downloads
-	maxDownloadWorkers int            // max concurrent workers for downloading files
+	apiKey              string         // Google Drive API key to use
+	client              *drive.Service // Google Drive service client (if using service account credentials)
+	apiUrl              string         // https://www.googleapis.com/drive/v3/files
+	timeout             int            // timeout in seconds for GDrive API v3
+	downloadIdleTimeout int            // timeout in seconds without receiving any data for GDrive file downloads
+	maxDownloadWorkers  int            // max concurrent workers for downloading files
 }
 
 // Returns a GDrive structure with the given API key and max download workers
@@ -50,10 +50,10 @@ func GetNewGDrive(apiKey, jsonPath string, config *configs.Config, maxDownloadWo
 	}
 
 	gdrive := &GDrive{
-		apiUrl:             "https://www.googleapis.com/drive/v3/files",
-		timeout:            15,
-		downloadTimeout:    900, // 15 minutes
-		maxDownloadWorkers: maxDownloadWorkers,
+		apiUrl:              "https://www.googleapis.com/drive/v3/files",
+		timeout:             15,
+		downloadIdleTimeout: utils.DOWNLOAD_IDLE_TIMEOUT,
+		maxDownloadWorkers:  maxDownloadWorkers,
 	}
 	if apiKey != "" {
 		gdrive.apiKey = apiKey
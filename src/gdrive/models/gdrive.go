@@ -1,5 +1,10 @@
 package models
 
+import (
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
 type GDriveFile struct {
 	Kind        string `json:"kind"`
 	Id          string `json:"id"`
@@ -20,6 +25,8 @@ type GDriveToDl struct {
 	Id 	     string
 	Type     string
 	FilePath string
+	PathVars *utils.PathVars
+	Post     *history.Post
 }
 
 type GdriveFileToDl struct {
@@ -29,6 +36,8 @@ type GdriveFileToDl struct {
 	MimeType    string
 	Md5Checksum string
 	FilePath    string
+	PathVars    *utils.PathVars
+	Post        *history.Post
 }
 
 type GdriveError struct {
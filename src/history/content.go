@@ -0,0 +1,98 @@
+package history
+
+import (
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// CONTENT_BUCKET is the name of the bucket that maps the SHA-256
+// checksum of each downloaded file to where it is stored on the disk.
+var CONTENT_BUCKET = []byte("content")
+
+// Content is the stored copy of a downloaded file in the content store
+type Content struct {
+	FilePath string `json:"file_path"`
+	Size     int64  `json:"size"`
+
+	// References is the number of files that refer to the stored file in the reference mode
+	References int `json:"references,omitempty"`
+}
+
+// GetContent returns the stored file with the given SHA-256 checksum or nil if there is none.
+func GetContent(checksum string) (*Content, error) {
+	historyDb := getDb()
+	if historyDb == nil {
+		return nil, nil
+	}
+
+	var content *Content
+	err := historyDb.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(CONTENT_BUCKET)
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get([]byte(checksum))
+		if data == nil {
+			return nil
+		}
+		content = &Content{}
+		return json.Unmarshal(data, content)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// RecordContent adds the file with the given SHA-256 checksum to the content store
+// so that identical files downloaded later can reuse it.
+func RecordContent(checksum string, content *Content) error {
+	historyDb := getDb()
+	if historyDb == nil {
+		return nil
+	}
+
+	return historyDb.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(CONTENT_BUCKET)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(content)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(checksum), data)
+	})
+}
+
+// AddContentReference increments the number of files that
+// refer to the stored file with the given SHA-256 checksum.
+func AddContentReference(checksum string) error {
+	historyDb := getDb()
+	if historyDb == nil {
+		return nil
+	}
+
+	return historyDb.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(CONTENT_BUCKET)
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get([]byte(checksum))
+		if data == nil {
+			return nil
+		}
+
+		var content Content
+		if err := json.Unmarshal(data, &content); err != nil {
+			return err
+		}
+		content.References++
+		data, err := json.Marshal(&content)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(checksum), data)
+	})
+}
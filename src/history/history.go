@@ -0,0 +1,371 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/manifest"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	bolt "go.etcd.io/bbolt"
+)
+
+const HISTORY_FILENAME = "history.db"
+
+// POSTS_BUCKET is the name of the bucket that contains
+// a nested bucket of downloaded posts for each site.
+var POSTS_BUCKET = []byte("posts")
+
+var (
+	db       *bolt.DB
+	dbOnce   sync.Once
+	dbFailed bool
+
+	// readOnly is true if the download history should not be created or modified, e.g. in the dry-run mode
+	readOnly bool
+)
+
+// Post is used to identify the post that a downloaded file belongs to.
+type Post struct {
+	Site      string
+	CreatorId string
+	PostId    string
+
+	// SyncCreatorId is the creator ID of the sync state of the post if it differs from
+	// CreatorId, e.g. "fanbox/123" for Kemono Party where the creator IDs are per service.
+	SyncCreatorId string
+}
+
+// getSyncCreatorId returns the creator ID of the sync state that the post belongs to.
+func (p *Post) getSyncCreatorId() string {
+	if p.SyncCreatorId != "" {
+		return p.SyncCreatorId
+	}
+	return p.CreatorId
+}
+
+// File contains the information of a downloaded file of a post.
+type File struct {
+	Url          string    `json:"url"`
+	FilePath     string    `json:"file_path"`
+	Size         int64     `json:"size"`
+	Checksum     string    `json:"checksum"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+}
+
+// Entry is the record of a downloaded post stored in the history database.
+type Entry struct {
+	Site         string    `json:"site"`
+	CreatorId    string    `json:"creator_id"`
+	PostId       string    `json:"post_id"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+
+	// Files is a map of the downloaded files keyed by the hash of the file URL
+	Files map[string]*File `json:"files"`
+}
+
+// NewFile returns the information of the downloaded file at the given file path
+// including its size and SHA-256 checksum.
+func NewFile(fileUrl, filePath string) (*File, error) {
+	size, checksum, err := manifest.HashFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return &File{
+		Url:          fileUrl,
+		FilePath:     filePath,
+		Size:         size,
+		Checksum:     checksum,
+		DownloadedAt: time.Now(),
+	}, nil
+}
+
+// SetReadOnly opens the download history as read-only so that it is never created or modified.
+//
+// Should be called before the download history is used.
+func SetReadOnly() {
+	readOnly = true
+}
+
+func getDb() *bolt.DB {
+	dbOnce.Do(func() {
+		dbPath := filepath.Join(utils.APP_PATH, HISTORY_FILENAME)
+		if readOnly && !utils.PathExists(dbPath) {
+			// there is no download history to read from
+			dbFailed = true
+			return
+		}
+
+		os.MkdirAll(utils.APP_PATH, 0755)
+		var err error
+		db, err = bolt.Open(
+			dbPath,
+			0666,
+			&bolt.Options{Timeout: 5 * time.Second, ReadOnly: readOnly},
+		)
+		if err != nil {
+			dbFailed = true
+			utils.LogError(
+				fmt.Errorf(
+					"history error %d: failed to open download history database, more info => %v",
+					utils.OS_ERROR,
+					err,
+				),
+				"Download history will not be used for this run.",
+				false,
+				utils.ERROR,
+			)
+		}
+	})
+	if dbFailed {
+		return nil
+	}
+	return db
+}
+
+// HashUrl returns the hash of the given URL without its query string
+// as signed URLs like the ones from Fantia will differ on every request.
+func HashUrl(fileUrl string) string {
+	if parsedUrl, err := url.Parse(fileUrl); err == nil {
+		parsedUrl.RawQuery = ""
+		parsedUrl.Fragment = ""
+		fileUrl = parsedUrl.String()
+	}
+	hash := sha256.Sum256([]byte(fileUrl))
+	return hex.EncodeToString(hash[:])
+}
+
+func getPostsBucket(tx *bolt.Tx, site string) *bolt.Bucket {
+	postsBucket := tx.Bucket(POSTS_BUCKET)
+	if postsBucket == nil {
+		return nil
+	}
+	return postsBucket.Bucket([]byte(site))
+}
+
+func getEntry(bucket *bolt.Bucket, postId string) (*Entry, error) {
+	data := bucket.Get([]byte(postId))
+	if data == nil {
+		return nil, nil
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// HasPost returns true if the post has been downloaded before.
+func HasPost(site, postId string) bool {
+	historyDb := getDb()
+	if historyDb == nil {
+		return false
+	}
+
+	hasPost := false
+	historyDb.View(func(tx *bolt.Tx) error {
+		if bucket := getPostsBucket(tx, site); bucket != nil {
+			hasPost = bucket.Get([]byte(postId)) != nil
+		}
+		return nil
+	})
+	return hasPost
+}
+
+// GetEntry returns the entry of the post in the download history or nil if the post has not been downloaded.
+func GetEntry(site, postId string) (*Entry, error) {
+	historyDb := getDb()
+	if historyDb == nil {
+		return nil, nil
+	}
+
+	var entry *Entry
+	err := historyDb.View(func(tx *bolt.Tx) error {
+		bucket := getPostsBucket(tx, site)
+		if bucket == nil {
+			return nil
+		}
+		var err error
+		entry, err = getEntry(bucket, postId)
+		return err
+	})
+	return entry, err
+}
+
+// FilterPosts returns the post IDs that are not in the download history.
+func FilterPosts(site string, postIds []string) []string {
+	filteredPostIds := make([]string, 0, len(postIds))
+	for _, postId := range postIds {
+		if !HasPost(site, postId) {
+			filteredPostIds = append(filteredPostIds, postId)
+		}
+	}
+
+	PrintSkippedMsg(site, len(postIds)-len(filteredPostIds))
+	return filteredPostIds
+}
+
+// PrintSkippedMsg informs the user about the number of posts skipped due to the download history.
+func PrintSkippedMsg(site string, skipped int) {
+	if skipped > 0 {
+		color.Yellow(
+			"Skipping %d %s post(s) that are in the download history (use --ignore_history to download them again)...",
+			skipped,
+			utils.GetReadableSiteStr(site),
+		)
+	}
+}
+
+// RecordPost adds the post and its downloaded files to the download history.
+func RecordPost(post *Post, files []*File) error {
+	historyDb := getDb()
+	if historyDb == nil {
+		return nil
+	}
+
+	return historyDb.Update(func(tx *bolt.Tx) error {
+		postsBucket, err := tx.CreateBucketIfNotExists(POSTS_BUCKET)
+		if err != nil {
+			return err
+		}
+		bucket, err := postsBucket.CreateBucketIfNotExists([]byte(post.Site))
+		if err != nil {
+			return err
+		}
+
+		entry, err := getEntry(bucket, post.PostId)
+		if err != nil || entry == nil {
+			entry = &Entry{
+				Site:      post.Site,
+				CreatorId: post.CreatorId,
+				PostId:    post.PostId,
+				Files:     make(map[string]*File),
+			}
+		}
+		if entry.CreatorId == "" {
+			entry.CreatorId = post.CreatorId
+		}
+		entry.DownloadedAt = time.Now()
+		for _, file := range files {
+			entry.Files[HashUrl(file.Url)] = file
+		}
+
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(post.PostId), data)
+	})
+}
+
+// iterEntries calls fn for every entry that matches the given site and creator ID.
+//
+// Leave the site or creator ID empty to match all sites or creators.
+func iterEntries(tx *bolt.Tx, site, creatorId string, fn func(bucket *bolt.Bucket, entry *Entry) error) error {
+	postsBucket := tx.Bucket(POSTS_BUCKET)
+	if postsBucket == nil {
+		return nil
+	}
+
+	return postsBucket.ForEachBucket(func(name []byte) error {
+		if site != "" && string(name) != site {
+			return nil
+		}
+		bucket := postsBucket.Bucket(name)
+
+		// collect the entries first as the bucket
+		// should not be modified while iterating over it
+		var entries []*Entry
+		err := bucket.ForEach(func(_, data []byte) error {
+			var entry Entry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				return nil
+			}
+			if creatorId == "" || entry.CreatorId == creatorId {
+				entries = append(entries, &entry)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if err := fn(bucket, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetEntries returns the entries in the download history that matches the given site and creator ID.
+//
+// Leave the site or creator ID empty to match all sites or creators.
+func GetEntries(site, creatorId string) ([]*Entry, error) {
+	historyDb := getDb()
+	if historyDb == nil {
+		return nil, nil
+	}
+
+	var entries []*Entry
+	err := historyDb.View(func(tx *bolt.Tx) error {
+		return iterEntries(tx, site, creatorId, func(_ *bolt.Bucket, entry *Entry) error {
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	return entries, err
+}
+
+// Prune removes the entries in the download history that were downloaded before the given time
+// and returns the number of removed entries.
+func Prune(site string, before time.Time) (int, error) {
+	historyDb := getDb()
+	if historyDb == nil {
+		return 0, nil
+	}
+
+	removed := 0
+	err := historyDb.Update(func(tx *bolt.Tx) error {
+		return iterEntries(tx, site, "", func(bucket *bolt.Bucket, entry *Entry) error {
+			if !entry.DownloadedAt.Before(before) {
+				return nil
+			}
+			removed++
+			return bucket.Delete([]byte(entry.PostId))
+		})
+	})
+	return removed, err
+}
+
+// Forget removes the entries in the download history that matches the given site, creator ID, and post ID
+// and returns the number of removed entries.
+//
+// Leave the creator ID or post ID empty to match all creators or posts.
+func Forget(site, creatorId, postId string) (int, error) {
+	historyDb := getDb()
+	if historyDb == nil {
+		return 0, nil
+	}
+
+	removed := 0
+	err := historyDb.Update(func(tx *bolt.Tx) error {
+		return iterEntries(tx, site, creatorId, func(bucket *bolt.Bucket, entry *Entry) error {
+			if postId != "" && entry.PostId != postId {
+				return nil
+			}
+			removed++
+			return bucket.Delete([]byte(entry.PostId))
+		})
+	})
+	return removed, err
+}
@@ -0,0 +1,69 @@
+package history
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+var (
+	processedMu    sync.Mutex
+	processedPosts = make(map[Post]struct{})
+
+	// failedPosts are the posts with any file that failed to download
+	failedPosts = make(map[Post]struct{})
+)
+
+// AddProcessedPost adds the post to be recorded in the download history when CommitPosts is called.
+//
+// This is for the posts without any files for the download process to record, like text-only posts
+// or posts with only GDrive links, so that they are not fetched again on the next run.
+func AddProcessedPost(post *Post) {
+	processedMu.Lock()
+	defer processedMu.Unlock()
+	processedPosts[*post] = struct{}{}
+}
+
+// MarkPostFailed marks the post as failed so that it will not be recorded when CommitPosts is called.
+//
+// The sync state of the creator of the post is also marked as failed.
+func MarkPostFailed(post *Post) {
+	if post == nil {
+		return
+	}
+
+	processedMu.Lock()
+	failedPosts[*post] = struct{}{}
+	processedMu.Unlock()
+	MarkSyncFailed(post)
+}
+
+// CommitPosts records the processed posts that are not in the download history yet
+// except for the posts with any file that failed to download.
+func CommitPosts() {
+	processedMu.Lock()
+	defer processedMu.Unlock()
+
+	var errSlice []error
+	for post := range processedPosts {
+		if _, failed := failedPosts[post]; failed || HasPost(post.Site, post.PostId) {
+			continue
+		}
+		if err := RecordPost(&post, nil); err != nil {
+			errSlice = append(errSlice, fmt.Errorf(
+				"history error %d: failed to add %s post %s to the download history, more info => %v",
+				utils.OS_ERROR,
+				utils.GetReadableSiteStr(post.Site),
+				post.PostId,
+				err,
+			))
+		}
+	}
+	processedPosts = make(map[Post]struct{})
+	failedPosts = make(map[Post]struct{})
+
+	if len(errSlice) > 0 {
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+}
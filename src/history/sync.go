@@ -0,0 +1,193 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	bolt "go.etcd.io/bbolt"
+)
+
+// SYNC_BUCKET is the name of the bucket that contains
+// the newest post of each creator seen from the last sync.
+var SYNC_BUCKET = []byte("sync")
+
+var (
+	pendingSyncMu     sync.Mutex
+	pendingSyncStates = make(map[string]*SyncState)
+
+	// failedSyncKeys are the sync keys of the creators with any post that failed to download
+	failedSyncKeys = make(map[string]struct{})
+)
+
+// SyncState contains the newest post of a creator seen from the last sync.
+type SyncState struct {
+	Site         string    `json:"site"`
+	CreatorId    string    `json:"creator_id"`
+	NewestPostId string    `json:"newest_post_id"`
+	PublishedAt  time.Time `json:"published_at"`
+	SyncedAt     time.Time `json:"synced_at"`
+}
+
+func getSyncKey(site, creatorId string) []byte {
+	return []byte(site + ":" + creatorId)
+}
+
+// isNewer returns true if the post is newer than the given post ID and published time.
+//
+// The published times are compared if both are available.
+// Otherwise, the post IDs are compared as most sites uses incrementing post IDs.
+func isNewer(postId string, publishedAt time.Time, thanPostId string, thanPublishedAt time.Time) bool {
+	if !publishedAt.IsZero() && !thanPublishedAt.IsZero() {
+		return publishedAt.After(thanPublishedAt)
+	}
+
+	postIdNum, err := strconv.ParseInt(postId, 10, 64)
+	if err != nil {
+		return false
+	}
+	thanPostIdNum, err := strconv.ParseInt(thanPostId, 10, 64)
+	if err != nil {
+		return false
+	}
+	return postIdNum > thanPostIdNum
+}
+
+// GetSyncState returns the sync state of the creator from the last sync.
+//
+// If the creator has not been synced before, an empty sync state will be returned.
+func GetSyncState(site, creatorId string) *SyncState {
+	syncState := &SyncState{
+		Site:      site,
+		CreatorId: creatorId,
+	}
+
+	historyDb := getDb()
+	if historyDb == nil {
+		return syncState
+	}
+
+	historyDb.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(SYNC_BUCKET)
+		if bucket == nil {
+			return nil
+		}
+		if data := bucket.Get(getSyncKey(site, creatorId)); data != nil {
+			json.Unmarshal(data, syncState)
+		}
+		return nil
+	})
+	return syncState
+}
+
+// HasSynced returns true if the creator has been synced before.
+func (s *SyncState) HasSynced() bool {
+	return s.NewestPostId != ""
+}
+
+// IsSeen returns true if the post is not newer than the newest post seen from the last sync.
+func (s *SyncState) IsSeen(postId string, publishedAt time.Time) bool {
+	if !s.HasSynced() {
+		return false
+	}
+	if postId == s.NewestPostId {
+		return true
+	}
+	return !isNewer(postId, publishedAt, s.NewestPostId, s.PublishedAt)
+}
+
+// Update updates the newest post of the sync state if the given post is newer.
+func (s *SyncState) Update(postId string, publishedAt time.Time) {
+	if !s.HasSynced() || isNewer(postId, publishedAt, s.NewestPostId, s.PublishedAt) {
+		s.NewestPostId = postId
+		s.PublishedAt = publishedAt
+	}
+}
+
+// SetPendingSyncState sets the sync state to be saved when CommitSyncStates is called.
+//
+// This is so that the sync state is only saved after the posts have been downloaded.
+func SetPendingSyncState(syncState *SyncState) {
+	if !syncState.HasSynced() {
+		return
+	}
+
+	pendingSyncMu.Lock()
+	defer pendingSyncMu.Unlock()
+	pendingSyncStates[string(getSyncKey(syncState.Site, syncState.CreatorId))] = syncState
+}
+
+// MarkSyncFailed marks the sync state of the creator of the post as failed so that it will not be saved
+// when CommitSyncStates is called as the post would otherwise be skipped on the next sync.
+func MarkSyncFailed(post *Post) {
+	if post == nil {
+		return
+	}
+
+	pendingSyncMu.Lock()
+	defer pendingSyncMu.Unlock()
+	failedSyncKeys[string(getSyncKey(post.Site, post.getSyncCreatorId()))] = struct{}{}
+}
+
+// CommitSyncStates saves all the pending sync states to the history database
+// except for the creators with any post that failed to download.
+func CommitSyncStates() {
+	pendingSyncMu.Lock()
+	defer pendingSyncMu.Unlock()
+	for key := range failedSyncKeys {
+		if syncState, ok := pendingSyncStates[key]; ok {
+			color.Yellow(
+				"Not saving the sync state of %s creator %s as some of the posts failed to download...",
+				utils.GetReadableSiteStr(syncState.Site),
+				syncState.CreatorId,
+			)
+			delete(pendingSyncStates, key)
+		}
+	}
+	failedSyncKeys = make(map[string]struct{})
+	if len(pendingSyncStates) == 0 {
+		return
+	}
+
+	historyDb := getDb()
+	if historyDb == nil {
+		return
+	}
+
+	err := historyDb.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(SYNC_BUCKET)
+		if err != nil {
+			return err
+		}
+
+		for key, syncState := range pendingSyncStates {
+			syncState.SyncedAt = time.Now()
+			data, err := json.Marshal(syncState)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(key), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.LogError(
+			fmt.Errorf(
+				"history error %d: failed to save the sync state of %d creator(s), more info => %v",
+				utils.OS_ERROR,
+				len(pendingSyncStates),
+				err,
+			),
+			"",
+			false,
+			utils.ERROR,
+		)
+	}
+	pendingSyncStates = make(map[string]*SyncState)
+}
@@ -0,0 +1,315 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const (
+	MANIFEST_FILENAME = "manifest.json"
+	MANIFEST_KIND     = "manifest"
+
+	// SCHEMA_VERSION is incremented whenever a breaking change is made to the Manifest schema
+	SCHEMA_VERSION = 1
+)
+
+// Expected contains the file information provided by the server
+// which is used to verify the file after it has been downloaded.
+//
+// Zero values are not checked.
+type Expected struct {
+	Size   int64  `json:"size,omitempty"`
+	Sha256 string `json:"sha256,omitempty"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+// Entry is the record of a downloaded file in the manifest
+type Entry struct {
+	Url    string `json:"url"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+
+	// Site and PostId are used to remove the post from the
+	// download history when the file has to be downloaded again.
+	Site   string `json:"site,omitempty"`
+	PostId string `json:"post_id,omitempty"`
+
+	Expected   *Expected `json:"expected,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+
+	// ModTime is the modification time of the file when it was recorded which is used
+	// to skip hashing the file again on the next run if it has not been modified since.
+	ModTime time.Time `json:"mod_time,omitempty"`
+}
+
+// Manifest contains the SHA-256 checksums of the downloaded files in a post folder
+// where the files are keyed by their path relative to the manifest file.
+type Manifest struct {
+	SchemaVersion int               `json:"schema_version"`
+	Files         map[string]*Entry `json:"files"`
+}
+
+// IntegrityError is returned when a file does not match its expected information
+type IntegrityError struct {
+	FilePath string
+	Field    string
+	Expected string
+	Actual   string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf(
+		"manifest error %d: %s mismatch for %s, expected %s but got %s",
+		utils.DOWNLOAD_ERROR,
+		e.Field,
+		e.FilePath,
+		e.Expected,
+		e.Actual,
+	)
+}
+
+// HashFile returns the size and the SHA-256 checksum of the file
+func HashFile(filePath string) (int64, string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Check returns an *IntegrityError if the file with the given size and
+// SHA-256 checksum does not match the information provided by the server.
+func (e *Expected) Check(filePath string, size int64, checksum string) error {
+	if e == nil {
+		return nil
+	}
+	if e.Size > 0 && e.Size != size {
+		return &IntegrityError{
+			FilePath: filePath,
+			Field:    "size",
+			Expected: fmt.Sprintf("%d bytes", e.Size),
+			Actual:   fmt.Sprintf("%d bytes", size),
+		}
+	}
+	if e.Sha256 != "" && e.Sha256 != checksum {
+		return &IntegrityError{
+			FilePath: filePath,
+			Field:    "sha256",
+			Expected: e.Sha256,
+			Actual:   checksum,
+		}
+	}
+	if e.Width <= 0 || e.Height <= 0 {
+		return nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// formats that cannot be decoded like WebP are not checked
+	imageConfig, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return nil
+	}
+	if imageConfig.Width != e.Width || imageConfig.Height != e.Height {
+		return &IntegrityError{
+			FilePath: filePath,
+			Field:    "dimensions",
+			Expected: fmt.Sprintf("%dx%d", e.Width, e.Height),
+			Actual:   fmt.Sprintf("%dx%d", imageConfig.Width, imageConfig.Height),
+		}
+	}
+	return nil
+}
+
+// GetPath returns the file path of the manifest for a downloaded file.
+//
+// If a path template is configured, the manifest will be saved based on the template
+// with "manifest.json" as its filename. Otherwise, the manifest will be saved in the post folder
+// or in the same folder as the file if the post folder is unknown.
+func GetPath(filePath, postFolderPath string, pathVars *utils.PathVars, config *configs.Config) string {
+	if config.PathTemplate != nil && pathVars != nil {
+		return config.PathTemplate.Execute(
+			utils.DOWNLOAD_PATH,
+			pathVars.ForFile(MANIFEST_KIND, 0),
+			MANIFEST_FILENAME,
+		)
+	}
+	if postFolderPath != "" {
+		return filepath.Join(postFolderPath, MANIFEST_FILENAME)
+	}
+	return filepath.Join(filepath.Dir(filePath), MANIFEST_FILENAME)
+}
+
+// Load reads the manifest at the given path.
+//
+// Returns an empty manifest if the manifest does not exist.
+func Load(manifestPath string) (*Manifest, error) {
+	manifest := &Manifest{
+		SchemaVersion: SCHEMA_VERSION,
+		Files:         make(map[string]*Entry),
+	}
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return manifest, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf(
+			"manifest error %d: failed to parse %s, more info => %v",
+			utils.JSON_ERROR,
+			manifestPath,
+			err,
+		)
+	}
+	if manifest.Files == nil {
+		manifest.Files = make(map[string]*Entry)
+	}
+	return manifest, nil
+}
+
+// Save writes the manifest to the given path
+func (m *Manifest) Save(manifestPath string) error {
+	m.SchemaVersion = SCHEMA_VERSION
+	data, err := json.MarshalIndent(m, "", "    ")
+	if err != nil {
+		return err
+	}
+	os.MkdirAll(filepath.Dir(manifestPath), 0755)
+	return os.WriteFile(manifestPath, data, 0666)
+}
+
+// GetFilePath returns the full path of the file in the manifest
+func GetFilePath(manifestPath, relPath string) string {
+	if filepath.IsAbs(relPath) {
+		return relPath
+	}
+	return filepath.Join(filepath.Dir(manifestPath), filepath.FromSlash(relPath))
+}
+
+// getRelPath returns the path of the file relative to the manifest file
+func getRelPath(manifestPath, filePath string) string {
+	relPath, err := filepath.Rel(filepath.Dir(manifestPath), filePath)
+	if err != nil {
+		return filePath
+	}
+	return filepath.ToSlash(relPath)
+}
+
+// GetUnchangedEntry returns the entry of the file in the manifest at the given path if the file
+// still has the same size and modification time as when it was recorded so that its recorded
+// SHA-256 checksum can be used instead of hashing the file again.
+//
+// Returns nil if the file has no such entry.
+func GetUnchangedEntry(manifestPath, filePath string) *Entry {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return nil
+	}
+	manifest, err := Load(manifestPath)
+	if err != nil {
+		return nil
+	}
+
+	entry, ok := manifest.Files[getRelPath(manifestPath, filePath)]
+	if !ok || entry.Sha256 == "" || entry.ModTime.IsZero() {
+		return nil
+	}
+	if entry.Size != fileInfo.Size() || !entry.ModTime.Equal(fileInfo.ModTime()) {
+		return nil
+	}
+	return entry
+}
+
+// SortedPaths returns the relative file paths in the manifest in sorted order
+func (m *Manifest) SortedPaths() []string {
+	paths := make([]string, 0, len(m.Files))
+	for relPath := range m.Files {
+		paths = append(paths, relPath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Recorder collects the manifest entries of the downloaded files
+// so that each manifest is only written once after all the downloads.
+type Recorder struct {
+	mu      sync.Mutex
+	entries map[string]map[string]*Entry
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{
+		entries: make(map[string]map[string]*Entry),
+	}
+}
+
+// Add records the downloaded file in the manifest at the given path
+func (r *Recorder) Add(manifestPath, filePath string, entry *Entry) {
+	if fileInfo, err := os.Stat(filePath); err == nil {
+		entry.ModTime = fileInfo.ModTime()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.entries[manifestPath]; !ok {
+		r.entries[manifestPath] = make(map[string]*Entry)
+	}
+	r.entries[manifestPath][getRelPath(manifestPath, filePath)] = entry
+}
+
+// Save merges the recorded entries into the existing manifests and writes them to the disk.
+func (r *Recorder) Save() []error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errSlice []error
+	for manifestPath, entries := range r.entries {
+		manifest, err := Load(manifestPath)
+		if err == nil {
+			for relPath, entry := range entries {
+				manifest.Files[relPath] = entry
+			}
+			err = manifest.Save(manifestPath)
+		}
+		if err != nil {
+			errSlice = append(errSlice, fmt.Errorf(
+				"manifest error %d: failed to save the manifest %s, more info => %v",
+				utils.OS_ERROR,
+				manifestPath,
+				err,
+			))
+		}
+	}
+	r.entries = make(map[string]map[string]*Entry)
+	return errSlice
+}
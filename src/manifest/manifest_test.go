@@ -0,0 +1,89 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetUnchangedEntry(t *testing.T) {
+	tests := []struct {
+		name string
+
+		// modify changes the recorded entry or the file after it was recorded
+		modify    func(t *testing.T, filePath string, entry *Entry)
+		wantEntry bool
+	}{
+		{
+			name:      "unchanged file",
+			modify:    func(t *testing.T, filePath string, entry *Entry) {},
+			wantEntry: true,
+		},
+		{
+			name: "file was modified",
+			modify: func(t *testing.T, filePath string, entry *Entry) {
+				modTime := time.Now().Add(time.Hour)
+				if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "file size changed",
+			modify: func(t *testing.T, filePath string, entry *Entry) {
+				if err := os.WriteFile(filePath, []byte("truncated"), 0666); err != nil {
+					t.Fatal(err)
+				}
+				os.Chtimes(filePath, entry.ModTime, entry.ModTime)
+			},
+		},
+		{
+			name: "entry without a modification time",
+			modify: func(t *testing.T, filePath string, entry *Entry) {
+				entry.ModTime = time.Time{}
+			},
+		},
+		{
+			name: "file was removed",
+			modify: func(t *testing.T, filePath string, entry *Entry) {
+				os.Remove(filePath)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			filePath := filepath.Join(dir, "images", "1.jpg")
+			manifestPath := filepath.Join(dir, MANIFEST_FILENAME)
+			os.MkdirAll(filepath.Dir(filePath), 0755)
+			if err := os.WriteFile(filePath, []byte("image content"), 0666); err != nil {
+				t.Fatal(err)
+			}
+			size, checksum, err := HashFile(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := NewRecorder()
+			entry := &Entry{Url: "https://example.com/1.jpg", Size: size, Sha256: checksum, RecordedAt: time.Now()}
+			recorder.Add(manifestPath, filePath, entry)
+			if entry.ModTime.IsZero() {
+				t.Fatalf("Add() should record the modification time of the file")
+			}
+			test.modify(t, filePath, entry)
+			if errs := recorder.Save(); len(errs) > 0 {
+				t.Fatalf("Save() errors = %v", errs)
+			}
+
+			got := GetUnchangedEntry(manifestPath, filePath)
+			if (got != nil) != test.wantEntry {
+				t.Fatalf("GetUnchangedEntry() = %+v, want an entry %t", got, test.wantEntry)
+			}
+			if got != nil && (got.Sha256 != checksum || got.Size != size) {
+				t.Errorf("GetUnchangedEntry() = %+v, want the recorded size %d and checksum %s", got, size, checksum)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package manifest
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Statuses of a verified file
+const (
+	OK_STATUS        = "ok"
+	MISSING_STATUS   = "missing"
+	TRUNCATED_STATUS = "truncated"
+	CORRUPTED_STATUS = "corrupted"
+	ERROR_STATUS     = "error"
+)
+
+// Result is the verification result of a file in a manifest
+type Result struct {
+	ManifestPath string
+	FilePath     string
+	Entry        *Entry
+	Status       string
+	Err          error
+}
+
+// verifyEntry re-hashes the file and compares it against its manifest entry
+func verifyEntry(manifestPath, relPath string, entry *Entry) *Result {
+	result := &Result{
+		ManifestPath: manifestPath,
+		FilePath:     GetFilePath(manifestPath, relPath),
+		Entry:        entry,
+		Status:       OK_STATUS,
+	}
+
+	size, checksum, err := HashFile(result.FilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			result.Status = MISSING_STATUS
+		} else {
+			result.Status = ERROR_STATUS
+			result.Err = err
+		}
+		return result
+	}
+
+	if size < entry.Size {
+		result.Status = TRUNCATED_STATUS
+	} else if size != entry.Size || checksum != entry.Sha256 {
+		result.Status = CORRUPTED_STATUS
+	}
+	return result
+}
+
+// FindManifests returns the paths of all the manifests in the given folder and its sub-folders.
+//
+// If the given path is a manifest file, only that manifest will be returned.
+func FindManifests(root string) ([]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{root}, nil
+	}
+
+	var manifestPaths []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && d.Name() == MANIFEST_FILENAME {
+			manifestPaths = append(manifestPaths, path)
+		}
+		return nil
+	})
+	return manifestPaths, err
+}
+
+// Verify re-hashes the files in the manifest and calls onResult with the result of each file.
+func Verify(manifestPath string, onResult func(*Result)) error {
+	manifest, err := Load(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	for _, relPath := range manifest.SortedPaths() {
+		onResult(verifyEntry(manifestPath, relPath, manifest.Files[relPath]))
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const (
+	METADATA_FILENAME = "post.json"
+	METADATA_KIND     = "metadata"
+
+	// SCHEMA_VERSION is incremented whenever a breaking change is made to the Post schema
+	SCHEMA_VERSION = 1
+)
+
+// Fee contains the fee tier required to view the post
+type Fee struct {
+	Name  string `json:"name,omitempty"`
+	Price int    `json:"price"`
+
+	// Currency is the ISO 4217 currency code of the price, e.g. "JPY"
+	Currency string `json:"currency"`
+}
+
+// Post is the normalised metadata of a post that is common across all the supported sites
+type Post struct {
+	SchemaVersion int        `json:"schema_version"`
+	Site          string     `json:"site"`
+	Url           string     `json:"url"`
+	PostId        string     `json:"post_id"`
+	Title         string     `json:"title"`
+	Body          string     `json:"body"`
+	CreatorId     string     `json:"creator_id"`
+	CreatorName   string     `json:"creator_name"`
+	PublishedAt   *time.Time `json:"published_at"`
+	EditedAt      *time.Time `json:"edited_at"`
+	Tags          []string   `json:"tags"`
+	Fee           *Fee       `json:"fee"`
+	SavedAt       time.Time  `json:"saved_at"`
+
+	// Raw is the raw JSON payload of the post from the site's API
+	Raw json.RawMessage `json:"raw"`
+}
+
+// ParseTime parses the time string with the given layout
+// and returns nil if the time string is empty or invalid.
+func ParseTime(layout, value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	parsedTime, err := time.Parse(layout, value)
+	if err != nil {
+		return nil
+	}
+	return &parsedTime
+}
+
+// getFilePath returns the file path of the post's metadata file.
+//
+// If a path template is configured, the metadata file will be saved
+// based on the template with "post.json" as its filename.
+func getFilePath(postFolderPath string, pathVars *utils.PathVars, config *configs.Config) string {
+	if config.PathTemplate != nil && pathVars != nil {
+		return config.PathTemplate.Execute(
+			utils.DOWNLOAD_PATH,
+			pathVars.ForFile(METADATA_KIND, 0),
+			METADATA_FILENAME,
+		)
+	}
+	return filepath.Join(postFolderPath, METADATA_FILENAME)
+}
+
+// Save writes the post's metadata file in the post folder if enabled in the config.
+//
+// Any errors will be logged as the metadata file is not essential for the download process.
+func Save(post *Post, postFolderPath string, pathVars *utils.PathVars, config *configs.Config) {
+	if config == nil || !config.SaveMetadata || config.DryRun {
+		return
+	}
+
+	post.SchemaVersion = SCHEMA_VERSION
+	post.SavedAt = time.Now()
+	if post.Tags == nil {
+		post.Tags = []string{}
+	}
+	if len(post.Raw) == 0 {
+		post.Raw = json.RawMessage("null")
+	}
+
+	filePath := getFilePath(postFolderPath, pathVars, config)
+	data, err := json.MarshalIndent(post, "", "    ")
+	if err == nil {
+		os.MkdirAll(filepath.Dir(filePath), 0755)
+		err = os.WriteFile(filePath, data, 0666)
+	}
+	if err != nil {
+		utils.LogError(
+			fmt.Errorf(
+				"metadata error %d: failed to save the metadata of %s post %s to %s, more info => %v",
+				utils.OS_ERROR,
+				utils.GetReadableSiteStr(post.Site),
+				post.PostId,
+				filePath,
+				err,
+			),
+			"",
+			false,
+			utils.ERROR,
+		)
+	}
+}
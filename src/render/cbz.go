@@ -0,0 +1,214 @@
+package render
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+)
+
+const (
+	// CBZ_FORMAT is the extension of the comic book archives without the dot
+	CBZ_FORMAT = "cbz"
+
+	// CBZ_KIND is the content kind of the comic book archives for the output path template
+	CBZ_KIND = "cbz"
+)
+
+// COMIC_INFO_FILENAME is the filename of the metadata in the comic book archive
+// based on the ComicInfo schema, https://anansi-project.github.io/docs/comicinfo/intro
+const COMIC_INFO_FILENAME = "ComicInfo.xml"
+
+// ComicInfo is the metadata of a comic book archive.
+//
+// PageCount will be set to the number of pages in the archive when it is saved.
+type ComicInfo struct {
+	XMLName xml.Name `xml:"ComicInfo"`
+
+	Title     string `xml:"Title,omitempty"`
+	Series    string `xml:"Series,omitempty"`
+	Number    string `xml:"Number,omitempty"`
+	Count     int    `xml:"Count,omitempty"`
+	Summary   string `xml:"Summary,omitempty"`
+	Year      int    `xml:"Year,omitempty"`
+	Month     int    `xml:"Month,omitempty"`
+	Day       int    `xml:"Day,omitempty"`
+	Writer    string `xml:"Writer,omitempty"`
+	Web       string `xml:"Web,omitempty"`
+	PageCount int    `xml:"PageCount"`
+	Tags      string `xml:"Tags,omitempty"`
+
+	// Manga is "Yes", "YesAndRightToLeft", or "No"
+	Manga string `xml:"Manga,omitempty"`
+}
+
+// Comic is a comic book archive with the pages in order
+// to be saved after the images of the pages have been downloaded.
+type Comic struct {
+	Info *ComicInfo
+
+	// PageUrls are the download URLs of the images of the pages in order
+	PageUrls []string
+
+	filePath string
+}
+
+// NewComic returns a new comic book archive to be saved to the file path.
+func NewComic(info *ComicInfo, filePath string) *Comic {
+	return &Comic{
+		Info:     info,
+		filePath: filePath,
+	}
+}
+
+var pendingComics []*Comic
+
+// AddPendingComic adds the comic book archive to be saved when RenderPending is called.
+func AddPendingComic(comic *Comic) {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	pendingComics = append(pendingComics, comic)
+}
+
+// getPagePaths returns the file paths of the downloaded pages of the comic in order.
+func (c *Comic) getPagePaths() []string {
+	var pagePaths []string
+	for _, pageUrl := range c.PageUrls {
+		filePath, ok := downloadedFiles[pageUrl]
+		if ok && utils.PathExists(filePath) {
+			pagePaths = append(pagePaths, filePath)
+		}
+	}
+	return pagePaths
+}
+
+func addFileToZip(zipWriter *zip.Writer, name, filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// images are already compressed so they are stored as they are
+	w, err := zipWriter.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// getExistingPageCount returns the number of pages in the existing CBZ file or 0 if it does not exist.
+func getExistingPageCount(filePath string) int {
+	zipReader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return 0
+	}
+	defer zipReader.Close()
+
+	pageCount := 0
+	for _, f := range zipReader.File {
+		if f.Name != COMIC_INFO_FILENAME && !f.FileInfo().IsDir() {
+			pageCount++
+		}
+	}
+	return pageCount
+}
+
+// writeZip writes the pages and the ComicInfo.xml of the comic to the zip writer
+func (c *Comic) writeZip(zipWriter *zip.Writer, pagePaths []string) error {
+	// pad the page numbers so that the pages are sorted in order by comic book readers
+	padding := len(fmt.Sprint(len(pagePaths)))
+	for idx, pagePath := range pagePaths {
+		name := fmt.Sprintf("%0*d%s", padding, idx+1, strings.ToLower(filepath.Ext(pagePath)))
+		if err := addFileToZip(zipWriter, name, pagePath); err != nil {
+			return err
+		}
+	}
+
+	c.Info.PageCount = len(pagePaths)
+	comicInfo, err := xml.MarshalIndent(c.Info, "", "  ")
+	if err != nil {
+		return err
+	}
+	w, err := zipWriter.Create(COMIC_INFO_FILENAME)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append([]byte(xml.Header), comicInfo...))
+	return err
+}
+
+// write saves the downloaded pages of the comic with its ComicInfo.xml as a CBZ file.
+//
+// The CBZ file is written to a temporary file first and renamed once completed
+// so that a failed write does not leave a truncated CBZ file behind.
+func (c *Comic) write(pagePaths []string) error {
+	os.MkdirAll(filepath.Dir(c.filePath), 0755)
+	f, err := os.CreateTemp(filepath.Dir(c.filePath), filepath.Base(c.filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpFilePath := f.Name()
+
+	zipWriter := zip.NewWriter(f)
+	err = c.writeZip(zipWriter, pagePaths)
+	if closeErr := zipWriter.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFilePath, c.filePath)
+	}
+	if err != nil {
+		os.Remove(tmpFilePath)
+	}
+	return err
+}
+
+// renderPendingComics saves all the pending comics that have any downloaded pages as CBZ files.
+//
+// Should be called with pendingMu locked.
+func renderPendingComics() []error {
+	comics := pendingComics
+	pendingComics = nil
+
+	var errSlice []error
+	for _, comic := range comics {
+		// the pages may not have been downloaded if they were skipped due to the download history
+		pagePaths := comic.getPagePaths()
+		if len(pagePaths) == 0 {
+			continue
+		}
+
+		// never replace an existing CBZ file with one that has fewer pages
+		// as the pages of the existing CBZ file may no longer be available
+		if existingPageCount := getExistingPageCount(comic.filePath); existingPageCount > len(pagePaths) {
+			color.Yellow(
+				"Not replacing %s with %d page(s) as it already has %d page(s)...",
+				comic.filePath,
+				len(pagePaths),
+				existingPageCount,
+			)
+			continue
+		}
+
+		if err := comic.write(pagePaths); err != nil {
+			errSlice = append(errSlice, fmt.Errorf(
+				"render error %d: failed to save the CBZ file to %s, more info => %v",
+				utils.OS_ERROR,
+				comic.filePath,
+				err,
+			))
+		}
+	}
+	return errSlice
+}
@@ -0,0 +1,165 @@
+package render
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writePages writes the pages to the directory and returns their download URLs mapped to their file paths
+func writePages(t *testing.T, dir string, exts ...string) ([]string, map[string]string) {
+	t.Helper()
+	var pageUrls []string
+	files := make(map[string]string)
+	for idx, ext := range exts {
+		pageUrl := fmt.Sprintf("https://example.com/page%d%s", idx+1, ext)
+		filePath := filepath.Join(dir, fmt.Sprintf("page%d%s", idx+1, ext))
+		if err := os.WriteFile(filePath, []byte(pageUrl), 0666); err != nil {
+			t.Fatal(err)
+		}
+		pageUrls = append(pageUrls, pageUrl)
+		files[pageUrl] = filePath
+	}
+	return pageUrls, files
+}
+
+// jpgPages returns the extensions of the given number of JPEG pages
+func jpgPages(count int) []string {
+	exts := make([]string, count)
+	for idx := range exts {
+		exts[idx] = ".jpg"
+	}
+	return exts
+}
+
+func TestRenderPendingComics(t *testing.T) {
+	tests := []struct {
+		name string
+
+		// pages are the extensions of the downloaded pages in order
+		pages []string
+
+		// missingPages are the download URLs of pages that were not downloaded
+		missingPages []string
+
+		// existingPages is the number of pages of the CBZ file that already exists, if any
+		existingPages int
+
+		wantNames []string
+		wantCount int
+		wantSaved bool
+	}{
+		{
+			name:      "pages in order with ComicInfo.xml",
+			pages:     []string{".jpg", ".PNG", ".jpg"},
+			wantNames: []string{"1.jpg", "2.png", "3.jpg", COMIC_INFO_FILENAME},
+			wantCount: 3,
+			wantSaved: true,
+		},
+		{
+			name:      "page numbers are zero-padded",
+			pages:     jpgPages(10),
+			wantNames: []string{"01.jpg", "02.jpg", "03.jpg", "04.jpg", "05.jpg", "06.jpg", "07.jpg", "08.jpg", "09.jpg", "10.jpg", COMIC_INFO_FILENAME},
+			wantCount: 10,
+			wantSaved: true,
+		},
+		{
+			name:         "pages that were not downloaded are skipped",
+			pages:        []string{".jpg", ".jpg"},
+			missingPages: []string{"https://example.com/missing.jpg"},
+			wantNames:    []string{"1.jpg", "2.jpg", COMIC_INFO_FILENAME},
+			wantCount:    2,
+			wantSaved:    true,
+		},
+		{
+			name:         "no downloaded pages",
+			missingPages: []string{"https://example.com/missing.jpg"},
+			wantSaved:    false,
+		},
+		{
+			name:          "existing CBZ file with more pages is kept",
+			pages:         []string{".jpg"},
+			existingPages: 2,
+			wantNames:     []string{"1.jpg", "2.jpg", COMIC_INFO_FILENAME},
+			wantCount:     2,
+			wantSaved:     true,
+		},
+		{
+			name:          "existing CBZ file with fewer pages is replaced",
+			pages:         []string{".jpg", ".jpg", ".jpg"},
+			existingPages: 2,
+			wantNames:     []string{"1.jpg", "2.jpg", "3.jpg", COMIC_INFO_FILENAME},
+			wantCount:     3,
+			wantSaved:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			filePath := filepath.Join(tmpDir, "comic", "chapter."+CBZ_FORMAT)
+
+			if test.existingPages > 0 {
+				existingDir := filepath.Join(tmpDir, "existing")
+				os.MkdirAll(existingDir, 0755)
+				existingUrls, existingFiles := writePages(t, existingDir, jpgPages(test.existingPages)...)
+				existing := NewComic(&ComicInfo{Title: "Existing"}, filePath)
+				existing.PageUrls = existingUrls
+				setDownloadedFiles(t, existingFiles)
+				if err := existing.write(existing.getPagePaths()); err != nil {
+					t.Fatalf("failed to write the existing CBZ file: %v", err)
+				}
+			}
+
+			pagesDir := filepath.Join(tmpDir, "pages")
+			os.MkdirAll(pagesDir, 0755)
+			pageUrls, pageFiles := writePages(t, pagesDir, test.pages...)
+			setDownloadedFiles(t, pageFiles)
+
+			comic := NewComic(&ComicInfo{Title: "Chapter", Series: "Series", Number: "1", Manga: "YesAndRightToLeft"}, filePath)
+			comic.PageUrls = append(pageUrls, test.missingPages...)
+			pendingComics = []*Comic{comic}
+			if errs := renderPendingComics(); len(errs) > 0 {
+				t.Fatalf("renderPendingComics() errors = %v", errs)
+			}
+			if len(pendingComics) != 0 {
+				t.Errorf("pendingComics should be cleared, got %d comic(s)", len(pendingComics))
+			}
+
+			if _, err := os.Stat(filePath); err != nil {
+				if test.wantSaved {
+					t.Fatalf("the CBZ file was not saved: %v", err)
+				}
+				return
+			} else if !test.wantSaved {
+				t.Fatalf("the CBZ file should not have been saved")
+			}
+
+			files, contents := readZip(t, filePath)
+			var names []string
+			for _, f := range files {
+				names = append(names, f.Name)
+			}
+			if !reflect.DeepEqual(names, test.wantNames) {
+				t.Errorf("files = %v, want %v", names, test.wantNames)
+			}
+
+			var info ComicInfo
+			if err := xml.Unmarshal([]byte(contents[COMIC_INFO_FILENAME]), &info); err != nil {
+				t.Fatalf("failed to parse %s: %v", COMIC_INFO_FILENAME, err)
+			}
+			if info.PageCount != test.wantCount {
+				t.Errorf("PageCount = %d, want %d", info.PageCount, test.wantCount)
+			}
+
+			// no temporary files should be left behind
+			entries, _ := os.ReadDir(filepath.Dir(filePath))
+			if len(entries) != 1 {
+				t.Errorf("expected only the CBZ file in the folder, got %d entries", len(entries))
+			}
+		})
+	}
+}
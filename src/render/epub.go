@@ -0,0 +1,241 @@
+package render
+
+import (
+	"archive/zip"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+var epubImageMediaTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".svg":  "image/svg+xml",
+}
+
+const epubContainerXml = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles>
+<rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+</rootfiles>
+</container>
+`
+
+const epubCss = "body{font-family:serif;line-height:1.8;}img{max-width:100%;}hr{page-break-after:always;border:none;}\n"
+
+// Book is an EPUB file with each document as a chapter
+// to be rendered after the images of the documents have been downloaded.
+type Book struct {
+	Title    string
+	Author   string
+	Url      string
+	Chapters []*Document
+
+	// CoverUrl is the download URL of the cover image, if any
+	CoverUrl string
+
+	// Language is the BCP 47 language tag of the book which defaults to "und" for undetermined
+	Language string
+
+	filePath string
+}
+
+// NewBook returns a new book to be saved to the file path.
+func NewBook(title, author, url, filePath string) *Book {
+	return &Book{
+		Title:    title,
+		Author:   author,
+		Url:      url,
+		filePath: filePath,
+	}
+}
+
+var pendingBooks []*Book
+
+// AddPendingBook adds the book to be rendered when RenderPending is called.
+func AddPendingBook(book *Book) {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	pendingBooks = append(pendingBooks, book)
+}
+
+// epubWriter writes the files of a book to the EPUB file
+// while keeping track of the images that have been added to it.
+type epubWriter struct {
+	zipWriter *zip.Writer
+	manifest  []string
+
+	// images maps the download URL of an image to its path in the EPUB file
+	images map[string]string
+}
+
+func (w *epubWriter) writeFile(name, content string) error {
+	f, err := w.zipWriter.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write([]byte(content))
+	return err
+}
+
+// addImage adds the downloaded file of the image URL to the EPUB file
+// and returns its path relative to the chapters or the URL if it was not downloaded.
+func (w *epubWriter) addImage(imageUrl string, properties string) string {
+	if imagePath, ok := w.images[imageUrl]; ok {
+		return imagePath
+	}
+
+	filePath, ok := downloadedFiles[imageUrl]
+	if !ok || !utils.PathExists(filePath) {
+		return imageUrl
+	}
+	ext := strings.ToLower(filepath.Ext(filePath))
+	mediaType, ok := epubImageMediaTypes[ext]
+	if !ok {
+		return imageUrl
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return imageUrl
+	}
+
+	id := fmt.Sprintf("img%d", len(w.images)+1)
+	imagePath := "images/" + id + ext
+	if err := w.writeFile("OEBPS/"+imagePath, string(data)); err != nil {
+		return imageUrl
+	}
+	item := fmt.Sprintf(`<item id="%s" href="%s" media-type="%s"`, id, imagePath, mediaType)
+	if properties != "" {
+		item += fmt.Sprintf(` properties="%s"`, properties)
+	}
+	w.manifest = append(w.manifest, item+"/>")
+	w.images[imageUrl] = imagePath
+	return imagePath
+}
+
+func xhtmlPage(title, body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head>
+<meta charset="utf-8"/>
+<title>` + html.EscapeString(title) + `</title>
+<link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+` + body + `</body>
+</html>
+`
+}
+
+// write saves the book as an EPUB 3 file
+func (b *Book) write() error {
+	os.MkdirAll(filepath.Dir(b.filePath), 0755)
+	f, err := os.Create(b.filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zipWriter := zip.NewWriter(f)
+	defer zipWriter.Close()
+
+	// the mimetype file must be the first file and must not be compressed
+	mimetype, err := zipWriter.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := mimetype.Write([]byte("application/epub+zip")); err != nil {
+		return err
+	}
+
+	w := &epubWriter{
+		zipWriter: zipWriter,
+		images:    make(map[string]string),
+	}
+	if err := w.writeFile("META-INF/container.xml", epubContainerXml); err != nil {
+		return err
+	}
+	if err := w.writeFile("OEBPS/style.css", epubCss); err != nil {
+		return err
+	}
+	w.manifest = append(w.manifest, `<item id="css" href="style.css" media-type="text/css"/>`)
+	if b.CoverUrl != "" {
+		w.addImage(b.CoverUrl, "cover-image")
+	}
+
+	var spine, toc []string
+	for idx, chapter := range b.Chapters {
+		id := fmt.Sprintf("chapter%d", idx+1)
+		href := id + ".xhtml"
+		body := "<h1>" + html.EscapeString(chapter.Title) + "</h1>\n"
+		body += blocksToHtml(chapter.Blocks, func(fileUrl string) string {
+			return w.addImage(fileUrl, "")
+		}, true)
+		if err := w.writeFile("OEBPS/"+href, xhtmlPage(chapter.Title, body)); err != nil {
+			return err
+		}
+
+		w.manifest = append(w.manifest, fmt.Sprintf(`<item id="%s" href="%s" media-type="application/xhtml+xml"/>`, id, href))
+		spine = append(spine, fmt.Sprintf(`<itemref idref="%s"/>`, id))
+		toc = append(toc, fmt.Sprintf(`<li><a href="%s">%s</a></li>`, href, html.EscapeString(chapter.Title)))
+	}
+
+	nav := "<nav epub:type=\"toc\">\n<h1>" + html.EscapeString(b.Title) + "</h1>\n<ol>\n" + strings.Join(toc, "\n") + "\n</ol>\n</nav>\n"
+	if err := w.writeFile("OEBPS/nav.xhtml", xhtmlPage(b.Title, nav)); err != nil {
+		return err
+	}
+	w.manifest = append(w.manifest, `<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>`)
+
+	language := b.Language
+	if language == "" {
+		language = "und"
+	}
+	opf := `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:identifier id="book-id">` + html.EscapeString(b.Url) + `</dc:identifier>
+<dc:title>` + html.EscapeString(b.Title) + `</dc:title>
+<dc:creator>` + html.EscapeString(b.Author) + `</dc:creator>
+<dc:language>` + html.EscapeString(language) + `</dc:language>
+<meta property="dcterms:modified">` + time.Now().UTC().Format("2006-01-02T15:04:05Z") + `</meta>
+</metadata>
+<manifest>
+` + strings.Join(w.manifest, "\n") + `
+</manifest>
+<spine>
+` + strings.Join(spine, "\n") + `
+</spine>
+</package>
+`
+	return w.writeFile("OEBPS/content.opf", opf)
+}
+
+// renderPendingBooks saves all the pending books as EPUB files.
+//
+// Should be called with pendingMu locked.
+func renderPendingBooks() []error {
+	books := pendingBooks
+	pendingBooks = nil
+
+	var errSlice []error
+	for _, book := range books {
+		if err := book.write(); err != nil {
+			errSlice = append(errSlice, fmt.Errorf(
+				"render error %d: failed to save the EPUB file to %s, more info => %v",
+				utils.OS_ERROR,
+				book.filePath,
+				err,
+			))
+		}
+	}
+	return errSlice
+}
@@ -0,0 +1,175 @@
+package render
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readZip returns the files in the zip file in order and their contents by name
+func readZip(t *testing.T, filePath string) ([]*zip.File, map[string]string) {
+	t.Helper()
+	zipReader, err := zip.OpenReader(filePath)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", filePath, err)
+	}
+	t.Cleanup(func() { zipReader.Close() })
+
+	contents := make(map[string]string)
+	for _, f := range zipReader.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s in the zip file: %v", f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s in the zip file: %v", f.Name, err)
+		}
+		contents[f.Name] = string(data)
+	}
+	return zipReader.File, contents
+}
+
+// checkWellFormedXml fails the test if the content is not well-formed XML
+func checkWellFormedXml(t *testing.T, name, content string) {
+	t.Helper()
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	decoder.Strict = true
+	decoder.Entity = xml.HTMLEntity
+	for {
+		_, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			t.Errorf("%s is not well-formed XML: %v", name, err)
+			return
+		}
+	}
+}
+
+// setDownloadedFiles sets the downloaded files for the test and restores them afterwards
+func setDownloadedFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+	original := downloadedFiles
+	downloadedFiles = files
+	t.Cleanup(func() { downloadedFiles = original })
+}
+
+func TestBookWrite(t *testing.T) {
+	tmpDir := t.TempDir()
+	imagePath := filepath.Join(tmpDir, "image.png")
+	if err := os.WriteFile(imagePath, []byte("\x89PNG\r\n\x1a\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	setDownloadedFiles(t, map[string]string{
+		"https://example.com/image.png": imagePath,
+		"https://example.com/gone.png":  filepath.Join(tmpDir, "gone.png"),
+	})
+
+	tests := []struct {
+		name         string
+		book         *Book
+		wantFiles    []string
+		wantContains map[string][]string
+		wantMissing  map[string][]string
+	}{
+		{
+			name: "single chapter without images",
+			book: &Book{
+				Title:  "Title & <Subtitle>",
+				Author: "Author",
+				Url:    "https://example.com/novel/1",
+				Chapters: []*Document{
+					{Title: "Chapter 1", Blocks: []*Block{TextBlock("Hello")}},
+				},
+			},
+			wantFiles: []string{"META-INF/container.xml", "OEBPS/style.css", "OEBPS/chapter1.xhtml", "OEBPS/nav.xhtml", "OEBPS/content.opf"},
+			wantContains: map[string][]string{
+				"OEBPS/content.opf":    {"<dc:title>Title &amp; &lt;Subtitle&gt;</dc:title>", "<dc:language>und</dc:language>", `<itemref idref="chapter1"/>`},
+				"OEBPS/chapter1.xhtml": {"<h1>Chapter 1</h1>", "<p>Hello</p>"},
+			},
+		},
+		{
+			name: "chapters with ruby, page breaks, and images",
+			book: &Book{
+				Title:    "Series",
+				Language: "ja",
+				CoverUrl: "https://example.com/image.png",
+				Chapters: []*Document{
+					{Title: "One", Blocks: []*Block{
+						{Type: PARAGRAPH_BLOCK, Spans: []*Span{{Text: "漢字", Ruby: "かんじ"}}},
+						{Type: PAGE_BREAK_BLOCK},
+						{Type: IMAGE_BLOCK, Url: "https://example.com/image.png", Name: "image"},
+					}},
+					{Title: "Two", Blocks: []*Block{
+						{Type: IMAGE_BLOCK, Url: "https://example.com/gone.png", Name: "gone"},
+					}},
+				},
+			},
+			wantFiles: []string{"OEBPS/images/img1.png", "OEBPS/chapter1.xhtml", "OEBPS/chapter2.xhtml"},
+			wantContains: map[string][]string{
+				"OEBPS/content.opf": {
+					`<item id="img1" href="images/img1.png" media-type="image/png" properties="cover-image"/>`,
+					"<dc:language>ja</dc:language>",
+					`<itemref idref="chapter1"/>` + "\n" + `<itemref idref="chapter2"/>`,
+				},
+				"OEBPS/chapter1.xhtml": {"<ruby>", "<hr/>", `<img src="images/img1.png" alt="image"/>`},
+				"OEBPS/chapter2.xhtml": {`<img src="https://example.com/gone.png" alt="gone"/>`},
+				"OEBPS/nav.xhtml":      {`<a href="chapter1.xhtml">One</a>`, `<a href="chapter2.xhtml">Two</a>`},
+			},
+			wantMissing: map[string][]string{
+				// the cover image is only added once even though it is also in the first chapter
+				"OEBPS/content.opf": {`id="img2"`},
+			},
+		},
+	}
+
+	for idx, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.book.filePath = filepath.Join(tmpDir, "books", string(rune('a'+idx))+".epub")
+			if err := test.book.write(); err != nil {
+				t.Fatalf("write() error = %v", err)
+			}
+
+			files, contents := readZip(t, test.book.filePath)
+			if len(files) == 0 || files[0].Name != "mimetype" || files[0].Method != zip.Store {
+				t.Fatalf("the first file must be the uncompressed mimetype file")
+			}
+			if contents["mimetype"] != "application/epub+zip" {
+				t.Errorf("mimetype = %q, want %q", contents["mimetype"], "application/epub+zip")
+			}
+
+			for _, name := range test.wantFiles {
+				if _, ok := contents[name]; !ok {
+					t.Errorf("missing %s in the EPUB file", name)
+				}
+			}
+			for name, content := range contents {
+				if strings.HasSuffix(name, ".xml") || strings.HasSuffix(name, ".xhtml") || strings.HasSuffix(name, ".opf") {
+					checkWellFormedXml(t, name, content)
+				}
+			}
+			for name, substrs := range test.wantContains {
+				for _, substr := range substrs {
+					if !strings.Contains(contents[name], substr) {
+						t.Errorf("%s does not contain %q:\n%s", name, substr, contents[name])
+					}
+				}
+			}
+			for name, substrs := range test.wantMissing {
+				for _, substr := range substrs {
+					if strings.Contains(contents[name], substr) {
+						t.Errorf("%s should not contain %q:\n%s", name, substr, contents[name])
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package render
+
+import (
+	"html"
+	"strings"
+)
+
+func rubyToHtml(text, ruby string) string {
+	return "<ruby>" + html.EscapeString(text) + "<rp>(</rp><rt>" + html.EscapeString(ruby) + "</rt><rp>)</rp></ruby>"
+}
+
+// spansToHtml renders the spans as HTML where lineBreak is the
+// tag to use for the new lines, "<br>" for HTML or "<br/>" for XHTML.
+func spansToHtml(spans []*Span, lineBreak string) string {
+	var htmlBuilder strings.Builder
+	for _, span := range spans {
+		text := strings.ReplaceAll(html.EscapeString(span.Text), "\n", lineBreak+"\n")
+		if span.Ruby != "" {
+			text = rubyToHtml(span.Text, span.Ruby)
+		}
+		if span.Bold {
+			text = "<strong>" + text + "</strong>"
+		}
+		if span.Link != "" {
+			text = "<a href=\"" + html.EscapeString(span.Link) + "\">" + text + "</a>"
+		}
+		htmlBuilder.WriteString(text)
+	}
+	return htmlBuilder.String()
+}
+
+// blocksToHtml renders the blocks as the HTML of the page body
+// where resolve returns the link to use for the URL of an image or file.
+//
+// If isXhtml is true, empty elements will be self-closed for XHTML documents like in EPUB files.
+func blocksToHtml(blocks []*Block, resolve func(string) string, isXhtml bool) string {
+	lineBreak, hr, imgEnd := "<br>", "<hr>", ">"
+	if isXhtml {
+		lineBreak, hr, imgEnd = "<br/>", "<hr/>", "/>"
+	}
+
+	var htmlBuilder strings.Builder
+	for _, block := range blocks {
+		switch block.Type {
+		case HEADER_BLOCK:
+			htmlBuilder.WriteString("<h2>" + spansToHtml(block.Spans, lineBreak) + "</h2>\n")
+		case IMAGE_BLOCK:
+			htmlBuilder.WriteString(
+				"<p><img src=\"" + html.EscapeString(resolve(block.Url)) + "\" alt=\"" + html.EscapeString(block.Name) + "\"" + imgEnd + "</p>\n",
+			)
+		case FILE_BLOCK:
+			name := block.Name
+			if name == "" {
+				name = block.Url
+			}
+			htmlBuilder.WriteString(
+				"<p><a href=\"" + html.EscapeString(resolve(block.Url)) + "\">" + html.EscapeString(name) + "</a></p>\n",
+			)
+		case PAGE_BREAK_BLOCK:
+			htmlBuilder.WriteString(hr + "\n")
+		default:
+			htmlBuilder.WriteString("<p>" + spansToHtml(block.Spans, lineBreak) + "</p>\n")
+		}
+	}
+	return htmlBuilder.String()
+}
+
+// toHtml renders the document as a standalone HTML page
+// where resolve returns the link to use for the URL of an image or file.
+func (d *Document) toHtml(resolve func(string) string) string {
+	var htmlBuilder strings.Builder
+	htmlBuilder.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	htmlBuilder.WriteString("<title>" + html.EscapeString(d.Title) + "</title>\n")
+	htmlBuilder.WriteString("<style>body{max-width:800px;margin:auto;padding:1em;font-family:sans-serif;}img{max-width:100%;}</style>\n")
+	htmlBuilder.WriteString("</head>\n<body>\n")
+	if d.Title != "" {
+		htmlBuilder.WriteString("<h1>" + html.EscapeString(d.Title) + "</h1>\n")
+	}
+	if d.PostUrl != "" {
+		escapedUrl := html.EscapeString(d.PostUrl)
+		htmlBuilder.WriteString("<p><a href=\"" + escapedUrl + "\">" + escapedUrl + "</a></p>\n")
+	}
+	htmlBuilder.WriteString(blocksToHtml(d.Blocks, resolve, false))
+	htmlBuilder.WriteString("</body>\n</html>\n")
+	return htmlBuilder.String()
+}
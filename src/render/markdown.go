@@ -0,0 +1,75 @@
+package render
+
+import (
+	"strings"
+)
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+	">", `\>`,
+	"#", `\#`,
+)
+
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+func spansToMarkdown(spans []*Span) string {
+	var mdBuilder strings.Builder
+	for _, span := range spans {
+		text := escapeMarkdown(span.Text)
+		if span.Ruby != "" {
+			// Markdown has no syntax for ruby text so HTML is used instead
+			text = rubyToHtml(span.Text, span.Ruby)
+		}
+		if strings.TrimSpace(text) != "" {
+			if span.Bold {
+				text = "**" + text + "**"
+			}
+			if span.Link != "" {
+				text = "[" + text + "](<" + span.Link + ">)"
+			}
+		}
+		mdBuilder.WriteString(text)
+	}
+	// use hard line breaks for the new lines in the paragraph
+	return strings.ReplaceAll(mdBuilder.String(), "\n", "  \n")
+}
+
+// toMarkdown renders the document as Markdown
+// where resolve returns the link to use for the URL of an image or file.
+func (d *Document) toMarkdown(resolve func(string) string) string {
+	var paragraphs []string
+	if d.Title != "" {
+		paragraphs = append(paragraphs, "# "+escapeMarkdown(d.Title))
+	}
+	if d.PostUrl != "" {
+		paragraphs = append(paragraphs, "<"+d.PostUrl+">")
+	}
+
+	for _, block := range d.Blocks {
+		switch block.Type {
+		case HEADER_BLOCK:
+			paragraphs = append(paragraphs, "## "+spansToMarkdown(block.Spans))
+		case IMAGE_BLOCK:
+			paragraphs = append(paragraphs, "!["+escapeMarkdown(block.Name)+"](<"+resolve(block.Url)+">)")
+		case FILE_BLOCK:
+			name := block.Name
+			if name == "" {
+				name = block.Url
+			}
+			paragraphs = append(paragraphs, "["+escapeMarkdown(name)+"](<"+resolve(block.Url)+">)")
+		case PAGE_BREAK_BLOCK:
+			paragraphs = append(paragraphs, "---")
+		default:
+			paragraphs = append(paragraphs, spansToMarkdown(block.Spans))
+		}
+	}
+	return strings.Join(paragraphs, "\n\n") + "\n"
+}
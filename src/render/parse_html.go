@@ -0,0 +1,120 @@
+package render
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var whitespaceRegex = regexp.MustCompile(`\s+`)
+
+type htmlParser struct {
+	resolveSrc func(string) string
+	blocks     []*Block
+	spans      []*Span
+}
+
+// flush adds the collected spans as a block of the given type
+func (p *htmlParser) flush(blockType string) {
+	if len(p.spans) == 0 {
+		return
+	}
+
+	p.spans[0].Text = strings.TrimLeft(p.spans[0].Text, " ")
+	lastSpan := p.spans[len(p.spans)-1]
+	lastSpan.Text = strings.TrimRight(lastSpan.Text, " ")
+	p.blocks = append(p.blocks, &Block{Type: blockType, Spans: p.spans})
+	p.spans = nil
+}
+
+func getHtmlAttr(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func isBlockElement(node *html.Node) bool {
+	if node.Type != html.ElementNode {
+		return false
+	}
+	switch node.DataAtom {
+	case atom.P, atom.Div, atom.Li, atom.Ul, atom.Ol, atom.Blockquote, atom.Pre, atom.Hr, atom.Table, atom.Tr:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *htmlParser) walkChildren(node *html.Node, bold bool, link string) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		p.walk(child, bold, link)
+	}
+}
+
+func (p *htmlParser) walk(node *html.Node, bold bool, link string) {
+	switch node.Type {
+	case html.TextNode:
+		text := whitespaceRegex.ReplaceAllString(node.Data, " ")
+		if text != "" {
+			p.spans = append(p.spans, &Span{Text: text, Bold: bold, Link: link})
+		}
+		return
+	case html.ElementNode:
+		switch node.DataAtom {
+		case atom.Script, atom.Style:
+			return
+		case atom.Br:
+			p.spans = append(p.spans, &Span{Text: "\n"})
+			return
+		case atom.Img:
+			p.flush(PARAGRAPH_BLOCK)
+			if src := getHtmlAttr(node, "src"); src != "" {
+				p.blocks = append(p.blocks, &Block{
+					Type: IMAGE_BLOCK,
+					Url:  p.resolveSrc(src),
+					Name: getHtmlAttr(node, "alt"),
+				})
+			}
+			return
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+			p.flush(PARAGRAPH_BLOCK)
+			p.walkChildren(node, bold, link)
+			p.flush(HEADER_BLOCK)
+			return
+		case atom.Strong, atom.B:
+			bold = true
+		case atom.A:
+			if href := getHtmlAttr(node, "href"); href != "" {
+				link = href
+			}
+		}
+	}
+
+	isBlock := isBlockElement(node)
+	if isBlock {
+		p.flush(PARAGRAPH_BLOCK)
+	}
+	p.walkChildren(node, bold, link)
+	if isBlock {
+		p.flush(PARAGRAPH_BLOCK)
+	}
+}
+
+// BlocksFromHtml converts the HTML content of a post into blocks
+// where resolveSrc returns the download URL of an image based on its src attribute.
+func BlocksFromHtml(content string, resolveSrc func(string) string) []*Block {
+	root, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return []*Block{TextBlock(content)}
+	}
+
+	parser := &htmlParser{resolveSrc: resolveSrc}
+	parser.walk(root, false, "")
+	parser.flush(PARAGRAPH_BLOCK)
+	return parser.blocks
+}
@@ -0,0 +1,242 @@
+package render
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const (
+	MARKDOWN_FORMAT = "md"
+	HTML_FORMAT     = "html"
+	EPUB_FORMAT     = "epub"
+
+	// BODY_KIND is the content kind of the rendered post body file for the output path template
+	BODY_KIND = "body"
+)
+
+var ACCEPTED_FORMATS = []string{MARKDOWN_FORMAT, HTML_FORMAT}
+
+// Types of a block in the post body
+const (
+	PARAGRAPH_BLOCK = "paragraph"
+	HEADER_BLOCK    = "header"
+	IMAGE_BLOCK     = "image"
+	FILE_BLOCK      = "file"
+
+	// PAGE_BREAK_BLOCK separates the pages of a document like a novel
+	PAGE_BREAK_BLOCK = "page_break"
+)
+
+// Span is a run of text in a block with the same formatting
+type Span struct {
+	Text string
+	Bold bool
+
+	// Link is the URL that the text links to, if any
+	Link string
+
+	// Ruby is the reading of the text shown above it, if any
+	Ruby string
+}
+
+// Block is a paragraph, header, image, file, or page break in the post body
+type Block struct {
+	Type  string
+	Spans []*Span
+
+	// Url is the download URL of the image or file which
+	// will be rewritten to the downloaded file's relative path if it was downloaded.
+	Url string
+
+	// Name is the filename of the file or the alt text of the image
+	Name string
+}
+
+// TextBlock returns a paragraph block with the given text without any formatting
+func TextBlock(text string) *Block {
+	return &Block{
+		Type:  PARAGRAPH_BLOCK,
+		Spans: []*Span{{Text: text}},
+	}
+}
+
+// Document is the body of a post to be rendered after its files have been downloaded
+type Document struct {
+	Title   string
+	PostUrl string
+	Blocks  []*Block
+
+	postFolderPath string
+	pathVars       *utils.PathVars
+
+	// filename and format are only set for documents that are always rendered
+	// to the same file in the same format regardless of the configured post body format
+	filename string
+	format   string
+}
+
+// NewDocument returns a new document for the post.
+//
+// The document will be saved in the post folder or
+// based on the output path template if one is configured.
+func NewDocument(title, postUrl, postFolderPath string, pathVars *utils.PathVars) *Document {
+	return &Document{
+		Title:          title,
+		PostUrl:        postUrl,
+		postFolderPath: postFolderPath,
+		pathVars:       pathVars,
+	}
+}
+
+// NewTranscript returns a new document like a chat transcript that is always rendered in the
+// given format to "<filename>.<format>" in the folder regardless of the configured post body format.
+func NewTranscript(title, url, folderPath, filename, format string, pathVars *utils.PathVars) *Document {
+	doc := NewDocument(title, url, folderPath, pathVars)
+	doc.filename = filename
+	doc.format = format
+	return doc
+}
+
+// AddBlocks appends the blocks to the document while skipping any empty paragraphs
+func (d *Document) AddBlocks(blocks ...*Block) {
+	for _, block := range blocks {
+		if block == nil {
+			continue
+		}
+		if (block.Type == PARAGRAPH_BLOCK || block.Type == HEADER_BLOCK) && isEmptySpans(block.Spans) {
+			continue
+		}
+		d.Blocks = append(d.Blocks, block)
+	}
+}
+
+func isEmptySpans(spans []*Span) bool {
+	for _, span := range spans {
+		if strings.TrimSpace(span.Text) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	pendingMu   sync.Mutex
+	pendingDocs []*Document
+
+	// downloadedFiles maps the download URL of a file to its downloaded file path
+	downloadedFiles = make(map[string]string)
+)
+
+// AddPending adds the document to be rendered when RenderPending is called.
+func AddPending(doc *Document) {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	pendingDocs = append(pendingDocs, doc)
+}
+
+// SetDownloadedFile records the file path of a downloaded file so that
+// the references to its URL in the pending documents can be rewritten to the local file.
+func SetDownloadedFile(url, filePath string) {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	downloadedFiles[url] = filePath
+}
+
+// getFilePath returns the file path of the rendered document
+func (d *Document) getFilePath(format string, config *configs.Config) string {
+	filename := "post." + format
+	if d.filename != "" {
+		filename = d.filename + "." + format
+	}
+	if config.PathTemplate != nil && d.pathVars != nil {
+		return config.PathTemplate.Execute(
+			utils.DOWNLOAD_PATH,
+			d.pathVars.ForFile(BODY_KIND, 0),
+			filename,
+		)
+	}
+	return filepath.Join(d.postFolderPath, filename)
+}
+
+// resolveUrl returns the relative path from the document to the downloaded file of the URL.
+// If the file was not downloaded, the URL will be returned as it is.
+func resolveUrl(fileUrl, docDir string) string {
+	filePath, ok := downloadedFiles[fileUrl]
+	if !ok || !utils.PathExists(filePath) {
+		return fileUrl
+	}
+
+	relPath, err := filepath.Rel(docDir, filePath)
+	if err != nil {
+		return fileUrl
+	}
+
+	// escape each part of the path so that
+	// filenames with spaces, etc. will still be valid links
+	pathParts := strings.Split(filepath.ToSlash(relPath), "/")
+	for idx, part := range pathParts {
+		pathParts[idx] = url.PathEscape(part)
+	}
+	return strings.Join(pathParts, "/")
+}
+
+// RenderPending renders all the pending documents in the configured format and saves the pending books and comics.
+//
+// Should be called after all the files of the posts have been downloaded.
+func RenderPending(config *configs.Config) {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	docs := pendingDocs
+	pendingDocs = nil
+	if config == nil {
+		pendingBooks = nil
+		pendingComics = nil
+		return
+	}
+
+	errSlice := renderPendingBooks()
+	errSlice = append(errSlice, renderPendingComics()...)
+	for _, doc := range docs {
+		format := doc.format
+		if format == "" {
+			format = config.BodyFormat
+		}
+		if format == "" {
+			continue
+		}
+
+		filePath := doc.getFilePath(format, config)
+		docDir := filepath.Dir(filePath)
+		resolve := func(fileUrl string) string {
+			return resolveUrl(fileUrl, docDir)
+		}
+
+		var content string
+		if format == HTML_FORMAT {
+			content = doc.toHtml(resolve)
+		} else {
+			content = doc.toMarkdown(resolve)
+		}
+
+		os.MkdirAll(docDir, 0755)
+		if err := os.WriteFile(filePath, []byte(content), 0666); err != nil {
+			errSlice = append(errSlice, fmt.Errorf(
+				"render error %d: failed to save the document to %s, more info => %v",
+				utils.OS_ERROR,
+				filePath,
+				err,
+			))
+		}
+	}
+
+	if len(errSlice) > 0 {
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+}
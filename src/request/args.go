@@ -18,6 +18,11 @@ type RequestArgs struct {
 	Url string
 	Timeout int
 
+	// IdleTimeout is the maximum number of seconds to wait for more data of the response body.
+	// If set, Timeout only limits the time to receive the response headers instead of the whole request
+	// so that large files downloaded slowly, e.g. due to the bandwidth limit, are not aborted.
+	IdleTimeout int
+
 	// Additional Request Options
 	Headers            map[string]string
 	Params             map[string]string
@@ -29,12 +34,16 @@ type RequestArgs struct {
 	Http2 bool
 	Http3 bool
 
-	// Check status will check the status code of the response for 200 OK.
-	// If the status code is not 200 OK, it will retry several times and 
-	// if the status code is still not 200 OK, it will return an error.
+	// Check status will check the status code of the response for a 2xx status code.
+	// If the status code is not 2xx, it will retry several times and 
+	// if the status code is still not 2xx, it will return a *StatusError.
 	// Otherwise, it will return the response regardless of the status code.
 	CheckStatus bool
 
+	// RetryPolicy determines how many times and how long to wait before retrying the request.
+	// If nil, the policy returned by DefaultRetryPolicy will be used.
+	RetryPolicy *RetryPolicy
+
 	// Context is used to cancel the request if needed.
 	// E.g. if the user presses Ctrl+C, we can use context.WithCancel(context.Background())
 	Context context.Context
@@ -116,6 +125,10 @@ func (args *RequestArgs) getDefaultArgs() {
 	if args.Context == nil {
 		args.Context = context.Background()
 	}
+
+	if args.RetryPolicy == nil {
+		args.RetryPolicy = DefaultRetryPolicy()
+	}
 }
 
 // ValidateArgs validates the arguments of the request
@@ -153,4 +166,13 @@ func (args *RequestArgs) ValidateArgs() {
 	} else if args.Timeout == 0 {
 		args.Timeout = 15
 	}
+
+	if args.IdleTimeout < 0 {
+		panic(
+			fmt.Errorf(
+				"error %d: idle timeout cannot be negative",
+				utils.DEV_ERROR,
+			),
+		)
+	}
 }
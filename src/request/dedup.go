@@ -0,0 +1,73 @@
+package request
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/dedup"
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// reuseStoredFile materialises the file from the content store without downloading it
+// if its SHA-256 checksum is known before the download like the files from Kemono Party.
+//
+// Returns the path of the file with the content and false if the file has to be downloaded.
+func reuseStoredFile(urlInfo *ToDownload, config *configs.Config) (string, bool) {
+	if config.Dedup == "" || urlInfo.Expected == nil || urlInfo.Expected.Sha256 == "" {
+		return "", false
+	}
+
+	// the filename is only known before the request if the file path has an extension
+	if filepath.Ext(urlInfo.FilePath) == "" {
+		return "", false
+	}
+	filePath, err := getFullFilePath(nil, urlInfo.FilePath)
+	if err != nil {
+		return "", false
+	}
+	if config.PathTemplate != nil && urlInfo.PathVars != nil {
+		filePath = config.PathTemplate.Execute(utils.DOWNLOAD_PATH, urlInfo.PathVars, filepath.Base(filePath))
+	}
+	if utils.PathExists(filePath) {
+		return "", false
+	}
+
+	src, reused := dedup.Reuse(urlInfo.Expected.Sha256, filePath, config.Dedup)
+	if !reused {
+		return "", false
+	}
+	if config.Dedup == dedup.REFERENCE_MODE {
+		return src, true
+	}
+	return filePath, true
+}
+
+// deduplicateFile replaces the file downloaded by this run based on the deduplication mode if an
+// identical file has been downloaded before and updates the file path of the file if needed.
+//
+// Files that were not written by this run are only recorded in the content store as they may have
+// been placed there by the user. Temporary files are not deduplicated as they are processed at their
+// file path after being downloaded.
+func deduplicateFile(urlInfo *ToDownload, file *history.File, downloaded bool, config *configs.Config) error {
+	if config.Dedup == "" || urlInfo.Temporary {
+		return nil
+	}
+
+	var err error
+	if downloaded {
+		file.FilePath, err = dedup.Deduplicate(file.Checksum, file.FilePath, file.Size, config.Dedup)
+	} else {
+		err = dedup.Record(file.Checksum, file.FilePath, file.Size)
+	}
+	if err != nil {
+		return fmt.Errorf(
+			"dedup error %d: failed to deduplicate %s, more info => %v",
+			utils.OS_ERROR,
+			file.FilePath,
+			err,
+		)
+	}
+	return nil
+}
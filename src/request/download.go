@@ -2,6 +2,7 @@ package request
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,6 +15,10 @@ import (
 	"syscall"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/dedup"
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/manifest"
+	"github.com/KJHJason/Cultured-Downloader-CLI/render"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
@@ -21,13 +26,10 @@ import (
 func getFullFilePath(res *http.Response, filePath string) (string, error) {
 	// check if filepath already have a filename attached
 	if filepath.Ext(filePath) != "" {
-		filePathDir := filepath.Dir(filePath)
-		os.MkdirAll(filePathDir, 0755)
 		filePathWithoutExt := utils.RemoveExtFromFilename(filePath)
 		return filePathWithoutExt + strings.ToLower(filepath.Ext(filePath)), nil
 	}
 
-	os.MkdirAll(filePath, 0755)
 	filename, err := url.PathUnescape(res.Request.URL.String())
 	if err != nil {
 		// should never happen but just in case
@@ -75,51 +77,94 @@ func checkIfCanSkipDl(contentLength int64, filePath string, forceOverwrite bool)
 	return false
 }
 
+// DlToFile writes the response body to a ".part" file and renames it to the given file path once completed.
+//
+// If the response is a 206 Partial Content response, the body will be appended to the existing ".part" file.
+// Otherwise, the ".part" file will be overwritten.
 func DlToFile(res *http.Response, url, filePath string) error {
-	file, err := os.Create(filePath) // create the file
+	if !IsSuccessStatus(res.StatusCode) {
+		return fmt.Errorf(
+			"download error %d: failed to download file, more info => %w",
+			utils.RESPONSE_ERROR,
+			&StatusError{Url: url, StatusCode: res.StatusCode, Status: res.Status, Attempts: 1},
+		)
+	}
+
+	// check the content before anything is written to the disk
+	// so that error pages from the server are not saved as the file.
+	body, err := validateContent(res, url, filePath)
+	if err != nil {
+		return err
+	}
+
+	partFilePath := filePath + PART_FILE_EXT
+	fileFlags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if res.StatusCode == http.StatusPartialContent {
+		rangeStart, err := getContentRangeStart(res)
+		partFileInfo, statErr := os.Stat(partFilePath)
+		if err != nil || statErr != nil || rangeStart != partFileInfo.Size() {
+			removePartialDl(filePath)
+			return fmt.Errorf(
+				"download error %d: unable to resume download as the received range does not match the partially downloaded file\nurl: %s",
+				utils.RESPONSE_ERROR,
+				url,
+			)
+		}
+		fileFlags = os.O_WRONLY | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(partFilePath, fileFlags, 0666)
 	if err != nil {
 		return fmt.Errorf(
 			"error %d: failed to create file, more info => %v\nfile path: %s",
 			utils.OS_ERROR,
 			err,
-			filePath,
+			partFilePath,
 		)
 	}
 
 	// write the body to file
 	// https://stackoverflow.com/a/11693049/16377492
-	_, err = io.Copy(file, res.Body)
+	ctx := context.Background()
+	if res.Request != nil {
+		ctx = res.Request.Context()
+	}
+	_, err = io.Copy(file, limitBandwidth(ctx, body))
+	file.Close()
 	if err != nil {
-		file.Close()
-		if fileErr := os.Remove(filePath); fileErr != nil {
-			utils.LogError(
-				fmt.Errorf(
-					"download error %d: failed to remove file at %s, more info => %v",
-					utils.OS_ERROR,
-					filePath,
-					fileErr,
-				),
-				"",
-				false,
-				utils.ERROR,
-			)
+		// The ".part" file is kept so that the download can be resumed on the next run.
+		if errors.Is(err, context.Canceled) {
+			return context.Canceled
 		}
+		return fmt.Errorf(
+			"download error %d: failed to download %s, more info => %w\nfile path: %s",
+			utils.DOWNLOAD_ERROR,
+			url,
+			err,
+			partFilePath,
+		)
+	}
 
-		if err != context.Canceled {
-			errorMsg := fmt.Sprintf("failed to download %s due to %v", url, err)
-			utils.LogError(err, errorMsg, false, utils.ERROR)
-			err = nil
-		}
-		return err
+	if err := os.Rename(partFilePath, filePath); err != nil {
+		return fmt.Errorf(
+			"download error %d: failed to rename %s to %s, more info => %v",
+			utils.OS_ERROR,
+			partFilePath,
+			filePath,
+			err,
+		)
 	}
-	file.Close()
+	removePartialDl(filePath)
 	return nil
 }
 
 // DownloadUrl is used to download a file from a URL
 //
-// Note: If the file already exists, the download process will be skipped
-func DownloadUrl(filePath string, queue chan struct{}, reqArgs *RequestArgs, overwriteExistingFile bool) error {
+// Note: If the file already exists, the download process will be skipped.
+// If a ".part" file from a previously interrupted download exists, the download will be resumed if possible.
+//
+// The returned boolean is true if the file was written by this download instead of already existing.
+func DownloadUrl(urlInfo *ToDownload, queue chan struct{}, reqArgs *RequestArgs, config *configs.Config) (string, bool, error) {
 	// Create a context that can be cancelled when SIGINT/SIGTERM signal is received
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -134,6 +179,10 @@ func DownloadUrl(filePath string, queue chan struct{}, reqArgs *RequestArgs, ove
 	defer signal.Stop(sigs)
 
 	queue <- struct{}{}
+	if filePath, reused := reuseStoredFile(urlInfo, config); reused {
+		return filePath, false, nil
+	}
+
 	// Send a HEAD request first to get the expected file size from the Content-Length header.
 	// A GET request might work but most of the time
 	// as the Content-Length header may not present due to chunked encoding.
@@ -152,45 +201,123 @@ func DownloadUrl(filePath string, queue chan struct{}, reqArgs *RequestArgs, ove
 		},
 	)
 	if err != nil {
-		return err
+		return "", false, err
 	}
-	fileReqContentLength := headRes.ContentLength
 	headRes.Body.Close()
 
+	filePath, err := getFullFilePath(headRes, urlInfo.FilePath)
+	if err != nil {
+		return "", false, err
+	}
+	if config.PathTemplate != nil && urlInfo.PathVars != nil {
+		filePath = config.PathTemplate.Execute(utils.DOWNLOAD_PATH, urlInfo.PathVars, filepath.Base(filePath))
+	}
+	os.MkdirAll(filepath.Dir(filePath), 0755)
+	if checkIfCanSkipDl(headRes.ContentLength, filePath, config.OverwriteFiles) {
+		return filePath, false, nil
+	}
+
+	// resume the download from the ".part" file if possible
+	partialDl := GetPartialDlFromRes(headRes)
+	if offset := PrepareResume(filePath, partialDl); offset > 0 {
+		reqArgs.Headers = partialDl.AddRangeHeaders(reqArgs.Headers, offset)
+	}
+
 	reqArgs.Context = ctx
 	res, err := reqArgs.RequestHandler(reqArgs)
 	if err != nil {
 		if err != context.Canceled {
 			err = fmt.Errorf(
-				"error %d: failed to download file, more info => %v\nurl: %s",
+				"error %d: failed to download file, more info => %w\nurl: %s",
 				utils.DOWNLOAD_ERROR,
 				err,
 				reqArgs.Url,
 			)
 		}
-		return err
+		return "", false, err
 	}
 	defer res.Body.Close()
 
-	filePath, err = getFullFilePath(res, filePath)
+	return filePath, true, DlToFile(res, reqArgs.Url, filePath)
+}
+
+// verifyDownload hashes the downloaded file once and verifies it against the file information provided by the server.
+//
+// Files that already existed are not hashed again if their manifest entry shows that they have not been modified
+// since they were recorded. If the file was written by this run and does not match, it will be removed so that it
+// will be downloaded again on the next run. Files that already existed are kept as they may have been placed there by the user.
+func verifyDownload(urlInfo *ToDownload, filePath string, downloaded bool, config *configs.Config) (*history.File, error) {
+	if !downloaded && !urlInfo.Temporary {
+		manifestPath := manifest.GetPath(filePath, urlInfo.PostFolder, urlInfo.PathVars, config)
+		if entry := manifest.GetUnchangedEntry(manifestPath, filePath); entry != nil {
+			file := &history.File{
+				Url:          urlInfo.Url,
+				FilePath:     filePath,
+				Size:         entry.Size,
+				Checksum:     entry.Sha256,
+				DownloadedAt: entry.RecordedAt,
+			}
+			if err := urlInfo.Expected.Check(filePath, file.Size, file.Checksum); err != nil {
+				return nil, err
+			}
+			return file, nil
+		}
+	}
+
+	file, err := history.NewFile(urlInfo.Url, filePath)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf(
+			"download error %d: failed to verify the downloaded file, more info => %v\nfile path: %s",
+			utils.OS_ERROR,
+			err,
+			filePath,
+		)
+	}
+
+	if err := urlInfo.Expected.Check(filePath, file.Size, file.Checksum); err != nil {
+		if downloaded {
+			os.Remove(filePath)
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// addToManifest records the verified file in the manifest of its post folder
+func addToManifest(recorder *manifest.Recorder, urlInfo *ToDownload, file *history.File, config *configs.Config) {
+	if urlInfo.Temporary {
+		return
 	}
 
-	if !checkIfCanSkipDl(fileReqContentLength, filePath, overwriteExistingFile) {
-		err = DlToFile(res, reqArgs.Url, filePath)
+	entry := &manifest.Entry{
+		Url:        file.Url,
+		Size:       file.Size,
+		Sha256:     file.Checksum,
+		Expected:   urlInfo.Expected,
+		RecordedAt: file.DownloadedAt,
+	}
+	if urlInfo.Post != nil {
+		entry.Site = urlInfo.Post.Site
+		entry.PostId = urlInfo.Post.PostId
 	}
-	return err
+	manifestPath := manifest.GetPath(file.FilePath, urlInfo.PostFolder, urlInfo.PathVars, config)
+	recorder.Add(manifestPath, file.FilePath, entry)
 }
 
 // DownloadUrls is used to download multiple files from URLs concurrently
 //
-// Note: If the file already exists, the download process will be skipped
+// Note: If the file already exists, the download process will be skipped.
+// Each downloaded file is verified against its expected information and
+// recorded with its SHA-256 checksum in the manifest of its post folder.
 func DownloadUrlsWithHandler(urlInfoSlice []*ToDownload, dlOptions *DlOptions, config *configs.Config, reqHandler RequestHandler) {
 	urlsLen := len(urlInfoSlice)
 	if urlsLen == 0 {
 		return
 	}
+	if config.DryRun {
+		planUrls(urlInfoSlice, dlOptions, config, reqHandler)
+		return
+	}
 	if urlsLen < dlOptions.MaxConcurrency {
 		dlOptions.MaxConcurrency = urlsLen
 	}
@@ -198,6 +325,8 @@ func DownloadUrlsWithHandler(urlInfoSlice []*ToDownload, dlOptions *DlOptions, c
 	var wg sync.WaitGroup
 	queue := make(chan struct{}, dlOptions.MaxConcurrency)
 	errChan := make(chan error, urlsLen)
+	dlHistory := newDlHistory()
+	manifestRecorder := manifest.NewRecorder()
 
 	baseMsg := "Downloading files [%d/" + fmt.Sprintf("%d]...", urlsLen)
 	progress := spinner.New(
@@ -220,18 +349,20 @@ func DownloadUrlsWithHandler(urlInfoSlice []*ToDownload, dlOptions *DlOptions, c
 	progress.Start()
 	for _, urlInfo := range urlInfoSlice {
 		wg.Add(1)
-		go func(fileUrl, filePath string) {
+		go func(urlInfo *ToDownload) {
 			defer func() {
 				wg.Done()
 				<-queue
 			}()
-			err := DownloadUrl(
-				filePath,
+			filePath, downloaded, err := DownloadUrl(
+				urlInfo,
 				queue,
 				&RequestArgs{
-					Url:            fileUrl,
+					Url:            urlInfo.Url,
 					Method:         "GET",
 					Timeout:        utils.DOWNLOAD_TIMEOUT,
+					IdleTimeout:    utils.DOWNLOAD_IDLE_TIMEOUT,
+					CheckStatus:    true,
 					Cookies:        dlOptions.Cookies,
 					Headers:        dlOptions.Headers,
 					Http2:          !dlOptions.UseHttp3,
@@ -239,16 +370,27 @@ func DownloadUrlsWithHandler(urlInfoSlice []*ToDownload, dlOptions *DlOptions, c
 					UserAgent:      config.UserAgent,
 					RequestHandler: reqHandler,
 				},
-				config.OverwriteFiles,
+				config,
 			)
+			var file *history.File
+			if err == nil {
+				file, err = verifyDownload(urlInfo, filePath, downloaded, config)
+			}
 			if err != nil {
 				errChan <- err
+			} else {
+				if dedupErr := deduplicateFile(urlInfo, file, downloaded, config); dedupErr != nil {
+					utils.LogError(dedupErr, "", false, utils.ERROR)
+				}
+				render.SetDownloadedFile(urlInfo.Url, file.FilePath)
+				addToManifest(manifestRecorder, urlInfo, file, config)
 			}
+			dlHistory.add(urlInfo, file, err)
 
 			if err != context.Canceled {
 				progress.MsgIncrement(baseMsg)
 			}
-		}(urlInfo.Url, urlInfo.FilePath)
+		}(urlInfo)
 	}
 	wg.Wait()
 	close(queue)
@@ -259,11 +401,16 @@ func DownloadUrlsWithHandler(urlInfoSlice []*ToDownload, dlOptions *DlOptions, c
 		hasErr = true
 		if kill := utils.LogErrors(false, errChan, utils.ERROR); kill {
 			progress.KillProgram(
-				"Stopped downloading files (incomplete downloads will be deleted)...",
+				"Stopped downloading files (incomplete downloads will be resumed on the next run)...",
 			)
 		}
 	}
 	progress.Stop(hasErr)
+	dlHistory.save()
+	if errSlice := manifestRecorder.Save(); len(errSlice) > 0 {
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	dedup.PrintReport()
 }
 
 // Same as DownloadUrlsWithHandler but uses the default request handler (CallRequest)
@@ -0,0 +1,62 @@
+package request
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// dlHistory keeps track of the downloaded files of each post
+// so that only the posts with all of its files downloaded are added to the download history.
+type dlHistory struct {
+	mu          sync.Mutex
+	files       map[history.Post][]*history.File
+	failedPosts map[history.Post]struct{}
+}
+
+func newDlHistory() *dlHistory {
+	return &dlHistory{
+		files:       make(map[history.Post][]*history.File),
+		failedPosts: make(map[history.Post]struct{}),
+	}
+}
+
+func (h *dlHistory) add(urlInfo *ToDownload, file *history.File, err error) {
+	if urlInfo.Post == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err != nil {
+		h.failedPosts[*urlInfo.Post] = struct{}{}
+		history.MarkPostFailed(urlInfo.Post)
+		return
+	}
+	h.files[*urlInfo.Post] = append(h.files[*urlInfo.Post], file)
+}
+
+func (h *dlHistory) save() {
+	var errSlice []error
+	for post, files := range h.files {
+		if _, failed := h.failedPosts[post]; failed {
+			continue
+		}
+
+		if err := history.RecordPost(&post, files); err != nil {
+			errSlice = append(errSlice, fmt.Errorf(
+				"history error %d: failed to add %s post %s to the download history, more info => %v",
+				utils.OS_ERROR,
+				utils.GetReadableSiteStr(post.Site),
+				post.PostId,
+				err,
+			))
+		}
+	}
+
+	if len(errSlice) > 0 {
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+}
@@ -0,0 +1,436 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const (
+	// MIRROR_HEALTH_CHECK_TIMEOUT is the timeout in seconds of the health check of each mirror
+	MIRROR_HEALTH_CHECK_TIMEOUT = 5
+
+	// MAX_MIRROR_REDIRECTS is the maximum number of redirects that are followed for a request to a mirror
+	MAX_MIRROR_REDIRECTS = 10
+)
+
+// ErrMirrorsDown is returned when all the hosts that a request can be sent to
+// were already down before the request and the request to them failed again.
+var ErrMirrorsDown = errors.New("all the mirrors are down")
+
+// mirrorGroup is a group of equivalent hosts that serve the same paths, e.g. "kemono.su" and "kemono.party".
+//
+// The hosts are health-checked the first time a request is sent to any of them and
+// the requests are then sent to the fastest healthy host, failing over to the next
+// host whenever the current host is down for the rest of the run.
+type mirrorGroup struct {
+	mu    sync.Mutex
+	once  sync.Once
+	hosts []string
+
+	// healthPath is the path that the hosts are health-checked with, e.g. an API path
+	healthPath string
+
+	// latencies of the healthy hosts from the health check or their last successful response
+	latencies map[string]time.Duration
+	down      map[string]bool
+}
+
+func newMirrorGroup(hosts []string, healthPath string) *mirrorGroup {
+	return &mirrorGroup{
+		hosts:      hosts,
+		healthPath: healthPath,
+		latencies:  make(map[string]time.Duration),
+		down:       make(map[string]bool),
+	}
+}
+
+var (
+	mirrorMu     sync.RWMutex
+	mirrorGroups []*mirrorGroup
+
+	// redirectGroups are the groups of the hosts that the mirrors redirect to without a mirror group
+	redirectGroups = make(map[string]*mirrorGroup)
+)
+
+// AddMirrorGroup adds a group of equivalent hosts in the order of preference for the requests to
+// fail over between. A group that shares a host with an existing group replaces the existing group
+// so that the default mirrors of a site can be overridden by the user.
+//
+// The hosts are health-checked with the health path which should return a 2xx response when the host
+// is up. If the health path is empty, the health path of the replaced group or "/" is used instead.
+func AddMirrorGroup(hosts []string, healthPath string) {
+	normalisedHosts := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" && !utils.SliceContains(normalisedHosts, host) {
+			normalisedHosts = append(normalisedHosts, host)
+		}
+	}
+	if len(normalisedHosts) == 0 {
+		return
+	}
+
+	mirrorMu.Lock()
+	defer mirrorMu.Unlock()
+	groups := make([]*mirrorGroup, 0, len(mirrorGroups)+1)
+	for _, group := range mirrorGroups {
+		isReplaced := false
+		for _, host := range normalisedHosts {
+			if utils.SliceContains(group.hosts, host) {
+				isReplaced = true
+				break
+			}
+		}
+		if !isReplaced {
+			groups = append(groups, group)
+		} else if healthPath == "" {
+			healthPath = group.healthPath
+		}
+	}
+	if healthPath == "" {
+		healthPath = "/"
+	}
+	mirrorGroups = append(groups, newMirrorGroup(normalisedHosts, healthPath))
+}
+
+// ParseMirrorGroup parses a group of equivalent hosts in the format
+// "<host>|<host>|..." such as "kemono.su|kemono.party" in the order of preference.
+func ParseMirrorGroup(mirrorGroup string) ([]string, error) {
+	var hosts []string
+	for _, host := range strings.Split(mirrorGroup, "|") {
+		host = strings.TrimSpace(host)
+		if host == "" || strings.ContainsAny(host, "/:") {
+			return nil, fmt.Errorf(
+				"error %d: invalid mirror group %q, expected \"<host>|<host>|...\" such as \"kemono.su|kemono.party\"",
+				utils.INPUT_ERROR,
+				mirrorGroup,
+			)
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+func getMirrorGroup(host string) *mirrorGroup {
+	host = strings.ToLower(host)
+	mirrorMu.RLock()
+	defer mirrorMu.RUnlock()
+	for _, group := range mirrorGroups {
+		if utils.SliceContains(group.hosts, host) {
+			return group
+		}
+	}
+	return nil
+}
+
+// getRedirectGroup returns the mirror group of the host that a mirror redirected to, e.g. a data server.
+//
+// A host without a mirror group gets a group of its own so that it is remembered when it is down.
+// It is not health-checked as the hosts such as the data servers have no path that returns a 2xx response.
+func getRedirectGroup(host string) *mirrorGroup {
+	if group := getMirrorGroup(host); group != nil {
+		return group
+	}
+
+	host = strings.ToLower(host)
+	mirrorMu.Lock()
+	defer mirrorMu.Unlock()
+	group, ok := redirectGroups[host]
+	if !ok {
+		group = newMirrorGroup([]string{host}, "")
+		group.once.Do(func() {})
+		redirectGroups[host] = group
+	}
+	return group
+}
+
+// healthCheck sends a GET request for the health path to each host at the same time with the same client
+// as the request and records the latency of the hosts that responded with a 2xx response in the meantime.
+//
+// Redirects are not followed so that a parked or redirecting domain is not considered to be healthy.
+func (g *mirrorGroup) healthCheck(ctx context.Context, reqArgs *RequestArgs) {
+	client := GetHttpClient(reqArgs)
+	client.Timeout = MIRROR_HEALTH_CHECK_TIMEOUT * time.Second
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	var wg sync.WaitGroup
+	for _, host := range g.hosts {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			req, err := http.NewRequestWithContext(ctx, "GET", "https://"+host+g.healthPath, nil)
+			if err != nil {
+				return
+			}
+			req.Header.Set("User-Agent", reqArgs.UserAgent)
+			if err := WaitForHost(ctx, req.URL.String()); err != nil {
+				return
+			}
+
+			start := time.Now()
+			res, err := client.Do(req)
+			latency := time.Since(start)
+			isHealthy := err == nil && IsSuccessStatus(res.StatusCode)
+			if err == nil {
+				res.Body.Close()
+			}
+
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			if isHealthy {
+				g.latencies[host] = latency
+			} else {
+				g.down[host] = true
+			}
+		}(host)
+	}
+	wg.Wait()
+}
+
+// getCandidates returns the hosts to try in order, starting with the fastest healthy host.
+//
+// The hosts that are down are still tried last in case they have recovered.
+func (g *mirrorGroup) getCandidates(reqArgs *RequestArgs) []string {
+	g.once.Do(func() {
+		// the health check is only done once, so it is not tied to the context of
+		// the first request as the group would be left unchecked if it was cancelled
+		ctx, cancel := context.WithTimeout(context.Background(), MIRROR_HEALTH_CHECK_TIMEOUT*time.Second)
+		defer cancel()
+		g.healthCheck(ctx, reqArgs)
+	})
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	candidates := make([]string, len(g.hosts))
+	copy(candidates, g.hosts)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		iIsDown, jIsDown := g.down[candidates[i]], g.down[candidates[j]]
+		if iIsDown != jIsDown {
+			return !iIsDown
+		}
+		iLatency, iOk := g.latencies[candidates[i]]
+		jLatency, jOk := g.latencies[candidates[j]]
+		if iOk && jOk {
+			return iLatency < jLatency
+		}
+		return iOk && !jOk
+	})
+	return candidates
+}
+
+// isAllDown returns true if all the hosts are down
+func (g *mirrorGroup) isAllDown(hosts []string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, host := range hosts {
+		if !g.down[host] {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *mirrorGroup) markDown(host string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.down[host] = true
+}
+
+func (g *mirrorGroup) markUp(host string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.down[host] {
+		// the host has recovered, so keep the host after the current
+		// fastest host unless the current fastest host goes down too
+		delete(g.down, host)
+		g.latencies[host] = time.Duration(1<<63 - 1)
+	}
+}
+
+// isMirrorDown returns true if the host should be failed over from
+// based on the error or the server error response of the request.
+func isMirrorDown(res *http.Response, err error, policy *RetryPolicy) bool {
+	if err != nil {
+		return policy.RetryTransportErrors && !errors.Is(err, context.Canceled)
+	}
+	return res.StatusCode >= 500 && policy.IsRetryableStatus(res.StatusCode)
+}
+
+// isRedirect returns true if the response redirects to another URL
+func isRedirect(res *http.Response) bool {
+	switch res.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return res.Header.Get("Location") != ""
+	default:
+		return false
+	}
+}
+
+// newRedirectRequest returns the request for the URL that the response redirected to like the client would.
+//
+// The headers such as the Range header are kept but the cookies are only added if they are for the new host.
+func newRedirectRequest(req *http.Request, res *http.Response, cookies []*http.Cookie) (*http.Request, error) {
+	location, err := res.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	method := req.Method
+	keepBody := res.StatusCode == http.StatusTemporaryRedirect || res.StatusCode == http.StatusPermanentRedirect
+	if !keepBody && method != "GET" && method != "HEAD" {
+		method = "GET"
+	}
+	redirectReq, err := http.NewRequestWithContext(req.Context(), method, location.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if keepBody && req.GetBody != nil {
+		if redirectReq.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+		redirectReq.GetBody = req.GetBody
+		redirectReq.ContentLength = req.ContentLength
+	}
+
+	for key, values := range req.Header {
+		if key != "Cookie" && key != "Authorization" {
+			redirectReq.Header[key] = append([]string(nil), values...)
+		}
+	}
+	for _, cookie := range cookies {
+		if isCookieForHost(cookie, redirectReq.URL.Hostname()) {
+			redirectReq.AddCookie(cookie)
+		}
+	}
+	return redirectReq, nil
+}
+
+// setRequestHost changes the host of the request URL to the mirror.
+//
+// The cookies are re-added for the mirror so that a cookie, such as the session cookie,
+// is only sent to the host that it was issued for instead of every host of the group.
+func setRequestHost(req *http.Request, host string, cookies []*http.Cookie) {
+	if req.URL.Host == host {
+		return
+	}
+	newUrl := *req.URL
+	newUrl.Host = host
+	req.URL = &newUrl
+	req.Host = host
+
+	req.Header.Del("Cookie")
+	for _, cookie := range cookies {
+		if isCookieForHost(cookie, host) {
+			req.AddCookie(cookie)
+		}
+	}
+}
+
+// isCookieForHost returns true if the domain of the cookie is the host or one of its parent domains
+func isCookieForHost(cookie *http.Cookie, host string) bool {
+	domain := strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+	if domain == "" {
+		return false
+	}
+	host = strings.ToLower(host)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// doRequest sends the request once after waiting for the rate limit of the host.
+//
+// If the host of the request URL belongs to a mirror group, the request is sent to
+// the fastest healthy mirror instead and fails over to the other mirrors if it is down.
+//
+// The redirects of the mirrors, e.g. from "kemono.su/data/..." to the data server that stores
+// the file, are followed here instead of by the client so that the host that is redirected to
+// goes through its own mirror group. A data server that is down is then remembered like the
+// other mirrors instead of using up the retries of every request that is redirected to it.
+func doRequest(client *http.Client, req *http.Request, reqArgs *RequestArgs, policy *RetryPolicy) (*http.Response, error) {
+	group := getMirrorGroup(req.URL.Hostname())
+	if group == nil {
+		if err := WaitForHost(req.Context(), req.URL.String()); err != nil {
+			return nil, err
+		}
+		return client.Do(req)
+	}
+
+	noRedirectClient := *client
+	noRedirectClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	res, err := group.do(&noRedirectClient, req, reqArgs, policy, group.getCandidates(reqArgs))
+	for redirects := 0; err == nil && isRedirect(res); redirects++ {
+		res.Body.Close()
+		if redirects == MAX_MIRROR_REDIRECTS {
+			return nil, fmt.Errorf("stopped after %d redirects from %s", MAX_MIRROR_REDIRECTS, req.URL.String())
+		}
+
+		var redirectReq *http.Request
+		if redirectReq, err = newRedirectRequest(res.Request, res, reqArgs.Cookies); err != nil {
+			return nil, err
+		}
+
+		// a redirect to another host of the same group is sent to that host as
+		// it would otherwise be changed back to the host that redirected to it
+		redirectHost := redirectReq.URL.Hostname()
+		redirectGroup := getRedirectGroup(redirectHost)
+		candidates := []string{strings.ToLower(redirectHost)}
+		if redirectGroup != group {
+			candidates = redirectGroup.getCandidates(reqArgs)
+		}
+		group = redirectGroup
+		res, err = group.do(&noRedirectClient, redirectReq, reqArgs, policy, candidates)
+	}
+	return res, err
+}
+
+// do sends the request to the candidates of the group in order and fails over to the next candidate if it is down.
+//
+// If all the candidates were already down before the request and are still down,
+// ErrMirrorsDown is returned so that the request is not retried on them.
+func (g *mirrorGroup) do(client *http.Client, req *http.Request, reqArgs *RequestArgs, policy *RetryPolicy, candidates []string) (*http.Response, error) {
+	var res *http.Response
+	var err error
+	wasDown := g.isAllDown(candidates)
+	for idx, host := range candidates {
+		if idx > 0 && req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		setRequestHost(req, host, reqArgs.Cookies)
+		if err = WaitForHost(req.Context(), req.URL.String()); err != nil {
+			return nil, err
+		}
+
+		res, err = client.Do(req)
+		if !isMirrorDown(res, err, policy) {
+			if err == nil {
+				g.markUp(host)
+			}
+			return res, err
+		}
+
+		g.markDown(host)
+		if (idx < len(candidates)-1 || wasDown) && err == nil {
+			res.Body.Close()
+		}
+	}
+
+	if wasDown {
+		if err == nil {
+			err = fmt.Errorf("status code => %s", res.Status)
+		}
+		return nil, fmt.Errorf("%w (%s), more info => %v", ErrMirrorsDown, strings.Join(candidates, ", "), err)
+	}
+	return res, err
+}
@@ -0,0 +1,494 @@
+package request
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// setMirrorGroups replaces the mirror groups and the groups of the redirected hosts for the test and restores them afterwards
+func setMirrorGroups(t *testing.T, groups []*mirrorGroup) {
+	t.Helper()
+	mirrorMu.Lock()
+	original, originalRedirectGroups := mirrorGroups, redirectGroups
+	mirrorGroups, redirectGroups = groups, make(map[string]*mirrorGroup)
+	mirrorMu.Unlock()
+	t.Cleanup(func() {
+		mirrorMu.Lock()
+		mirrorGroups, redirectGroups = original, originalRedirectGroups
+		mirrorMu.Unlock()
+	})
+}
+
+// newCheckedMirrorGroup returns a mirror group with the given health check results
+// so that the hosts are not health-checked over the network.
+func newCheckedMirrorGroup(hosts []string, latencies map[string]time.Duration, down []string) *mirrorGroup {
+	group := newMirrorGroup(hosts, "/")
+	group.once.Do(func() {})
+	for host, latency := range latencies {
+		group.latencies[host] = latency
+	}
+	for _, host := range down {
+		group.down[host] = true
+	}
+	return group
+}
+
+func TestParseMirrorGroup(t *testing.T) {
+	tests := []struct {
+		mirrorGroup string
+		want        []string
+		wantErr     bool
+	}{
+		{mirrorGroup: "kemono.su|kemono.party", want: []string{"kemono.su", "kemono.party"}},
+		{mirrorGroup: " kemono.su | kemono.cr ", want: []string{"kemono.su", "kemono.cr"}},
+		{mirrorGroup: "kemono.su", want: []string{"kemono.su"}},
+		{mirrorGroup: "kemono.su||kemono.party", wantErr: true},
+		{mirrorGroup: "https://kemono.su|kemono.party", wantErr: true},
+		{mirrorGroup: "kemono.su:443|kemono.party", wantErr: true},
+		{mirrorGroup: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mirrorGroup, func(t *testing.T) {
+			got, err := ParseMirrorGroup(test.mirrorGroup)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseMirrorGroup() error = %v, wantErr %t", err, test.wantErr)
+			}
+			if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseMirrorGroup() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAddMirrorGroup(t *testing.T) {
+	type addArgs struct {
+		hosts      []string
+		healthPath string
+	}
+	tests := []struct {
+		name            string
+		adds            []addArgs
+		wantHosts       [][]string
+		wantHealthPaths []string
+	}{
+		{
+			name:            "hosts are normalised and deduplicated",
+			adds:            []addArgs{{hosts: []string{" Kemono.su", "kemono.su", "kemono.party", ""}, healthPath: "/api/v1/app/version"}},
+			wantHosts:       [][]string{{"kemono.su", "kemono.party"}},
+			wantHealthPaths: []string{"/api/v1/app/version"},
+		},
+		{
+			name: "group sharing a host replaces the existing group and keeps its health path",
+			adds: []addArgs{
+				{hosts: []string{"kemono.su", "kemono.party"}, healthPath: "/api/v1/app/version"},
+				{hosts: []string{"coomer.su", "coomer.party"}, healthPath: "/api/v1/app/version"},
+				{hosts: []string{"kemono.cr", "kemono.su"}},
+			},
+			wantHosts:       [][]string{{"coomer.su", "coomer.party"}, {"kemono.cr", "kemono.su"}},
+			wantHealthPaths: []string{"/api/v1/app/version", "/api/v1/app/version"},
+		},
+		{
+			name:            "new group without a health path",
+			adds:            []addArgs{{hosts: []string{"a.example", "b.example"}}},
+			wantHosts:       [][]string{{"a.example", "b.example"}},
+			wantHealthPaths: []string{"/"},
+		},
+		{
+			name:            "empty group is ignored",
+			adds:            []addArgs{{hosts: []string{" ", ""}}},
+			wantHosts:       nil,
+			wantHealthPaths: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setMirrorGroups(t, nil)
+			for _, add := range test.adds {
+				AddMirrorGroup(add.hosts, add.healthPath)
+			}
+
+			var gotHosts [][]string
+			var gotHealthPaths []string
+			for _, group := range mirrorGroups {
+				gotHosts = append(gotHosts, group.hosts)
+				gotHealthPaths = append(gotHealthPaths, group.healthPath)
+			}
+			if !reflect.DeepEqual(gotHosts, test.wantHosts) {
+				t.Errorf("hosts = %v, want %v", gotHosts, test.wantHosts)
+			}
+			if !reflect.DeepEqual(gotHealthPaths, test.wantHealthPaths) {
+				t.Errorf("health paths = %v, want %v", gotHealthPaths, test.wantHealthPaths)
+			}
+		})
+	}
+}
+
+func TestGetCandidates(t *testing.T) {
+	hosts := []string{"a.example", "b.example", "c.example"}
+	tests := []struct {
+		name      string
+		latencies map[string]time.Duration
+		down      []string
+		want      []string
+	}{
+		{
+			name: "preference order without health check results",
+			want: []string{"a.example", "b.example", "c.example"},
+		},
+		{
+			name:      "fastest healthy host first",
+			latencies: map[string]time.Duration{"a.example": 300 * time.Millisecond, "b.example": 100 * time.Millisecond, "c.example": 200 * time.Millisecond},
+			want:      []string{"b.example", "c.example", "a.example"},
+		},
+		{
+			name:      "hosts that are down are tried last",
+			latencies: map[string]time.Duration{"b.example": 100 * time.Millisecond, "c.example": 200 * time.Millisecond},
+			down:      []string{"a.example"},
+			want:      []string{"b.example", "c.example", "a.example"},
+		},
+		{
+			name:      "hosts without a latency are after the healthy hosts",
+			latencies: map[string]time.Duration{"c.example": 200 * time.Millisecond},
+			want:      []string{"c.example", "a.example", "b.example"},
+		},
+		{
+			name: "all hosts are down",
+			down: []string{"a.example", "b.example", "c.example"},
+			want: []string{"a.example", "b.example", "c.example"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			group := newCheckedMirrorGroup(hosts, test.latencies, test.down)
+			if got := group.getCandidates(&RequestArgs{}); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("getCandidates() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// mirrorTransport responds to the requests based on the host of the request URL
+type mirrorTransport struct {
+	mu        sync.Mutex
+	responses map[string]int    // status code of each host, or 0 for a transport error
+	locations map[string]string // Location header of the redirects of each host
+	requested []string
+	cookies   []string // Cookie header of each request
+	ranges    []string // Range header of each request
+	bodies    []string
+}
+
+func (m *mirrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requested = append(m.requested, req.URL.Host)
+	m.cookies = append(m.cookies, req.Header.Get("Cookie"))
+	m.ranges = append(m.ranges, req.Header.Get("Range"))
+	if req.Body != nil {
+		body, _ := io.ReadAll(req.Body)
+		req.Body.Close()
+		m.bodies = append(m.bodies, string(body))
+	}
+
+	statusCode := m.responses[req.URL.Host]
+	if statusCode == 0 {
+		return nil, errors.New("connection refused")
+	}
+	header := http.Header{}
+	if location := m.locations[req.URL.Host]; location != "" {
+		header.Set("Location", location)
+	}
+	return &http.Response{
+		StatusCode: statusCode,
+		Status:     http.StatusText(statusCode),
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(req.URL.Host)),
+		Request:    req,
+	}, nil
+}
+
+func TestDoRequestFailover(t *testing.T) {
+	hosts := []string{"a.example", "b.example", "c.example"}
+	cookies := []*http.Cookie{
+		{Name: "session", Value: "a", Domain: "a.example"},
+		{Name: "session", Value: "c", Domain: ".c.example"},
+	}
+	wantCookies := map[string]string{"a.example": "session=a", "c.example": "session=c"}
+	tests := []struct {
+		name      string
+		url       string
+		responses map[string]int
+
+		wantRequested []string
+		wantStatus    int
+		wantErr       bool
+		wantDown      []string
+	}{
+		{
+			name:          "first host is up",
+			url:           "https://a.example/api",
+			responses:     map[string]int{"a.example": 200, "b.example": 200, "c.example": 200},
+			wantRequested: []string{"a.example"},
+			wantStatus:    200,
+		},
+		{
+			name:          "request to another host of the group starts at the preferred host",
+			url:           "https://c.example/api",
+			responses:     map[string]int{"a.example": 200, "b.example": 200, "c.example": 200},
+			wantRequested: []string{"a.example"},
+			wantStatus:    200,
+		},
+		{
+			name:          "fails over on server errors",
+			url:           "https://a.example/api",
+			responses:     map[string]int{"a.example": 503, "b.example": 502, "c.example": 200},
+			wantRequested: []string{"a.example", "b.example", "c.example"},
+			wantStatus:    200,
+			wantDown:      []string{"a.example", "b.example"},
+		},
+		{
+			name:          "fails over on transport errors",
+			url:           "https://a.example/api",
+			responses:     map[string]int{"b.example": 200},
+			wantRequested: []string{"a.example", "b.example"},
+			wantStatus:    200,
+			wantDown:      []string{"a.example"},
+		},
+		{
+			name:          "client errors are returned without failing over",
+			url:           "https://a.example/api",
+			responses:     map[string]int{"a.example": 404, "b.example": 200},
+			wantRequested: []string{"a.example"},
+			wantStatus:    404,
+		},
+		{
+			name:          "last response is returned if all hosts are down",
+			url:           "https://a.example/api",
+			responses:     map[string]int{"a.example": 503, "b.example": 503, "c.example": 500},
+			wantRequested: []string{"a.example", "b.example", "c.example"},
+			wantStatus:    500,
+			wantDown:      []string{"a.example", "b.example", "c.example"},
+		},
+		{
+			name:          "last error is returned if all hosts are unreachable",
+			url:           "https://a.example/api",
+			responses:     map[string]int{},
+			wantRequested: []string{"a.example", "b.example", "c.example"},
+			wantErr:       true,
+			wantDown:      []string{"a.example", "b.example", "c.example"},
+		},
+		{
+			name:          "hosts outside of the group are not failed over",
+			url:           "https://other.example/api",
+			responses:     map[string]int{"other.example": 503, "a.example": 200},
+			wantRequested: []string{"other.example"},
+			wantStatus:    503,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			group := newCheckedMirrorGroup(hosts, nil, nil)
+			setMirrorGroups(t, []*mirrorGroup{group})
+
+			transport := &mirrorTransport{responses: test.responses}
+			client := &http.Client{Transport: transport}
+			req, err := http.NewRequest("POST", test.url, strings.NewReader("body"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			AddCookies(test.url, cookies, req)
+
+			res, err := doRequest(client, req, &RequestArgs{Cookies: cookies}, DefaultRetryPolicy())
+			if (err != nil) != test.wantErr {
+				t.Fatalf("doRequest() error = %v, wantErr %t", err, test.wantErr)
+			}
+			if err == nil {
+				res.Body.Close()
+				if res.StatusCode != test.wantStatus {
+					t.Errorf("status code = %d, want %d", res.StatusCode, test.wantStatus)
+				}
+			}
+			if !reflect.DeepEqual(transport.requested, test.wantRequested) {
+				t.Errorf("requested hosts = %v, want %v", transport.requested, test.wantRequested)
+			}
+			for _, body := range transport.bodies {
+				if body != "body" {
+					t.Errorf("request body = %q, want the body to be resent to each host", body)
+				}
+			}
+			for idx, host := range transport.requested {
+				if transport.cookies[idx] != wantCookies[host] {
+					t.Errorf("cookies sent to %s = %q, want %q", host, transport.cookies[idx], wantCookies[host])
+				}
+			}
+
+			var gotDown []string
+			for _, host := range hosts {
+				if group.down[host] {
+					gotDown = append(gotDown, host)
+				}
+			}
+			if !reflect.DeepEqual(gotDown, test.wantDown) {
+				t.Errorf("hosts that are down = %v, want %v", gotDown, test.wantDown)
+			}
+		})
+	}
+}
+
+func TestDoRequestRedirects(t *testing.T) {
+	tests := []struct {
+		name       string
+		dataGroup  []string // mirror group of the data servers, if any
+		responses  map[string]int
+		locations  map[string]string
+		requests   int // number of times the request is sent
+		cookieHost string
+
+		wantRequested []string
+		wantStatus    int
+		wantErr       bool
+		wantMirrorErr bool
+	}{
+		{
+			name:          "redirect to a data server",
+			responses:     map[string]int{"a.example": 302, "n1.data.example": 200},
+			locations:     map[string]string{"a.example": "https://n1.data.example/file"},
+			requests:      1,
+			wantRequested: []string{"a.example", "n1.data.example"},
+			wantStatus:    200,
+		},
+		{
+			name:          "protocol-relative redirect",
+			responses:     map[string]int{"a.example": 302, "n1.data.example": 200},
+			locations:     map[string]string{"a.example": "//n1.data.example/file"},
+			requests:      1,
+			wantRequested: []string{"a.example", "n1.data.example"},
+			wantStatus:    200,
+		},
+		{
+			name:          "redirect to another host of the same group is not changed back",
+			responses:     map[string]int{"a.example": 302, "b.example": 200},
+			locations:     map[string]string{"a.example": "https://b.example/file"},
+			requests:      1,
+			wantRequested: []string{"a.example", "b.example"},
+			wantStatus:    200,
+		},
+		{
+			name:          "data server that is down returns its response the first time",
+			responses:     map[string]int{"a.example": 302, "n1.data.example": 503},
+			locations:     map[string]string{"a.example": "https://n1.data.example/file"},
+			requests:      1,
+			wantRequested: []string{"a.example", "n1.data.example"},
+			wantStatus:    503,
+		},
+		{
+			name:          "data server that is known to be down fails fast",
+			responses:     map[string]int{"a.example": 302, "n1.data.example": 503},
+			locations:     map[string]string{"a.example": "https://n1.data.example/file"},
+			requests:      2,
+			wantRequested: []string{"a.example", "n1.data.example", "a.example", "n1.data.example"},
+			wantErr:       true,
+			wantMirrorErr: true,
+		},
+		{
+			name:          "data servers in a mirror group fail over",
+			dataGroup:     []string{"n1.data.example", "n2.data.example"},
+			responses:     map[string]int{"a.example": 302, "n2.data.example": 200},
+			locations:     map[string]string{"a.example": "https://n1.data.example/file"},
+			requests:      1,
+			wantRequested: []string{"a.example", "n1.data.example", "n2.data.example"},
+			wantStatus:    200,
+		},
+		{
+			name:          "redirect loop",
+			responses:     map[string]int{"a.example": 302},
+			locations:     map[string]string{"a.example": "https://a.example/file"},
+			requests:      1,
+			wantRequested: []string{"a.example", "a.example", "a.example", "a.example", "a.example", "a.example", "a.example", "a.example", "a.example", "a.example", "a.example"},
+			wantErr:       true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			groups := []*mirrorGroup{newCheckedMirrorGroup([]string{"a.example", "b.example"}, nil, nil)}
+			if test.dataGroup != nil {
+				groups = append(groups, newCheckedMirrorGroup(test.dataGroup, nil, nil))
+			}
+			setMirrorGroups(t, groups)
+
+			transport := &mirrorTransport{responses: test.responses, locations: test.locations}
+			client := &http.Client{Transport: transport}
+			cookies := []*http.Cookie{{Name: "session", Value: "a", Domain: "a.example"}}
+
+			var res *http.Response
+			var err error
+			for i := 0; i < test.requests; i++ {
+				req, reqErr := http.NewRequest("GET", "https://a.example/data/file", nil)
+				if reqErr != nil {
+					t.Fatal(reqErr)
+				}
+				req.Header.Set("Range", "bytes=5-")
+				AddCookies(req.URL.String(), cookies, req)
+				if res, err = doRequest(client, req, &RequestArgs{Cookies: cookies}, DefaultRetryPolicy()); err == nil {
+					res.Body.Close()
+				}
+			}
+
+			if (err != nil) != test.wantErr {
+				t.Fatalf("doRequest() error = %v, wantErr %t", err, test.wantErr)
+			}
+			if errors.Is(err, ErrMirrorsDown) != test.wantMirrorErr {
+				t.Errorf("doRequest() error = %v, want ErrMirrorsDown %t", err, test.wantMirrorErr)
+			}
+			if err == nil && res.StatusCode != test.wantStatus {
+				t.Errorf("status code = %d, want %d", res.StatusCode, test.wantStatus)
+			}
+			if !reflect.DeepEqual(transport.requested, test.wantRequested) {
+				t.Errorf("requested hosts = %v, want %v", transport.requested, test.wantRequested)
+			}
+			for idx, host := range transport.requested {
+				wantCookie := ""
+				if host == "a.example" {
+					wantCookie = "session=a"
+				}
+				if transport.cookies[idx] != wantCookie {
+					t.Errorf("cookies sent to %s = %q, want %q", host, transport.cookies[idx], wantCookie)
+				}
+			}
+			if len(transport.ranges) != len(transport.requested) {
+				t.Fatalf("got %d Range headers for %d requests", len(transport.ranges), len(transport.requested))
+			}
+			for idx, rangeHeader := range transport.ranges {
+				if rangeHeader != "bytes=5-" {
+					t.Errorf("Range header sent to %s = %q, want it to be kept on redirects", transport.requested[idx], rangeHeader)
+				}
+			}
+		})
+	}
+}
+
+func TestMarkUpAfterRecovery(t *testing.T) {
+	group := newCheckedMirrorGroup(
+		[]string{"a.example", "b.example"},
+		map[string]time.Duration{"b.example": 100 * time.Millisecond},
+		[]string{"a.example"},
+	)
+	group.markUp("a.example")
+
+	// the recovered host is kept after the current fastest host
+	want := []string{"b.example", "a.example"}
+	if got := group.getCandidates(&RequestArgs{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("getCandidates() = %v, want %v", got, want)
+	}
+}
@@ -1,10 +1,47 @@
 package request
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/history"
+	"github.com/KJHJason/Cultured-Downloader-CLI/manifest"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
 
 type ToDownload struct {
 	Url      string
 	FilePath string
+
+	// Post is the post that the file belongs to.
+	// If not nil, the post will be added to the download history
+	// once all of its files have been downloaded successfully.
+	Post *history.Post
+
+	// PathVars contains the variables of the file for the output path template.
+	// If nil or if there is no path template configured, FilePath will be used as it is.
+	PathVars *utils.PathVars
+
+	// PostFolder is the folder of the post where the manifest of the downloaded files will be saved.
+	// If empty, the manifest will be saved in the same folder as the file.
+	PostFolder string
+
+	// Expected contains the file information provided by the server such as its size or hash
+	// which will be used to verify the file after it has been downloaded.
+	Expected *manifest.Expected
+
+	// Temporary files such as Ugoira ZIP files that are removed after
+	// being processed will not be added to the manifest.
+	Temporary bool
+}
+
+// SetPathVars sets the path template variables of each file of a post
+// where its content kind is based on the file's default path in the post folder.
+func SetPathVars(toDownload []*ToDownload, postVars *utils.PathVars, postFolderPath, rootKind string) {
+	for idx, urlInfo := range toDownload {
+		kind := utils.GetContentKind(postFolderPath, urlInfo.FilePath, rootKind)
+		urlInfo.PathVars = postVars.ForFile(kind, idx+1)
+		urlInfo.PostFolder = postFolderPath
+	}
 }
 
 type DlOptions struct {
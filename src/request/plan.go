@@ -0,0 +1,286 @@
+package request
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Formats of the download plan in the dry-run mode
+const (
+	TABLE_PLAN_FORMAT = "table"
+	JSON_PLAN_FORMAT  = "json"
+	CSV_PLAN_FORMAT   = "csv"
+)
+
+var ACCEPTED_PLAN_FORMATS = []string{TABLE_PLAN_FORMAT, JSON_PLAN_FORMAT, CSV_PLAN_FORMAT}
+
+// Actions of a planned download
+const (
+	DOWNLOAD_ACTION = "download"
+	SKIP_ACTION     = "skip"
+	ERROR_ACTION    = "error"
+)
+
+// PlannedDownload is a file that would be downloaded in the dry-run mode
+type PlannedDownload struct {
+	Url      string `json:"url"`
+	FilePath string `json:"file_path"`
+
+	// Size is the expected file size in bytes or -1 if unknown
+	Size int64 `json:"size"`
+
+	// Action is "download", "skip" if the file already exists, or "error" if the file info could not be retrieved
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+var (
+	planMu sync.Mutex
+	plan   []*PlannedDownload
+)
+
+// AddToPlan adds the planned downloads to be printed when PrintPlan is called.
+func AddToPlan(planned ...*PlannedDownload) {
+	planMu.Lock()
+	defer planMu.Unlock()
+	plan = append(plan, planned...)
+}
+
+// planUrl gets the file info of the URL with a HEAD request without downloading the file
+func planUrl(urlInfo *ToDownload, reqArgs *RequestArgs, config *configs.Config) *PlannedDownload {
+	planned := &PlannedDownload{
+		Url:      urlInfo.Url,
+		FilePath: urlInfo.FilePath,
+		Size:     -1,
+		Action:   DOWNLOAD_ACTION,
+	}
+
+	headRes, err := reqArgs.RequestHandler(
+		&RequestArgs{
+			Url:         reqArgs.Url,
+			Method:      "HEAD",
+			Timeout:     10,
+			Cookies:     reqArgs.Cookies,
+			Headers:     reqArgs.Headers,
+			UserAgent:   reqArgs.UserAgent,
+			CheckStatus: true,
+			Http3:       reqArgs.Http3,
+			Http2:       reqArgs.Http2,
+			Context:     context.Background(),
+		},
+	)
+	if err != nil {
+		planned.Action = ERROR_ACTION
+		planned.Error = err.Error()
+		return planned
+	}
+	headRes.Body.Close()
+
+	filePath, err := getFullFilePath(headRes, urlInfo.FilePath)
+	if err != nil {
+		planned.Action = ERROR_ACTION
+		planned.Error = err.Error()
+		return planned
+	}
+	if config.PathTemplate != nil && urlInfo.PathVars != nil {
+		filePath = config.PathTemplate.Execute(utils.DOWNLOAD_PATH, urlInfo.PathVars, filepath.Base(filePath))
+	}
+
+	planned.FilePath = filePath
+	if headRes.ContentLength >= 0 {
+		planned.Size = headRes.ContentLength
+	}
+	if checkIfCanSkipDl(headRes.ContentLength, filePath, config.OverwriteFiles) {
+		planned.Action = SKIP_ACTION
+	}
+	return planned
+}
+
+// planUrls is the dry-run version of DownloadUrlsWithHandler
+// which adds the files to the download plan instead of downloading them.
+func planUrls(urlInfoSlice []*ToDownload, dlOptions *DlOptions, config *configs.Config, reqHandler RequestHandler) {
+	urlsLen := len(urlInfoSlice)
+	if urlsLen < dlOptions.MaxConcurrency {
+		dlOptions.MaxConcurrency = urlsLen
+	}
+
+	var wg sync.WaitGroup
+	queue := make(chan struct{}, dlOptions.MaxConcurrency)
+	baseMsg := "Getting file information [%d/" + fmt.Sprintf("%d]...", urlsLen)
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		fmt.Sprintf(
+			baseMsg,
+			0,
+		),
+		fmt.Sprintf(
+			"Finished getting the file information of %d files",
+			urlsLen,
+		),
+		"",
+		urlsLen,
+	)
+	progress.Start()
+	for _, urlInfo := range urlInfoSlice {
+		wg.Add(1)
+		queue <- struct{}{}
+		go func(urlInfo *ToDownload) {
+			defer func() {
+				wg.Done()
+				<-queue
+			}()
+			AddToPlan(
+				planUrl(
+					urlInfo,
+					&RequestArgs{
+						Url:            urlInfo.Url,
+						Cookies:        dlOptions.Cookies,
+						Headers:        dlOptions.Headers,
+						Http2:          !dlOptions.UseHttp3,
+						Http3:          dlOptions.UseHttp3,
+						UserAgent:      config.UserAgent,
+						RequestHandler: reqHandler,
+					},
+					config,
+				),
+			)
+			progress.MsgIncrement(baseMsg)
+		}(urlInfo)
+	}
+	wg.Wait()
+	close(queue)
+	progress.Stop(false)
+}
+
+func printPlanTable(output io.Writer, planned []*PlannedDownload) error {
+	writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ACTION\tSIZE\tFILE PATH\tURL")
+	for _, file := range planned {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", file.Action, utils.FormatFileSize(file.Size), file.FilePath, file.Url)
+	}
+	return writer.Flush()
+}
+
+// printPlanSummary prints the number of files that would be downloaded or skipped
+func printPlanSummary(planned []*PlannedDownload) {
+	var toDownload, toSkip, failed int
+	var totalSize int64
+	unknownSize := false
+	for _, file := range planned {
+		switch file.Action {
+		case DOWNLOAD_ACTION:
+			toDownload++
+			if file.Size < 0 {
+				unknownSize = true
+			} else {
+				totalSize += file.Size
+			}
+		case SKIP_ACTION:
+			toSkip++
+		default:
+			failed++
+		}
+	}
+
+	sizeMsg := utils.FormatFileSize(totalSize)
+	if unknownSize {
+		sizeMsg = "at least " + sizeMsg
+	}
+	color.Green(
+		"\nDry run: %d file(s) would be downloaded (%s), %d file(s) would be skipped, and %d file(s) could not be checked.",
+		toDownload,
+		sizeMsg,
+		toSkip,
+		failed,
+	)
+}
+
+func printPlanCsv(output io.Writer, planned []*PlannedDownload) error {
+	writer := csv.NewWriter(output)
+	writer.Write([]string{"action", "size", "file_path", "url", "error"})
+	for _, file := range planned {
+		writer.Write([]string{
+			file.Action,
+			strconv.FormatInt(file.Size, 10),
+			file.FilePath,
+			file.Url,
+			file.Error,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writePlan(output io.Writer, planned []*PlannedDownload, format string) error {
+	switch format {
+	case JSON_PLAN_FORMAT:
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "    ")
+		return encoder.Encode(planned)
+	case CSV_PLAN_FORMAT:
+		return printPlanCsv(output, planned)
+	default:
+		return printPlanTable(output, planned)
+	}
+}
+
+// PrintPlan prints all the planned downloads in the configured format and resets the download plan.
+//
+// The plan will be written to the configured output file if any, otherwise it will be printed to stdout.
+func PrintPlan(config *configs.Config) {
+	planMu.Lock()
+	planned := plan
+	plan = nil
+	planMu.Unlock()
+	if planned == nil {
+		planned = []*PlannedDownload{}
+	}
+	sort.SliceStable(planned, func(i, j int) bool {
+		return planned[i].FilePath < planned[j].FilePath
+	})
+
+	var err error
+	if config.DryRunOutput == "" {
+		fmt.Println()
+		err = writePlan(os.Stdout, planned, config.DryRunFormat)
+	} else {
+		var file *os.File
+		file, err = os.Create(config.DryRunOutput)
+		if err == nil {
+			err = writePlan(file, planned, config.DryRunFormat)
+			file.Close()
+		}
+		if err == nil {
+			color.Green("Saved the download plan to %s", config.DryRunOutput)
+		}
+	}
+	printPlanSummary(planned)
+
+	if err != nil {
+		utils.LogError(
+			fmt.Errorf(
+				"error %d: failed to print the download plan, more info => %v",
+				utils.UNEXPECTED_ERROR,
+				err,
+			),
+			"",
+			false,
+			utils.ERROR,
+		)
+	}
+}
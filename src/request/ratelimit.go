@@ -0,0 +1,225 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// tokenBucket is a thread-safe token bucket where
+// the tokens are refilled at the given rate per second up to the burst size.
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64
+	burst    float64
+	tokens   float64
+	lastTime time.Time
+}
+
+func newTokenBucket(rate, burst float64) *tokenBucket {
+	return &tokenBucket{
+		rate:     rate,
+		burst:    burst,
+		tokens:   burst,
+		lastTime: time.Now(),
+	}
+}
+
+// reserve takes n tokens from the bucket and
+// returns the duration to wait before the tokens can be used.
+func (b *tokenBucket) reserve(n float64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.lastTime).Seconds()*b.rate)
+	b.lastTime = now
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// wait takes n tokens from the bucket and blocks until they
+// can be used or until the context is cancelled.
+func (b *tokenBucket) wait(ctx context.Context, n float64) error {
+	delay := b.reserve(n)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type hostRateLimit struct {
+	host   string
+	bucket *tokenBucket
+}
+
+var (
+	rateLimitMu     sync.RWMutex
+	bandwidthBucket *tokenBucket
+	hostRateLimits  []*hostRateLimit
+)
+
+// SetBandwidthLimit sets the maximum total download speed in bytes per second
+// that is shared across all downloads. A limit of 0 or less removes the limit.
+func SetBandwidthLimit(bytesPerSec int64) {
+	rateLimitMu.Lock()
+	defer rateLimitMu.Unlock()
+	if bytesPerSec <= 0 {
+		bandwidthBucket = nil
+		return
+	}
+	bandwidthBucket = newTokenBucket(float64(bytesPerSec), float64(bytesPerSec))
+}
+
+// SetHostRateLimits sets the maximum number of requests per second for each host.
+//
+// A host also matches its subdomains, e.g. "pximg.net" matches "i.pximg.net".
+func SetHostRateLimits(limits map[string]float64) {
+	rateLimitMu.Lock()
+	defer rateLimitMu.Unlock()
+	hostRateLimits = nil
+	for host, reqPerSec := range limits {
+		if reqPerSec <= 0 {
+			continue
+		}
+		hostRateLimits = append(hostRateLimits, &hostRateLimit{
+			host:   strings.ToLower(host),
+			bucket: newTokenBucket(reqPerSec, 1),
+		})
+	}
+}
+
+func getHostBucket(reqUrl string) *tokenBucket {
+	rateLimitMu.RLock()
+	defer rateLimitMu.RUnlock()
+	if len(hostRateLimits) == 0 {
+		return nil
+	}
+
+	parsedUrl, err := url.Parse(reqUrl)
+	if err != nil {
+		return nil
+	}
+	host := strings.ToLower(parsedUrl.Hostname())
+
+	// use the most specific host if multiple hosts matches
+	var matched *hostRateLimit
+	for _, limit := range hostRateLimits {
+		if host != limit.host && !strings.HasSuffix(host, "."+limit.host) {
+			continue
+		}
+		if matched == nil || len(limit.host) > len(matched.host) {
+			matched = limit
+		}
+	}
+	if matched == nil {
+		return nil
+	}
+	return matched.bucket
+}
+
+// WaitForHost blocks until a request can be sent to the host of the URL
+// based on the configured requests per second for the host.
+func WaitForHost(ctx context.Context, reqUrl string) error {
+	bucket := getHostBucket(reqUrl)
+	if bucket == nil {
+		return nil
+	}
+	return bucket.wait(ctx, 1)
+}
+
+type rateLimitedReader struct {
+	ctx    context.Context
+	reader io.Reader
+	bucket *tokenBucket
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if waitErr := r.bucket.wait(r.ctx, float64(n)); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// limitBandwidth returns a reader that is throttled by the shared bandwidth limit if there is one.
+func limitBandwidth(ctx context.Context, reader io.Reader) io.Reader {
+	rateLimitMu.RLock()
+	bucket := bandwidthBucket
+	rateLimitMu.RUnlock()
+	if bucket == nil {
+		return reader
+	}
+	return &rateLimitedReader{ctx: ctx, reader: reader, bucket: bucket}
+}
+
+// ParseByteRate parses a rate such as "500K", "5M", or "1.5MB" into bytes per second
+// where the units are based on 1024 like curl's --limit-rate option.
+func ParseByteRate(rate string) (int64, error) {
+	rateStr := strings.ToUpper(strings.TrimSpace(rate))
+	rateStr = strings.TrimSuffix(rateStr, "/S")
+	rateStr = strings.TrimSuffix(rateStr, "IB")
+	rateStr = strings.TrimSuffix(rateStr, "B")
+
+	multiplier := 1.0
+	if rateStr != "" {
+		switch rateStr[len(rateStr)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		}
+		if multiplier > 1 {
+			rateStr = rateStr[:len(rateStr)-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(rateStr, 64)
+	if err != nil || value <= 0 || math.IsInf(value, 0) {
+		return 0, fmt.Errorf(
+			"error %d: invalid rate %q, expected a positive number with an optional K, M, or G suffix such as \"5M\"",
+			utils.INPUT_ERROR,
+			rate,
+		)
+	}
+	return int64(value * multiplier), nil
+}
+
+// ParseHostRateLimit parses a per-host rate limit in the format
+// "<host>=<requests per second>" such as "i.pximg.net=2" or "kemono.su=0.5".
+func ParseHostRateLimit(hostRateLimit string) (string, float64, error) {
+	host, reqPerSecStr, found := strings.Cut(strings.TrimSpace(hostRateLimit), "=")
+	host = strings.TrimSpace(host)
+	reqPerSec, err := strconv.ParseFloat(strings.TrimSpace(reqPerSecStr), 64)
+	if !found || host == "" || err != nil || reqPerSec <= 0 || math.IsInf(reqPerSec, 0) {
+		return "", 0, fmt.Errorf(
+			"error %d: invalid host rate limit %q, expected \"<host>=<requests per second>\" such as \"i.pximg.net=2\"",
+			utils.INPUT_ERROR,
+			hostRateLimit,
+		)
+	}
+	return host, reqPerSec, nil
+}
@@ -0,0 +1,177 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseByteRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    string
+		want    int64
+		wantErr bool
+	}{
+		{name: "bytes", rate: "1024", want: 1024},
+		{name: "kilobytes", rate: "500K", want: 500 << 10},
+		{name: "megabytes", rate: "5M", want: 5 << 20},
+		{name: "gigabytes", rate: "1G", want: 1 << 30},
+		{name: "fractional megabytes", rate: "1.5MB", want: 3 << 19},
+		{name: "lowercase with per second suffix", rate: " 2mb/s ", want: 2 << 20},
+		{name: "binary unit suffix", rate: "3MiB", want: 3 << 20},
+		{name: "bytes suffix", rate: "100B", want: 100},
+		{name: "empty", rate: "", wantErr: true},
+		{name: "unit only", rate: "M", wantErr: true},
+		{name: "zero", rate: "0K", wantErr: true},
+		{name: "negative", rate: "-5M", wantErr: true},
+		{name: "unknown unit", rate: "5T", wantErr: true},
+		{name: "not a number", rate: "fast", wantErr: true},
+		{name: "infinite", rate: "Inf", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseByteRate(test.rate)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseByteRate(%q) error = %v, wantErr %t", test.rate, err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("ParseByteRate(%q) = %d, want %d", test.rate, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseHostRateLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		hostRateLimit string
+		wantHost      string
+		wantReqPerSec float64
+		wantErr       bool
+	}{
+		{name: "host and rate", hostRateLimit: "i.pximg.net=2", wantHost: "i.pximg.net", wantReqPerSec: 2},
+		{name: "fractional rate", hostRateLimit: "kemono.su=0.5", wantHost: "kemono.su", wantReqPerSec: 0.5},
+		{name: "spaces around the values", hostRateLimit: " kemono.su = 1 ", wantHost: "kemono.su", wantReqPerSec: 1},
+		{name: "missing separator", hostRateLimit: "kemono.su", wantErr: true},
+		{name: "missing host", hostRateLimit: "=2", wantErr: true},
+		{name: "missing rate", hostRateLimit: "kemono.su=", wantErr: true},
+		{name: "zero rate", hostRateLimit: "kemono.su=0", wantErr: true},
+		{name: "negative rate", hostRateLimit: "kemono.su=-1", wantErr: true},
+		{name: "invalid rate", hostRateLimit: "kemono.su=fast", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			host, reqPerSec, err := ParseHostRateLimit(test.hostRateLimit)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseHostRateLimit(%q) error = %v, wantErr %t", test.hostRateLimit, err, test.wantErr)
+			}
+			if host != test.wantHost || reqPerSec != test.wantReqPerSec {
+				t.Errorf(
+					"ParseHostRateLimit(%q) = %q, %v, want %q, %v",
+					test.hostRateLimit, host, reqPerSec, test.wantHost, test.wantReqPerSec,
+				)
+			}
+		})
+	}
+}
+
+func TestGetHostBucket(t *testing.T) {
+	SetHostRateLimits(map[string]float64{
+		"pximg.net":   1,
+		"i.pximg.net": 2,
+		"Kemono.su":   3,
+		"disabled.io": 0,
+	})
+	t.Cleanup(func() { SetHostRateLimits(nil) })
+
+	tests := []struct {
+		name     string
+		url      string
+		wantRate float64 // 0 for no rate limit
+	}{
+		{name: "exact host", url: "https://pximg.net/img.jpg", wantRate: 1},
+		{name: "subdomain", url: "https://s.pximg.net/img.jpg", wantRate: 1},
+		{name: "most specific host", url: "https://i.pximg.net/img.jpg", wantRate: 2},
+		{name: "subdomain of the most specific host", url: "https://a.i.pximg.net/img.jpg", wantRate: 2},
+		{name: "case insensitive host with port", url: "https://KEMONO.SU:443/api", wantRate: 3},
+		{name: "host only sharing the suffix", url: "https://notpximg.net/img.jpg"},
+		{name: "unrelated host", url: "https://example.com/"},
+		{name: "disabled limit", url: "https://disabled.io/"},
+		{name: "invalid url", url: "://pximg.net"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bucket := getHostBucket(test.url)
+			if test.wantRate == 0 {
+				if bucket != nil {
+					t.Errorf("getHostBucket(%q) = bucket with rate %v, want no rate limit", test.url, bucket.rate)
+				}
+				return
+			}
+			if bucket == nil || bucket.rate != test.wantRate {
+				t.Errorf("getHostBucket(%q) = %+v, want a bucket with rate %v", test.url, bucket, test.wantRate)
+			}
+		})
+	}
+}
+
+func TestTokenBucketReserve(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     float64
+		burst    float64
+		reserves []float64
+		want     time.Duration // the delay of the last reservation
+	}{
+		{name: "within the burst", rate: 10, burst: 5, reserves: []float64{5}, want: 0},
+		{name: "over the burst", rate: 10, burst: 5, reserves: []float64{5, 1}, want: 100 * time.Millisecond},
+		{name: "waits accumulate", rate: 10, burst: 1, reserves: []float64{1, 1, 1}, want: 200 * time.Millisecond},
+		{name: "larger than the burst", rate: 100, burst: 10, reserves: []float64{60}, want: 500 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bucket := newTokenBucket(test.rate, test.burst)
+			var got time.Duration
+			for _, n := range test.reserves {
+				got = bucket.reserve(n)
+			}
+			// allow for the tokens refilled while the test was running
+			if got > test.want || got < test.want-10*time.Millisecond {
+				t.Errorf("reserve() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	bucket := newTokenBucket(10, 2)
+	bucket.reserve(2)
+
+	// the tokens refilled while idle are capped at the burst size
+	bucket.lastTime = bucket.lastTime.Add(-time.Hour)
+	if delay := bucket.reserve(2); delay != 0 {
+		t.Errorf("reserve() after refilling = %v, want 0", delay)
+	}
+	if delay := bucket.reserve(1); delay <= 0 {
+		t.Errorf("reserve() over the burst after refilling = %v, want a delay", delay)
+	}
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	bucket := newTokenBucket(1, 1)
+	if err := bucket.wait(context.Background(), 1); err != nil {
+		t.Fatalf("wait() within the burst error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := bucket.wait(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("wait() with a cancelled context error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -19,17 +19,26 @@ import (
 
 // Get a new HTTP/2 or HTTP/3 client based on the request arguments
 func GetHttpClient(reqArgs *RequestArgs) *http.Client {
+	var transport http.RoundTripper
 	if reqArgs.Http2 {
-		return &http.Client{
-			Transport: &http.Transport{
-				DisableCompression: reqArgs.DisableCompression,
-			},
+		transport = &http.Transport{
+			DisableCompression: reqArgs.DisableCompression,
+		}
+	} else {
+		transport = &http3.RoundTripper{
+			DisableCompression: reqArgs.DisableCompression,
+		}
+	}
+
+	if reqArgs.IdleTimeout > 0 {
+		transport = &idleTimeoutTransport{
+			base:          transport,
+			headerTimeout: time.Duration(reqArgs.Timeout) * time.Second,
+			idleTimeout:   time.Duration(reqArgs.IdleTimeout) * time.Second,
 		}
 	}
 	return &http.Client{
-		Transport: &http3.RoundTripper{
-			DisableCompression: reqArgs.DisableCompression,
-		},
+		Transport: transport,
 	}
 }
 
@@ -75,6 +84,7 @@ func AddParams(params map[string]string, req *http.Request) {
 }
 
 // send the request to the target URL and retries if the request was not successful
+// based on the retry policy of the request arguments.
 func sendRequest(req *http.Request, reqArgs *RequestArgs) (*http.Response, error) {
 	AddCookies(reqArgs.Url, reqArgs.Cookies, req)
 	AddHeaders(reqArgs.Headers, reqArgs.UserAgent, req)
@@ -83,32 +93,55 @@ func sendRequest(req *http.Request, reqArgs *RequestArgs) (*http.Response, error
 	var err error
 	var res *http.Response
 
+	policy := reqArgs.RetryPolicy
+	if policy == nil {
+		policy = DefaultRetryPolicy()
+	}
 	client := GetHttpClient(reqArgs)
-	client.Timeout = time.Duration(reqArgs.Timeout) * time.Second
-	for i := 1; i <= utils.RETRY_COUNTER; i++ {
-		res, err = client.Do(req)
+	if reqArgs.IdleTimeout == 0 {
+		client.Timeout = time.Duration(reqArgs.Timeout) * time.Second
+	}
+
+	attempts := 0
+	for attempts < policy.MaxAttempts {
+		attempts++
+		if attempts > 1 && req.GetBody != nil {
+			// rewind the request body as it was consumed by the previous attempt
+			if req.Body, err = req.GetBody(); err != nil {
+				break
+			}
+		}
+		res, err = doRequest(client, req, reqArgs, policy)
 		if err == nil {
-			if !reqArgs.CheckStatus {
-				return res, nil
-			} else if res.StatusCode == 200 {
+			if !reqArgs.CheckStatus || IsSuccessStatus(res.StatusCode) {
 				return res, nil
 			}
 			res.Body.Close()
+			if !policy.IsRetryableStatus(res.StatusCode) {
+				break
+			}
 		} else if errors.Is(err, context.Canceled) {
 			return nil, context.Canceled
-		} else {
+		} else if errors.Is(err, ErrMirrorsDown) || !policy.RetryTransportErrors {
 			break
 		}
 
-		if i < utils.RETRY_COUNTER {
-			time.Sleep(utils.GetRandomDelay())
+		if attempts >= policy.MaxAttempts {
+			break
+		}
+		delay, canRetry := policy.GetDelay(attempts, res)
+		if !canRetry {
+			break
+		}
+		if sleepErr := SleepWithContext(req.Context(), delay); sleepErr != nil {
+			return nil, sleepErr
 		}
 	}
 
 	errMsg := fmt.Sprintf(
-		"the request to %s failed after %d retries",
+		"the request to %s failed after %d attempt(s)",
 		reqArgs.Url,
-		utils.RETRY_COUNTER,
+		attempts,
 	)
 	if err != nil {
 		err = fmt.Errorf("%s, more info => %v",
@@ -116,10 +149,12 @@ func sendRequest(req *http.Request, reqArgs *RequestArgs) (*http.Response, error
 			err,
 		)
 	} else if res != nil {
-		err = fmt.Errorf("%s, status code => %s",
-			errMsg,
-			res.Status,
-		)
+		err = &StatusError{
+			Url:        reqArgs.Url,
+			StatusCode: res.StatusCode,
+			Status:     res.Status,
+			Attempts:   attempts,
+		}
 	} else {
 		err = errors.New(errMsg)
 	}
@@ -128,8 +163,8 @@ func sendRequest(req *http.Request, reqArgs *RequestArgs) (*http.Response, error
 
 // CallRequest is used to make a request to a URL and return the response
 //
-// If the request fails, it will retry the request again up
-// to the defined max retries in the constants.go in utils package
+// If the request fails, it will retry the request again
+// based on the retry policy of the request arguments
 func CallRequest(reqArgs *RequestArgs) (*http.Response, error) {
 	reqArgs.ValidateArgs()
 	req, err := http.NewRequestWithContext(
@@ -0,0 +1,173 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const (
+	PART_FILE_EXT      = ".part"
+	PART_FILE_INFO_EXT = ".part.json"
+)
+
+// PartialDl contains the validators of a file that is being downloaded.
+//
+// The validators are saved beside the ".part" file so that
+// the download can be resumed on the next run if the file on the server has not changed.
+type PartialDl struct {
+	ETag          string `json:"etag"`
+	LastModified  string `json:"last_modified"`
+	ContentLength int64  `json:"content_length"`
+
+	// Checksum is used for validators that are not from the HTTP headers like GDrive's md5Checksum
+	Checksum string `json:"checksum"`
+}
+
+// GetPartialDlFromRes returns the validators of the file from the response headers.
+func GetPartialDlFromRes(res *http.Response) *PartialDl {
+	return &PartialDl{
+		ETag:          res.Header.Get("ETag"),
+		LastModified:  res.Header.Get("Last-Modified"),
+		ContentLength: res.ContentLength,
+	}
+}
+
+func (p *PartialDl) canResume() bool {
+	return p.ContentLength > 0 && (p.ETag != "" || p.LastModified != "" || p.Checksum != "")
+}
+
+func (p *PartialDl) matches(other *PartialDl) bool {
+	return p.ETag == other.ETag &&
+		p.LastModified == other.LastModified &&
+		p.ContentLength == other.ContentLength &&
+		p.Checksum == other.Checksum
+}
+
+// GetRangeHeaders returns the headers needed to resume the download from the given offset.
+func (p *PartialDl) GetRangeHeaders(offset int64) map[string]string {
+	headers := map[string]string{
+		"Range": fmt.Sprintf("bytes=%d-", offset),
+	}
+
+	// If-Range makes the server send the whole file
+	// instead of the requested range if the file has changed.
+	// Note: weak ETags are not allowed in the If-Range header.
+	if p.ETag != "" && !strings.HasPrefix(p.ETag, "W/") {
+		headers["If-Range"] = p.ETag
+	} else if p.LastModified != "" {
+		headers["If-Range"] = p.LastModified
+	}
+	return headers
+}
+
+// AddRangeHeaders returns a copy of the given headers with the Range headers added.
+func (p *PartialDl) AddRangeHeaders(headers map[string]string, offset int64) map[string]string {
+	newHeaders := make(map[string]string, len(headers)+2)
+	for k, v := range headers {
+		newHeaders[k] = v
+	}
+	for k, v := range p.GetRangeHeaders(offset) {
+		newHeaders[k] = v
+	}
+	return newHeaders
+}
+
+func removePartialDl(filePath string) {
+	for _, path := range []string{filePath + PART_FILE_EXT, filePath + PART_FILE_INFO_EXT} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			utils.LogError(
+				fmt.Errorf(
+					"download error %d: failed to remove file at %s, more info => %v",
+					utils.OS_ERROR,
+					path,
+					err,
+				),
+				"",
+				false,
+				utils.ERROR,
+			)
+		}
+	}
+}
+
+func readPartialDl(filePath string) (*PartialDl, error) {
+	data, err := os.ReadFile(filePath + PART_FILE_INFO_EXT)
+	if err != nil {
+		return nil, err
+	}
+
+	var partialDl PartialDl
+	if err := json.Unmarshal(data, &partialDl); err != nil {
+		return nil, err
+	}
+	return &partialDl, nil
+}
+
+func savePartialDl(filePath string, partialDl *PartialDl) error {
+	data, err := json.Marshal(partialDl)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath+PART_FILE_INFO_EXT, data, 0666)
+}
+
+// PrepareResume checks if there is a ".part" file of the given file path
+// that was downloaded from the same file on the server and returns its size.
+//
+// If the download cannot be resumed, the old ".part" file will be removed
+// and 0 will be returned so that the file will be downloaded from the start.
+// Either way, the validators will be saved for the next run.
+func PrepareResume(filePath string, partialDl *PartialDl) int64 {
+	if !partialDl.canResume() {
+		removePartialDl(filePath)
+		return 0
+	}
+
+	var offset int64
+	oldPartialDl, err := readPartialDl(filePath)
+	if err == nil && oldPartialDl.matches(partialDl) {
+		if partFileInfo, err := os.Stat(filePath + PART_FILE_EXT); err == nil {
+			offset = partFileInfo.Size()
+		}
+	}
+
+	// if the ".part" file is complete or larger than expected,
+	// something went wrong previously so download the file again.
+	if offset <= 0 || offset >= partialDl.ContentLength {
+		removePartialDl(filePath)
+		offset = 0
+	}
+
+	if err := savePartialDl(filePath, partialDl); err != nil {
+		utils.LogError(
+			fmt.Errorf(
+				"download error %d: failed to save download info for %s, more info => %v",
+				utils.OS_ERROR,
+				filePath,
+				err,
+			),
+			"",
+			false,
+			utils.ERROR,
+		)
+	}
+	return offset
+}
+
+// getContentRangeStart returns the starting byte of the Content-Range header.
+// E.g. "bytes 100-199/200" will return 100.
+func getContentRangeStart(res *http.Response) (int64, error) {
+	contentRange := res.Header.Get("Content-Range")
+	contentRange = strings.TrimPrefix(contentRange, "bytes ")
+	start, _, found := strings.Cut(contentRange, "-")
+	if !found {
+		return -1, fmt.Errorf("invalid Content-Range header, %q", res.Header.Get("Content-Range"))
+	}
+	return strconv.ParseInt(start, 10, 64)
+}
@@ -0,0 +1,332 @@
+package request
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetRangeHeaders(t *testing.T) {
+	tests := []struct {
+		name      string
+		partialDl *PartialDl
+		offset    int64
+		want      map[string]string
+	}{
+		{
+			name:      "strong ETag",
+			partialDl: &PartialDl{ETag: `"abc"`, LastModified: "Mon, 01 Jan 2024 00:00:00 GMT"},
+			offset:    100,
+			want:      map[string]string{"Range": "bytes=100-", "If-Range": `"abc"`},
+		},
+		{
+			name:      "weak ETag falls back to Last-Modified",
+			partialDl: &PartialDl{ETag: `W/"abc"`, LastModified: "Mon, 01 Jan 2024 00:00:00 GMT"},
+			offset:    5,
+			want:      map[string]string{"Range": "bytes=5-", "If-Range": "Mon, 01 Jan 2024 00:00:00 GMT"},
+		},
+		{
+			name:      "weak ETag only",
+			partialDl: &PartialDl{ETag: `W/"abc"`},
+			offset:    5,
+			want:      map[string]string{"Range": "bytes=5-"},
+		},
+		{
+			name:      "checksum only",
+			partialDl: &PartialDl{Checksum: "d41d8cd98f00b204e9800998ecf8427e"},
+			offset:    1,
+			want:      map[string]string{"Range": "bytes=1-"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.partialDl.GetRangeHeaders(test.offset); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("GetRangeHeaders(%d) = %v, want %v", test.offset, got, test.want)
+			}
+		})
+	}
+}
+
+func TestAddRangeHeadersCopiesHeaders(t *testing.T) {
+	headers := map[string]string{"Referer": "https://example.com"}
+	got := (&PartialDl{ETag: `"abc"`}).AddRangeHeaders(headers, 10)
+	want := map[string]string{"Referer": "https://example.com", "Range": "bytes=10-", "If-Range": `"abc"`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AddRangeHeaders() = %v, want %v", got, want)
+	}
+	if len(headers) != 1 {
+		t.Errorf("AddRangeHeaders() modified the given headers, got %v", headers)
+	}
+}
+
+func TestPrepareResume(t *testing.T) {
+	validators := &PartialDl{ETag: `"abc"`, ContentLength: 10}
+	tests := []struct {
+		name      string
+		oldInfo   *PartialDl
+		partData  string // contents of the existing ".part" file, if any
+		partialDl *PartialDl
+
+		wantOffset   int64
+		wantPartFile bool
+		wantInfoFile bool
+	}{
+		{
+			name:         "no partial download",
+			partialDl:    validators,
+			wantOffset:   0,
+			wantInfoFile: true,
+		},
+		{
+			name:         "same file on the server",
+			oldInfo:      validators,
+			partData:     "hello",
+			partialDl:    validators,
+			wantOffset:   5,
+			wantPartFile: true,
+			wantInfoFile: true,
+		},
+		{
+			name:         "file changed on the server",
+			oldInfo:      &PartialDl{ETag: `"old"`, ContentLength: 10},
+			partData:     "hello",
+			partialDl:    validators,
+			wantOffset:   0,
+			wantInfoFile: true,
+		},
+		{
+			name:         "part file without the download info",
+			partData:     "hello",
+			partialDl:    validators,
+			wantOffset:   0,
+			wantInfoFile: true,
+		},
+		{
+			name:         "part file is already complete",
+			oldInfo:      validators,
+			partData:     "helloworld",
+			partialDl:    validators,
+			wantOffset:   0,
+			wantInfoFile: true,
+		},
+		{
+			name:       "no validators",
+			oldInfo:    &PartialDl{ContentLength: 10},
+			partData:   "hello",
+			partialDl:  &PartialDl{ContentLength: 10},
+			wantOffset: 0,
+		},
+		{
+			name:       "unknown content length",
+			oldInfo:    &PartialDl{ETag: `"abc"`, ContentLength: -1},
+			partData:   "hello",
+			partialDl:  &PartialDl{ETag: `"abc"`, ContentLength: -1},
+			wantOffset: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "file.bin")
+			if test.oldInfo != nil {
+				if err := savePartialDl(filePath, test.oldInfo); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if test.partData != "" {
+				if err := os.WriteFile(filePath+PART_FILE_EXT, []byte(test.partData), 0666); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := PrepareResume(filePath, test.partialDl); got != test.wantOffset {
+				t.Errorf("PrepareResume() = %d, want %d", got, test.wantOffset)
+			}
+
+			_, err := os.Stat(filePath + PART_FILE_EXT)
+			if hasPartFile := err == nil; hasPartFile != test.wantPartFile {
+				t.Errorf("part file exists = %t, want %t", hasPartFile, test.wantPartFile)
+			}
+			savedInfo, err := readPartialDl(filePath)
+			if hasInfoFile := err == nil; hasInfoFile != test.wantInfoFile {
+				t.Fatalf("download info file exists = %t, want %t", hasInfoFile, test.wantInfoFile)
+			}
+			if test.wantInfoFile && !savedInfo.matches(test.partialDl) {
+				t.Errorf("saved download info = %+v, want %+v", savedInfo, test.partialDl)
+			}
+		})
+	}
+}
+
+func TestDlToFile(t *testing.T) {
+	tests := []struct {
+		name         string
+		partData     string // contents of the existing ".part" file, if any
+		statusCode   int
+		contentRange string
+		body         string
+		bodyErr      error // error returned by the body after the body was read, if any
+
+		wantErr      bool
+		wantContent  string
+		wantPartFile bool
+	}{
+		{
+			name:        "full download",
+			statusCode:  http.StatusOK,
+			body:        "hello world",
+			wantContent: "hello world",
+		},
+		{
+			name:        "full download overwrites the part file",
+			partData:    "stale",
+			statusCode:  http.StatusOK,
+			body:        "hello world",
+			wantContent: "hello world",
+		},
+		{
+			name:         "resumed download is appended",
+			partData:     "hello",
+			statusCode:   http.StatusPartialContent,
+			contentRange: "bytes 5-10/11",
+			body:         " world",
+			wantContent:  "hello world",
+		},
+		{
+			name:         "mismatched range",
+			partData:     "hello",
+			statusCode:   http.StatusPartialContent,
+			contentRange: "bytes 3-10/11",
+			body:         "lo world",
+			wantErr:      true,
+		},
+		{
+			name:         "missing part file",
+			statusCode:   http.StatusPartialContent,
+			contentRange: "bytes 5-10/11",
+			body:         " world",
+			wantErr:      true,
+		},
+		{
+			name:         "invalid Content-Range header",
+			partData:     "hello",
+			statusCode:   http.StatusPartialContent,
+			contentRange: "bytes */11",
+			body:         " world",
+			wantErr:      true,
+		},
+		{
+			name:         "interrupted download keeps the part file",
+			statusCode:   http.StatusOK,
+			body:         strings.Repeat("hello", 200), // longer than the bytes read to validate the content
+			bodyErr:      errors.New("connection reset by peer"),
+			wantErr:      true,
+			wantPartFile: true,
+		},
+		{
+			name:         "interrupted resumed download keeps the part file",
+			partData:     "hello",
+			statusCode:   http.StatusPartialContent,
+			contentRange: "bytes 5-10/11",
+			body:         " wo",
+			bodyErr:      errors.New("unexpected EOF"),
+			wantErr:      true,
+			wantPartFile: true,
+		},
+		{
+			name:       "unsuccessful status",
+			statusCode: http.StatusNotFound,
+			body:       "not found",
+			wantErr:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "file.bin")
+			if test.partData != "" {
+				if err := os.WriteFile(filePath+PART_FILE_EXT, []byte(test.partData), 0666); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var body io.Reader = strings.NewReader(test.body)
+			if test.bodyErr != nil {
+				body = io.MultiReader(body, &errReader{err: test.bodyErr})
+			}
+			res := &http.Response{
+				StatusCode: test.statusCode,
+				Status:     http.StatusText(test.statusCode),
+				Header:     http.Header{},
+				Body:       io.NopCloser(body),
+			}
+			if test.contentRange != "" {
+				res.Header.Set("Content-Range", test.contentRange)
+			}
+
+			err := DlToFile(res, "https://example.com/file.bin", filePath)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("DlToFile() error = %v, wantErr %t", err, test.wantErr)
+			}
+			if test.bodyErr != nil && !errors.Is(err, test.bodyErr) {
+				t.Errorf("DlToFile() error = %v, want it to wrap %v", err, test.bodyErr)
+			}
+			if _, statErr := os.Stat(filePath + PART_FILE_EXT); (statErr == nil) != test.wantPartFile {
+				t.Errorf("part file exists = %t, want %t", statErr == nil, test.wantPartFile)
+			}
+			if test.wantErr {
+				return
+			}
+
+			content, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatalf("failed to read the downloaded file: %v", err)
+			}
+			if string(content) != test.wantContent {
+				t.Errorf("downloaded file = %q, want %q", content, test.wantContent)
+			}
+		})
+	}
+}
+
+// errReader returns the error on every read
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+func TestGetContentRangeStart(t *testing.T) {
+	tests := []struct {
+		contentRange string
+		want         int64
+		wantErr      bool
+	}{
+		{contentRange: "bytes 100-199/200", want: 100},
+		{contentRange: "bytes 0-0/*", want: 0},
+		{contentRange: "bytes */200", wantErr: true},
+		{contentRange: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.contentRange, func(t *testing.T) {
+			res := &http.Response{Header: http.Header{}}
+			res.Header.Set("Content-Range", test.contentRange)
+			got, err := getContentRangeStart(res)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("getContentRangeStart() error = %v, wantErr %t", err, test.wantErr)
+			}
+			if !test.wantErr && got != test.want {
+				t.Errorf("getContentRangeStart() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
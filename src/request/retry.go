@@ -0,0 +1,129 @@
+package request
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// RetryPolicy determines when and how long to wait before a failed request is retried
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first request
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry which is doubled after every attempt up to MaxDelay.
+	// The actual delay is randomised between half and the full delay to avoid retrying at the same time.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// RetryableStatuses are the status codes of the responses that will be retried
+	// when the status code is checked. Other status codes will fail immediately.
+	RetryableStatuses []int
+
+	// RetryTransportErrors is a flag to retry the request on transport errors
+	// such as DNS lookup failures, TLS handshake timeouts, connection resets, etc.
+	RetryTransportErrors bool
+
+	// MaxRetryAfter is the maximum delay from the Retry-After header that will be waited for.
+	// If the server asks to wait for longer than this, the request will not be retried.
+	MaxRetryAfter time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used when the request arguments do not have one.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: utils.RETRY_COUNTER,
+		BaseDelay:   utils.MIN_RETRY_DELAY * time.Second,
+		MaxDelay:    30 * time.Second,
+		RetryableStatuses: []int{
+			http.StatusRequestTimeout,
+			http.StatusTooEarly,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RetryTransportErrors: true,
+		MaxRetryAfter:        5 * time.Minute,
+	}
+}
+
+// IsRetryableStatus returns true if the response with the status code should be retried
+func (p *RetryPolicy) IsRetryableStatus(statusCode int) bool {
+	for _, retryableStatus := range p.RetryableStatuses {
+		if statusCode == retryableStatus {
+			return true
+		}
+	}
+	return false
+}
+
+// getBackoffDelay returns the exponential backoff delay with jitter after the given attempt (1-based)
+func (p *RetryPolicy) getBackoffDelay(attempt int) time.Duration {
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	return time.Duration(delay/2 + rand.Float64()*delay/2)
+}
+
+// GetDelay returns the delay before the next attempt after the given attempt (1-based) has failed.
+//
+// If the response has a valid Retry-After header, it will be used instead of the backoff delay.
+// Returns false if the request should not be retried as the server asked to wait for longer than MaxRetryAfter.
+func (p *RetryPolicy) GetDelay(attempt int, res *http.Response) (time.Duration, bool) {
+	if res != nil {
+		if retryAfter, ok := ParseRetryAfter(res.Header.Get("Retry-After"), time.Now()); ok {
+			if p.MaxRetryAfter > 0 && retryAfter > p.MaxRetryAfter {
+				return 0, false
+			}
+			return retryAfter, true
+		}
+	}
+	return p.getBackoffDelay(attempt), true
+}
+
+// ParseRetryAfter parses the value of the Retry-After header which
+// can either be the number of seconds to wait or a HTTP date.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	retryTime, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	delay := retryTime.Sub(now)
+	if delay < 0 {
+		delay = 0
+	}
+	return delay, true
+}
+
+// SleepWithContext sleeps for the given duration or until the context is cancelled
+func SleepWithContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
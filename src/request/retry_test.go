@@ -0,0 +1,112 @@
+package request
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOk bool
+	}{
+		{name: "delta seconds", value: "120", want: 2 * time.Minute, wantOk: true},
+		{name: "zero delta seconds", value: "0", want: 0, wantOk: true},
+		{name: "delta seconds with spaces", value: " 5 ", want: 5 * time.Second, wantOk: true},
+		{name: "negative delta seconds", value: "-1"},
+		{name: "HTTP date", value: "Mon, 01 Jan 2024 00:00:30 GMT", want: 30 * time.Second, wantOk: true},
+		{name: "RFC 850 date", value: "Monday, 01-Jan-24 00:01:00 GMT", want: time.Minute, wantOk: true},
+		{name: "HTTP date in the past", value: "Sun, 31 Dec 2023 23:59:00 GMT", want: 0, wantOk: true},
+		{name: "empty", value: ""},
+		{name: "invalid", value: "soon"},
+		{name: "fractional seconds", value: "1.5"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := ParseRetryAfter(test.value, now)
+			if ok != test.wantOk {
+				t.Fatalf("ParseRetryAfter(%q) ok = %t, want %t", test.value, ok, test.wantOk)
+			}
+			if got != test.want {
+				t.Errorf("ParseRetryAfter(%q) = %v, want %v", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestGetBackoffDelay(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	tests := []struct {
+		name    string
+		attempt int
+		want    time.Duration // the delay before the jitter is applied
+	}{
+		{name: "first attempt", attempt: 1, want: policy.BaseDelay},
+		{name: "second attempt", attempt: 2, want: 2 * policy.BaseDelay},
+		{name: "third attempt", attempt: 3, want: 4 * policy.BaseDelay},
+		{name: "clamped to the max delay", attempt: 10, want: policy.MaxDelay},
+		{name: "clamped on a large attempt", attempt: 100, want: policy.MaxDelay},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				got := policy.getBackoffDelay(test.attempt)
+				if got < test.want/2 || got > test.want {
+					t.Fatalf("getBackoffDelay(%d) = %v, want between %v and %v", test.attempt, got, test.want/2, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetDelay(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	tests := []struct {
+		name       string
+		retryAfter string // empty for no Retry-After header
+		noResponse bool
+
+		wantDelay time.Duration // 0 to expect the backoff delay
+		wantRetry bool
+	}{
+		{name: "no response", noResponse: true, wantRetry: true},
+		{name: "no Retry-After header", wantRetry: true},
+		{name: "Retry-After header", retryAfter: "10", wantDelay: 10 * time.Second, wantRetry: true},
+		{name: "Retry-After at the max", retryAfter: "300", wantDelay: policy.MaxRetryAfter, wantRetry: true},
+		{name: "Retry-After over the max", retryAfter: "301"},
+		{name: "invalid Retry-After header", retryAfter: "later", wantRetry: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var res *http.Response
+			if !test.noResponse {
+				res = &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+				if test.retryAfter != "" {
+					res.Header.Set("Retry-After", test.retryAfter)
+				}
+			}
+
+			got, retry := policy.GetDelay(1, res)
+			if retry != test.wantRetry {
+				t.Fatalf("GetDelay() retry = %t, want %t", retry, test.wantRetry)
+			}
+			if !retry {
+				return
+			}
+			if test.wantDelay != 0 {
+				if got != test.wantDelay {
+					t.Errorf("GetDelay() = %v, want %v", got, test.wantDelay)
+				}
+			} else if got < policy.BaseDelay/2 || got > policy.BaseDelay {
+				t.Errorf("GetDelay() = %v, want the backoff delay between %v and %v", got, policy.BaseDelay/2, policy.BaseDelay)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// idleTimeoutTransport aborts the request if the response headers are not received within the header timeout
+// or if no data of the response body is received within the idle timeout.
+//
+// Unlike http.Client.Timeout, the time spent waiting outside of the reads of the response body,
+// e.g. due to the bandwidth limit, does not count towards the timeout.
+type idleTimeoutTransport struct {
+	base          http.RoundTripper
+	headerTimeout time.Duration
+	idleTimeout   time.Duration
+}
+
+func (t *idleTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	timedOut := &atomic.Bool{}
+	timer := time.AfterFunc(t.headerTimeout, func() {
+		timedOut.Store(true)
+		cancel()
+	})
+
+	res, err := t.base.RoundTrip(req.WithContext(ctx))
+	timer.Stop()
+	if err != nil {
+		cancel()
+		if timedOut.Load() {
+			err = fmt.Errorf(
+				"error %d: no response was received within %v",
+				utils.CONNECTION_ERROR,
+				t.headerTimeout,
+			)
+		}
+		return nil, err
+	}
+
+	res.Body = &idleTimeoutBody{
+		ReadCloser: res.Body,
+		timer:      timer,
+		timeout:    t.idleTimeout,
+		timedOut:   timedOut,
+		cancel:     cancel,
+	}
+	return res, nil
+}
+
+// idleTimeoutBody cancels the request if a read of the response body does not return within the timeout.
+type idleTimeoutBody struct {
+	io.ReadCloser
+	timer    *time.Timer
+	timeout  time.Duration
+	timedOut *atomic.Bool
+	cancel   context.CancelFunc
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	b.timer.Reset(b.timeout)
+	n, err := b.ReadCloser.Read(p)
+	b.timer.Stop()
+	if err != nil && err != io.EOF && b.timedOut.Load() {
+		err = fmt.Errorf(
+			"error %d: no data was received within %v",
+			utils.CONNECTION_ERROR,
+			b.timeout,
+		)
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,225 @@
+package request
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// StatusError is returned when the response still has an unsuccessful status code after all the retries.
+type StatusError struct {
+	Url        string
+	StatusCode int
+	Status     string
+	Attempts   int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf(
+		"the request to %s failed after %d attempt(s), status code => %s",
+		e.Url,
+		e.Attempts,
+		e.Status,
+	)
+}
+
+// ContentMismatchError is returned when the downloaded content does not match the file extension,
+// e.g. when a ".jpg" file is actually a HTML or XML error page from the server.
+type ContentMismatchError struct {
+	Url         string
+	FilePath    string
+	ContentType string // Content-Type header of the response
+	Detected    string // content type detected from the first few bytes of the response body
+}
+
+func (e *ContentMismatchError) Error() string {
+	detected := ""
+	if e.Detected != "" {
+		detected = fmt.Sprintf(", detected: %q", e.Detected)
+	}
+	return fmt.Sprintf(
+		"download error %d: the response is not a valid %q file (Content-Type: %q%s)\nurl: %s\nfile path: %s",
+		utils.DOWNLOAD_ERROR,
+		getFileExt(e.FilePath),
+		e.ContentType,
+		detected,
+		e.Url,
+		e.FilePath,
+	)
+}
+
+// IsSuccessStatus returns true if the status code is a 2xx status code
+func IsSuccessStatus(statusCode int) bool {
+	return statusCode >= 200 && statusCode < 300
+}
+
+// sniffLen is the number of bytes used by http.DetectContentType
+const sniffLen = 512
+
+type magicSignature struct {
+	offset int
+	magic  []byte
+}
+
+// maskedSignature is compared to the content after applying the mask
+// for the file types where only some bits of the first few bytes are fixed.
+type maskedSignature struct {
+	magicSignature
+	mask []byte
+}
+
+// quickTimeSignatures are the atoms that a QuickTime file can start with
+var quickTimeSignatures = []magicSignature{
+	{4, []byte("ftyp")},
+	{4, []byte("moov")},
+	{4, []byte("mdat")},
+	{4, []byte("wide")},
+	{4, []byte("free")},
+	{4, []byte("skip")},
+	{4, []byte("pnot")},
+}
+
+// fileSignatures contains the magic bytes of the common file extensions.
+// The file extensions that are not in this map are only checked for error pages.
+var fileSignatures = map[string][]magicSignature{
+	"jpg":  {{0, []byte{0xFF, 0xD8, 0xFF}}},
+	"jpeg": {{0, []byte{0xFF, 0xD8, 0xFF}}},
+	"jfif": {{0, []byte{0xFF, 0xD8, 0xFF}}},
+	"png":  {{0, []byte("\x89PNG\r\n\x1a\n")}},
+	"gif":  {{0, []byte("GIF87a")}, {0, []byte("GIF89a")}},
+	"webp": {{8, []byte("WEBP")}},
+	"bmp":  {{0, []byte("BM")}},
+	"tif":  {{0, []byte("II*\x00")}, {0, []byte("MM\x00*")}},
+	"tiff": {{0, []byte("II*\x00")}, {0, []byte("MM\x00*")}},
+	"psd":  {{0, []byte("8BPS")}},
+	"pdf":  {{0, []byte("%PDF-")}},
+	"zip":  {{0, []byte("PK\x03\x04")}, {0, []byte("PK\x05\x06")}, {0, []byte("PK\x07\x08")}},
+	"cbz":  {{0, []byte("PK\x03\x04")}, {0, []byte("PK\x05\x06")}},
+	"epub": {{0, []byte("PK\x03\x04")}},
+	"rar":  {{0, []byte("Rar!\x1a\x07")}},
+	"7z":   {{0, []byte("7z\xbc\xaf\x27\x1c")}},
+	"gz":   {{0, []byte{0x1F, 0x8B}}},
+	"mp4":  {{4, []byte("ftyp")}},
+	"m4v":  {{4, []byte("ftyp")}},
+	"m4a":  {{4, []byte("ftyp")}},
+	"mov":  quickTimeSignatures,
+	"webm": {{0, []byte{0x1A, 0x45, 0xDF, 0xA3}}},
+	"mkv":  {{0, []byte{0x1A, 0x45, 0xDF, 0xA3}}},
+	"mp3":  {{0, []byte("ID3")}},
+	"wav":  {{8, []byte("WAVE")}},
+	"ogg":  {{0, []byte("OggS")}},
+	"flac": {{0, []byte("fLaC")}},
+}
+
+// maskedSignatures contains the masked magic bytes of the file extensions
+// which are checked in addition to the magic bytes in fileSignatures.
+var maskedSignatures = map[string][]maskedSignature{
+	// the 11-bit frame sync of MPEG audio frames, e.g. FF FB, FF FA, FF F3, or FF E3
+	"mp3": {{magicSignature{0, []byte{0xFF, 0xE0}}, []byte{0xFF, 0xE0}}},
+}
+
+// errorPageTypes are the content types of the error pages returned by the servers
+var errorPageTypes = []string{
+	"text/html",
+	"application/xhtml+xml",
+	"text/xml",
+	"application/xml",
+	"application/json",
+}
+
+// textFileExts are the file extensions where an error page content type is expected
+var textFileExts = []string{"html", "htm", "xhtml", "xml", "json", "txt", "md", "svg"}
+
+func getFileExt(filePath string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(filePath), "."))
+}
+
+func isErrorPageType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return utils.SliceContains(errorPageTypes, mediaType)
+}
+
+func matchesSignature(content []byte, signatures []magicSignature) bool {
+	for _, signature := range signatures {
+		end := signature.offset + len(signature.magic)
+		if len(content) >= end && bytes.Equal(content[signature.offset:end], signature.magic) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesMaskedSignature(content []byte, signatures []maskedSignature) bool {
+	for _, signature := range signatures {
+		end := signature.offset + len(signature.magic)
+		if len(content) < end {
+			continue
+		}
+
+		matched := true
+		for idx, b := range content[signature.offset:end] {
+			if b&signature.mask[idx] != signature.magic[idx] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// validateContent checks the Content-Type header and the magic bytes of the
+// response body against the file extension before the body is written to the file.
+//
+// Returns a reader that must be used in place of the response body as the first few bytes have been read.
+func validateContent(res *http.Response, url, filePath string) (io.Reader, error) {
+	ext := getFileExt(filePath)
+	reader := bufio.NewReaderSize(res.Body, sniffLen)
+	if utils.SliceContains(textFileExts, ext) {
+		return reader, nil
+	}
+
+	contentType := res.Header.Get("Content-Type")
+	mismatchErr := &ContentMismatchError{
+		Url:         url,
+		FilePath:    filePath,
+		ContentType: contentType,
+	}
+	if isErrorPageType(contentType) {
+		return nil, mismatchErr
+	}
+
+	// The start of the file is not in the response when resuming a download
+	// and was already checked when the download was started.
+	if res.StatusCode == http.StatusPartialContent {
+		return reader, nil
+	}
+
+	content, err := reader.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	mismatchErr.Detected = http.DetectContentType(content)
+	signatures, hasSignatures := fileSignatures[ext]
+	masked, hasMasked := maskedSignatures[ext]
+	if hasSignatures || hasMasked {
+		if !matchesSignature(content, signatures) && !matchesMaskedSignature(content, masked) {
+			return nil, mismatchErr
+		}
+	} else if isErrorPageType(mismatchErr.Detected) {
+		return nil, mismatchErr
+	}
+	return reader, nil
+}
@@ -0,0 +1,101 @@
+package request
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestValidateContent(t *testing.T) {
+	tests := []struct {
+		name        string
+		filePath    string
+		statusCode  int
+		contentType string
+		body        string
+		wantErr     bool
+	}{
+		{name: "jpeg", filePath: "a.jpg", contentType: "image/jpeg", body: "\xFF\xD8\xFF\xE0rest"},
+		{name: "uppercase extension", filePath: "a.JPG", body: "\xFF\xD8\xFF\xE0rest"},
+		{name: "png", filePath: "a.png", body: "\x89PNG\r\n\x1a\nrest"},
+		{name: "webp at an offset", filePath: "a.webp", body: "RIFF\x00\x00\x00\x00WEBPVP8 "},
+		{name: "gif89a", filePath: "a.gif", body: "GIF89a..."},
+		{name: "zip", filePath: "a.zip", body: "PK\x03\x04rest"},
+		{name: "empty zip", filePath: "a.zip", body: "PK\x05\x06rest"},
+		{name: "mp4", filePath: "a.mp4", body: "\x00\x00\x00\x18ftypmp42"},
+		{name: "mp3 with ID3 tag", filePath: "a.mp3", body: "ID3\x04\x00rest"},
+		{name: "mp3 MPEG-1 layer 3 frame", filePath: "a.mp3", body: "\xFF\xFB\x90\x00rest"},
+		{name: "mp3 MPEG-2 frame", filePath: "a.mp3", body: "\xFF\xF3\x90\x00rest"},
+		{name: "mp3 MPEG-2.5 frame", filePath: "a.mp3", body: "\xFF\xE3\x90\x00rest"},
+		{name: "mov with ftyp atom", filePath: "a.mov", body: "\x00\x00\x00\x14ftypqt  "},
+		{name: "mov with moov atom", filePath: "a.mov", body: "\x00\x00\x01\x00moovrest"},
+		{name: "mov with wide atom", filePath: "a.mov", body: "\x00\x00\x00\x08widerest"},
+		{name: "mov with mdat atom", filePath: "a.mov", body: "\x00\x00\x01\x00mdatrest"},
+		{name: "unknown extension with binary content", filePath: "a.bin", body: "\x00\x01\x02\x03"},
+		{name: "text file with html content type", filePath: "a.html", contentType: "text/html", body: "<html></html>"},
+		{name: "json file", filePath: "a.json", contentType: "application/json; charset=utf-8", body: "{}"},
+
+		{name: "html error page as jpeg", filePath: "a.jpg", contentType: "text/html; charset=utf-8", body: "<html></html>", wantErr: true},
+		{name: "json error as png", filePath: "a.png", contentType: "application/json", body: `{"error":"forbidden"}`, wantErr: true},
+		{name: "wrong magic bytes", filePath: "a.png", contentType: "image/png", body: "\xFF\xD8\xFF\xE0rest", wantErr: true},
+		{name: "truncated magic bytes", filePath: "a.png", body: "\x89PN", wantErr: true},
+		{name: "mp3 without frame sync", filePath: "a.mp3", body: "\xFF\xC0\x90\x00rest", wantErr: true},
+		{name: "mov with unknown atom", filePath: "a.mov", body: "\x00\x00\x00\x08abcdrest", wantErr: true},
+		{name: "sniffed html for unknown extension", filePath: "a.bin", body: "<!DOCTYPE html><html></html>", wantErr: true},
+		{
+			name:        "resumed download skips the magic bytes",
+			filePath:    "a.png",
+			statusCode:  http.StatusPartialContent,
+			contentType: "image/png",
+			body:        "middle of the file",
+		},
+		{
+			name:        "resumed download still checks for error pages",
+			filePath:    "a.png",
+			statusCode:  http.StatusPartialContent,
+			contentType: "text/html",
+			body:        "<html></html>",
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statusCode := test.statusCode
+			if statusCode == 0 {
+				statusCode = http.StatusOK
+			}
+			res := &http.Response{
+				StatusCode: statusCode,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(test.body)),
+			}
+			if test.contentType != "" {
+				res.Header.Set("Content-Type", test.contentType)
+			}
+
+			reader, err := validateContent(res, "https://example.com/"+test.filePath, test.filePath)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("validateContent() error = %v, wantErr %t", err, test.wantErr)
+			}
+			if err != nil {
+				var mismatchErr *ContentMismatchError
+				if !errors.As(err, &mismatchErr) {
+					t.Errorf("validateContent() error = %T, want *ContentMismatchError", err)
+				}
+				return
+			}
+
+			// the returned reader must still contain the bytes that were read to check the content
+			content, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("failed to read the returned reader: %v", err)
+			}
+			if string(content) != test.body {
+				t.Errorf("returned reader = %q, want %q", content, test.body)
+			}
+		})
+	}
+}
@@ -50,10 +50,12 @@ const (
 	MAX_API_CALLS                  = 10
 
 	PAGE_NUM_REGEX_STR = `[1-9]\d*(-[1-9]\d*)?`
-	DOWNLOAD_TIMEOUT   = 25 * 60 // 25 minutes in seconds as downloads
-	// can take quite a while for large files (especially for Pixiv)
-	// However, the average max file size on these platforms is around 300MB.
-	// Note: Fantia do have a max file size per post of 3GB if one paid extra for it.
+	DOWNLOAD_TIMEOUT   = 30 // seconds to wait for the response headers of a download
+
+	// DOWNLOAD_IDLE_TIMEOUT is the number of seconds to wait for more data of a download
+	// instead of a deadline for the whole download as large files can take quite a while,
+	// especially with a bandwidth limit (Fantia has a max file size per post of 3GB if one paid extra for it).
+	DOWNLOAD_IDLE_TIMEOUT = 60
 
 	FANTIA               = "fantia"
 	FANTIA_TITLE         = "Fantia"
@@ -80,6 +82,7 @@ const (
 	KEMONO_COOKIE_BACKUP_DOMAIN = "kemono.su"
 	KEMONO_TITLE                = "Kemono Party"
 	KEMONO_PER_PAGE             = 50
+	KEMONO_DISCORD_PER_PAGE     = 150
 	KEMONO_TLD                  = "party"
 	KEMONO_BACKUP_TLD           = "su"
 	KEMONO_URL                  = "https://kemono.party"
@@ -87,6 +90,15 @@ const (
 	BACKUP_KEMONO_URL           = "https://kemono.su"
 	BACKUP_KEMONO_API_URL       = "https://kemono.su/api/v1"
 
+	// Coomer Party runs the same API as Kemono Party for OnlyFans, Fansly, and CandFans
+	COOMER                      = "coomer"
+	COOMER_COOKIE_DOMAIN        = "coomer.party"
+	COOMER_BACKUP               = "coomer_backup"
+	COOMER_COOKIE_BACKUP_DOMAIN = "coomer.su"
+	COOMER_TITLE                = "Coomer Party"
+	COOMER_URL                  = "https://coomer.party"
+	BACKUP_COOMER_URL           = "https://coomer.su"
+
 	PASSWORD_FILENAME = "detected_passwords.txt"
 	ATTACHMENT_FOLDER = "attachments"
 	IMAGES_FOLDER     = "images"
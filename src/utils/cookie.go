@@ -50,6 +50,18 @@ func GetSessionCookieInfo(site string) *cookieInfo {
 			Name:     KEMONO_SESSION_COOKIE_NAME,
 			SameSite: http.SameSiteNoneMode,
 		}
+	case COOMER:
+		return &cookieInfo{
+			Domain:   COOMER_COOKIE_DOMAIN,
+			Name:     KEMONO_SESSION_COOKIE_NAME,
+			SameSite: http.SameSiteNoneMode,
+		}
+	case COOMER_BACKUP:
+		return &cookieInfo{
+			Domain:   COOMER_COOKIE_BACKUP_DOMAIN,
+			Name:     KEMONO_SESSION_COOKIE_NAME,
+			SameSite: http.SameSiteNoneMode,
+		}
 	default:
 		panic(
 			fmt.Errorf(
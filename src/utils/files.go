@@ -35,6 +35,26 @@ func GetFileSize(filePath string) (int64, error) {
 	return fileInfo.Size(), nil
 }
 
+// FormatFileSize returns the size in a human readable format such as "1.50 MiB"
+// or "unknown" if the size is negative.
+func FormatFileSize(size int64) string {
+	if size < 0 {
+		return "unknown"
+	}
+
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(size)
+	unitIdx := 0
+	for value >= 1024 && unitIdx < len(units)-1 {
+		value /= 1024
+		unitIdx++
+	}
+	if unitIdx == 0 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.2f %s", value, units[unitIdx])
+}
+
 // Uses bufio.Reader to read a line from a file and returns it as a byte slice
 //
 // Mostly thanks to https://devmarkpro.com/working-big-files-golang
@@ -22,7 +22,7 @@ func IsHttp3Supported(site string, isApi bool) bool {
 		return !isApi
 	case PIXIV_MOBILE:
 		return true
-	case KEMONO, KEMONO_BACKUP:
+	case KEMONO, KEMONO_BACKUP, COOMER, COOMER_BACKUP:
 		return false
 	default:
 		panic(
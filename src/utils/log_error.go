@@ -140,12 +140,28 @@ func LogErrors(exit bool, errChan chan error, level int, errs ...error) bool {
 	return hasCanceled
 }
 
-var logToPathMux sync.Mutex
+var (
+	logToPathMux      sync.Mutex
+	logToPathDisabled bool
+)
+
+// DisableLogToPath disables LogMessageToPath so that no log files
+// such as the detected passwords or URLs are written to the post folders.
+//
+// Used for the dry-run mode where nothing should be written to the disk.
+func DisableLogToPath() {
+	logToPathMux.Lock()
+	defer logToPathMux.Unlock()
+	logToPathDisabled = true
+}
 
 // Thread-safe logging function that logs to the provided file path
 func LogMessageToPath(message, filePath string, level int) {
 	logToPathMux.Lock()
 	defer logToPathMux.Unlock()
+	if logToPathDisabled {
+		return
+	}
 
 	os.MkdirAll(filepath.Dir(filePath), 0755)
 	if PathExists(filePath) {
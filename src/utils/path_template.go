@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Content kinds of the files that are directly in the post folder.
+// Files in a sub-folder of the post folder uses the sub-folder name as its content kind, e.g. "images".
+const (
+	THUMBNAIL_KIND = "thumbnail"
+	ARTWORK_KIND   = "artwork"
+	UGOIRA_KIND    = "ugoira"
+
+	// REVISION_KIND is the content kind of the files of an earlier revision of a post
+	REVISION_KIND = "revision"
+)
+
+// PathVars contains the values of the variables that can be used in a path template
+type PathVars struct {
+	Site        string
+	CreatorName string
+	CreatorId   string
+	PostId      string
+	PostTitle   string
+	PublishedAt time.Time
+
+	// RevisionId is the ID of the earlier revision of the post that the file belongs to.
+	// Empty for the files of the current post.
+	RevisionId string
+
+	// Kind is the content kind of the file, e.g. "images", "attachments", "thumbnail", etc.
+	Kind string
+
+	// Index is the 1-based index of the file in the post
+	Index int
+}
+
+// ForFile returns a copy of the path variables for a file of the post
+func (v *PathVars) ForFile(kind string, index int) *PathVars {
+	fileVars := *v
+	fileVars.Kind = kind
+	fileVars.Index = index
+	return &fileVars
+}
+
+// ForRevision returns a copy of the path variables for the files of an earlier revision of the post
+func (v *PathVars) ForRevision(revisionId string) *PathVars {
+	revisionVars := *v
+	revisionVars.RevisionId = revisionId
+	return &revisionVars
+}
+
+// GetContentKind returns the content kind of a file based on its
+// default file path (or folder path) in the post folder.
+//
+// E.g. "images" for "<postFolderPath>/images/1.jpg" or rootKind for "<postFolderPath>/1.jpg"
+func GetContentKind(postFolderPath, filePath, rootKind string) string {
+	relPath, err := filepath.Rel(postFolderPath, filePath)
+	if err != nil {
+		return rootKind
+	}
+
+	folderName := strings.SplitN(filepath.ToSlash(relPath), "/", 2)[0]
+	switch folderName {
+	case IMAGES_FOLDER, ATTACHMENT_FOLDER, GDRIVE_FOLDER, KEMONO_CONTENT_FOLDER, KEMONO_EMBEDS_FOLDER:
+		return folderName
+	default:
+		return rootKind
+	}
+}
+
+var pathTemplateVars = map[string]struct{}{
+	"site":        {},
+	"creator":     {},
+	"creator_id":  {},
+	"post_id":     {},
+	"title":       {},
+	"yyyy":        {},
+	"mm":          {},
+	"dd":          {},
+	"yyyy-mm-dd":  {},
+	"index":       {},
+	"filename":    {},
+	"name":        {},
+	"ext":         {},
+	"kind":        {},
+	"revision_id": {},
+}
+
+type pathTemplateSegment struct {
+	literal  string
+	variable string
+	format   string // only used for the index variable, e.g. "03" for zero-padding to 3 digits
+}
+
+// PathTemplate is a parsed output path template such as
+// "{site}/{creator_id}/{yyyy}/{yyyy-mm-dd}_{post_id}/{index:03}_{filename}"
+type PathTemplate struct {
+	raw      string
+	segments []*pathTemplateSegment
+}
+
+func getPathTemplateErr(template, msg string) error {
+	return fmt.Errorf(
+		"error %d: invalid path template, %q, %s",
+		INPUT_ERROR,
+		template,
+		msg,
+	)
+}
+
+// ParsePathTemplate parses the output path template.
+//
+// Variables: {site}, {creator}, {creator_id}, {post_id}, {title}, {yyyy}, {mm}, {dd}, {yyyy-mm-dd},
+// {index} (or {index:03} to zero-pad it), {filename}, {name} (filename without the extension), {ext}, {kind},
+// and {revision_id} (empty for files that are not from an earlier revision of a post).
+func ParsePathTemplate(template string) (*PathTemplate, error) {
+	template = filepath.ToSlash(strings.TrimSpace(template))
+	if template == "" || strings.HasPrefix(template, "/") || filepath.IsAbs(template) {
+		return nil, getPathTemplateErr(template, "it must be a non-empty relative path")
+	}
+	for _, part := range strings.Split(template, "/") {
+		if part == ".." {
+			return nil, getPathTemplateErr(template, "it must not contain \"..\"")
+		}
+	}
+
+	var segments []*pathTemplateSegment
+	hasFilename := false
+	remaining := template
+	for remaining != "" {
+		start := strings.IndexByte(remaining, '{')
+		if start == -1 {
+			if strings.ContainsRune(remaining, '}') {
+				return nil, getPathTemplateErr(template, "found an unexpected \"}\"")
+			}
+			segments = append(segments, &pathTemplateSegment{literal: remaining})
+			break
+		}
+		if start > 0 {
+			if strings.ContainsRune(remaining[:start], '}') {
+				return nil, getPathTemplateErr(template, "found an unexpected \"}\"")
+			}
+			segments = append(segments, &pathTemplateSegment{literal: remaining[:start]})
+		}
+
+		end := strings.IndexByte(remaining[start:], '}')
+		if end == -1 {
+			return nil, getPathTemplateErr(template, "found an unclosed \"{\"")
+		}
+		variable, format, _ := strings.Cut(remaining[start+1:start+end], ":")
+		if _, ok := pathTemplateVars[variable]; !ok {
+			return nil, getPathTemplateErr(template, fmt.Sprintf("unknown variable {%s}", variable))
+		}
+		if format != "" {
+			if _, err := strconv.Atoi(format); variable != "index" || err != nil {
+				return nil, getPathTemplateErr(
+					template,
+					fmt.Sprintf("invalid format %q for {%s}, only {index} can be zero-padded such as {index:03}", format, variable),
+				)
+			}
+		}
+		if variable == "filename" || variable == "name" {
+			hasFilename = true
+		}
+
+		segments = append(segments, &pathTemplateSegment{variable: variable, format: format})
+		remaining = remaining[start+end+1:]
+	}
+
+	if !hasFilename || strings.HasSuffix(template, "/") {
+		return nil, getPathTemplateErr(template, "it must end with a filename containing {filename} or {name}")
+	}
+	return &PathTemplate{raw: template, segments: segments}, nil
+}
+
+// String returns the raw path template
+func (t *PathTemplate) String() string {
+	return t.raw
+}
+
+func formatPathDate(publishedAt time.Time, layout string) string {
+	if publishedAt.IsZero() {
+		return "unknown"
+	}
+	return publishedAt.Format(layout)
+}
+
+func (t *PathTemplate) getValue(segment *pathTemplateSegment, vars *PathVars, name, ext string) string {
+	switch segment.variable {
+	case "site":
+		return CleanPathName(vars.Site)
+	case "creator":
+		return CleanPathName(vars.CreatorName)
+	case "creator_id":
+		return CleanPathName(vars.CreatorId)
+	case "post_id":
+		return CleanPathName(vars.PostId)
+	case "title":
+		return CleanPathName(vars.PostTitle)
+	case "yyyy":
+		return formatPathDate(vars.PublishedAt, "2006")
+	case "mm":
+		return formatPathDate(vars.PublishedAt, "01")
+	case "dd":
+		return formatPathDate(vars.PublishedAt, "02")
+	case "yyyy-mm-dd":
+		return formatPathDate(vars.PublishedAt, "2006-01-02")
+	case "index":
+		width, _ := strconv.Atoi(segment.format)
+		return fmt.Sprintf("%0*d", width, vars.Index)
+	case "filename":
+		if ext == "" {
+			return CleanPathName(name)
+		}
+		return CleanPathName(name) + "." + CleanPathName(ext)
+	case "name":
+		return CleanPathName(name)
+	case "ext":
+		return CleanPathName(ext)
+	case "kind":
+		return CleanPathName(vars.Kind)
+	case "revision_id":
+		return CleanPathName(vars.RevisionId)
+	default:
+		return ""
+	}
+}
+
+// Execute returns the file path for the file with the given filename
+// by substituting the variables in the path template.
+//
+// Each substituted variable is sanitised with CleanPathName.
+func (t *PathTemplate) Execute(downloadPath string, vars *PathVars, filename string) string {
+	ext := filepath.Ext(filename)
+	name := strings.TrimSuffix(filename, ext)
+	ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+
+	var pathBuilder strings.Builder
+	for _, segment := range t.segments {
+		if segment.variable == "" {
+			pathBuilder.WriteString(segment.literal)
+		} else {
+			pathBuilder.WriteString(t.getValue(segment, vars, name, ext))
+		}
+	}
+	return filepath.Join(downloadPath, filepath.FromSlash(pathBuilder.String()))
+}
@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParsePathTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  bool
+	}{
+		{name: "default example", template: "{site}/{creator_id}/{yyyy}/{yyyy-mm-dd}_{post_id}/{index:03}_{filename}"},
+		{name: "name and extension", template: "{creator}/{title}/{name}.{ext}"},
+		{name: "surrounding whitespace", template: "  {site}/{filename}  "},
+		{name: "revision kind", template: "{post_id}/{kind}/{revision_id}/{filename}"},
+		{name: "empty", template: "", wantErr: true},
+		{name: "absolute path", template: "/{site}/{filename}", wantErr: true},
+		{name: "parent folder", template: "{site}/../{filename}", wantErr: true},
+		{name: "unknown variable", template: "{site}/{unknown}/{filename}", wantErr: true},
+		{name: "unclosed brace", template: "{site/{filename}", wantErr: true},
+		{name: "unexpected closing brace", template: "site}/{filename}", wantErr: true},
+		{name: "unexpected closing brace after a variable", template: "{site}}/{filename}", wantErr: true},
+		{name: "no filename", template: "{site}/{post_id}", wantErr: true},
+		{name: "ends with a folder", template: "{site}/{filename}/", wantErr: true},
+		{name: "format on a variable other than index", template: "{post_id:03}/{filename}", wantErr: true},
+		{name: "invalid index format", template: "{index:abc}_{filename}", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParsePathTemplate(test.template)
+			if (err != nil) != test.wantErr {
+				t.Errorf("ParsePathTemplate(%q) error = %v, wantErr %t", test.template, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestPathTemplateExecute(t *testing.T) {
+	vars := &PathVars{
+		Site:        "fantia",
+		CreatorName: "Creator: Name",
+		CreatorId:   "123",
+		PostId:      "456",
+		PostTitle:   "Title/With*Chars?",
+		PublishedAt: time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC),
+		Kind:        IMAGES_FOLDER,
+		Index:       7,
+	}
+	tests := []struct {
+		name     string
+		template string
+		vars     *PathVars
+		filename string
+		want     string
+	}{
+		{
+			name:     "default example",
+			template: "{site}/{creator_id}/{yyyy}/{yyyy-mm-dd}_{post_id}/{index:03}_{filename}",
+			vars:     vars,
+			filename: "image.JPG",
+			want:     "fantia/123/2024/2024-01-02_456/007_image.jpg",
+		},
+		{
+			name:     "date parts, name, and extension",
+			template: "{mm}-{dd}/{kind}/{name}.{ext}",
+			vars:     vars,
+			filename: "archive.tar.gz",
+
+			// the dots in the name are replaced by CleanPathName like the other path names
+			want: "01-02/images/archive,tar.gz",
+		},
+		{
+			name:     "unpadded index",
+			template: "{index}_{filename}",
+			vars:     vars,
+			filename: "a.png",
+			want:     "7_a.png",
+		},
+		{
+			name:     "filename without an extension",
+			template: "{post_id}/{filename}",
+			vars:     vars,
+			filename: "README",
+			want:     "456/README",
+		},
+		{
+			name:     "unknown published date",
+			template: "{yyyy-mm-dd}/{filename}",
+			vars:     &PathVars{},
+			filename: "a.png",
+			want:     "unknown/a.png",
+		},
+		{
+			name:     "revision ID",
+			template: "{post_id}/{kind}/{revision_id}/{filename}",
+			vars:     vars.ForRevision("789").ForFile(REVISION_KIND, 1),
+			filename: "a.png",
+			want:     "456/revision/789/a.png",
+		},
+		{
+			name:     "empty revision ID for current files",
+			template: "{post_id}/{revision_id}/{filename}",
+			vars:     vars,
+			filename: "a.png",
+			want:     "456/a.png",
+		},
+	}
+
+	downloadPath := filepath.Join("downloads", "root")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			template, err := ParsePathTemplate(test.template)
+			if err != nil {
+				t.Fatalf("ParsePathTemplate(%q) error = %v", test.template, err)
+			}
+			want := filepath.Join(downloadPath, filepath.FromSlash(test.want))
+			if got := template.Execute(downloadPath, test.vars, test.filename); got != want {
+				t.Errorf("Execute() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestPathTemplateExecuteCleansVariables(t *testing.T) {
+	template, err := ParsePathTemplate("{creator}/{title}/{filename}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := &PathVars{CreatorName: "Creator: Name", PostTitle: "Title/With*Chars?"}
+	want := filepath.Join("root", CleanPathName(vars.CreatorName), CleanPathName(vars.PostTitle), "a.png")
+	if got := template.Execute("root", vars, "a.png"); got != want {
+		t.Errorf("Execute() = %q, want %q", got, want)
+	}
+	if got := CleanPathName(vars.PostTitle); filepath.Base(got) != got {
+		t.Errorf("CleanPathName(%q) = %q, the variables must not add folders", vars.PostTitle, got)
+	}
+}
+
+func TestGetContentKind(t *testing.T) {
+	postFolderPath := filepath.Join("downloads", "post")
+	tests := []struct {
+		name     string
+		filePath string
+		rootKind string
+		want     string
+	}{
+		{name: "file in the post folder", filePath: filepath.Join(postFolderPath, "thumbnail.jpg"), rootKind: THUMBNAIL_KIND, want: THUMBNAIL_KIND},
+		{name: "images folder", filePath: filepath.Join(postFolderPath, IMAGES_FOLDER, "1.jpg"), rootKind: THUMBNAIL_KIND, want: IMAGES_FOLDER},
+		{name: "nested attachments folder", filePath: filepath.Join(postFolderPath, ATTACHMENT_FOLDER, "a", "b.zip"), rootKind: THUMBNAIL_KIND, want: ATTACHMENT_FOLDER},
+		{name: "gdrive folder", filePath: filepath.Join(postFolderPath, GDRIVE_FOLDER), rootKind: GDRIVE_FOLDER, want: GDRIVE_FOLDER},
+		{name: "unknown folder uses the root kind", filePath: filepath.Join(postFolderPath, "revisions", "1", IMAGES_FOLDER, "1.jpg"), rootKind: REVISION_KIND, want: REVISION_KIND},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := GetContentKind(postFolderPath, test.filePath, test.rootKind); got != test.want {
+				t.Errorf("GetContentKind() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestForFileCopiesVars(t *testing.T) {
+	vars := &PathVars{PostId: "1"}
+	fileVars := vars.ForFile(ARTWORK_KIND, 2)
+	if fileVars == vars || vars.Kind != "" || vars.Index != 0 {
+		t.Errorf("ForFile() must not modify the post's path variables")
+	}
+	if fileVars.PostId != "1" || fileVars.Kind != ARTWORK_KIND || fileVars.Index != 2 {
+		t.Errorf("ForFile() = %+v, want the post ID with the artwork kind and index 2", fileVars)
+	}
+}
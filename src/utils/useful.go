@@ -34,6 +34,8 @@ func GetReadableSiteStr(site string) string {
 		return PIXIV_TITLE
 	case KEMONO, KEMONO_BACKUP:
 		return KEMONO_TITLE
+	case COOMER, COOMER_BACKUP:
+		return COOMER_TITLE
 	default:
 		// panic since this is a dev error
 		panic(